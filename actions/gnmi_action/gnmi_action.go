@@ -72,6 +72,11 @@ type gnmiAction struct {
 	Values []string `mapstructure:"values,omitempty"`
 	// gNMI encoding
 	Encoding string `mapstructure:"encoding,omitempty"`
+	// master arbitration election ID, used in case RPC=`set*`.
+	// if not set, the target configured election ID is used.
+	ElectionID uint64 `mapstructure:"election-id,omitempty"`
+	// master arbitration role, used with ElectionID
+	Role string `mapstructure:"role,omitempty"`
 	// Debug
 	Debug bool `mapstructure:"debug,omitempty"`
 	// Ignore ENV proxy
@@ -310,7 +315,10 @@ func (g *gnmiAction) createGetRequest(in *actions.Context) (*gnmi.GetRequest, er
 }
 
 func (g *gnmiAction) createSetRequest(in *actions.Context) (*gnmi.SetRequest, error) {
-	gnmiOpts := make([]api.GNMIOption, 0, len(g.paths))
+	gnmiOpts := make([]api.GNMIOption, 0, len(g.paths)+1)
+	if g.ElectionID > 0 {
+		gnmiOpts = append(gnmiOpts, api.Extension_MasterArbitration(g.Role, g.ElectionID))
+	}
 	var err error
 	b := new(bytes.Buffer)
 	if g.Prefix != "" {
@@ -7,6 +7,7 @@ import (
 	"github.com/karimra/gnmic/formatters"
 	"github.com/karimra/gnmic/testutils"
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
 )
 
 type getRequestTestItem struct {
@@ -156,6 +157,51 @@ var setRequestTestSet = map[string]struct {
 			},
 		},
 	},
+	"set_with_master_arbitration": {
+		actionType: actionType,
+		action: map[string]interface{}{
+			"type":        "gnmi",
+			"name":        "act1",
+			"rpc":         "set",
+			"paths":       []string{"/path"},
+			"values":      []string{"value1"},
+			"election-id": 42,
+			"role":        "role1",
+		},
+		tests: []setRequestTestItem{
+			{
+				input: nil,
+				output: &gnmi.SetRequest{
+					Update: []*gnmi.Update{
+						{
+							Path: &gnmi.Path{
+								Elem: []*gnmi.PathElem{
+									{
+										Name: "path",
+									},
+								},
+							},
+							Val: &gnmi.TypedValue{
+								Value: &gnmi.TypedValue_JsonVal{
+									JsonVal: []byte("\"value1\""),
+								},
+							},
+						},
+					},
+					Extension: []*gnmi_ext.Extension{
+						{
+							Ext: &gnmi_ext.Extension_MasterArbitration{
+								MasterArbitration: &gnmi_ext.MasterArbitration{
+									Role:       &gnmi_ext.Role{Id: "role1"},
+									ElectionId: &gnmi_ext.Uint128{Low: 42},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
 	"set_with_templates_in_path": {
 		actionType: actionType,
 		action: map[string]interface{}{
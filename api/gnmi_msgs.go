@@ -367,6 +367,36 @@ func Extension_HistoryRange(start, end string) func(msg proto.Message) error {
 	}
 }
 
+// Extension_MasterArbitration creates a GNMIOption that adds a gNMI extension of
+// type MasterArbitration with the supplied role and election ID.
+// the role is optional, an empty role is the default role.
+func Extension_MasterArbitration(role string, electionID uint64) func(msg proto.Message) error {
+	return func(msg proto.Message) error {
+		if msg == nil {
+			return ErrInvalidMsgType
+		}
+		switch msg := msg.ProtoReflect().Interface().(type) {
+		case *gnmi.SetRequest:
+			ma := &gnmi_ext.MasterArbitration{
+				ElectionId: &gnmi_ext.Uint128{Low: electionID},
+			}
+			if role != "" {
+				ma.Role = &gnmi_ext.Role{Id: role}
+			}
+			fn := Extension(
+				&gnmi_ext.Extension{
+					Ext: &gnmi_ext.Extension_MasterArbitration{
+						MasterArbitration: ma,
+					},
+				},
+			)
+			return fn(msg)
+		default:
+			return fmt.Errorf("option Extension_MasterArbitration: %w: %T", ErrInvalidMsgType, msg)
+		}
+	}
+}
+
 // Prefix creates a GNMIOption that creates a *gnmi.Path and adds it to the supplied
 // proto.Message (as a Path Prefix).
 // The proto.Message can be a *gnmi.GetRequest, *gnmi.SetRequest or a *gnmi.SubscribeRequest with RequestType Subscribe.
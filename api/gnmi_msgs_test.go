@@ -763,6 +763,33 @@ var setRequestTestSet = map[string]setRequestInput{
 			},
 		},
 	},
+	"master_arbitration": {
+		opts: []GNMIOption{
+			Delete("/system/name/host-name"),
+			Extension_MasterArbitration("role1", 42),
+		},
+		req: &gnmi.SetRequest{
+			Delete: []*gnmi.Path{
+				{
+					Elem: []*gnmi.PathElem{
+						{Name: "system"},
+						{Name: "name"},
+						{Name: "host-name"},
+					},
+				},
+			},
+			Extension: []*gnmi_ext.Extension{
+				{
+					Ext: &gnmi_ext.Extension_MasterArbitration{
+						MasterArbitration: &gnmi_ext.MasterArbitration{
+							Role:       &gnmi_ext.Role{Id: "role1"},
+							ElectionId: &gnmi_ext.Uint128{Low: 42},
+						},
+					},
+				},
+			},
+		},
+	},
 }
 
 func TestNewSetRequest(t *testing.T) {
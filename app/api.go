@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -33,6 +35,7 @@ func (a *App) newAPIServer() (*http.Server, error) {
 		a.reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 		a.reg.MustRegister(subscribeResponseReceivedCounter)
 		go a.startClusterMetrics()
+		go a.startTargetsMetrics()
 	}
 	s := &http.Server{
 		Addr:         a.Config.APIServer.Address,
@@ -86,6 +89,13 @@ func (a *App) handleConfigTargetsPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
+	// with instance placement, targets added to a single instance
+	// would not be part of the other instances placement.
+	if a.instancePlacement() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target config add %v", errInstancePlacement)}})
+		return
+	}
 	tc := new(types.TargetConfig)
 	err = json.Unmarshal(body, tc)
 	if err != nil {
@@ -103,6 +113,11 @@ func (a *App) handleConfigTargetsPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *App) handleConfigTargetsDelete(w http.ResponseWriter, r *http.Request) {
+	if a.instancePlacement() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target config delete %v", errInstancePlacement)}})
+		return
+	}
 	vars := mux.Vars(r)
 	id := vars["id"]
 	err := a.DeleteTarget(r.Context(), id)
@@ -114,7 +129,77 @@ func (a *App) handleConfigTargetsDelete(w http.ResponseWriter, r *http.Request)
 }
 
 func (a *App) handleConfigSubscriptions(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, r, a.Config.Subscriptions)
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		a.handlerCommonGet(w, r, a.Config.Subscriptions)
+		return
+	}
+	a.configLock.RLock()
+	sc, ok := a.Config.Subscriptions[id]
+	a.configLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("subscription %q not found", id)}})
+		return
+	}
+	a.handlerCommonGet(w, r, sc)
+}
+
+func (a *App) handleConfigSubscriptionsPost(w http.ResponseWriter, r *http.Request) {
+	sc, err := readSubscriptionConfig(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	err = a.AddSubscriptionConfig(sc)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+}
+
+func (a *App) handleConfigSubscriptionsPut(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	sc, err := readSubscriptionConfig(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	if sc.Name == "" {
+		sc.Name = id
+	}
+	if sc.Name != id {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("subscription name %q does not match %q", sc.Name, id)}})
+		return
+	}
+	if !a.subscriptionConfigExists(id) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("subscription %q not found", id)}})
+		return
+	}
+	err = a.UpdateSubscriptionConfig(sc)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+}
+
+func (a *App) handleConfigSubscriptionsDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	err := a.DeleteSubscriptionConfig(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
 }
 
 func (a *App) handleConfigOutputs(w http.ResponseWriter, r *http.Request) {
@@ -160,6 +245,26 @@ func (a *App) handleTargetsGet(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(APIErrors{Errors: []string{"no targets found"}})
 }
 
+func (a *App) handleTargetsStateGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	a.operLock.RLock()
+	t, ok := a.Targets[id]
+	a.operLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target %q not found", id)}})
+		return
+	}
+	b, err := json.Marshal(t.State())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	w.Write(b)
+}
+
 func (a *App) handleTargetsPost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -400,6 +505,185 @@ func (a *App) handleClusteringLeaderGet(w http.ResponseWriter, r *http.Request)
 	w.Write(b)
 }
 
+type clusterMovesResponse struct {
+	Moves []*targetMove `json:"moves,omitempty"`
+}
+
+func (a *App) handleClusteringDrainPost(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := a.checkClusterLeader(w)
+	if !ok {
+		return
+	}
+	batchSize, interval, err := readMovesBatch(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	moves, err := a.planDrainMoves(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.Logger.Printf("[cluster-leader] draining instance %q, moving %d target(s)", id, len(moves))
+	go a.executeMoves(ctx, moves, batchSize, interval)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(clusterMovesResponse{Moves: moves})
+}
+
+func (a *App) handleClusteringDrainDelete(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.checkClusterLeader(w); !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	drained, err := a.isDrained(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	if !drained {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("instance %q is not drained", id)}})
+		return
+	}
+	err = a.setDrained(r.Context(), id, false)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.Logger.Printf("[cluster-leader] instance %q is no longer drained", id)
+}
+
+func (a *App) handleClusteringRebalancePost(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := a.checkClusterLeader(w)
+	if !ok {
+		return
+	}
+	batchSize, interval, err := readMovesBatch(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	moves, err := a.planRebalanceMoves(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.Logger.Printf("[cluster-leader] rebalancing cluster, moving %d target(s)", len(moves))
+	go a.executeMoves(ctx, moves, batchSize, interval)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(clusterMovesResponse{Moves: moves})
+}
+
+func (a *App) handleClusteringTargetMovePost(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := a.checkClusterLeader(w)
+	if !ok {
+		return
+	}
+	if a.instancePlacement() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{errInstancePlacement.Error()}})
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	instance := r.URL.Query().Get("instance")
+	if instance == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"missing instance query parameter"}})
+		return
+	}
+	if !a.targetConfigExists(id) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target %q not found", id)}})
+		return
+	}
+	if !a.isMember(instance) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("instance %q not found", instance)}})
+		return
+	}
+	mapping, err := a.getTargetToInstanceMapping()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	mv := &targetMove{Target: id, From: mapping[id], To: instance}
+	if mv.From == mv.To {
+		json.NewEncoder(w).Encode(clusterMovesResponse{})
+		return
+	}
+	err = a.moveTarget(ctx, mv)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	json.NewEncoder(w).Encode(clusterMovesResponse{Moves: []*targetMove{mv}})
+}
+
+// checkClusterLeader writes an error response and returns false
+// if the instance is not the cluster leader.
+// It returns the context canceled when the instance loses the leader role.
+func (a *App) checkClusterLeader(w http.ResponseWriter) (context.Context, bool) {
+	if a.Config.Clustering == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"clustering is not enabled"}})
+		return nil, false
+	}
+	ctx, ok := a.leaderContext()
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"instance is not the cluster leader"}})
+		return nil, false
+	}
+	return ctx, true
+}
+
+// readMovesBatch reads the batch-size and batch-interval query parameters.
+func readMovesBatch(r *http.Request) (int, time.Duration, error) {
+	batchSize := defaultMoveBatchSize
+	interval := defaultMoveBatchInterval
+	var err error
+	q := r.URL.Query()
+	if v := q.Get("batch-size"); v != "" {
+		batchSize, err = strconv.Atoi(v)
+		if err != nil || batchSize <= 0 {
+			return 0, 0, fmt.Errorf("invalid batch-size %q", v)
+		}
+	}
+	if v := q.Get("batch-interval"); v != "" {
+		interval, err = time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			return 0, 0, fmt.Errorf("invalid batch-interval %q", v)
+		}
+	}
+	return batchSize, interval, nil
+}
+
+func readSubscriptionConfig(r *http.Request) (*types.SubscriptionConfig, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	sc := new(types.SubscriptionConfig)
+	err = json.Unmarshal(body, sc)
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
 func headersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -67,7 +67,15 @@ type App struct {
 	locker lockers.Locker
 	// api
 	apiServices map[string]*lockers.Service
-	isLeader    bool
+	// guards isLeader and leaderCtx
+	leaderLock *sync.RWMutex
+	isLeader   bool
+	// canceled when the instance loses the leader role
+	leaderCtx context.Context
+	// triggers a targets placement computation
+	placementCh chan struct{}
+	// serializes the leader targets dispatch and moves
+	dispatchLock *sync.Mutex
 	// prometheus registry
 	reg *prometheus.Registry
 	//
@@ -121,6 +129,8 @@ func New() *App {
 		//
 		router:        mux.NewRouter(),
 		apiServices:   make(map[string]*lockers.Service),
+		leaderLock:    new(sync.RWMutex),
+		placementCh:   make(chan struct{}, 1),
 		Logger:        log.New(io.Discard, "[gnmic] ", log.LstdFlags|log.Lmsgprefix),
 		out:           os.Stdout,
 		PromptHistory: make([]string, 0, 128),
@@ -130,6 +140,8 @@ func New() *App {
 
 		wg:        new(sync.WaitGroup),
 		printLock: new(sync.Mutex),
+		// clustering
+		dispatchLock: new(sync.Mutex),
 		// tunnel server
 		ttm:          new(sync.RWMutex),
 		tunTargets:   make(map[tunnel.Target]struct{}),
@@ -377,7 +389,7 @@ func (a *App) loadTargets(e fsnotify.Event) {
 			return
 		}
 		// in a cluster
-		if !a.isLeader {
+		if !a.leader() {
 			return
 		}
 		// in cluster && leader
@@ -117,30 +117,38 @@ func (a *App) startCluster() {
 	// register api service
 	go a.apiServiceRegistration()
 
+	if a.instancePlacement() {
+		// each instance watches the cluster members and the targets,
+		// and places the targets itself
+		go a.watchMembers(a.ctx)
+		go a.startPlacementLoader(a.ctx)
+		go a.runTargetPlacement(a.ctx)
+	}
+
 	leaderKey := a.leaderKey()
-	var err error
 START:
 	// acquire leader key lock
 	for {
-		a.isLeader = false
-		err = nil
-		a.isLeader, err = a.locker.Lock(a.ctx, leaderKey, []byte(a.Config.Clustering.InstanceName))
+		ok, err := a.locker.Lock(a.ctx, leaderKey, []byte(a.Config.Clustering.InstanceName))
 		if err != nil {
 			a.Logger.Printf("failed to acquire leader lock: %v", err)
 			time.Sleep(retryTimer)
 			continue
 		}
-		if !a.isLeader {
+		if !ok {
 			time.Sleep(retryTimer)
 			continue
 		}
-		a.isLeader = true
 		a.Logger.Printf("%q became the leader", a.Config.Clustering.InstanceName)
 		break
 	}
 	ctx, cancel := context.WithCancel(a.ctx)
 	defer cancel()
+	a.setLeader(ctx)
 	go func() {
+		if a.instancePlacement() {
+			return
+		}
 		go a.watchMembers(ctx)
 		a.Logger.Printf("leader waiting %s before dispatching targets", a.Config.Clustering.LeaderWaitTimer)
 		time.Sleep(a.Config.Clustering.LeaderWaitTimer)
@@ -154,18 +162,42 @@ START:
 	case <-doneCh:
 		a.Logger.Printf("%q lost leader role", a.Config.Clustering.InstanceName)
 		cancel()
-		a.isLeader = false
+		a.setLeader(nil)
 		goto START
 	case err := <-errCh:
 		a.Logger.Printf("%q failed to maintain the leader key: %v", a.Config.Clustering.InstanceName, err)
 		cancel()
-		a.isLeader = false
+		a.setLeader(nil)
 		goto START
 	case <-a.ctx.Done():
 		return
 	}
 }
 
+// setLeader records the leader role of the instance,
+// ctx is canceled when the role is lost, a nil ctx means the instance is not the leader.
+func (a *App) setLeader(ctx context.Context) {
+	a.leaderLock.Lock()
+	defer a.leaderLock.Unlock()
+	a.isLeader = ctx != nil
+	a.leaderCtx = ctx
+}
+
+// leader returns true if the instance is the cluster leader.
+func (a *App) leader() bool {
+	a.leaderLock.RLock()
+	defer a.leaderLock.RUnlock()
+	return a.isLeader
+}
+
+// leaderContext returns the context canceled when the instance loses the leader role,
+// and false if the instance is not the leader.
+func (a *App) leaderContext() (context.Context, bool) {
+	a.leaderLock.RLock()
+	defer a.leaderLock.RUnlock()
+	return a.leaderCtx, a.isLeader
+}
+
 func (a *App) watchMembers(ctx context.Context) {
 	serviceName := fmt.Sprintf("%s-%s", a.Config.Clustering.ClusterName, apiServiceName)
 START:
@@ -199,6 +231,9 @@ START:
 func (a *App) updateServices(srvs []*lockers.Service) {
 	a.configLock.Lock()
 	defer a.configLock.Unlock()
+	if a.instancePlacement() {
+		defer a.triggerPlacement()
+	}
 
 	numNewSrv := len(srvs)
 	numCurrentSrv := len(a.apiServices)
@@ -233,6 +268,11 @@ func (a *App) updateServices(srvs []*lockers.Service) {
 		if _, ok := newSrvs[n]; !ok {
 			a.Logger.Printf("deleting service id %q", n)
 			delete(a.apiServices, n)
+			// a drained instance that left the cluster
+			// is no longer drained if it joins again
+			if a.leader() && !a.instancePlacement() {
+				go a.undrainInstance(strings.TrimSuffix(n, "-api"))
+			}
 		}
 	}
 	// add new services
@@ -257,7 +297,9 @@ func (a *App) dispatchTargets(ctx context.Context) {
 			//a.m.RLock()
 			dctx, cancel := context.WithTimeout(ctx, a.Config.Clustering.TargetsWatchTimer)
 			for _, tc := range a.Config.Targets {
+				a.dispatchLock.Lock()
 				err = a.dispatchTarget(dctx, tc)
+				a.dispatchLock.Unlock()
 				if err != nil {
 					a.Logger.Printf("failed to dispatch target %q: %v", tc.Name, err)
 				}
@@ -301,7 +343,11 @@ func (a *App) dispatchTarget(ctx context.Context, tc *types.TargetConfig) error
 		return nil
 	}
 	a.Logger.Printf("dispatching target %q", tc.Name)
-	denied := make([]string, 0)
+	// drained instances are not assigned new targets
+	denied, err := a.drainedServices(ctx)
+	if err != nil {
+		return err
+	}
 SELECTSERVICE:
 	service, err := a.selectService(tc.Tags, denied...)
 	if err != nil {
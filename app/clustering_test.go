@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"sort"
 	"testing"
 
@@ -246,3 +247,33 @@ func TestGetHighestTagsMatches(t *testing.T) {
 		})
 	}
 }
+
+func TestLeaderContext(t *testing.T) {
+	a := New()
+	if _, ok := a.leaderContext(); ok {
+		t.Fatal("expected the instance not to be the leader")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			a.leader()
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	a.setLeader(ctx)
+	lctx, ok := a.leaderContext()
+	if !ok || !a.leader() {
+		t.Fatal("expected the instance to be the leader")
+	}
+	// losing the leader role cancels the leader context
+	cancel()
+	a.setLeader(nil)
+	<-done
+	if lctx.Err() == nil {
+		t.Error("expected the leader context to be canceled")
+	}
+	if a.leader() {
+		t.Error("expected the instance not to be the leader")
+	}
+}
@@ -125,7 +125,7 @@ func (a *App) yangFilesPreProcessing() error {
 		}
 		a.modules.AddPath(expanded...)
 	}
-	yfiles, err := findYangFiles(a.Config.GlobalFlags.File)
+	yfiles, err := utils.FindYangFiles(a.Config.GlobalFlags.File)
 	if err != nil {
 		return err
 	}
@@ -511,51 +511,3 @@ func resolveGlobs(globs []string) ([]string, error) {
 	}
 	return config.ExpandOSPaths(results)
 }
-
-func walkDir(path, ext string) ([]string, error) {
-	fs := make([]string, 0)
-	err := filepath.Walk(path,
-		func(path string, _ os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			fi, err := os.Stat(path)
-			if err != nil {
-				return err
-			}
-			switch mode := fi.Mode(); {
-			case mode.IsRegular():
-				if filepath.Ext(path) == ext {
-					fs = append(fs, path)
-				}
-			}
-			return nil
-		})
-	if err != nil {
-		return nil, err
-	}
-	return fs, nil
-}
-
-func findYangFiles(files []string) ([]string, error) {
-	yfiles := make([]string, 0, len(files))
-	for _, file := range files {
-		fi, err := os.Stat(file)
-		if err != nil {
-			return nil, err
-		}
-		switch mode := fi.Mode(); {
-		case mode.IsDir():
-			fls, err := walkDir(file, ".yang")
-			if err != nil {
-				return nil, err
-			}
-			yfiles = append(yfiles, fls...)
-		case mode.IsRegular():
-			if filepath.Ext(file) == ".yang" {
-				yfiles = append(yfiles, file)
-			}
-		}
-	}
-	return yfiles, nil
-}
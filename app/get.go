@@ -44,10 +44,11 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed reading actions config: %v", err)
 	}
-	evps, err := a.intializeEventProcessors()
+	evps, err := a.intializeEventProcessors(a.Config.GetProcessor)
 	if err != nil {
 		return fmt.Errorf("failed to init event processors: %v", err)
 	}
+	defer formatters.CloseProcessors(evps)
 	if a.PromptMode {
 		// prompt mode
 		for _, tc := range targetsConfig {
@@ -167,13 +168,14 @@ func (a *App) InitGetFlags(cmd *cobra.Command) {
 	})
 }
 
-func (a *App) intializeEventProcessors() ([]formatters.EventProcessor, error) {
+// intializeEventProcessors initializes the event processors listed in names, in order.
+func (a *App) intializeEventProcessors(names []string) ([]formatters.EventProcessor, error) {
 	_, err := a.Config.GetEventProcessors()
 	if err != nil {
 		return nil, fmt.Errorf("failed reading event processors config: %v", err)
 	}
 	var evps = make([]formatters.EventProcessor, 0)
-	for _, epName := range a.Config.GetProcessor {
+	for _, epName := range names {
 		if epCfg, ok := a.Config.Processors[epName]; ok {
 			epType := ""
 			for k := range epCfg {
@@ -186,6 +188,7 @@ func (a *App) intializeEventProcessors() ([]formatters.EventProcessor, error) {
 					formatters.WithLogger(a.Logger),
 					formatters.WithTargets(a.Config.Targets),
 					formatters.WithActions(a.Config.Actions),
+					formatters.WithProcessors(a.Config.Processors),
 				)
 				if err != nil {
 					return nil, fmt.Errorf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
@@ -168,6 +168,8 @@ func (a *App) InitGetSetFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetSetReplace, "replace", "", "", "set replace path template, a Go template or a jq expression")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetSetDelete, "delete", "", "", "set delete path template, a Go template or a jq expression")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetSetValue, "value", "", "", "set value template, a Go template or a jq expression")
+	cmd.Flags().Uint64VarP(&a.Config.LocalFlags.GetSetElectionID, "election-id", "", 0, "master arbitration election ID, if set the set request includes a master arbitration extension")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.GetSetRole, "role", "", "", "master arbitration role, used with --election-id")
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
 	})
@@ -156,20 +156,15 @@ func (a *App) clientSubscribe(ctx context.Context, tc *types.TargetConfig) error
 		return fmt.Errorf("unknown target name: %q", tc.Name)
 	}
 	a.operLock.RUnlock()
-	subscriptionsConfigs := t.Subscriptions
-	if len(subscriptionsConfigs) == 0 {
-		subscriptionsConfigs = a.Config.Subscriptions
-	}
+	subscriptionsConfigs := a.targetSubscriptions(t)
 	if len(subscriptionsConfigs) == 0 {
 		return fmt.Errorf("target %q has no subscriptions defined", tc.Name)
 	}
-	subRequests := make([]subscriptionRequest, 0)
 	for _, sc := range subscriptionsConfigs {
-		req, err := a.Config.CreateSubscribeRequest(sc, tc.Name)
+		_, err := a.subscribeRequest(sc, tc.Name)
 		if err != nil {
 			return err
 		}
-		subRequests = append(subRequests, subscriptionRequest{name: sc.Name, req: req})
 	}
 	if t.Cfn != nil {
 		t.Cfn()
@@ -199,13 +194,38 @@ CRCLIENT:
 			} else {
 				a.Logger.Printf("failed to initialize target %q: %v", tc.Name, err)
 			}
-			a.Logger.Printf("retrying target %q in %s", tc.Name, t.Config.RetryTimer)
-			time.Sleep(t.Config.RetryTimer)
-			goto CRCLIENT
+			delay := t.RetryDelay()
+			if t.CircuitOpen() {
+				a.Logger.Printf("target %q circuit open, retrying in %s", tc.Name, delay)
+			} else {
+				a.Logger.Printf("retrying target %q in %s", tc.Name, delay)
+			}
+			select {
+			case <-gnmiCtx.Done():
+				return gnmiCtx.Err()
+			case <-time.After(delay):
+				goto CRCLIENT
+			}
 		}
 	}
+	t.ResetRetry()
 	a.Logger.Printf("target %q gNMI client created", t.Config.Name)
-
+	// subscriptions added while the gNMI client was being created
+	// are part of the returned subscriptions list.
+	subRequests := make([]subscriptionRequest, 0, len(subscriptionsConfigs))
+	scs := t.SetSubscribeContext(gnmiCtx)
+	if len(scs) == 0 {
+		for _, sc := range subscriptionsConfigs {
+			scs = append(scs, sc)
+		}
+	}
+	for _, sc := range scs {
+		req, err := a.subscribeRequest(sc, tc.Name)
+		if err != nil {
+			return err
+		}
+		subRequests = append(subRequests, subscriptionRequest{name: sc.Name, req: req})
+	}
 	for _, sreq := range subRequests {
 		a.Logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
 			sreq.req, sreq.req.GetSubscribe().GetMode(), sreq.req.GetSubscribe().GetEncoding(), t.Config.Name)
@@ -224,16 +244,13 @@ func (a *App) clientSubscribeOnce(ctx context.Context, tc *types.TargetConfig) e
 		return fmt.Errorf("unknown target name: %q", tc.Name)
 	}
 
-	subscriptionsConfigs := t.Subscriptions
-	if len(subscriptionsConfigs) == 0 {
-		subscriptionsConfigs = a.Config.Subscriptions
-	}
+	subscriptionsConfigs := a.targetSubscriptions(t)
 	if len(subscriptionsConfigs) == 0 {
 		return fmt.Errorf("target %q has no subscriptions defined", tc.Name)
 	}
 	subRequests := make([]subscriptionRequest, 0)
 	for _, sc := range subscriptionsConfigs {
-		req, err := a.Config.CreateSubscribeRequest(sc, tc.Name)
+		req, err := a.subscribeRequest(sc, tc.Name)
 		if err != nil {
 			return err
 		}
@@ -259,11 +276,16 @@ CRCLIENT:
 		} else {
 			a.Logger.Printf("failed to initialize target %q: %v", tc.Name, err)
 		}
-		a.Logger.Printf("retrying target %q in %s", tc.Name, t.Config.RetryTimer)
-		time.Sleep(t.Config.RetryTimer)
-		goto CRCLIENT
-
+		delay := t.RetryDelay()
+		a.Logger.Printf("retrying target %q in %s", tc.Name, delay)
+		select {
+		case <-gnmiCtx.Done():
+			return gnmiCtx.Err()
+		case <-time.After(delay):
+			goto CRCLIENT
+		}
 	}
+	t.ResetRetry()
 	a.Logger.Printf("target %q gNMI client created", t.Config.Name)
 OUTER:
 	for _, sreq := range subRequests {
@@ -301,7 +323,8 @@ func (a *App) clientSubscribePoll(targetName, subscriptionName string) (*gnmi.Su
 	a.operLock.RLock()
 	defer a.operLock.RUnlock()
 	if t, ok := a.Targets[targetName]; ok {
-		if sub, ok := t.Subscriptions[subscriptionName]; ok {
+		subs, _ := t.GetSubscriptions()
+		if sub, ok := subs[subscriptionName]; ok {
 			if strings.ToUpper(sub.Mode) != "POLL" {
 				return nil, fmt.Errorf("subscription %q is not a POLL subscription", subscriptionName)
 			}
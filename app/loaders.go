@@ -11,22 +11,86 @@ func (a *App) startLoader(ctx context.Context) {
 	if len(a.Config.Loader) == 0 {
 		return
 	}
-	if a.inCluster() {
+	if a.inCluster() && !a.instancePlacement() {
 		ticker := time.NewTicker(time.Second)
 		// wait for instance to become the leader
 		for range ticker.C {
-			if a.isLeader {
+			if a.leader() {
 				ticker.Stop()
 				break
 			}
 		}
 	}
-	ldTypeS := a.Config.Loader["type"].(string)
+	a.runLoader(ctx, a.Config.Loader)
+}
+
+// startPlacementLoader runs the targets loader with target-placement rendezvous.
+// Every instance runs the loader to build its own targets configuration,
+// but only the leader runs the loader on-add and on-delete actions, so that they run once per target.
+// The loader is restarted when the instance gains or loses the leader role.
+func (a *App) startPlacementLoader(ctx context.Context) {
+	if len(a.Config.Loader) == 0 {
+		return
+	}
+	for {
+		lctx, ok := a.leaderContext()
+		if !ok {
+			var cancel context.CancelFunc
+			lctx, cancel = context.WithCancel(ctx)
+			go a.cancelOnLeader(lctx, cancel)
+			a.runLoader(lctx, loaderConfigWithoutActions(a.Config.Loader))
+		} else {
+			a.runLoader(lctx, a.Config.Loader)
+		}
+		// the loader returns early if it fails to initialize,
+		// wait for the leader role to change before restarting it.
+		select {
+		case <-ctx.Done():
+			return
+		case <-lctx.Done():
+		}
+	}
+}
+
+// cancelOnLeader calls cancel once the instance becomes the leader, or when ctx is done.
+func (a *App) cancelOnLeader(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.leader() {
+				return
+			}
+		}
+	}
+}
+
+// loaderConfigWithoutActions returns a copy of the loader configuration
+// without the on-add and on-delete actions.
+func loaderConfigWithoutActions(cfg map[string]interface{}) map[string]interface{} {
+	ncfg := make(map[string]interface{}, len(cfg))
+	for k, v := range cfg {
+		if k == "on-add" || k == "on-delete" {
+			continue
+		}
+		ncfg[k] = v
+	}
+	return ncfg
+}
+
+// runLoader initializes a loader from the configuration cfg
+// and applies its target operations until ctx is done.
+func (a *App) runLoader(ctx context.Context, cfg map[string]interface{}) {
+	ldTypeS := cfg["type"].(string)
 START:
 	a.Logger.Printf("initializing loader type %q", ldTypeS)
 
 	ld := loaders.Loaders[ldTypeS]()
-	err := ld.Init(ctx, a.Config.Loader, a.Logger,
+	err := ld.Init(ctx, cfg, a.Logger,
 		loaders.WithRegistry(a.reg),
 		loaders.WithActions(a.Config.Actions),
 		loaders.WithTargetsDefaults(a.Config.SetTargetConfigDefaults),
@@ -37,6 +101,10 @@ START:
 	}
 	a.Logger.Printf("starting loader type %q", ldTypeS)
 	for targetOp := range ld.Start(ctx) {
+		if a.instancePlacement() {
+			a.placeLoadedTargets(ctx, targetOp)
+			continue
+		}
 		for _, del := range targetOp.Del {
 			// not clustered, delete local target
 			if !a.inCluster() {
@@ -69,7 +137,9 @@ START:
 			// clustered, dispatch
 			a.configLock.Lock()
 			a.Config.Targets[add.Name] = add
+			a.dispatchLock.Lock()
 			err = a.dispatchTarget(ctx, add)
+			a.dispatchLock.Unlock()
 			if err != nil {
 				a.Logger.Printf("failed dispatching target %q: %v", add.Name, err)
 			}
@@ -84,3 +154,23 @@ START:
 		goto START
 	}
 }
+
+// placeLoadedTargets updates the targets config with the loader target operation,
+// the targets are then started by the instance they are placed on.
+func (a *App) placeLoadedTargets(ctx context.Context, targetOp *loaders.TargetOperation) {
+	for _, del := range targetOp.Del {
+		err := a.DeleteTarget(ctx, del)
+		if err != nil {
+			a.Logger.Printf("failed deleting target %q: %v", del, err)
+		}
+	}
+	for _, add := range targetOp.Add {
+		err := a.Config.SetTargetConfigDefaults(add)
+		if err != nil {
+			a.Logger.Printf("failed parsing new target configuration %#v: %v", add, err)
+			continue
+		}
+		a.AddTargetConfig(add)
+	}
+	a.triggerPlacement()
+}
@@ -10,6 +10,7 @@ import (
 
 const (
 	clusterMetricsUpdatePeriod = 10 * time.Second
+	targetsMetricsUpdatePeriod = 10 * time.Second
 )
 
 // subscribe
@@ -20,6 +21,21 @@ var subscribeResponseReceivedCounter = prometheus.NewCounterVec(prometheus.Count
 	Help:      "Total number of received subscribe response messages",
 }, []string{"source", "subscription"})
 
+// target
+var targetCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "target",
+	Name:      "circuit_open",
+	Help:      "Has value 1 if the target retry circuit breaker is open, 0 otherwise",
+}, []string{"name"})
+
+var targetConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "target",
+	Name:      "number_of_consecutive_failures",
+	Help:      "Number of consecutive gRPC dial or subscribe failures of the target",
+}, []string{"name"})
+
 // cluster
 var clusterNumberOfLockedTargets = prometheus.NewGauge(prometheus.GaugeOpts{
 	Namespace: "gnmic",
@@ -84,3 +100,40 @@ func (a *App) startClusterMetrics() {
 		}
 	}
 }
+
+func (a *App) startTargetsMetrics() {
+	if a.Config.APIServer == nil || !a.Config.APIServer.EnableMetrics {
+		return
+	}
+	var err error
+	err = a.reg.Register(targetCircuitOpen)
+	if err != nil {
+		a.Logger.Printf("failed to register metric: %v", err)
+	}
+	err = a.reg.Register(targetConsecutiveFailures)
+	if err != nil {
+		a.Logger.Printf("failed to register metric: %v", err)
+	}
+	ticker := time.NewTicker(targetsMetricsUpdatePeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			targetCircuitOpen.Reset()
+			targetConsecutiveFailures.Reset()
+			a.operLock.RLock()
+			for name, t := range a.Targets {
+				rs := t.RetryState()
+				if rs.CircuitOpen {
+					targetCircuitOpen.WithLabelValues(name).Set(1)
+				} else {
+					targetCircuitOpen.WithLabelValues(name).Set(0)
+				}
+				targetConsecutiveFailures.WithLabelValues(name).Set(float64(rs.ConsecutiveFailures))
+			}
+			a.operLock.RUnlock()
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/config"
+	"github.com/karimra/gnmic/lockers"
+	"github.com/karimra/gnmic/types"
+)
+
+// instancePlacement returns true if each instance of the cluster
+// computes its own targets set, instead of having the leader dispatch them.
+func (a *App) instancePlacement() bool {
+	return a.inCluster() && a.Config.Clustering.TargetPlacement == config.PlacementRendezvous
+}
+
+// triggerPlacement requests a new computation of the targets placement,
+// without waiting for the next targets-watch-timer tick.
+func (a *App) triggerPlacement() {
+	select {
+	case a.placementCh <- struct{}{}:
+	default:
+	}
+}
+
+// runTargetPlacement periodically places the configured targets on the cluster members,
+// starts the targets placed on this instance and stops the ones placed elsewhere.
+// The targets locks are still acquired before subscribing,
+// they guard against two instances with different views of the cluster members
+// subscribing to the same target.
+func (a *App) runTargetPlacement(ctx context.Context) {
+	// targets started by this instance
+	owned := make(map[string]*placedTarget)
+	defer func() {
+		for _, pt := range owned {
+			pt.cancel()
+		}
+	}()
+	ticker := time.NewTicker(a.Config.Clustering.TargetsWatchTimer)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.placementCh:
+		case <-ticker.C:
+		}
+		a.updatePlacement(ctx, owned)
+	}
+}
+
+// placedTarget is a target started by this instance.
+type placedTarget struct {
+	cancel context.CancelFunc
+	// closed when the target subscribe stream returns
+	done chan struct{}
+}
+
+func (a *App) updatePlacement(ctx context.Context, owned map[string]*placedTarget) {
+	a.configLock.RLock()
+	members := make(map[string]*lockers.Service, len(a.apiServices))
+	for id, s := range a.apiServices {
+		members[strings.TrimSuffix(id, "-api")] = s
+	}
+	tcs := make([]*types.TargetConfig, 0, len(a.Config.Targets))
+	for _, tc := range a.Config.Targets {
+		tcs = append(tcs, tc)
+	}
+	a.configLock.RUnlock()
+
+	if len(members) == 0 {
+		a.Logger.Printf("no services found, waiting...")
+		return
+	}
+	self := a.Config.Clustering.InstanceName
+	placement := placeTargets(tcs, members, a.Config.Clustering.LoadFactor)
+	if a.Config.Debug {
+		a.Logger.Printf("targets placement: %+v", placement)
+	}
+	// release the targets placed on other instances or deleted
+	for name, pt := range owned {
+		if placement[name] == self {
+			continue
+		}
+		a.Logger.Printf("releasing target %q, placed on %q", name, placement[name])
+		pt.cancel()
+		delete(owned, name)
+		err := a.stopTarget(ctx, name)
+		if err != nil && a.Config.Debug {
+			a.Logger.Printf("failed to stop target %q: %v", name, err)
+		}
+	}
+	// start the targets placed on this instance
+	for _, tc := range tcs {
+		if placement[tc.Name] != self {
+			continue
+		}
+		if pt, ok := owned[tc.Name]; ok {
+			select {
+			case <-pt.done:
+				// the subscribe stream returned, e.g the target failed to initialize
+				a.Logger.Printf("target %q stopped, restarting it", tc.Name)
+				pt.cancel()
+			default:
+				continue
+			}
+		} else {
+			a.Logger.Printf("target %q placed on %q, starting it", tc.Name, self)
+		}
+		tctx, cancel := context.WithCancel(ctx)
+		pt := &placedTarget{cancel: cancel, done: make(chan struct{})}
+		owned[tc.Name] = pt
+		a.wg.Add(1)
+		go func(tc *types.TargetConfig) {
+			defer close(pt.done)
+			a.subscribeStream(tctx, tc)
+		}(tc)
+	}
+}
+
+// placeTargets returns the instance name each target is placed on,
+// using rendezvous hashing with bounded loads:
+// each target goes to the instance with the highest hash weight among
+// the instances with the most matching tags, unless that instance already
+// holds ceil(loadFactor * numTargets / numInstances) targets, in which case the next one is picked.
+// Given the same inputs, every instance of the cluster computes the same placement.
+// Plain rendezvous hashing only moves the targets a joining or leaving member gains or loses,
+// with bounded loads a change of capacity can push targets to their next instance in turn,
+// so a few more targets may move between the other members, fewer as loadFactor grows.
+func placeTargets(tcs []*types.TargetConfig, members map[string]*lockers.Service, loadFactor float64) map[string]string {
+	placement := make(map[string]string, len(tcs))
+	if len(members) == 0 || len(tcs) == 0 {
+		return placement
+	}
+	if loadFactor < 1 {
+		loadFactor = 1
+	}
+	capacity := int(math.Ceil(loadFactor * float64(len(tcs)) / float64(len(members))))
+	names := make([]string, 0, len(members))
+	for n := range members {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	// place targets in a deterministic order
+	sorted := make([]*types.TargetConfig, len(tcs))
+	copy(sorted, tcs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	load := make(map[string]int, len(members))
+	for _, tc := range sorted {
+		candidates := matchingMembers(tc.Tags, names, members)
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return rendezvousWeight(candidates[i], tc.Name) > rendezvousWeight(candidates[j], tc.Name)
+		})
+		selected := ""
+		for _, n := range candidates {
+			if load[n] < capacity {
+				selected = n
+				break
+			}
+		}
+		// all the candidates are full, fallback to the least loaded one
+		if selected == "" {
+			for _, n := range candidates {
+				if selected == "" || load[n] < load[selected] {
+					selected = n
+				}
+			}
+		}
+		placement[tc.Name] = selected
+		load[selected]++
+	}
+	return placement
+}
+
+// matchingMembers returns the members with the highest number of tags matching
+// the target tags, using the same matching as the leader dispatch.
+// If the target has no tags, all the members are returned.
+func matchingMembers(tags []string, names []string, members map[string]*lockers.Service) []string {
+	if len(tags) == 0 {
+		result := make([]string, len(names))
+		copy(result, names)
+		return result
+	}
+	result := make([]string, 0, len(names))
+	high := -1
+	for _, n := range names {
+		c := 0
+		for i, tag := range members[n].Tags {
+			if i+1 > len(tags) || tag != tags[i] {
+				break
+			}
+			c++
+		}
+		if c > high {
+			result = []string{n}
+			high = c
+			continue
+		}
+		if c == high {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// rendezvousWeight returns the weight of the (instance, target) pair.
+func rendezvousWeight(instance, target string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(instance))
+	h.Write([]byte{0})
+	h.Write([]byte(target))
+	// fnv outputs are poorly distributed for close inputs,
+	// mix them with the splitmix64 finalizer.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
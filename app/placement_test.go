@@ -0,0 +1,111 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/karimra/gnmic/lockers"
+	"github.com/karimra/gnmic/types"
+)
+
+func testPlacementTargets(n int) []*types.TargetConfig {
+	tcs := make([]*types.TargetConfig, 0, n)
+	for i := 0; i < n; i++ {
+		tcs = append(tcs, &types.TargetConfig{Name: fmt.Sprintf("router%d", i)})
+	}
+	return tcs
+}
+
+func testPlacementMembers(names ...string) map[string]*lockers.Service {
+	members := make(map[string]*lockers.Service)
+	for _, n := range names {
+		members[n] = &lockers.Service{ID: n + "-api"}
+	}
+	return members
+}
+
+func TestPlaceTargetsBoundedLoad(t *testing.T) {
+	tcs := testPlacementTargets(100)
+	members := testPlacementMembers("gnmic1", "gnmic2", "gnmic3", "gnmic4")
+	placement := placeTargets(tcs, members, 1.25)
+	if len(placement) != len(tcs) {
+		t.Fatalf("expected %d placed targets, got %d", len(tcs), len(placement))
+	}
+	load := make(map[string]int)
+	for _, instance := range placement {
+		load[instance]++
+	}
+	for instance, l := range load {
+		if l > 32 {
+			t.Errorf("instance %q load %d exceeds the bound", instance, l)
+		}
+	}
+	// the placement is deterministic
+	again := placeTargets(tcs, members, 1.25)
+	for n, instance := range placement {
+		if again[n] != instance {
+			t.Fatalf("target %q placed on %q then on %q", n, instance, again[n])
+		}
+	}
+}
+
+func TestPlaceTargetsMemberLeave(t *testing.T) {
+	tcs := testPlacementTargets(100)
+	before := placeTargets(tcs, testPlacementMembers("gnmic1", "gnmic2", "gnmic3", "gnmic4"), 1.25)
+	after := placeTargets(tcs, testPlacementMembers("gnmic1", "gnmic2", "gnmic3"), 1.25)
+	moved := 0
+	for n, instance := range before {
+		if instance == "gnmic4" {
+			continue
+		}
+		if after[n] != instance {
+			moved++
+		}
+	}
+	// only a few targets not held by the leaving member should move,
+	// because of the load bound.
+	if moved > 15 {
+		t.Errorf("%d targets moved between remaining members", moved)
+	}
+}
+
+func TestPlaceTargetsMemberJoin(t *testing.T) {
+	tcs := testPlacementTargets(100)
+	before := placeTargets(tcs, testPlacementMembers("gnmic1", "gnmic2", "gnmic3"), 1.25)
+	after := placeTargets(tcs, testPlacementMembers("gnmic1", "gnmic2", "gnmic3", "gnmic4"), 1.25)
+	gained, moved := 0, 0
+	for n, instance := range before {
+		switch after[n] {
+		case instance:
+		case "gnmic4":
+			gained++
+		default:
+			moved++
+		}
+	}
+	// the joining member takes about a quarter of the targets
+	if gained < 20 || gained > 32 {
+		t.Errorf("joining member gained %d targets", gained)
+	}
+	// the load bound can cascade moves between the existing members,
+	// only a few of them should move.
+	if moved > 5 {
+		t.Errorf("%d targets moved between existing members", moved)
+	}
+}
+
+func TestPlaceTargetsTags(t *testing.T) {
+	members := testPlacementMembers("gnmic1", "gnmic2", "gnmic3")
+	members["gnmic1"].Tags = []string{"cluster-name=c1", "instance-name=gnmic1"}
+	members["gnmic2"].Tags = []string{"cluster-name=c1", "instance-name=gnmic2"}
+	tcs := testPlacementTargets(10)
+	for _, tc := range tcs {
+		tc.Tags = []string{"cluster-name=c1", "instance-name=gnmic2"}
+	}
+	placement := placeTargets(tcs, members, 1.25)
+	for n, instance := range placement {
+		if instance != "gnmic2" {
+			t.Errorf("target %q placed on %q, expected gnmic2", n, instance)
+		}
+	}
+}
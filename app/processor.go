@@ -0,0 +1,212 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/karimra/gnmic/config"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// InitProcessorTestFlags used to init or reset processorTestCmd flags for gnmic-prompt mode
+func (a *App) InitProcessorTestFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.ProcessorTestInput, "input", "", "", "file containing the event messages or the subscribe responses to run through the processors, in JSON format")
+	cmd.MarkFlagRequired("input")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.ProcessorTestName, "name", "", []string{}, "list of processor names to run, in order")
+	cmd.MarkFlagRequired("name")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.ProcessorTestGolden, "golden", "", "", "golden file containing the expected event messages")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.ProcessorTestUpdateGolden, "update-golden", "", false, "write the resulting event messages to the golden file instead of comparing them")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.ProcessorTestSubName, "subscription-name", "", "default", "subscription name used to convert the subscribe responses to event messages")
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) ProcessorTestPreRunE(cmd *cobra.Command, args []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	a.Config.LocalFlags.ProcessorTestName = config.SanitizeArrayFlagValue(a.Config.LocalFlags.ProcessorTestName)
+	if a.Config.LocalFlags.ProcessorTestUpdateGolden && a.Config.LocalFlags.ProcessorTestGolden == "" {
+		return errors.New("flag --update-golden requires a --golden file")
+	}
+	return nil
+}
+
+func (a *App) ProcessorTestRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitProcessorTestFlags(cmd)
+
+	_, err := a.Config.GetActions()
+	if err != nil {
+		return fmt.Errorf("failed reading actions config: %v", err)
+	}
+	evps, err := a.intializeEventProcessors(a.Config.ProcessorTestName)
+	if err != nil {
+		return fmt.Errorf("failed to init event processors: %v", err)
+	}
+	defer formatters.CloseProcessors(evps)
+	b, err := os.ReadFile(a.Config.ProcessorTestInput)
+	if err != nil {
+		return err
+	}
+	msgs, err := readTestMessages(b, a.Config.ProcessorTestSubName)
+	if err != nil {
+		return fmt.Errorf("failed reading input file %q: %v", a.Config.ProcessorTestInput, err)
+	}
+	results, err := a.runProcessorTest(cmd.OutOrStdout(), msgs, evps)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	switch {
+	case a.Config.ProcessorTestGolden == "":
+		fmt.Fprintf(cmd.OutOrStdout(), "result:\n%s", string(out))
+		return nil
+	case a.Config.ProcessorTestUpdateGolden:
+		err = os.WriteFile(a.Config.ProcessorTestGolden, out, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "golden file %q updated\n", a.Config.ProcessorTestGolden)
+		return nil
+	}
+	return compareGolden(out, a.Config.ProcessorTestGolden)
+}
+
+// runProcessorTest runs each list of event messages in msgs through the processors evps,
+// the changes made by each processor are written to w.
+func (a *App) runProcessorTest(w io.Writer, msgs [][]*formatters.EventMsg, evps []formatters.EventProcessor) ([][]*formatters.EventMsg, error) {
+	results := make([][]*formatters.EventMsg, 0, len(msgs))
+	for i, es := range msgs {
+		before, err := toGeneric(es)
+		if err != nil {
+			return nil, err
+		}
+		for j, ep := range evps {
+			es = ep.Apply(es...)
+			after, err := toGeneric(es)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(w, "message #%d, processor %q:\n", i, a.Config.ProcessorTestName[j])
+			if d := cmp.Diff(before, after); d != "" {
+				fmt.Fprintf(w, "%s\n", d)
+			} else {
+				fmt.Fprintf(w, "no change\n\n")
+			}
+			before = after
+		}
+		results = append(results, es)
+	}
+	return results, nil
+}
+
+// readTestMessages reads a stream of JSON values from b.
+// Each value is either a list of event messages, a single event message,
+// or a subscribe response in protojson format which is converted to event messages.
+func readTestMessages(b []byte, subName string) ([][]*formatters.EventMsg, error) {
+	msgs := make([][]*formatters.EventMsg, 0)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+		switch raw[0] {
+		case '[':
+			es := make([]*formatters.EventMsg, 0)
+			err = json.Unmarshal(raw, &es)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, es)
+		case '{':
+			es, err := readTestObject(raw, subName)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, es)
+		default:
+			return nil, fmt.Errorf("unexpected JSON value: %s", string(raw))
+		}
+	}
+}
+
+func readTestObject(raw json.RawMessage, subName string) ([]*formatters.EventMsg, error) {
+	fields := make(map[string]json.RawMessage)
+	err := json.Unmarshal(raw, &fields)
+	if err != nil {
+		return nil, err
+	}
+	_, isUpdate := fields["update"]
+	_, isSync := fields["syncResponse"]
+	if isUpdate || isSync {
+		rsp := new(gnmi.SubscribeResponse)
+		err = protojson.Unmarshal(raw, rsp)
+		if err != nil {
+			return nil, err
+		}
+		return formatters.ResponseToEventMsgs(subName, rsp, nil)
+	}
+	e := new(formatters.EventMsg)
+	err = json.Unmarshal(raw, e)
+	if err != nil {
+		return nil, err
+	}
+	return []*formatters.EventMsg{e}, nil
+}
+
+// toGeneric converts v to its JSON representation as a generic value,
+// so that it can be compared to values read from JSON files.
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var g interface{}
+	err = json.Unmarshal(b, &g)
+	return g, err
+}
+
+func compareGolden(out []byte, golden string) error {
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		return err
+	}
+	var expected, actual interface{}
+	err = json.Unmarshal(b, &expected)
+	if err != nil {
+		return fmt.Errorf("failed reading golden file %q: %v", golden, err)
+	}
+	err = json.Unmarshal(out, &actual)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		return fmt.Errorf("result does not match golden file %q (-expected +actual):\n%s", golden, cmp.Diff(expected, actual))
+	}
+	return nil
+}
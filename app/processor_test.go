@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+	_ "github.com/karimra/gnmic/formatters/event_add_tag"
+	_ "github.com/karimra/gnmic/formatters/event_drop"
+)
+
+const testInput = `[
+  {"name": "sub1", "timestamp": 1, "tags": {"source": "r1"}, "values": {"/interface/oper-state": "up"}}
+]
+{"name": "sub1", "timestamp": 2, "tags": {"source": "r2"}, "values": {"/interface/oper-state": "down"}}
+{
+  "update": {
+    "timestamp": "3",
+    "prefix": {"elem": [{"name": "interface", "key": {"name": "ethernet-1/1"}}]},
+    "update": [{"path": {"elem": [{"name": "oper-state"}]}, "val": {"stringVal": "up"}}]
+  }
+}
+`
+
+func TestReadTestMessages(t *testing.T) {
+	msgs, err := readTestMessages([]byte(testInput), "sub1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+	e := msgs[2][0]
+	if e.Name != "sub1" || e.Timestamp != 3 || e.Tags["interface_name"] != "ethernet-1/1" || e.Values["/interface/oper-state"] != "up" {
+		t.Errorf("unexpected event converted from subscribe response: %+v", e)
+	}
+	_, err = readTestMessages([]byte(`"not an event"`), "sub1")
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestProcessorTest(t *testing.T) {
+	a := New()
+	a.Config.Processors = map[string]map[string]interface{}{
+		"add-tag": {
+			"event-add-tag": map[string]interface{}{
+				"value-names": []string{".*"},
+				"add":         map[string]string{"env": "lab"},
+			},
+		},
+		"drop-down": {
+			"event-drop": map[string]interface{}{
+				"condition": `.values["/interface/oper-state"] == "down"`,
+			},
+		},
+	}
+	a.Config.ProcessorTestName = []string{"add-tag", "drop-down"}
+	evps := make([]formatters.EventProcessor, 0, 2)
+	for _, n := range a.Config.ProcessorTestName {
+		epCfg := a.Config.Processors[n]
+		for epType, cfg := range epCfg {
+			ep := formatters.EventProcessors[epType]()
+			err := ep.Init(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			evps = append(evps, ep)
+		}
+	}
+	msgs, err := readTestMessages([]byte(testInput), "sub1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := new(bytes.Buffer)
+	results, err := a.runProcessorTest(w, msgs, evps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), `message #0, processor "drop-down":`+"\nno change") {
+		t.Errorf("unexpected steps output:\n%s", w.String())
+	}
+	if results[0][0].Tags["env"] != "lab" {
+		t.Errorf("unexpected result: %+v", results[0][0])
+	}
+
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	err = os.WriteFile(golden, []byte(`[[{"name": "sub1", "timestamp": 1, "tags": {"source": "r1", "env": "lab"}, "values": {"/interface/oper-state": "up"}}]]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = compareGolden([]byte(`[[{"name":"sub1","timestamp":1,"tags":{"env":"lab","source":"r1"},"values":{"/interface/oper-state":"up"}}]]`), golden)
+	if err != nil {
+		t.Errorf("unexpected golden file mismatch: %v", err)
+	}
+	err = compareGolden([]byte(`[[{"name":"sub1","timestamp":1,"tags":{"source":"r1"},"values":{"/interface/oper-state":"up"}}]]`), golden)
+	if err == nil {
+		t.Error("expected a golden file mismatch")
+	}
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMoveBatchSize     = 10
+	defaultMoveBatchInterval = 10 * time.Second
+)
+
+var errInstancePlacement = errors.New("not supported with target-placement rendezvous")
+
+// targetMove is the move of a target from an instance to another.
+type targetMove struct {
+	Target string `json:"target,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// drainKey returns the locker key marking the instance as drained.
+// The drain markers are kept in the locker so that a new leader
+// does not assign targets to the drained instances.
+func (a *App) drainKey(instance string) string {
+	return fmt.Sprintf("gnmic/%s/drained/%s", a.Config.Clustering.ClusterName, instance)
+}
+
+// drainedInstances returns the names of the drained instances,
+// drained instances are not assigned new targets.
+func (a *App) drainedInstances(ctx context.Context) (map[string]struct{}, error) {
+	prefix := a.drainKey("")
+	kvs, err := a.locker.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	drained := make(map[string]struct{}, len(kvs))
+	for k := range kvs {
+		drained[strings.TrimPrefix(k, prefix)] = struct{}{}
+	}
+	return drained, nil
+}
+
+func (a *App) isDrained(ctx context.Context, instance string) (bool, error) {
+	drained, err := a.drainedInstances(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := drained[instance]
+	return ok, nil
+}
+
+// drainedServices returns the API service IDs of the drained instances.
+func (a *App) drainedServices(ctx context.Context) ([]string, error) {
+	drained, err := a.drainedInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	srvs := make([]string, 0, len(drained))
+	for n := range drained {
+		srvs = append(srvs, n+"-api")
+	}
+	return srvs, nil
+}
+
+func (a *App) setDrained(ctx context.Context, instance string, drained bool) error {
+	if drained {
+		return a.locker.Put(ctx, a.drainKey(instance), []byte(instance))
+	}
+	return a.locker.Delete(ctx, a.drainKey(instance))
+}
+
+// planDrainMoves returns the moves releasing the instance targets to the other members,
+// the instance is marked as drained once the moves are planned.
+func (a *App) planDrainMoves(ctx context.Context, instance string) ([]*targetMove, error) {
+	if a.instancePlacement() {
+		return nil, errInstancePlacement
+	}
+	if !a.isMember(instance) {
+		return nil, fmt.Errorf("unknown instance %q", instance)
+	}
+	mapping, err := a.getTargetToInstanceMapping()
+	if err != nil {
+		return nil, err
+	}
+	load, candidates, err := a.movesCandidates(ctx, mapping, instance)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0)
+	for t, i := range mapping {
+		if i == instance {
+			targets = append(targets, t)
+		}
+	}
+	moves := planDrain(targets, instance, load, candidates)
+	err = a.setDrained(ctx, instance, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark instance %q as drained: %v", instance, err)
+	}
+	return moves, nil
+}
+
+// planRebalanceMoves returns the moves evening out the number of targets
+// held by the members which are not drained.
+func (a *App) planRebalanceMoves(ctx context.Context) ([]*targetMove, error) {
+	if a.instancePlacement() {
+		return nil, errInstancePlacement
+	}
+	mapping, err := a.getTargetToInstanceMapping()
+	if err != nil {
+		return nil, err
+	}
+	load, candidates, err := a.movesCandidates(ctx, mapping)
+	if err != nil {
+		return nil, err
+	}
+	return planRebalance(mapping, load, candidates), nil
+}
+
+// undrainInstance removes the drain marker of an instance which left the cluster.
+func (a *App) undrainInstance(instance string) {
+	err := a.setDrained(a.ctx, instance, false)
+	if err != nil {
+		a.Logger.Printf("[cluster-leader] failed to remove instance %q drain marker: %v", instance, err)
+	}
+}
+
+// isMember returns true if the instance API service is registered.
+func (a *App) isMember(instance string) bool {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	_, ok := a.apiServices[instance+"-api"]
+	return ok
+}
+
+// movesCandidates returns the load of the members which are neither drained nor excluded,
+// as well as the members each locked target can be moved to, based on the targets tags.
+func (a *App) movesCandidates(ctx context.Context, mapping map[string]string, exclude ...string) (map[string]int, map[string][]string, error) {
+	drained, err := a.drainedInstances(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range exclude {
+		drained[e] = struct{}{}
+	}
+	a.configLock.RLock()
+	instances := make([]string, 0, len(a.apiServices))
+	for id := range a.apiServices {
+		name := strings.TrimSuffix(id, "-api")
+		if _, ok := drained[name]; !ok {
+			instances = append(instances, name)
+		}
+	}
+	a.configLock.RUnlock()
+	load, err := a.getInstancesLoad(instances...)
+	if err != nil {
+		return nil, nil, err
+	}
+	candidates := make(map[string][]string, len(mapping))
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	for t := range mapping {
+		tc, ok := a.Config.Targets[t]
+		if !ok {
+			continue
+		}
+		if len(tc.Tags) == 0 {
+			candidates[t] = instances
+			continue
+		}
+		tagCount := a.getInstancesTagsMatches(tc.Tags)
+		for d := range drained {
+			delete(tagCount, d)
+		}
+		candidates[t] = a.getHighestTagsMatches(tagCount)
+	}
+	return load, candidates, nil
+}
+
+// planDrain moves each target to its least loaded candidate instance.
+// targets without any candidate are not moved.
+func planDrain(targets []string, instance string, load map[string]int, candidates map[string][]string) []*targetMove {
+	sort.Strings(targets)
+	moves := make([]*targetMove, 0, len(targets))
+	for _, t := range targets {
+		to := leastLoaded(load, candidates[t], instance)
+		if to == "" {
+			continue
+		}
+		load[to]++
+		moves = append(moves, &targetMove{Target: t, From: instance, To: to})
+	}
+	return moves
+}
+
+// planRebalance moves targets from the most loaded instances to the least loaded ones,
+// until the loads of the instances differ by at most one target,
+// or no target can be moved without breaking its tags affinity.
+// Each target is moved at most once.
+func planRebalance(mapping map[string]string, load map[string]int, candidates map[string][]string) []*targetMove {
+	byInstance := make(map[string][]string)
+	for t, i := range mapping {
+		if _, ok := load[i]; !ok {
+			// target held by a drained or unknown instance
+			continue
+		}
+		byInstance[i] = append(byInstance[i], t)
+	}
+	for _, ts := range byInstance {
+		sort.Strings(ts)
+	}
+	instances := make([]string, 0, len(load))
+	for i := range load {
+		instances = append(instances, i)
+	}
+	moves := make([]*targetMove, 0)
+	moved := make(map[string]struct{})
+	for {
+		// most loaded instances first
+		sort.Slice(instances, func(i, j int) bool {
+			if load[instances[i]] == load[instances[j]] {
+				return instances[i] < instances[j]
+			}
+			return load[instances[i]] > load[instances[j]]
+		})
+		var mv *targetMove
+	FROM:
+		for _, from := range instances {
+			for _, t := range byInstance[from] {
+				if _, ok := moved[t]; ok {
+					continue
+				}
+				to := leastLoaded(load, candidates[t], from)
+				if to == "" || load[to]+1 >= load[from] {
+					continue
+				}
+				mv = &targetMove{Target: t, From: from, To: to}
+				break FROM
+			}
+		}
+		if mv == nil {
+			return moves
+		}
+		moved[mv.Target] = struct{}{}
+		load[mv.From]--
+		load[mv.To]++
+		moves = append(moves, mv)
+	}
+}
+
+// leastLoaded returns the candidate with the lowest load, excluding the instance except.
+// candidates missing from the load map are ignored.
+func leastLoaded(load map[string]int, candidates []string, except string) string {
+	selected := ""
+	for _, c := range candidates {
+		if c == except {
+			continue
+		}
+		l, ok := load[c]
+		if !ok {
+			continue
+		}
+		if selected == "" || l < load[selected] || l == load[selected] && c < selected {
+			selected = c
+		}
+	}
+	return selected
+}
+
+// executeMoves runs the moves in batches of batchSize, waiting interval between batches.
+func (a *App) executeMoves(ctx context.Context, moves []*targetMove, batchSize int, interval time.Duration) {
+	if batchSize <= 0 {
+		batchSize = defaultMoveBatchSize
+	}
+	for i, mv := range moves {
+		if i > 0 && i%batchSize == 0 {
+			a.Logger.Printf("[cluster-leader] moved %d/%d target(s), waiting %s before the next batch", i, len(moves), interval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+		err := a.moveTarget(ctx, mv)
+		if err != nil {
+			a.Logger.Printf("[cluster-leader] failed to move target %q from %q to %q: %v", mv.Target, mv.From, mv.To, err)
+		}
+	}
+	a.Logger.Printf("[cluster-leader] done moving %d target(s)", len(moves))
+}
+
+// moveTarget unassigns the target from its current instance, assigns it to the
+// destination instance and waits for the destination instance to lock it.
+func (a *App) moveTarget(ctx context.Context, mv *targetMove) error {
+	a.configLock.RLock()
+	tc, ok := a.Config.Targets[mv.Target]
+	service, sok := a.apiServices[mv.To+"-api"]
+	a.configLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("target %q %w", mv.Target, errNotFound)
+	}
+	if !sok {
+		return fmt.Errorf("instance %q %w", mv.To, errNotFound)
+	}
+	// prevent the targets dispatch from assigning the target
+	// while it is moved
+	a.dispatchLock.Lock()
+	defer a.dispatchLock.Unlock()
+	a.Logger.Printf("[cluster-leader] moving target %q from %q to %q", mv.Target, mv.From, mv.To)
+	if mv.From != "" {
+		err := a.unassignTarget(ctx, mv.Target, mv.From+"-api")
+		if err != nil {
+			return err
+		}
+	}
+	err := a.assignTarget(ctx, tc, service)
+	if err != nil {
+		return err
+	}
+	return a.waitTargetLock(ctx, mv.Target, mv.To)
+}
+
+// waitTargetLock waits for the target lock to be acquired by the instance,
+// for at most the target assignment timeout.
+func (a *App) waitTargetLock(ctx context.Context, name, instance string) error {
+	key := a.targetLockKey(name)
+	ctx, cancel := context.WithTimeout(ctx, a.Config.Clustering.TargetAssignmentTimeout)
+	defer cancel()
+	ticker := time.NewTicker(lockWaitTime)
+	defer ticker.Stop()
+	for {
+		values, err := a.locker.List(ctx, key)
+		if err == nil && values[key] == instance {
+			a.Logger.Printf("[cluster-leader] lock %q acquired by %q", key, instance)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lock %q not acquired by %q: %v", key, instance, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanDrain(t *testing.T) {
+	load := map[string]int{
+		"gnmic2": 2,
+		"gnmic3": 0,
+	}
+	candidates := map[string][]string{
+		"router1": {"gnmic1", "gnmic2", "gnmic3"},
+		"router2": {"gnmic1", "gnmic2", "gnmic3"},
+		"router3": {"gnmic1", "gnmic2", "gnmic3"},
+		"router4": {"gnmic1"},
+	}
+	moves := planDrain([]string{"router4", "router3", "router2", "router1"}, "gnmic1", load, candidates)
+	expected := []*targetMove{
+		{Target: "router1", From: "gnmic1", To: "gnmic3"},
+		{Target: "router2", From: "gnmic1", To: "gnmic3"},
+		{Target: "router3", From: "gnmic1", To: "gnmic2"},
+	}
+	if !cmp.Equal(expected, moves) {
+		t.Errorf("unexpected moves: %s", cmp.Diff(expected, moves))
+	}
+}
+
+func TestPlanRebalance(t *testing.T) {
+	all := []string{"gnmic1", "gnmic2", "gnmic3"}
+	mapping := map[string]string{
+		"router1": "gnmic1",
+		"router2": "gnmic1",
+		"router3": "gnmic1",
+		"router4": "gnmic1",
+		"router5": "gnmic1",
+		"router6": "gnmic2",
+	}
+	load := map[string]int{
+		"gnmic1": 5,
+		"gnmic2": 1,
+		"gnmic3": 0,
+	}
+	candidates := map[string][]string{
+		"router1": {"gnmic1"},
+		"router2": all,
+		"router3": all,
+		"router4": all,
+		"router5": all,
+		"router6": all,
+	}
+	moves := planRebalance(mapping, load, candidates)
+	expected := []*targetMove{
+		{Target: "router2", From: "gnmic1", To: "gnmic3"},
+		{Target: "router3", From: "gnmic1", To: "gnmic2"},
+		{Target: "router4", From: "gnmic1", To: "gnmic3"},
+	}
+	if !cmp.Equal(expected, moves) {
+		t.Errorf("unexpected moves: %s", cmp.Diff(expected, moves))
+	}
+	if !cmp.Equal(map[string]int{"gnmic1": 2, "gnmic2": 2, "gnmic3": 2}, load) {
+		t.Errorf("unexpected load after rebalance: %v", load)
+	}
+}
+
+func TestPlanRebalanceBalanced(t *testing.T) {
+	mapping := map[string]string{
+		"router1": "gnmic1",
+		"router2": "gnmic1",
+		"router3": "gnmic2",
+	}
+	load := map[string]int{
+		"gnmic1": 2,
+		"gnmic2": 1,
+	}
+	candidates := map[string][]string{
+		"router1": {"gnmic1", "gnmic2"},
+		"router2": {"gnmic1", "gnmic2"},
+		"router3": {"gnmic1", "gnmic2"},
+	}
+	moves := planRebalance(mapping, load, candidates)
+	if len(moves) != 0 {
+		t.Errorf("unexpected moves: %+v", moves)
+	}
+}
@@ -18,6 +18,10 @@ func (a *App) clusterRoutes(r *mux.Router) {
 	r.HandleFunc("/cluster", a.handleClusteringGet).Methods(http.MethodGet)
 	r.HandleFunc("/cluster/members", a.handleClusteringMembersGet).Methods(http.MethodGet)
 	r.HandleFunc("/cluster/leader", a.handleClusteringLeaderGet).Methods(http.MethodGet)
+	r.HandleFunc("/cluster/members/{id}/drain", a.handleClusteringDrainPost).Methods(http.MethodPost)
+	r.HandleFunc("/cluster/members/{id}/drain", a.handleClusteringDrainDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/cluster/rebalance", a.handleClusteringRebalancePost).Methods(http.MethodPost)
+	r.HandleFunc("/cluster/targets/{id}/move", a.handleClusteringTargetMovePost).Methods(http.MethodPost)
 }
 
 func (a *App) configRoutes(r *mux.Router) {
@@ -30,6 +34,10 @@ func (a *App) configRoutes(r *mux.Router) {
 	r.HandleFunc("/config/targets/{id}", a.handleConfigTargetsDelete).Methods(http.MethodDelete)
 	// config/subscriptions
 	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptionsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsDelete).Methods(http.MethodDelete)
 	// config/outputs
 	r.HandleFunc("/config/outputs", a.handleConfigOutputs).Methods(http.MethodGet)
 	// config/inputs
@@ -48,6 +56,7 @@ func (a *App) targetRoutes(r *mux.Router) {
 	// targets
 	r.HandleFunc("/targets", a.handleTargetsGet).Methods(http.MethodGet)
 	r.HandleFunc("/targets/{id}", a.handleTargetsGet).Methods(http.MethodGet)
+	r.HandleFunc("/targets/{id}/state", a.handleTargetsStateGet).Methods(http.MethodGet)
 	r.HandleFunc("/targets/{id}", a.handleTargetsPost).Methods(http.MethodPost)
 	r.HandleFunc("/targets/{id}", a.handleTargetsDelete).Methods(http.MethodDelete)
 }
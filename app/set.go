@@ -116,6 +116,8 @@ func (a *App) InitSetFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetRequestFile, "request-file", "", []string{}, "set request template file(s)")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SetRequestVars, "request-vars", "", "", "set request variables file")
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SetDryRun, "dry-run", "", false, "prints the set request without initiating a gRPC connection")
+	cmd.Flags().Uint64VarP(&a.Config.LocalFlags.SetElectionID, "election-id", "", 0, "master arbitration election ID, if set the request includes a master arbitration extension")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.SetRole, "role", "", "", "master arbitration role, used with --election-id")
 
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
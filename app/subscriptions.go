@@ -0,0 +1,198 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/karimra/gnmic/target"
+	"github.com/karimra/gnmic/types"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// AddSubscriptionConfig adds a *SubscriptionConfig to the configuration map,
+// then starts it on the active targets referencing it.
+// The subscription is added only if its gNMI SubscribeRequest
+// can be created for all those targets.
+func (a *App) AddSubscriptionConfig(sc *types.SubscriptionConfig) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[sc.Name]; ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q already exists", sc.Name)
+	}
+	err := a.Config.SetSubscriptionConfigDefaults(sc)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	reqs, err := a.subscribeRequests(sc)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	a.Config.Subscriptions[sc.Name] = sc
+	a.configLock.Unlock()
+	a.Logger.Printf("subscription %q added to config", sc.Name)
+	a.startSubscription(sc, reqs)
+	return nil
+}
+
+// UpdateSubscriptionConfig replaces an existing *SubscriptionConfig in the configuration map,
+// then restarts it on the active targets referencing it.
+// The subscription is replaced only if its gNMI SubscribeRequest
+// can be created for all those targets, otherwise the running subscription is left untouched.
+func (a *App) UpdateSubscriptionConfig(sc *types.SubscriptionConfig) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[sc.Name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q does not exist", sc.Name)
+	}
+	err := a.Config.SetSubscriptionConfigDefaults(sc)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	reqs, err := a.subscribeRequests(sc)
+	if err != nil {
+		a.configLock.Unlock()
+		return err
+	}
+	a.Config.Subscriptions[sc.Name] = sc
+	a.configLock.Unlock()
+	a.Logger.Printf("subscription %q updated", sc.Name)
+
+	a.operLock.RLock()
+	for _, t := range a.Targets {
+		t.StopSubscription(sc.Name)
+	}
+	a.operLock.RUnlock()
+	a.startSubscription(sc, reqs)
+	return nil
+}
+
+// DeleteSubscriptionConfig deletes a subscription from the configuration map,
+// then stops and deletes it from the active targets.
+func (a *App) DeleteSubscriptionConfig(name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q does not exist", name)
+	}
+	delete(a.Config.Subscriptions, name)
+	for _, tc := range a.Config.Targets {
+		pruneTargetSubscription(tc, name)
+	}
+	a.configLock.Unlock()
+	a.Logger.Printf("subscription %q deleted from config", name)
+
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	for _, t := range a.Targets {
+		t.DeleteSubscription(name)
+	}
+	return nil
+}
+
+// subscribeRequests creates the gNMI SubscribeRequest of subscription sc
+// for each active target referencing it, indexed by target name.
+func (a *App) subscribeRequests(sc *types.SubscriptionConfig) (map[string]*gnmi.SubscribeRequest, error) {
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	reqs := make(map[string]*gnmi.SubscribeRequest)
+	for _, t := range a.Targets {
+		if !targetReferencesSubscription(t.Config, sc.Name) {
+			continue
+		}
+		req, err := a.subscribeRequest(sc, t.Config.Name)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %v", t.Config.Name, err)
+		}
+		reqs[t.Config.Name] = req
+	}
+	return reqs, nil
+}
+
+// startSubscription starts subscription sc on the active targets
+// with a request in reqs, the other subscriptions of those targets are not affected.
+func (a *App) startSubscription(sc *types.SubscriptionConfig, reqs map[string]*gnmi.SubscribeRequest) {
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	for _, t := range a.Targets {
+		req, ok := reqs[t.Config.Name]
+		if !ok {
+			continue
+		}
+		a.Logger.Printf("starting subscription %q on target %q", sc.Name, t.Config.Name)
+		t.AddSubscription(sc, req)
+	}
+}
+
+// subscribeRequest registers the extensions of subscription sc
+// to decode the ones received from target tName, then creates its gNMI SubscribeRequest.
+func (a *App) subscribeRequest(sc *types.SubscriptionConfig, tName string) (*gnmi.SubscribeRequest, error) {
+	err := a.Config.RegisterExtensions(tName, sc.Name, sc.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	return a.Config.CreateSubscribeRequest(sc, tName)
+}
+
+// targetReferencesSubscription returns true if the target config tc
+// lists the subscription name, or if it does not list any subscription.
+func targetReferencesSubscription(tc *types.TargetConfig, name string) bool {
+	if len(tc.Subscriptions) == 0 {
+		return !subscriptionsEmptied(tc)
+	}
+	for _, sn := range tc.Subscriptions {
+		if sn == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneTargetSubscription removes the subscription name from the target config tc.
+// If it was the last subscription listed, tc.Subscriptions is left as an empty
+// non nil slice, so that the target does not fallback to all the subscriptions.
+func pruneTargetSubscription(tc *types.TargetConfig, name string) {
+	if len(tc.Subscriptions) == 0 {
+		return
+	}
+	subs := make([]string, 0, len(tc.Subscriptions))
+	for _, sn := range tc.Subscriptions {
+		if sn != name {
+			subs = append(subs, sn)
+		}
+	}
+	tc.Subscriptions = subs
+}
+
+// subscriptionsEmptied returns true if all the subscriptions listed
+// in the target config tc were deleted.
+func subscriptionsEmptied(tc *types.TargetConfig) bool {
+	return tc.Subscriptions != nil && len(tc.Subscriptions) == 0
+}
+
+// targetSubscriptions returns a copy of the subscriptions of target t.
+// If the target subscriptions were never set, the configured subscriptions are returned,
+// unless the subscriptions listed in the target config were all deleted.
+func (a *App) targetSubscriptions(t *target.Target) map[string]*types.SubscriptionConfig {
+	subs, ok := t.GetSubscriptions()
+	if ok {
+		return subs
+	}
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	if subscriptionsEmptied(t.Config) {
+		return subs
+	}
+	for n, sc := range a.Config.Subscriptions {
+		subs[n] = sc
+	}
+	return subs
+}
+
+func (a *App) subscriptionConfigExists(name string) bool {
+	a.configLock.RLock()
+	_, ok := a.Config.Subscriptions[name]
+	a.configLock.RUnlock()
+	return ok
+}
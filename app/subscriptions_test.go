@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/karimra/gnmic/target"
+	"github.com/karimra/gnmic/types"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+)
+
+func TestSubscriptionConfigAddUpdateDelete(t *testing.T) {
+	a := New()
+	a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+		"sub1": {
+			Name:  "sub1",
+			Paths: []string{"/interface"},
+		},
+	}
+	a.Targets = map[string]*target.Target{
+		"t1": target.NewTarget(&types.TargetConfig{Name: "t1", Subscriptions: []string{"sub1"}}),
+		"t2": target.NewTarget(&types.TargetConfig{Name: "t2"}),
+	}
+	sc := &types.SubscriptionConfig{
+		Name:  "sub2",
+		Paths: []string{"/system"},
+	}
+	err := a.AddSubscriptionConfig(sc)
+	if err != nil {
+		t.Fatalf("failed to add subscription: %v", err)
+	}
+	if sc.Mode != "STREAM" || sc.Encoding == "" {
+		t.Errorf("subscription defaults not set: %+v", sc)
+	}
+	if _, ok := a.Targets["t1"].Subscriptions["sub2"]; ok {
+		t.Errorf("subscription added to a target not referencing it")
+	}
+	if _, ok := a.Targets["t2"].Subscriptions["sub2"]; !ok {
+		t.Errorf("subscription not added to target t2")
+	}
+	err = a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: "sub2", Paths: []string{"/system"}})
+	if err == nil {
+		t.Errorf("expected an error adding an existing subscription")
+	}
+	err = a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: "sub3"})
+	if err == nil {
+		t.Errorf("expected an error adding a subscription without paths")
+	}
+
+	err = a.UpdateSubscriptionConfig(&types.SubscriptionConfig{Name: "sub2", Paths: []string{"/network-instance"}})
+	if err != nil {
+		t.Fatalf("failed to update subscription: %v", err)
+	}
+	if p := a.Targets["t2"].Subscriptions["sub2"].Paths[0]; p != "/network-instance" {
+		t.Errorf("subscription not updated on target t2, got path %q", p)
+	}
+	err = a.UpdateSubscriptionConfig(&types.SubscriptionConfig{Name: "sub4", Paths: []string{"/system"}})
+	if err == nil {
+		t.Errorf("expected an error updating a non existing subscription")
+	}
+
+	err = a.DeleteSubscriptionConfig("sub2")
+	if err != nil {
+		t.Fatalf("failed to delete subscription: %v", err)
+	}
+	if _, ok := a.Config.Subscriptions["sub2"]; ok {
+		t.Errorf("subscription not deleted from config")
+	}
+	if _, ok := a.Targets["t2"].Subscriptions["sub2"]; ok {
+		t.Errorf("subscription not deleted from target t2")
+	}
+	err = a.DeleteSubscriptionConfig("sub2")
+	if err == nil {
+		t.Errorf("expected an error deleting a non existing subscription")
+	}
+}
+
+func TestSubscriptionConfigInvalidRequest(t *testing.T) {
+	a := New()
+	a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+		"sub1": {
+			Name:  "sub1",
+			Paths: []string{"/interface"},
+		},
+	}
+	a.Targets = map[string]*target.Target{
+		"t1": target.NewTarget(&types.TargetConfig{Name: "t1"}),
+	}
+	err := a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: "sub2", Paths: []string{"/system[name"}})
+	if err == nil {
+		t.Fatalf("expected an error adding a subscription with an invalid path")
+	}
+	if _, ok := a.Config.Subscriptions["sub2"]; ok {
+		t.Errorf("invalid subscription added to config")
+	}
+	// a valid retry is not rejected as an existing subscription
+	err = a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: "sub2", Paths: []string{"/system"}})
+	if err != nil {
+		t.Fatalf("failed to add subscription: %v", err)
+	}
+
+	err = a.UpdateSubscriptionConfig(&types.SubscriptionConfig{Name: "sub2", Paths: []string{"/system[name"}})
+	if err == nil {
+		t.Fatalf("expected an error updating a subscription with an invalid path")
+	}
+	if p := a.Config.Subscriptions["sub2"].Paths[0]; p != "/system" {
+		t.Errorf("subscription config replaced by the invalid one, got path %q", p)
+	}
+	if p := a.Targets["t1"].Subscriptions["sub2"].Paths[0]; p != "/system" {
+		t.Errorf("subscription replaced on target t1, got path %q", p)
+	}
+}
+
+// failingGNMIClient is a gnmi.GNMIClient failing all Subscribe RPCs
+type failingGNMIClient struct {
+	gnmi.GNMIClient
+}
+
+func (c *failingGNMIClient) Subscribe(ctx context.Context, opts ...grpc.CallOption) (gnmi.GNMI_SubscribeClient, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestSubscriptionConfigStartedTarget(t *testing.T) {
+	a := New()
+	a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+		"sub1": {
+			Name:  "sub1",
+			Paths: []string{"/interface"},
+		},
+	}
+	tc := &types.TargetConfig{Name: "t1", Subscriptions: []string{"sub1"}, BufferSize: 100}
+	a.Config.Targets = map[string]*types.TargetConfig{"t1": tc}
+	tg := target.NewTarget(tc)
+	tg.Client = &failingGNMIClient{}
+	tg.Subscriptions["sub1"] = a.Config.Subscriptions["sub1"]
+	a.Targets = map[string]*target.Target{"t1": tg}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// mark the target subscriptions as started
+	tg.SetSubscribeContext(ctx)
+	// read the target subscriptions the way a reconnecting target does
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			for range a.targetSubscriptions(tg) {
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("sub%d", i+2)
+		tc.Subscriptions = append(tc.Subscriptions, name)
+		err := a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: name, Paths: []string{"/system"}})
+		if err != nil {
+			t.Fatalf("failed to add subscription: %v", err)
+		}
+		err = a.DeleteSubscriptionConfig(name)
+		if err != nil {
+			t.Fatalf("failed to delete subscription: %v", err)
+		}
+	}
+	<-done
+	err := a.DeleteSubscriptionConfig("sub1")
+	if err != nil {
+		t.Fatalf("failed to delete subscription: %v", err)
+	}
+	if tc.Subscriptions == nil || len(tc.Subscriptions) != 0 {
+		t.Errorf("deleted subscriptions not pruned from the target config: %v", tc.Subscriptions)
+	}
+	// a subscription added to the config is not started on the emptied target
+	err = a.AddSubscriptionConfig(&types.SubscriptionConfig{Name: "sub0", Paths: []string{"/system"}})
+	if err != nil {
+		t.Fatalf("failed to add subscription: %v", err)
+	}
+	// the emptied target does not fallback to all the configured subscriptions
+	if subs := a.targetSubscriptions(tg); len(subs) != 0 {
+		t.Errorf("expected no subscriptions, got %v", subs)
+	}
+}
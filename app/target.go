@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/fullstorydev/grpcurl"
+	"github.com/karimra/gnmic/formatters"
 	"github.com/karimra/gnmic/target"
 	"github.com/karimra/gnmic/types"
 )
@@ -21,7 +22,7 @@ func (a *App) initTarget(tc *types.TargetConfig) (*target.Target, error) {
 				t.Subscriptions[subName] = sub
 			}
 		}
-		if len(t.Subscriptions) == 0 {
+		if len(t.Subscriptions) == 0 && !subscriptionsEmptied(tc) {
 			for _, sub := range a.Config.Subscriptions {
 				t.Subscriptions[sub.Name] = sub
 			}
@@ -30,6 +31,14 @@ func (a *App) initTarget(tc *types.TargetConfig) (*target.Target, error) {
 		if err != nil {
 			return nil, err
 		}
+		err = a.Config.RegisterExtensions(tc.Name, "", tc.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %v", tc.Name, err)
+		}
+		t.Extensions, err = a.Config.CreateExtensions(tc.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %v", tc.Name, err)
+		}
 		a.Targets[t.Config.Name] = t
 		return t, nil
 	}
@@ -51,6 +60,7 @@ func (a *App) stopTarget(ctx context.Context, name string) error {
 	t := a.Targets[name]
 	t.StopSubscriptions()
 	delete(a.Targets, name)
+	formatters.UnregisterExtensions(name)
 	if a.locker == nil {
 		return nil
 	}
@@ -80,6 +90,7 @@ func (a *App) DeleteTarget(ctx context.Context, name string) error {
 	if t, ok := a.Targets[name]; ok {
 		delete(a.Targets, name)
 		t.Close()
+		formatters.UnregisterExtensions(name)
 		if a.locker != nil {
 			return a.locker.Unlock(ctx, a.targetLockKey(name))
 		}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// processorCmd represents the processor command
+func newProcessorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "processor",
+		Aliases:      []string{"proc"},
+		Short:        "event processors related commands",
+		SilenceUsage: true,
+	}
+	return cmd
+}
+
+// processorTestCmd represents the processor test command
+func newProcessorTestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "test",
+		Short:        "run event messages through a list of processors",
+		PreRunE:      gApp.ProcessorTestPreRunE,
+		RunE:         gApp.ProcessorTestRunE,
+		SilenceUsage: true,
+	}
+	gApp.InitProcessorTestFlags(cmd)
+	return cmd
+}
@@ -70,6 +70,9 @@ func newRootCmd() *cobra.Command {
 	gApp.RootCmd.AddCommand(genCmd)
 	//
 	gApp.RootCmd.AddCommand(newPromptCmd())
+	procCmd := newProcessorCmd()
+	procCmd.AddCommand(newProcessorTestCmd())
+	gApp.RootCmd.AddCommand(procCmd)
 	gApp.RootCmd.AddCommand(newSetCmd())
 	gApp.RootCmd.AddCommand(newSubscribeCmd())
 	//
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -12,6 +13,16 @@ const (
 	defaultTargetAssignmentTimeout = 10 * time.Second
 	defaultServicesWatchTimer      = 1 * time.Minute
 	defaultLeaderWaitTimer         = 5 * time.Second
+	defaultLoadFactor              = 1.25
+)
+
+const (
+	// PlacementLockPolling is the default target placement mode,
+	// the cluster leader dispatches the unlocked targets to the least loaded instance.
+	PlacementLockPolling = "lock-polling"
+	// PlacementRendezvous makes each instance compute its own targets set
+	// from the cluster members list using rendezvous hashing with bounded loads.
+	PlacementRendezvous = "rendezvous"
 )
 
 type clustering struct {
@@ -22,6 +33,8 @@ type clustering struct {
 	TargetsWatchTimer       time.Duration          `mapstructure:"targets-watch-timer,omitempty" json:"targets-watch-timer,omitempty" yaml:"targets-watch-timer,omitempty"`
 	TargetAssignmentTimeout time.Duration          `mapstructure:"target-assignment-timeout,omitempty" json:"target-assignment-timeout,omitempty" yaml:"target-assignment-timeout,omitempty"`
 	LeaderWaitTimer         time.Duration          `mapstructure:"leader-wait-timer,omitempty" json:"leader-wait-timer,omitempty" yaml:"leader-wait-timer,omitempty"`
+	TargetPlacement         string                 `mapstructure:"target-placement,omitempty" json:"target-placement,omitempty" yaml:"target-placement,omitempty"`
+	LoadFactor              float64                `mapstructure:"load-factor,omitempty" json:"load-factor,omitempty" yaml:"load-factor,omitempty"`
 	Tags                    []string               `mapstructure:"tags,omitempty" json:"tags,omitempty" yaml:"tags,omitempty"`
 	Locker                  map[string]interface{} `mapstructure:"locker,omitempty" json:"locker,omitempty" yaml:"locker,omitempty"`
 }
@@ -38,15 +51,20 @@ func (c *Config) GetClustering() error {
 	c.Clustering.TargetAssignmentTimeout = c.FileConfig.GetDuration("clustering/target-assignment-timeout")
 	c.Clustering.ServicesWatchTimer = c.FileConfig.GetDuration("clustering/services-watch-timer")
 	c.Clustering.LeaderWaitTimer = c.FileConfig.GetDuration("clustering/leader-wait-timer")
+	c.Clustering.TargetPlacement = c.FileConfig.GetString("clustering/target-placement")
+	c.Clustering.LoadFactor = c.FileConfig.GetFloat64("clustering/load-factor")
 	c.Clustering.Tags = c.FileConfig.GetStringSlice("clustering/tags")
 	for i := range c.Clustering.Tags {
 		c.Clustering.Tags[i] = os.ExpandEnv(c.Clustering.Tags[i])
 	}
-	c.setClusteringDefaults()
+	err := c.setClusteringDefaults()
+	if err != nil {
+		return err
+	}
 	return c.getLocker()
 }
 
-func (c *Config) setClusteringDefaults() {
+func (c *Config) setClusteringDefaults() error {
 	// set $clustering.cluster-name to $cluster-name if it's empty string
 	if c.Clustering.ClusterName == "" {
 		c.Clustering.ClusterName = c.ClusterName
@@ -78,4 +96,16 @@ func (c *Config) setClusteringDefaults() {
 	if c.Clustering.LeaderWaitTimer <= defaultLeaderWaitTimer {
 		c.Clustering.LeaderWaitTimer = defaultLeaderWaitTimer
 	}
+	switch c.Clustering.TargetPlacement {
+	case "":
+		c.Clustering.TargetPlacement = PlacementLockPolling
+	case PlacementLockPolling, PlacementRendezvous:
+	default:
+		return fmt.Errorf("unknown clustering target-placement %q", c.Clustering.TargetPlacement)
+	}
+	// a load factor lower than 1 would leave some targets without an instance
+	if c.Clustering.LoadFactor < 1 {
+		c.Clustering.LoadFactor = defaultLoadFactor
+	}
+	return nil
 }
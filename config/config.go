@@ -134,6 +134,8 @@ type LocalFlags struct {
 	SetRequestFile  []string `mapstructure:"set-request-file,omitempty" json:"set-request-file,omitempty" yaml:"set-request-file,omitempty"`
 	SetRequestVars  string   `mapstructure:"set-request-vars,omitempty" json:"set-request-vars,omitempty" yaml:"set-request-vars,omitempty"`
 	SetDryRun       bool     `mapstructure:"set-dry-run,omitempty" json:"set-dry-run,omitempty" yaml:"set-dry-run,omitempty"`
+	SetElectionID   uint64   `mapstructure:"set-election-id,omitempty" json:"set-election-id,omitempty" yaml:"set-election-id,omitempty"`
+	SetRole         string   `mapstructure:"set-role,omitempty" json:"set-role,omitempty" yaml:"set-role,omitempty"`
 	// Sub
 	SubscribePrefix            string        `mapstructure:"subscribe-prefix,omitempty" json:"subscribe-prefix,omitempty" yaml:"subscribe-prefix,omitempty"`
 	SubscribePath              []string      `mapstructure:"subscribe-path,omitempty" json:"subscribe-path,omitempty" yaml:"subscribe-path,omitempty"`
@@ -182,16 +184,18 @@ type LocalFlags struct {
 	// VersionUpgrade
 	UpgradeUsePkg bool `mapstructure:"upgrade-use-pkg" json:"upgrade-use-pkg,omitempty" yaml:"upgrade-use-pkg,omitempty"`
 	// GetSet
-	GetSetPrefix    string `mapstructure:"getset-prefix,omitempty" json:"getset-prefix,omitempty" yaml:"getset-prefix,omitempty"`
-	GetSetGet       string `mapstructure:"getset-get,omitempty" json:"getset-get,omitempty" yaml:"getset-get,omitempty"`
-	GetSetModel     []string
-	GetSetTarget    string `mapstructure:"getset-target,omitempty" json:"getset-target,omitempty" yaml:"getset-target,omitempty"`
-	GetSetType      string `mapstructure:"getset-type,omitempty" json:"getset-type,omitempty" yaml:"getset-type,omitempty"`
-	GetSetCondition string `mapstructure:"getset-condition,omitempty" json:"getset-condition,omitempty" yaml:"getset-condition,omitempty"`
-	GetSetUpdate    string `mapstructure:"getset-update,omitempty" json:"getset-update,omitempty" yaml:"getset-update,omitempty"`
-	GetSetReplace   string `mapstructure:"getset-replace,omitempty" json:"getset-replace,omitempty" yaml:"getset-replace,omitempty"`
-	GetSetDelete    string `mapstructure:"getset-delete,omitempty" json:"getset-delete,omitempty" yaml:"getset-delete,omitempty"`
-	GetSetValue     string `mapstructure:"getset-value,omitempty" json:"getset-value,omitempty" yaml:"getset-value,omitempty"`
+	GetSetPrefix     string `mapstructure:"getset-prefix,omitempty" json:"getset-prefix,omitempty" yaml:"getset-prefix,omitempty"`
+	GetSetGet        string `mapstructure:"getset-get,omitempty" json:"getset-get,omitempty" yaml:"getset-get,omitempty"`
+	GetSetModel      []string
+	GetSetTarget     string `mapstructure:"getset-target,omitempty" json:"getset-target,omitempty" yaml:"getset-target,omitempty"`
+	GetSetType       string `mapstructure:"getset-type,omitempty" json:"getset-type,omitempty" yaml:"getset-type,omitempty"`
+	GetSetCondition  string `mapstructure:"getset-condition,omitempty" json:"getset-condition,omitempty" yaml:"getset-condition,omitempty"`
+	GetSetUpdate     string `mapstructure:"getset-update,omitempty" json:"getset-update,omitempty" yaml:"getset-update,omitempty"`
+	GetSetReplace    string `mapstructure:"getset-replace,omitempty" json:"getset-replace,omitempty" yaml:"getset-replace,omitempty"`
+	GetSetDelete     string `mapstructure:"getset-delete,omitempty" json:"getset-delete,omitempty" yaml:"getset-delete,omitempty"`
+	GetSetValue      string `mapstructure:"getset-value,omitempty" json:"getset-value,omitempty" yaml:"getset-value,omitempty"`
+	GetSetElectionID uint64 `mapstructure:"getset-election-id,omitempty" json:"getset-election-id,omitempty" yaml:"getset-election-id,omitempty"`
+	GetSetRole       string `mapstructure:"getset-role,omitempty" json:"getset-role,omitempty" yaml:"getset-role,omitempty"`
 	// Generate
 	GenerateOutput     string `mapstructure:"generate-output,omitempty" json:"generate-output,omitempty" yaml:"generate-output,omitempty"`
 	GenerateJSON       bool   `mapstructure:"generate-json,omitempty" json:"generate-json,omitempty" yaml:"generate-json,omitempty"`
@@ -221,6 +225,12 @@ type LocalFlags struct {
 	DiffRef     string   `mapstructure:"diff-ref,omitempty" json:"diff-ref,omitempty" yaml:"diff-ref,omitempty"`
 	DiffCompare []string `mapstructure:"diff-compare,omitempty" json:"diff-compare,omitempty" yaml:"diff-compare,omitempty"`
 	DiffQos     uint32   `mapstructure:"diff-qos,omitempty" json:"diff-qos,omitempty" yaml:"diff-qos,omitempty"`
+	// Processor test
+	ProcessorTestInput        string   `mapstructure:"processor-test-input,omitempty" json:"processor-test-input,omitempty" yaml:"processor-test-input,omitempty"`
+	ProcessorTestName         []string `mapstructure:"processor-test-name,omitempty" json:"processor-test-name,omitempty" yaml:"processor-test-name,omitempty"`
+	ProcessorTestGolden       string   `mapstructure:"processor-test-golden,omitempty" json:"processor-test-golden,omitempty" yaml:"processor-test-golden,omitempty"`
+	ProcessorTestUpdateGolden bool     `mapstructure:"processor-test-update-golden,omitempty" json:"processor-test-update-golden,omitempty" yaml:"processor-test-update-golden,omitempty"`
+	ProcessorTestSubName      string   `mapstructure:"processor-test-subscription-name,omitempty" json:"processor-test-subscription-name,omitempty" yaml:"processor-test-subscription-name,omitempty"`
 	//
 	TunnelServerSubscribe bool
 }
@@ -427,9 +437,12 @@ func (c *Config) CreateGASGetRequest() (*gnmi.GetRequest, error) {
 }
 
 func (c *Config) CreateGASSetRequest(input interface{}) (*gnmi.SetRequest, error) {
-	gnmiOpts := make([]api.GNMIOption, 0, 3)
+	gnmiOpts := make([]api.GNMIOption, 0, 4)
 	gnmiOpts = append(gnmiOpts, api.Prefix(c.LocalFlags.GetSetPrefix))
 	gnmiOpts = append(gnmiOpts, api.Target(c.LocalFlags.GetSetTarget))
+	if c.LocalFlags.GetSetElectionID > 0 {
+		gnmiOpts = append(gnmiOpts, api.Extension_MasterArbitration(c.LocalFlags.GetSetRole, c.LocalFlags.GetSetElectionID))
+	}
 
 	delPath, err := c.execPathTemplate(c.LocalFlags.GetSetDelete, input)
 	if err != nil {
@@ -569,6 +582,9 @@ func (c *Config) CreateSetRequest(targetName string) ([]*gnmi.SetRequest, error)
 		api.Prefix(c.LocalFlags.SetPrefix),
 		api.Target(c.LocalFlags.SetTarget),
 	)
+	if c.LocalFlags.SetElectionID > 0 {
+		gnmiOpts = append(gnmiOpts, api.Extension_MasterArbitration(c.LocalFlags.SetRole, c.LocalFlags.SetElectionID))
+	}
 	for _, p := range c.LocalFlags.SetDelete {
 		gnmiOpts = append(gnmiOpts, api.Delete(strings.TrimSpace(p)))
 	}
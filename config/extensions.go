@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+)
+
+// cache of the loaded extensions message descriptors,
+// keyed by message name, proto files and proto dirs.
+var extensionDescriptors = struct {
+	m  *sync.Mutex
+	md map[string]*desc.MessageDescriptor
+}{
+	m:  new(sync.Mutex),
+	md: make(map[string]*desc.MessageDescriptor),
+}
+
+// RegisterExtensions loads the proto message descriptors of the registered extensions ecs
+// and registers them to decode the extensions received from target in the responses to subscription.
+// An empty subscription name registers them for all the target responses.
+func (c *Config) RegisterExtensions(target, subscription string, ecs []*types.ExtensionConfig) error {
+	for _, ec := range ecs {
+		if ec == nil {
+			continue
+		}
+		md, err := loadExtensionDescriptor(ec)
+		if err != nil {
+			return fmt.Errorf("extension %d: %v", ec.ID, err)
+		}
+		formatters.RegisterExtension(target, subscription, ec.ID, md)
+	}
+	return nil
+}
+
+// CreateExtensions returns the gNMI extensions built from
+// the registered extensions ecs with a configured value.
+func (c *Config) CreateExtensions(ecs []*types.ExtensionConfig) ([]*gnmi_ext.Extension, error) {
+	exts := make([]*gnmi_ext.Extension, 0, len(ecs))
+	for _, ec := range ecs {
+		if ec == nil {
+			continue
+		}
+		md, err := loadExtensionDescriptor(ec)
+		if err != nil {
+			return nil, fmt.Errorf("extension %d: %v", ec.ID, err)
+		}
+		if ec.Value == nil {
+			continue
+		}
+		b, err := json.Marshal(utils.Convert(ec.Value))
+		if err != nil {
+			return nil, fmt.Errorf("extension %d: %v", ec.ID, err)
+		}
+		m := dynamic.NewMessage(md)
+		err = m.UnmarshalJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("extension %d: failed to encode value as %q: %v", ec.ID, ec.MessageName, err)
+		}
+		msg, err := m.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("extension %d: %v", ec.ID, err)
+		}
+		exts = append(exts, &gnmi_ext.Extension{
+			Ext: &gnmi_ext.Extension_RegisteredExt{
+				RegisteredExt: &gnmi_ext.RegisteredExtension{
+					Id:  gnmi_ext.ExtensionID(ec.ID),
+					Msg: msg,
+				},
+			},
+		})
+	}
+	return exts, nil
+}
+
+func loadExtensionDescriptor(ec *types.ExtensionConfig) (*desc.MessageDescriptor, error) {
+	if ec.ID <= 0 {
+		return nil, errors.New("missing or invalid extension id")
+	}
+	if ec.MessageName == "" {
+		return nil, errors.New("missing message-name")
+	}
+	if len(ec.ProtoFiles) == 0 {
+		return nil, errors.New("missing proto-files")
+	}
+	key := strings.Join([]string{
+		ec.MessageName,
+		strings.Join(ec.ProtoFiles, ","),
+		strings.Join(ec.ProtoDirs, ","),
+	}, "|")
+	extensionDescriptors.m.Lock()
+	defer extensionDescriptors.m.Unlock()
+	if md, ok := extensionDescriptors.md[key]; ok {
+		return md, nil
+	}
+	descSource, err := grpcurl.DescriptorSourceFromProtoFiles(ec.ProtoDirs, ec.ProtoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proto files: %v", err)
+	}
+	d, err := descSource.FindSymbol(ec.MessageName)
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a proto message", ec.MessageName)
+	}
+	extensionDescriptors.md[key] = md
+	return md, nil
+}
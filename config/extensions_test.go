@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/karimra/gnmic/types"
+)
+
+const testExtensionProto = `
+syntax = "proto3";
+package test;
+message CommitInfo {
+  string commit_id = 1;
+}
+`
+
+func TestCreateExtensions(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "commit.proto"), []byte(testExtensionProto), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	sc := &types.SubscriptionConfig{
+		Name:  "sub1",
+		Paths: []string{"/interface"},
+		Extensions: []*types.ExtensionConfig{
+			{
+				ID:          1001,
+				MessageName: "test.CommitInfo",
+				ProtoFiles:  []string{"commit.proto"},
+				ProtoDirs:   []string{dir},
+				Value:       map[string]interface{}{"commit_id": "abc"},
+			},
+			{
+				// decode only
+				ID:          1002,
+				MessageName: "test.CommitInfo",
+				ProtoFiles:  []string{"commit.proto"},
+				ProtoDirs:   []string{dir},
+			},
+		},
+	}
+	req, err := c.CreateSubscribeRequest(sc, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.GetExtension()) != 1 {
+		t.Fatalf("expected 1 extension, got %d", len(req.GetExtension()))
+	}
+	rext := req.GetExtension()[0].GetRegisteredExt()
+	if rext.GetId() != 1001 {
+		t.Errorf("expected extension id 1001, got %d", rext.GetId())
+	}
+	md, err := loadExtensionDescriptor(sc.Extensions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamic.NewMessage(md)
+	err = m.Unmarshal(rext.GetMsg())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := m.GetFieldByName("commit_id"); v != "abc" {
+		t.Errorf("expected commit_id %q, got %v", "abc", v)
+	}
+
+	_, err = c.CreateExtensions([]*types.ExtensionConfig{{ID: 1003, ProtoFiles: []string{"commit.proto"}}})
+	if err == nil {
+		t.Errorf("expected an error creating an extension without message name")
+	}
+	_, err = c.CreateExtensions([]*types.ExtensionConfig{{
+		ID:          1004,
+		MessageName: "test.CommitInfo",
+		ProtoFiles:  []string{"commit.proto"},
+		ProtoDirs:   []string{dir},
+		Value:       map[string]interface{}{"unknown": "abc"},
+	}})
+	if err == nil {
+		t.Errorf("expected an error encoding an unknown field")
+	}
+}
@@ -27,6 +27,11 @@ func (c *Config) GetOutputs() (map[string]map[string]interface{}, error) {
 				if _, ok := outputs.OutputTypes[outType.(string)]; !ok {
 					return nil, fmt.Errorf("unknown output type: %q", outType)
 				}
+				if _, ok := outCfg["buffer"]; ok {
+					if _, ok := outputs.BufferedOutputTypes[outType.(string)]; !ok {
+						return nil, fmt.Errorf("output %q: buffer is not supported by output type %q", name, outType)
+					}
+				}
 				if _, ok := outputs.Outputs[outType.(string)]; ok {
 					format, ok := outCfg["format"]
 					if !ok || (ok && format == "") {
@@ -94,3 +94,45 @@ func TestGetOutputs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOutputsBuffer(t *testing.T) {
+	tests := map[string]struct {
+		in      []byte
+		wantErr bool
+	}{
+		"supported": {
+			in: []byte(`
+outputs:
+  output1:
+    type: kafka
+    buffer:
+      path: /tmp/output1
+`),
+		},
+		"not_supported": {
+			in: []byte(`
+outputs:
+  output1:
+    type: nats
+    buffer:
+      path: /tmp/output1
+`),
+			wantErr: true,
+		},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.SetLogger()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBuffer(data.in))
+			if err != nil {
+				t.Fatalf("failed reading config: %v", err)
+			}
+			_, err = cfg.GetOutputs()
+			if (err != nil) != data.wantErr {
+				t.Errorf("got error %v, want error: %v", err, data.wantErr)
+			}
+		})
+	}
+}
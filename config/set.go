@@ -117,6 +117,9 @@ func (c *Config) CreateSetRequestFromFile(targetName string) ([]*gnmi.SetRequest
 			return nil, err
 		}
 		gnmiOpts := make([]api.GNMIOption, 0)
+		if c.LocalFlags.SetElectionID > 0 {
+			gnmiOpts = append(gnmiOpts, api.Extension_MasterArbitration(c.LocalFlags.SetRole, c.LocalFlags.SetElectionID))
+		}
 		buf.Reset()
 		for _, upd := range reqFile.Updates {
 			if upd.Path == "" {
@@ -157,6 +157,25 @@ func (c *Config) setSubscriptionDefaults(sub *types.SubscriptionConfig, cmd *cob
 	}
 }
 
+// SetSubscriptionConfigDefaults sets the defaults of subscription sc
+// and validates it against the other configured subscriptions.
+func (c *Config) SetSubscriptionConfigDefaults(sc *types.SubscriptionConfig) error {
+	if sc.Name == "" {
+		return errors.New("missing subscription name")
+	}
+	c.setSubscriptionDefaults(sc, nil)
+	err := setDefaults(sc)
+	if err != nil {
+		return err
+	}
+	subs := make(map[string]*types.SubscriptionConfig, len(c.Subscriptions)+1)
+	for n, s := range c.Subscriptions {
+		subs[n] = s
+	}
+	subs[sc.Name] = sc
+	return validateSubscriptionsConfig(subs)
+}
+
 func (c *Config) GetSubscriptionsFromFile() []*types.SubscriptionConfig {
 	subs, err := c.GetSubscriptions(nil)
 	if err != nil {
@@ -172,7 +191,7 @@ func (c *Config) GetSubscriptionsFromFile() []*types.SubscriptionConfig {
 	return subscriptions
 }
 
-func (*Config) CreateSubscribeRequest(sc *types.SubscriptionConfig, target string) (*gnmi.SubscribeRequest, error) {
+func (c *Config) CreateSubscribeRequest(sc *types.SubscriptionConfig, target string) (*gnmi.SubscribeRequest, error) {
 	err := setDefaults(sc)
 	if err != nil {
 		return nil, err
@@ -193,6 +212,14 @@ func (*Config) CreateSubscribeRequest(sc *types.SubscriptionConfig, target strin
 			gnmiOpts = append(gnmiOpts, api.Extension_HistoryRange(sc.History.Start, sc.History.End))
 		}
 	}
+	// registered extensions
+	exts, err := c.CreateExtensions(sc.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range exts {
+		gnmiOpts = append(gnmiOpts, api.Extension(ext))
+	}
 	if sc.Qos != nil {
 		gnmiOpts = append(gnmiOpts, api.Qos(*sc.Qos))
 	}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/karimra/gnmic/types"
 	"github.com/mitchellh/mapstructure"
@@ -14,7 +15,9 @@ import (
 )
 
 const (
-	defaultTargetBufferSize = 100
+	defaultTargetBufferSize      = 100
+	defaultRetryPolicyMax        = 5 * time.Minute
+	defaultRetryPolicyMultiplier = 2
 )
 
 var ErrNoTargetsFound = errors.New("no targets found")
@@ -235,9 +238,39 @@ func (c *Config) SetTargetConfigDefaults(tc *types.TargetConfig) error {
 	if tc.BufferSize == 0 {
 		tc.BufferSize = defaultTargetBufferSize
 	}
+	if tc.RetryPolicy != nil {
+		setRetryPolicyDefaults(tc.RetryPolicy, tc.RetryTimer)
+	}
 	return nil
 }
 
+func setRetryPolicyDefaults(rp *types.RetryPolicy, retryTimer time.Duration) {
+	if rp.Initial <= 0 {
+		rp.Initial = retryTimer
+	}
+	if rp.Max <= 0 {
+		rp.Max = defaultRetryPolicyMax
+	}
+	if rp.Max < rp.Initial {
+		rp.Max = rp.Initial
+	}
+	if rp.Multiplier < 1 {
+		rp.Multiplier = defaultRetryPolicyMultiplier
+	}
+	if rp.Jitter < 0 {
+		rp.Jitter = 0
+	}
+	if rp.Jitter > 1 {
+		rp.Jitter = 1
+	}
+	if rp.CircuitBreakerThreshold < 0 {
+		rp.CircuitBreakerThreshold = 0
+	}
+	if rp.CircuitBreakerTimeout <= 0 {
+		rp.CircuitBreakerTimeout = rp.Max
+	}
+}
+
 func (c *Config) TargetsList() []*types.TargetConfig {
 	targets := make([]*types.TargetConfig, 0, len(c.Targets))
 	for _, tc := range c.Targets {
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/AlekSi/pointer"
 	"github.com/karimra/gnmic/types"
@@ -289,6 +290,45 @@ targets:
 		},
 		outErr: nil,
 	},
+	"target_with_retry_policy": {
+		in: []byte(`
+retry: 5s
+targets:
+  10.1.1.1:57400:
+    username: admin
+    password: admin
+    retry-policy:
+      max: 1m
+      jitter: 0.2
+      circuit-breaker-threshold: 10
+`),
+		out: map[string]*types.TargetConfig{
+			"10.1.1.1:57400": {
+				Address:      "10.1.1.1:57400",
+				Name:         "10.1.1.1:57400",
+				Password:     pointer.ToString("admin"),
+				Username:     pointer.ToString("admin"),
+				Token:        pointer.ToString(""),
+				TLSCert:      pointer.ToString(""),
+				TLSKey:       pointer.ToString(""),
+				LogTLSSecret: pointer.ToBool(false),
+				Insecure:     pointer.ToBool(false),
+				SkipVerify:   pointer.ToBool(false),
+				Gzip:         pointer.ToBool(false),
+				BufferSize:   uint(100),
+				RetryTimer:   5 * time.Second,
+				RetryPolicy: &types.RetryPolicy{
+					Initial:                 5 * time.Second,
+					Max:                     time.Minute,
+					Multiplier:              2,
+					Jitter:                  0.2,
+					CircuitBreakerThreshold: 10,
+					CircuitBreakerTimeout:   time.Minute,
+				},
+			},
+		},
+		outErr: nil,
+	},
 }
 
 func TestGetTargets(t *testing.T) {
@@ -2,21 +2,30 @@ package all
 
 import (
 	_ "github.com/karimra/gnmic/formatters/event_add_tag"
+	_ "github.com/karimra/gnmic/formatters/event_aggregate"
+	_ "github.com/karimra/gnmic/formatters/event_alarm"
 	_ "github.com/karimra/gnmic/formatters/event_allow"
+	_ "github.com/karimra/gnmic/formatters/event_combine"
 	_ "github.com/karimra/gnmic/formatters/event_convert"
 	_ "github.com/karimra/gnmic/formatters/event_data_convert"
 	_ "github.com/karimra/gnmic/formatters/event_date_string"
+	_ "github.com/karimra/gnmic/formatters/event_dedup"
 	_ "github.com/karimra/gnmic/formatters/event_delete"
 	_ "github.com/karimra/gnmic/formatters/event_drop"
 	_ "github.com/karimra/gnmic/formatters/event_duration_convert"
+	_ "github.com/karimra/gnmic/formatters/event_enrich"
 	_ "github.com/karimra/gnmic/formatters/event_extract_tags"
 	_ "github.com/karimra/gnmic/formatters/event_group_by"
+	_ "github.com/karimra/gnmic/formatters/event_join"
 	_ "github.com/karimra/gnmic/formatters/event_jq"
 	_ "github.com/karimra/gnmic/formatters/event_merge"
 	_ "github.com/karimra/gnmic/formatters/event_override_ts"
+	_ "github.com/karimra/gnmic/formatters/event_rate"
+	_ "github.com/karimra/gnmic/formatters/event_starlark"
 	_ "github.com/karimra/gnmic/formatters/event_strings"
 	_ "github.com/karimra/gnmic/formatters/event_to_tag"
 	_ "github.com/karimra/gnmic/formatters/event_trigger"
+	_ "github.com/karimra/gnmic/formatters/event_units"
 	_ "github.com/karimra/gnmic/formatters/event_value_tag"
 	_ "github.com/karimra/gnmic/formatters/event_write"
 )
@@ -32,6 +32,7 @@ func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[stri
 		return nil, nil
 	}
 	evs := make([]*EventMsg, 0)
+	extTags := ExtensionsTags(meta["source"], name, rsp.GetExtension())
 	switch rsp := rsp.Response.(type) {
 	case *gnmi.SubscribeResponse_Update:
 		namePrefix, prefixTags := TagsFromGNMIPath(rsp.Update.Prefix)
@@ -41,6 +42,9 @@ func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[stri
 			if err != nil {
 				return nil, err
 			}
+			for k, v := range extTags {
+				e.Tags[k] = v
+			}
 			for k, v := range meta {
 				if k == "format" {
 					continue
@@ -70,6 +74,9 @@ func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[stri
 			for k, v := range prefixTags {
 				e.Tags[k] = v
 			}
+			for k, v := range extTags {
+				e.Tags[k] = v
+			}
 			for k, v := range meta {
 				if k == "format" {
 					continue
@@ -95,6 +102,7 @@ func GetResponseToEventMsgs(rsp *gnmi.GetResponse, meta map[string]string, eps .
 		return nil, nil
 	}
 	evs := make([]*EventMsg, 0)
+	extTags := ExtensionsTags(meta["source"], "", rsp.GetExtension())
 	for _, notif := range rsp.GetNotification() {
 		namePrefix, prefixTags := TagsFromGNMIPath(notif.GetPrefix())
 		for _, upd := range notif.GetUpdate() {
@@ -102,6 +110,9 @@ func GetResponseToEventMsgs(rsp *gnmi.GetResponse, meta map[string]string, eps .
 			if err != nil {
 				return nil, err
 			}
+			for k, v := range extTags {
+				e.Tags[k] = v
+			}
 			for k, v := range meta {
 				if k == "format" {
 					continue
@@ -0,0 +1,445 @@
+package event_aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType = "event-aggregate"
+	loggingPrefix = "[" + processorType + "] "
+	defaultWindow = time.Minute
+)
+
+var defaultFunctions = []string{"min", "max", "avg"}
+
+// aggregate buffers the values of events over a tumbling or sliding window,
+// and emits one aggregated event per name and tag set at the end of each window
+type aggregate struct {
+	Window       time.Duration  `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Slide        time.Duration  `mapstructure:"slide,omitempty" json:"slide,omitempty"`
+	Tags         []string       `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	Aggregations []*aggregation `mapstructure:"aggregations,omitempty" json:"aggregations,omitempty"`
+	Debug        bool           `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	m      *sync.Mutex
+	groups map[string]*group
+	// true once the processor emits events
+	emitting bool
+	warned   bool
+	now      func() time.Time
+	logger   *log.Logger
+}
+
+// aggregation defines the functions applied to the values with a name matching one of the regexes
+type aggregation struct {
+	Values    []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Functions []string `mapstructure:"functions,omitempty" json:"functions,omitempty"`
+
+	values    []*regexp.Regexp
+	functions []*function
+}
+
+type function struct {
+	name string
+	// percentile rank, set for percentile functions only
+	rank float64
+}
+
+// group holds the samples of the events with the same name and tag set
+type group struct {
+	name   string
+	tags   map[string]string
+	values map[string]*series
+}
+
+type series struct {
+	aggregation *aggregation
+	samples     []sample
+}
+
+type sample struct {
+	t time.Time
+	v float64
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &aggregate{
+			m:      new(sync.Mutex),
+			groups: make(map[string]*group),
+			now:    time.Now,
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *aggregate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Window <= 0 {
+		p.Window = defaultWindow
+	}
+	if p.Slide <= 0 {
+		p.Slide = p.Window
+	}
+	if p.Slide > p.Window {
+		return fmt.Errorf("slide %s cannot be larger than window %s", p.Slide, p.Window)
+	}
+	if len(p.Aggregations) == 0 {
+		p.Aggregations = []*aggregation{{Values: []string{".*"}}}
+	}
+	for _, agg := range p.Aggregations {
+		if len(agg.Values) == 0 {
+			agg.Values = []string{".*"}
+		}
+		if len(agg.Functions) == 0 {
+			agg.Functions = defaultFunctions
+		}
+		agg.values = make([]*regexp.Regexp, 0, len(agg.Values))
+		for _, reg := range agg.Values {
+			re, err := regexp.Compile(reg)
+			if err != nil {
+				return err
+			}
+			agg.values = append(agg.values, re)
+		}
+		agg.functions = make([]*function, 0, len(agg.Functions))
+		for _, fn := range agg.Functions {
+			f, err := parseFunction(fn)
+			if err != nil {
+				return err
+			}
+			agg.functions = append(agg.functions, f)
+		}
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *aggregate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.emitting {
+		if !p.warned {
+			p.logger.Printf("processor is not emitting events, the output does not support asynchronous events: events are not aggregated")
+			p.warned = true
+		}
+		return es
+	}
+	// the windows are processing-time windows: the samples are bucketed
+	// by their reception time, not by the events timestamp.
+	now := p.now()
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		key, tags, ok := p.groupKey(e)
+		if !ok {
+			result = append(result, e)
+			continue
+		}
+		valuesLen := len(e.Values)
+		for k, v := range e.Values {
+			agg := p.aggregationFor(k)
+			if agg == nil {
+				continue
+			}
+			fv, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			g, ok := p.groups[key]
+			if !ok {
+				g = &group{
+					name:   e.Name,
+					tags:   tags,
+					values: make(map[string]*series),
+				}
+				p.groups[key] = g
+			}
+			s, ok := g.values[k]
+			if !ok {
+				s = &series{aggregation: agg}
+				g.values[k] = s
+			}
+			s.samples = append(s.samples, sample{t: now, v: fv})
+			delete(e.Values, k)
+		}
+		// pass through the events with values that are not aggregated
+		if len(e.Values) > 0 || (valuesLen == 0 && len(e.Deletes) > 0) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (p *aggregate) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *aggregate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *aggregate) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *aggregate) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// StartEmitting emits the aggregated events every slide period until ctx is done
+func (p *aggregate) StartEmitting(ctx context.Context, fn func(...*formatters.EventMsg)) {
+	p.m.Lock()
+	p.emitting = true
+	p.m.Unlock()
+	go func() {
+		ticker := time.NewTicker(p.Slide)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				evs := p.flush(p.now())
+				if p.Debug {
+					p.logger.Printf("emitting %d aggregated event(s)", len(evs))
+				}
+				if len(evs) > 0 {
+					fn(evs...)
+				}
+			}
+		}
+	}()
+}
+
+// flush builds the aggregated events of the window ending at now,
+// then evicts the samples that are not part of the next window.
+// Samples older than the window are evicted by the previous flushes,
+// so a delayed flush does not drop them.
+func (p *aggregate) flush(now time.Time) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	nextWindowStart := now.Add(p.Slide - p.Window)
+	keys := make([]string, 0, len(p.groups))
+	for k := range p.groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]*formatters.EventMsg, 0, len(keys))
+	for _, k := range keys {
+		g := p.groups[k]
+		ev := &formatters.EventMsg{
+			Name:      g.name,
+			Timestamp: now.UnixNano(),
+			Tags:      make(map[string]string, len(g.tags)),
+			Values:    make(map[string]interface{}),
+		}
+		for tn, tv := range g.tags {
+			ev.Tags[tn] = tv
+		}
+		for vn, s := range g.values {
+			vs := make([]float64, 0, len(s.samples))
+			for _, smpl := range s.samples {
+				if !smpl.t.After(now) {
+					vs = append(vs, smpl.v)
+				}
+			}
+			if len(vs) > 0 {
+				for _, f := range s.aggregation.functions {
+					ev.Values[vn+"_"+f.name] = f.apply(vs)
+				}
+			}
+			// evict the samples that are not part of the next window
+			i := 0
+			for i < len(s.samples) && !s.samples[i].t.After(nextWindowStart) {
+				i++
+			}
+			s.samples = s.samples[i:]
+			if len(s.samples) == 0 {
+				delete(g.values, vn)
+			}
+		}
+		if len(g.values) == 0 {
+			delete(p.groups, k)
+		}
+		if len(ev.Values) > 0 {
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
+// groupKey returns the key of the group event e belongs to and the group tags,
+// it returns false if e does not have one of the configured tags.
+func (p *aggregate) groupKey(e *formatters.EventMsg) (string, map[string]string, bool) {
+	tags := make(map[string]string)
+	if len(p.Tags) == 0 {
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+	} else {
+		for _, t := range p.Tags {
+			v, ok := e.Tags[t]
+			if !ok {
+				return "", nil, false
+			}
+			tags[t] = v
+		}
+	}
+	tagNames := make([]string, 0, len(tags))
+	for k := range tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(tags[k])
+	}
+	return sb.String(), tags, true
+}
+
+func (p *aggregate) aggregationFor(valueName string) *aggregation {
+	for _, agg := range p.Aggregations {
+		for _, re := range agg.values {
+			if re.MatchString(valueName) {
+				return agg
+			}
+		}
+	}
+	return nil
+}
+
+func parseFunction(name string) (*function, error) {
+	switch name {
+	case "min", "max", "avg", "sum", "count", "first", "last":
+		return &function{name: name}, nil
+	case "mean":
+		return &function{name: "avg"}, nil
+	}
+	if strings.HasPrefix(name, "p") {
+		rank, err := strconv.ParseFloat(name[1:], 64)
+		if err == nil && rank >= 0 && rank <= 100 {
+			return &function{name: name, rank: rank}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown aggregation function %q", name)
+}
+
+// apply returns the result of the function f applied to the values vs,
+// vs is expected to be in arrival order and not empty.
+func (f *function) apply(vs []float64) float64 {
+	switch f.name {
+	case "count":
+		return float64(len(vs))
+	case "first":
+		return vs[0]
+	case "last":
+		return vs[len(vs)-1]
+	case "sum", "avg":
+		sum := 0.0
+		for _, v := range vs {
+			sum += v
+		}
+		if f.name == "avg" {
+			return sum / float64(len(vs))
+		}
+		return sum
+	case "min":
+		min := vs[0]
+		for _, v := range vs[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	case "max":
+		max := vs[0]
+		for _, v := range vs[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	}
+	return percentile(vs, f.rank)
+}
+
+// percentile returns the rank-th percentile of vs,
+// interpolating linearly between the closest ranks.
+func percentile(vs []float64, rank float64) float64 {
+	sorted := make([]float64, len(vs))
+	copy(sorted, vs)
+	sort.Float64s(sorted)
+	pos := rank / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
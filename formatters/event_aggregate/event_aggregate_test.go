@@ -0,0 +1,173 @@
+package event_aggregate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	_ "github.com/karimra/gnmic/formatters/event_add_tag"
+)
+
+func newTestProcessor(t *testing.T, cfg map[string]interface{}) (*aggregate, *time.Time) {
+	p := formatters.EventProcessors[processorType]().(*aggregate)
+	err := p.Init(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+	// windows are flushed by the tests
+	p.emitting = true
+	return p, &now
+}
+
+func event(intf string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1,
+		Tags:      map[string]string{"source": "router1", "interface_name": intf, "subscription-name": "sub1"},
+		Values:    values,
+	}
+}
+
+func TestTumblingWindow(t *testing.T) {
+	p, now := newTestProcessor(t, map[string]interface{}{
+		"window": "10s",
+		"tags":   []string{"source", "interface_name"},
+		"aggregations": []interface{}{
+			map[string]interface{}{
+				"value-names": []string{"depth$"},
+				"functions":   []string{"min", "max", "avg", "sum", "count", "p50", "last"},
+			},
+		},
+	})
+	for i, v := range []interface{}{uint64(1), "3", 2.0, int64(10)} {
+		*now = time.Unix(int64(i+1), 0)
+		out := p.Apply(event("ethernet-1/1", map[string]interface{}{"queue-depth": v, "oper-state": "up"}))
+		expected := []*formatters.EventMsg{event("ethernet-1/1", map[string]interface{}{"oper-state": "up"})}
+		if !reflect.DeepEqual(out, expected) {
+			t.Fatalf("unexpected Apply output: %+v", out)
+		}
+	}
+	*now = time.Unix(10, 0)
+	out := p.flush(*now)
+	expected := []*formatters.EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: time.Unix(10, 0).UnixNano(),
+			Tags:      map[string]string{"source": "router1", "interface_name": "ethernet-1/1"},
+			Values: map[string]interface{}{
+				"queue-depth_min":   1.0,
+				"queue-depth_max":   10.0,
+				"queue-depth_avg":   4.0,
+				"queue-depth_sum":   16.0,
+				"queue-depth_count": 4.0,
+				"queue-depth_p50":   2.5,
+				"queue-depth_last":  10.0,
+			},
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("unexpected flush output:\n got: %+v\nwant: %+v", out, expected)
+	}
+	if len(p.groups) != 0 {
+		t.Errorf("expected the window state to be cleared, got %d groups", len(p.groups))
+	}
+	if out := p.flush(time.Unix(20, 0)); len(out) != 0 {
+		t.Errorf("expected no events for an empty window, got %+v", out)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	p, now := newTestProcessor(t, map[string]interface{}{
+		"window": "10s",
+		"slide":  "5s",
+		"tags":   []string{"interface_name"},
+		"aggregations": []interface{}{
+			map[string]interface{}{
+				"functions": []string{"sum"},
+			},
+		},
+	})
+	*now = time.Unix(3, 0)
+	p.Apply(event("ethernet-1/1", map[string]interface{}{"a": 1}))
+	*now = time.Unix(7, 0)
+	p.Apply(event("ethernet-1/1", map[string]interface{}{"a": 2}))
+
+	out := p.flush(time.Unix(10, 0))
+	if len(out) != 1 || out[0].Values["a_sum"] != 3.0 {
+		t.Fatalf("unexpected first window output: %+v", out)
+	}
+	out = p.flush(time.Unix(15, 0))
+	if len(out) != 1 || out[0].Values["a_sum"] != 2.0 {
+		t.Fatalf("unexpected second window output: %+v", out)
+	}
+	out = p.flush(time.Unix(20, 0))
+	if len(out) != 0 {
+		t.Fatalf("unexpected third window output: %+v", out)
+	}
+}
+
+func TestNotEmitting(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*aggregate)
+	err := p.Init(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := event("ethernet-1/1", map[string]interface{}{"a": 1})
+	out := p.Apply(in)
+	if len(out) != 1 || out[0] != in || len(in.Values) != 1 {
+		t.Errorf("expected the event to pass through, got %+v", out)
+	}
+}
+
+func TestStartEmitters(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{"window": "10ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := formatters.EventProcessors["event-add-tag"]()
+	err = next.Init(map[string]interface{}{
+		"value-names": []string{".*"},
+		"add":         map[string]string{"aggregated": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan *formatters.EventMsg, 1)
+	formatters.StartEmitters(ctx, []formatters.EventProcessor{p, next}, func(evs ...*formatters.EventMsg) {
+		for _, ev := range evs {
+			ch <- ev
+		}
+	})
+	p.Apply(event("ethernet-1/1", map[string]interface{}{"a": 1}))
+	select {
+	case ev := <-ch:
+		if ev.Tags["aggregated"] != "true" {
+			t.Errorf("expected the emitted event to go through the next processor: %+v", ev)
+		}
+		if ev.Values["a_avg"] != 1.0 {
+			t.Errorf("unexpected emitted event: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for an emitted event")
+	}
+}
+
+func TestParseFunction(t *testing.T) {
+	for _, name := range []string{"p99.9", "mean", "first"} {
+		if _, err := parseFunction(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"p101", "median", "px"} {
+		if _, err := parseFunction(name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,583 @@
+package event_alarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/karimra/gnmic/actions"
+	_ "github.com/karimra/gnmic/actions/all"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType     = "event-alarm"
+	loggingPrefix     = "[" + processorType + "] "
+	defaultAlarmName  = "alarm"
+	defaultAlpha      = 0.3
+	defaultMinSample  = 10
+	defaultExpiration = 10 * time.Minute
+
+	directionAbove = "above"
+	directionBelow = "below"
+
+	stateRaised  = "raised"
+	stateCleared = "cleared"
+
+	alarmNameTag      = "alarm-name"
+	alarmStateTag     = "alarm-state"
+	alarmValueNameTag = "alarm-value-name"
+	alarmSeverityTag  = "alarm-severity"
+	alarmExpiredTag   = "alarm-expired"
+)
+
+// alarm tracks the state of each series matching the configured value names,
+// it raises an alarm when a series crosses a threshold or deviates from its moving average,
+// and clears it when the series is back within the clear limits.
+// An alarm event is added to the events on each raise and clear transition.
+type alarm struct {
+	Name       string                 `mapstructure:"name,omitempty" json:"name,omitempty"`
+	ValueNames []string               `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Severity   string                 `mapstructure:"severity,omitempty" json:"severity,omitempty"`
+	Threshold  *thresholdCfg          `mapstructure:"threshold,omitempty" json:"threshold,omitempty"`
+	EWMA       *ewmaCfg               `mapstructure:"ewma,omitempty" json:"ewma,omitempty"`
+	RaiseCount int                    `mapstructure:"raise-count,omitempty" json:"raise-count,omitempty"`
+	ClearCount int                    `mapstructure:"clear-count,omitempty" json:"clear-count,omitempty"`
+	Actions    []string               `mapstructure:"actions,omitempty" json:"actions,omitempty"`
+	Vars       map[string]interface{} `mapstructure:"vars,omitempty" json:"vars,omitempty"`
+	VarsFile   string                 `mapstructure:"vars-file,omitempty" json:"vars-file,omitempty"`
+	Async      bool                   `mapstructure:"async,omitempty" json:"async,omitempty"`
+	Expiration time.Duration          `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	Debug      bool                   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	actions    []actions.Action
+	vars       map[string]interface{}
+
+	m         *sync.Mutex
+	series    map[string]*series
+	lastSweep time.Time
+
+	targets map[string]*types.TargetConfig
+	acts    map[string]map[string]interface{}
+	logger  *log.Logger
+}
+
+// thresholdCfg raises an alarm when a value crosses the raise threshold
+// and clears it when it crosses back the clear threshold.
+type thresholdCfg struct {
+	Raise     *float64 `mapstructure:"raise,omitempty" json:"raise,omitempty"`
+	Clear     *float64 `mapstructure:"clear,omitempty" json:"clear,omitempty"`
+	Direction string   `mapstructure:"direction,omitempty" json:"direction,omitempty"`
+}
+
+// ewmaCfg raises an alarm when a value deviates from the series exponentially weighted moving average
+// by more than deviation times the moving standard deviation,
+// and clears it when the value is back within clear-deviation times the standard deviation.
+type ewmaCfg struct {
+	Alpha          float64 `mapstructure:"alpha,omitempty" json:"alpha,omitempty"`
+	Deviation      float64 `mapstructure:"deviation,omitempty" json:"deviation,omitempty"`
+	ClearDeviation float64 `mapstructure:"clear-deviation,omitempty" json:"clear-deviation,omitempty"`
+	MinSamples     int     `mapstructure:"min-samples,omitempty" json:"min-samples,omitempty"`
+}
+
+// series is the alarm state of a value
+type series struct {
+	raised     bool
+	raiseCount int
+	clearCount int
+	// ewma state
+	samples  int
+	mean     float64
+	variance float64
+	// time the series was last updated at, used to expire stale series
+	seen time.Time
+	// alarm event of the last raise, used to clear the alarm when the series expires
+	raisedEvent *formatters.EventMsg
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &alarm{
+			m:      new(sync.Mutex),
+			series: make(map[string]*series),
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *alarm) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	for _, name := range p.Actions {
+		actCfg, ok := p.acts[name]
+		if !ok {
+			return fmt.Errorf("failed to initialize action %q: config not found", name)
+		}
+		err = p.initializeAction(actCfg)
+		if err != nil {
+			return err
+		}
+	}
+	err = p.readVars()
+	if err != nil {
+		return err
+	}
+	p.lastSweep = time.Now()
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *alarm) setDefaults() error {
+	if len(p.ValueNames) == 0 {
+		return errors.New("missing value-names")
+	}
+	switch {
+	case p.Threshold == nil && p.EWMA == nil:
+		return errors.New("one of threshold or ewma must be set")
+	case p.Threshold != nil && p.EWMA != nil:
+		return errors.New("only one of threshold or ewma can be set")
+	}
+	if p.Name == "" {
+		p.Name = defaultAlarmName
+	}
+	if p.RaiseCount <= 0 {
+		p.RaiseCount = 1
+	}
+	if p.ClearCount <= 0 {
+		p.ClearCount = 1
+	}
+	if p.Expiration <= 0 {
+		p.Expiration = defaultExpiration
+	}
+	if t := p.Threshold; t != nil {
+		if t.Raise == nil {
+			return errors.New("missing threshold raise value")
+		}
+		if t.Clear == nil {
+			t.Clear = t.Raise
+		}
+		if t.Direction == "" {
+			t.Direction = directionAbove
+		}
+		switch t.Direction {
+		case directionAbove:
+			if *t.Clear > *t.Raise {
+				return fmt.Errorf("threshold clear value %v cannot be higher than raise value %v", *t.Clear, *t.Raise)
+			}
+		case directionBelow:
+			if *t.Clear < *t.Raise {
+				return fmt.Errorf("threshold clear value %v cannot be lower than raise value %v", *t.Clear, *t.Raise)
+			}
+		default:
+			return fmt.Errorf("unknown threshold direction %q, expecting %q or %q", t.Direction, directionAbove, directionBelow)
+		}
+	}
+	if e := p.EWMA; e != nil {
+		if e.Alpha == 0 {
+			e.Alpha = defaultAlpha
+		}
+		if e.Alpha <= 0 || e.Alpha > 1 {
+			return fmt.Errorf("ewma alpha %v out of range (0, 1]", e.Alpha)
+		}
+		if e.Deviation <= 0 {
+			return errors.New("missing ewma deviation")
+		}
+		if e.ClearDeviation <= 0 {
+			e.ClearDeviation = e.Deviation
+		}
+		if e.ClearDeviation > e.Deviation {
+			return fmt.Errorf("ewma clear-deviation %v cannot be higher than deviation %v", e.ClearDeviation, e.Deviation)
+		}
+		if e.MinSamples <= 0 {
+			e.MinSamples = defaultMinSample
+		}
+	}
+	return nil
+}
+
+func (p *alarm) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := time.Now()
+	alarms := make([]*formatters.EventMsg, 0)
+	if now.Sub(p.lastSweep) >= p.Expiration {
+		for _, ae := range p.expire(now) {
+			alarms = append(alarms, ae)
+			p.notify(ae)
+		}
+	}
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		var prefix string
+		for _, k := range sortedValueNames(e.Values) {
+			if !p.match(k) {
+				continue
+			}
+			v, ok := toFloat(e.Values[k])
+			if !ok {
+				continue
+			}
+			if prefix == "" {
+				prefix = seriesPrefix(e)
+			}
+			s, ok := p.series[prefix+k]
+			if !ok {
+				s = new(series)
+				p.series[prefix+k] = s
+			}
+			s.seen = now
+			ae := p.evaluate(s, e, k, v)
+			if ae == nil {
+				continue
+			}
+			if p.Debug {
+				p.logger.Printf("alarm %s for series %q: %+v", ae.Tags[alarmStateTag], prefix+k, ae)
+			}
+			alarms = append(alarms, ae)
+			p.notify(ae)
+		}
+	}
+	return append(es, alarms...)
+}
+
+// notify runs the configured actions with the alarm event ae as input,
+// asynchronously if async is set.
+func (p *alarm) notify(ae *formatters.EventMsg) {
+	if len(p.actions) == 0 {
+		return
+	}
+	if p.Async {
+		go p.runActions(ae)
+		return
+	}
+	p.runActions(ae)
+}
+
+func (p *alarm) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *alarm) WithTargets(tcs map[string]*types.TargetConfig) {
+	p.targets = tcs
+}
+
+func (p *alarm) WithActions(acts map[string]map[string]interface{}) {
+	p.acts = acts
+}
+
+func (p *alarm) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// evaluate updates the state of series s with value v of event e,
+// it returns an alarm event if the series state transitioned.
+func (p *alarm) evaluate(s *series, e *formatters.EventMsg, valueName string, v float64) *formatters.EventMsg {
+	var breach, recovered bool
+	values := map[string]interface{}{"value": v}
+	switch {
+	case p.Threshold != nil:
+		breach, recovered = p.Threshold.check(v)
+		if s.raised {
+			values["threshold"] = *p.Threshold.Clear
+		} else {
+			values["threshold"] = *p.Threshold.Raise
+		}
+	default:
+		var ok bool
+		breach, recovered, ok = p.EWMA.check(s, v)
+		if ok {
+			values["mean"] = s.mean
+			values["stddev"] = math.Sqrt(s.variance)
+		}
+		p.EWMA.update(s, v)
+		if !ok {
+			return nil
+		}
+	}
+	if !s.raised {
+		if !breach {
+			s.raiseCount = 0
+			return nil
+		}
+		s.raiseCount++
+		if s.raiseCount < p.RaiseCount {
+			return nil
+		}
+		s.raised, s.raiseCount = true, 0
+		s.raisedEvent = p.alarmEvent(e, valueName, stateRaised, values)
+		return s.raisedEvent
+	}
+	if !recovered {
+		s.clearCount = 0
+		return nil
+	}
+	s.clearCount++
+	if s.clearCount < p.ClearCount {
+		return nil
+	}
+	s.raised, s.clearCount, s.raisedEvent = false, 0, nil
+	return p.alarmEvent(e, valueName, stateCleared, values)
+}
+
+// expire deletes the series that were not updated during the last expiration period,
+// it returns a cleared alarm event, marked as expired, for each deleted series with a raised alarm.
+func (p *alarm) expire(now time.Time) []*formatters.EventMsg {
+	var alarms []*formatters.EventMsg
+	for k, s := range p.series {
+		if now.Sub(s.seen) < p.Expiration {
+			continue
+		}
+		if p.Debug {
+			p.logger.Printf("series %q expired, raised=%t", k, s.raised)
+		}
+		delete(p.series, k)
+		if !s.raised || s.raisedEvent == nil {
+			continue
+		}
+		ae := &formatters.EventMsg{
+			Name:      s.raisedEvent.Name,
+			Timestamp: now.UnixNano(),
+			Tags:      make(map[string]string, len(s.raisedEvent.Tags)+1),
+			Values:    map[string]interface{}{"last-seen": s.seen.UnixNano()},
+		}
+		for tk, tv := range s.raisedEvent.Tags {
+			ae.Tags[tk] = tv
+		}
+		ae.Tags[alarmStateTag] = stateCleared
+		ae.Tags[alarmExpiredTag] = "true"
+		alarms = append(alarms, ae)
+	}
+	p.lastSweep = now
+	return alarms
+}
+
+func (p *alarm) alarmEvent(e *formatters.EventMsg, valueName, state string, values map[string]interface{}) *formatters.EventMsg {
+	ae := &formatters.EventMsg{
+		Name:      p.Name,
+		Timestamp: e.Timestamp,
+		Tags:      make(map[string]string, len(e.Tags)+4),
+		Values:    values,
+	}
+	for k, v := range e.Tags {
+		ae.Tags[k] = v
+	}
+	ae.Tags[alarmNameTag] = p.Name
+	ae.Tags[alarmStateTag] = state
+	ae.Tags[alarmValueNameTag] = valueName
+	if p.Severity != "" {
+		ae.Tags[alarmSeverityTag] = p.Severity
+	}
+	return ae
+}
+
+// check returns true in breach if v crosses the raise threshold,
+// and true in recovered if v is back past the clear threshold.
+func (t *thresholdCfg) check(v float64) (breach bool, recovered bool) {
+	if t.Direction == directionBelow {
+		return v < *t.Raise, v >= *t.Clear
+	}
+	return v > *t.Raise, v <= *t.Clear
+}
+
+// check returns true in breach if v deviates from the series moving average by more than the deviation,
+// and true in recovered if v is back within the clear deviation.
+// It returns false in ok if the series did not receive enough samples yet.
+func (c *ewmaCfg) check(s *series, v float64) (breach bool, recovered bool, ok bool) {
+	if s.samples < c.MinSamples {
+		return false, false, false
+	}
+	dev := math.Abs(v - s.mean)
+	stddev := math.Sqrt(s.variance)
+	return dev > c.Deviation*stddev, dev <= c.ClearDeviation*stddev, true
+}
+
+// update adds v to the series exponentially weighted moving average and variance
+func (c *ewmaCfg) update(s *series, v float64) {
+	s.samples++
+	if s.samples == 1 {
+		s.mean = v
+		return
+	}
+	diff := v - s.mean
+	incr := c.Alpha * diff
+	s.mean += incr
+	s.variance = (1 - c.Alpha) * (s.variance + diff*incr)
+}
+
+func (p *alarm) match(k string) bool {
+	for _, re := range p.valueNames {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *alarm) initializeAction(cfg map[string]interface{}) error {
+	if len(cfg) == 0 {
+		return errors.New("missing action definition")
+	}
+	actType, ok := cfg["type"]
+	if !ok {
+		return errors.New("missing type field under action")
+	}
+	switch actType := actType.(type) {
+	case string:
+		in, ok := actions.Actions[actType]
+		if !ok {
+			return fmt.Errorf("unknown action type %q", actType)
+		}
+		act := in()
+		err := act.Init(cfg, actions.WithLogger(p.logger), actions.WithTargets(p.targets))
+		if err != nil {
+			return err
+		}
+		p.actions = append(p.actions, act)
+		return nil
+	default:
+		return fmt.Errorf("unexpected action field type %T", actType)
+	}
+}
+
+func (p *alarm) readVars() error {
+	if p.VarsFile == "" {
+		p.vars = p.Vars
+		return nil
+	}
+	b, err := utils.ReadFile(context.TODO(), p.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	p.vars = utils.MergeMaps(v, p.Vars)
+	return nil
+}
+
+// runActions runs the configured actions sequentially with the alarm event ae as input
+func (p *alarm) runActions(ae *formatters.EventMsg) {
+	actx := &actions.Context{Input: ae, Env: make(map[string]interface{}), Vars: p.vars}
+	for _, act := range p.actions {
+		res, err := act.Run(context.TODO(), actx)
+		if err != nil {
+			p.logger.Printf("alarm action %q failed: %+v", act.NName(), err)
+			return
+		}
+		actx.Env[act.NName()] = res
+		if p.Debug {
+			p.logger.Printf("action %q result: %+v", act.NName(), res)
+		}
+	}
+}
+
+// seriesPrefix builds the part of the series key common to all the values of event e:
+// its name and tags sorted by tag name.
+func seriesPrefix(e *formatters.EventMsg) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	sb.WriteString(",")
+	return sb.String()
+}
+
+func sortedValueNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
@@ -0,0 +1,222 @@
+package event_alarm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+func cpuEvent(v interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1,
+		Tags:      map[string]string{"source": "router1"},
+		Values:    map[string]interface{}{"cpu-usage": v, "state": "up"},
+	}
+}
+
+func alarmEvent(state string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "high-cpu",
+		Timestamp: 1,
+		Tags: map[string]string{
+			"source":           "router1",
+			"alarm-name":       "high-cpu",
+			"alarm-state":      state,
+			"alarm-value-name": "cpu-usage",
+			"alarm-severity":   "major",
+		},
+		Values: values,
+	}
+}
+
+func TestThresholdAlarm(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"name":        "high-cpu",
+		"severity":    "major",
+		"value-names": []string{"cpu"},
+		"threshold": map[string]interface{}{
+			"raise": 90,
+			"clear": 80,
+		},
+		"raise-count": 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value  interface{}
+		alarms []*formatters.EventMsg
+	}{
+		{value: 95},
+		// raised after 2 consecutive samples above the threshold
+		{value: "96", alarms: []*formatters.EventMsg{
+			alarmEvent("raised", map[string]interface{}{"value": 96.0, "threshold": 90.0}),
+		}},
+		// repeated raises are not reported
+		{value: 97},
+		// hysteresis, the alarm stays raised until the value is lower than the clear threshold
+		{value: 85},
+		{value: uint64(80), alarms: []*formatters.EventMsg{
+			alarmEvent("cleared", map[string]interface{}{"value": 80.0, "threshold": 80.0}),
+		}},
+		{value: 95},
+		// the raise count is reset by samples below the threshold
+		{value: 50},
+		{value: 95},
+	}
+	for i, tt := range tests {
+		outs := p.Apply(cpuEvent(tt.value))
+		expected := append([]*formatters.EventMsg{cpuEvent(tt.value)}, tt.alarms...)
+		if !reflect.DeepEqual(outs, expected) {
+			t.Errorf("item %d: expected %+v, got: %+v", i, expected, outs)
+		}
+	}
+}
+
+func TestEWMAAlarm(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*alarm)
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"cpu"},
+		"ewma": map[string]interface{}{
+			"alpha":           0.5,
+			"deviation":       3,
+			"clear-deviation": 1,
+			"min-samples":     4,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make([]string, 0)
+	for _, v := range []float64{10, 12, 10, 12, 11, 40, 41, 11, 11} {
+		for _, e := range p.Apply(cpuEvent(v))[1:] {
+			states = append(states, e.Tags[alarmStateTag])
+			if e.Name != defaultAlarmName {
+				t.Errorf("unexpected alarm name %q", e.Name)
+			}
+			if _, ok := e.Values["mean"]; !ok {
+				t.Errorf("missing mean value in %+v", e)
+			}
+		}
+	}
+	expected := []string{"raised", "cleared"}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected alarm states %v, got %v", expected, states)
+	}
+}
+
+func TestAlarmActions(t *testing.T) {
+	received := make([]map[string]interface{}, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := make(map[string]interface{})
+		err := json.NewDecoder(r.Body).Decode(&input)
+		if err != nil {
+			t.Errorf("failed to decode action body: %v", err)
+		}
+		received = append(received, input)
+	}))
+	defer srv.Close()
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"cpu"},
+		"threshold": map[string]interface{}{
+			"raise": 90,
+		},
+		"actions": []string{"notify"},
+	}, formatters.WithActions(map[string]map[string]interface{}{
+		"notify": {
+			"name":   "notify",
+			"type":   "http",
+			"method": "POST",
+			"url":    srv.URL,
+			"body":   `{"state": "{{ index .Input.Tags "alarm-state" }}"}`,
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []int{95, 96, 50} {
+		p.Apply(cpuEvent(v))
+	}
+	expected := []map[string]interface{}{{"state": "raised"}, {"state": "cleared"}}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected actions inputs %v, got %v", expected, received)
+	}
+}
+
+func TestAlarmExpiration(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*alarm)
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"cpu"},
+		"threshold":   map[string]interface{}{"raise": 90},
+		"expiration":  "1m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Apply(cpuEvent(95))
+	if len(p.series) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(p.series))
+	}
+	alarms := p.expire(time.Now().Add(2 * time.Minute))
+	if len(p.series) != 0 {
+		t.Errorf("expected the series to be expired, got %d", len(p.series))
+	}
+	// the raised alarm of the expired series is cleared
+	if len(alarms) != 1 {
+		t.Fatalf("expected 1 alarm event, got %d", len(alarms))
+	}
+	if alarms[0].Tags[alarmStateTag] != stateCleared || alarms[0].Tags[alarmExpiredTag] != "true" {
+		t.Errorf("expected an expired cleared alarm, got tags %v", alarms[0].Tags)
+	}
+	if alarms[0].Tags["source"] != cpuEvent(95).Tags["source"] || alarms[0].Tags[alarmValueNameTag] != "cpu-usage" {
+		t.Errorf("expected the raised alarm tags, got %v", alarms[0].Tags)
+	}
+	// a series without a raised alarm expires silently
+	p.Apply(cpuEvent(50))
+	alarms = p.expire(time.Now().Add(2 * time.Minute))
+	if len(alarms) != 0 {
+		t.Errorf("expected no alarm event, got %v", alarms)
+	}
+}
+
+func TestAlarmInitErrors(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"no_value_names": {"threshold": map[string]interface{}{"raise": 1}},
+		"no_mode":        {"value-names": []string{"cpu"}},
+		"both_modes": {
+			"value-names": []string{"cpu"},
+			"threshold":   map[string]interface{}{"raise": 1},
+			"ewma":        map[string]interface{}{"deviation": 3},
+		},
+		"bad_hysteresis": {
+			"value-names": []string{"cpu"},
+			"threshold":   map[string]interface{}{"raise": 1, "clear": 2},
+		},
+		"bad_direction": {
+			"value-names": []string{"cpu"},
+			"threshold":   map[string]interface{}{"raise": 1, "direction": "sideways"},
+		},
+		"no_deviation": {
+			"value-names": []string{"cpu"},
+			"ewma":        map[string]interface{}{"alpha": 0.5},
+		},
+		"unknown_action": {
+			"value-names": []string{"cpu"},
+			"threshold":   map[string]interface{}{"raise": 1},
+			"actions":     []string{"unknown"},
+		},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package event_combine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType = "event-combine"
+	loggingPrefix = "[" + processorType + "] "
+)
+
+// combine applies an ordered list of processors to the event messages,
+// each processor is applied only to the events matching its condition.
+type combine struct {
+	Processors []*procStep `mapstructure:"processors,omitempty" json:"processors,omitempty"`
+	Debug      bool        `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	steps    []*step
+	targets  map[string]*types.TargetConfig
+	actions  map[string]map[string]interface{}
+	procsCfg map[string]map[string]interface{}
+	// logger passed to the pipeline processors
+	logOutput *log.Logger
+	logger    *log.Logger
+}
+
+// procStep is the configuration of a pipeline step
+type procStep struct {
+	Condition string `mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	Name      string `mapstructure:"name,omitempty" json:"name,omitempty"`
+}
+
+type step struct {
+	name      string
+	condition string
+	code      *gojq.Code
+	proc      formatters.EventProcessor
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &combine{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *combine) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.Processors) == 0 {
+		return errors.New("missing processors list")
+	}
+	p.steps = make([]*step, 0, len(p.Processors))
+	for i, ps := range p.Processors {
+		if ps == nil || ps.Name == "" {
+			return fmt.Errorf("processor #%d: missing name", i)
+		}
+		s, err := p.initStep(ps)
+		if err != nil {
+			return fmt.Errorf("processor %q: %v", ps.Name, err)
+		}
+		p.steps = append(p.steps, s)
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *combine) initStep(ps *procStep) (*step, error) {
+	s := &step{
+		name:      ps.Name,
+		condition: strings.TrimSpace(ps.Condition),
+	}
+	if s.condition != "" {
+		q, err := gojq.Parse(s.condition)
+		if err != nil {
+			return nil, err
+		}
+		s.code, err = gojq.Compile(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+	epCfg, ok := p.procsCfg[ps.Name]
+	if !ok {
+		return nil, errors.New("processor not found")
+	}
+	epType := ""
+	for k := range epCfg {
+		epType = k
+		break
+	}
+	if epType == processorType {
+		return nil, fmt.Errorf("processors of type %q cannot be nested", processorType)
+	}
+	in, ok := formatters.EventProcessors[epType]
+	if !ok {
+		return nil, fmt.Errorf("unknown processor type %q", epType)
+	}
+	s.proc = in()
+	err := s.proc.Init(epCfg[epType],
+		formatters.WithLogger(p.logOutput),
+		formatters.WithTargets(p.targets),
+		formatters.WithActions(p.actions),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the pipeline processors.
+func (p *combine) Close() error {
+	for _, s := range p.steps {
+		if c, ok := s.proc.(formatters.EventProcessorCloser); ok {
+			c.Close()
+		}
+	}
+	return nil
+}
+
+func (p *combine) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	return p.applySteps(p.steps, es)
+}
+
+// applySteps runs the events es through steps,
+// the events not matching a step condition skip that step.
+func (p *combine) applySteps(steps []*step, es []*formatters.EventMsg) []*formatters.EventMsg {
+	for _, s := range steps {
+		if len(es) == 0 {
+			return es
+		}
+		if s.code == nil {
+			es = s.proc.Apply(es...)
+			continue
+		}
+		matched := make([]*formatters.EventMsg, 0, len(es))
+		result := make([]*formatters.EventMsg, 0, len(es))
+		for _, e := range es {
+			if e == nil {
+				continue
+			}
+			ok, err := formatters.CheckCondition(s.code, e)
+			if err != nil {
+				p.logger.Printf("processor %q: condition check failed: %v", s.name, err)
+			}
+			if ok {
+				matched = append(matched, e)
+				continue
+			}
+			result = append(result, e)
+		}
+		if len(matched) == 0 {
+			es = result
+			continue
+		}
+		es = append(result, s.proc.Apply(matched...)...)
+	}
+	return es
+}
+
+// StartEmitting starts the processors in the pipeline emitting events asynchronously,
+// the emitted events go through the steps following the emitting processor.
+func (p *combine) StartEmitting(ctx context.Context, fn func(...*formatters.EventMsg)) {
+	for i, s := range p.steps {
+		em, ok := s.proc.(formatters.EventEmitter)
+		if !ok {
+			continue
+		}
+		next := p.steps[i+1:]
+		em.StartEmitting(ctx, func(es ...*formatters.EventMsg) {
+			es = p.applySteps(next, es)
+			if len(es) == 0 {
+				return
+			}
+			fn(es...)
+		})
+	}
+}
+
+func (p *combine) WithLogger(l *log.Logger) {
+	p.logOutput = l
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *combine) WithTargets(tcs map[string]*types.TargetConfig) {
+	p.targets = tcs
+}
+
+func (p *combine) WithActions(act map[string]map[string]interface{}) {
+	p.actions = act
+}
+
+func (p *combine) WithProcessors(procs map[string]map[string]interface{}) {
+	p.procsCfg = procs
+}
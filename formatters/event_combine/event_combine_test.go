@@ -0,0 +1,206 @@
+package event_combine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	_ "github.com/karimra/gnmic/formatters/event_add_tag"
+	_ "github.com/karimra/gnmic/formatters/event_aggregate"
+	_ "github.com/karimra/gnmic/formatters/event_drop"
+	_ "github.com/karimra/gnmic/formatters/event_strings"
+)
+
+var processorsCfg = map[string]map[string]interface{}{
+	"trim-srl-prefix": {
+		"event-strings": map[string]interface{}{
+			"value-names": []string{".*"},
+			"transforms": []interface{}{
+				map[string]interface{}{
+					"trim-prefix": map[string]interface{}{
+						"apply-on": "name",
+						"prefix":   "/srl_nokia-interfaces:",
+					},
+				},
+			},
+		},
+	},
+	"add-vendor-tag": {
+		"event-add-tag": map[string]interface{}{
+			"value-names": []string{".*"},
+			"add":         map[string]string{"vendor": "nokia"},
+		},
+	},
+	"drop-all": {
+		"event-drop": map[string]interface{}{
+			"condition": "true",
+		},
+	},
+	"aggregate": {
+		"event-aggregate": map[string]interface{}{
+			"window": "10ms",
+		},
+	},
+	"nested": {
+		"event-combine": map[string]interface{}{},
+	},
+}
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"conditional_steps": {
+		processor: map[string]interface{}{
+			"processors": []interface{}{
+				map[string]interface{}{
+					"name":      "trim-srl-prefix",
+					"condition": `.tags.source == "srl1"`,
+				},
+				map[string]interface{}{
+					"name":      "add-vendor-tag",
+					"condition": `.tags.source | startswith("srl")`,
+				},
+			},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Tags:   map[string]string{"source": "srl1"},
+						Values: map[string]interface{}{"/srl_nokia-interfaces:interface/oper-state": "up"},
+					},
+					{
+						Name:   "sub1",
+						Tags:   map[string]string{"source": "xr1"},
+						Values: map[string]interface{}{"/srl_nokia-interfaces:interface/oper-state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Tags:   map[string]string{"source": "xr1"},
+						Values: map[string]interface{}{"/srl_nokia-interfaces:interface/oper-state": "up"},
+					},
+					{
+						Name:   "sub1",
+						Tags:   map[string]string{"source": "srl1", "vendor": "nokia"},
+						Values: map[string]interface{}{"interface/oper-state": "up"},
+					},
+				},
+			},
+		},
+	},
+	"no_condition": {
+		processor: map[string]interface{}{
+			"processors": []interface{}{
+				map[string]interface{}{
+					"name": "drop-all",
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "sub1",
+						Values: map[string]interface{}{"a": 1},
+					},
+				},
+				// event-drop empties the dropped events
+				output: []*formatters.EventMsg{{}},
+			},
+		},
+	},
+}
+
+func newProcessor(cfg map[string]interface{}) (formatters.EventProcessor, error) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(cfg, formatters.WithProcessors(processorsCfg))
+	return p, err
+}
+
+func TestEventCombine(t *testing.T) {
+	for name, ts := range testset {
+		p, err := newProcessor(ts.processor)
+		if err != nil {
+			t.Fatalf("%s: failed to initialize processor: %v", name, err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
+
+func TestEventCombineInitErrors(t *testing.T) {
+	for name, procs := range map[string][]interface{}{
+		"no_processors":     {},
+		"missing_name":      {map[string]interface{}{"condition": "true"}},
+		"unknown_processor": {map[string]interface{}{"name": "unknown"}},
+		"bad_condition":     {map[string]interface{}{"name": "drop-all", "condition": ".tags["}},
+		"nested":            {map[string]interface{}{"name": "nested"}},
+	} {
+		_, err := newProcessor(map[string]interface{}{"processors": procs})
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEventCombineEmitter(t *testing.T) {
+	p, err := newProcessor(map[string]interface{}{
+		"processors": []interface{}{
+			map[string]interface{}{
+				"name":      "aggregate",
+				"condition": `.tags.source == "srl1"`,
+			},
+			map[string]interface{}{
+				"name": "add-vendor-tag",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan *formatters.EventMsg, 1)
+	formatters.StartEmitters(ctx, []formatters.EventProcessor{p}, func(evs ...*formatters.EventMsg) {
+		for _, ev := range evs {
+			ch <- ev
+		}
+	})
+	outs := p.Apply(
+		&formatters.EventMsg{Name: "sub1", Tags: map[string]string{"source": "srl1"}, Values: map[string]interface{}{"a": 1}},
+		&formatters.EventMsg{Name: "sub1", Tags: map[string]string{"source": "xr1"}, Values: map[string]interface{}{"a": 1}},
+	)
+	expected := []*formatters.EventMsg{
+		{Name: "sub1", Tags: map[string]string{"source": "xr1", "vendor": "nokia"}, Values: map[string]interface{}{"a": 1}},
+	}
+	if !reflect.DeepEqual(outs, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, outs)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Tags["vendor"] != "nokia" || ev.Values["a_avg"] != 1.0 {
+			t.Errorf("unexpected emitted event: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for an emitted event")
+	}
+}
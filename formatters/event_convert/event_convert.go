@@ -129,6 +129,8 @@ func (c *Convert) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (c *Convert) WithActions(act map[string]map[string]interface{}) {}
 
+func (c *Convert) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func convertToInt(i interface{}) (int, error) {
 	switch i := i.(type) {
 	case string:
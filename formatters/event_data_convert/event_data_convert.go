@@ -134,6 +134,8 @@ func (c *dataConvert) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (c *dataConvert) WithActions(act map[string]map[string]interface{}) {}
 
+func (c *dataConvert) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func (c *dataConvert) convertData(k string, i interface{}, from *units.Unit) (float64, error) {
 	if from == nil && c.From == "" {
 		from = unitFromName(k)
@@ -167,6 +167,8 @@ func (d *DateString) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (d *DateString) WithActions(act map[string]map[string]interface{}) {}
 
+func (d *DateString) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func convertToInt(i interface{}) (int, error) {
 	switch i := i.(type) {
 	case string:
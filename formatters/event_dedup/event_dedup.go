@@ -0,0 +1,211 @@
+package event_dedup
+
+import (
+	"container/list"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType    = "event-dedup"
+	loggingPrefix    = "[" + processorType + "] "
+	defaultMaxSeries = 100000
+)
+
+// dedup drops the events whose values did not change since the last event of the same series
+type dedup struct {
+	Values    []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Heartbeat time.Duration `mapstructure:"heartbeat,omitempty" json:"heartbeat,omitempty"`
+	MaxSeries int           `mapstructure:"max-series,omitempty" json:"max-series,omitempty"`
+	Debug     bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values []*regexp.Regexp
+
+	m *sync.Mutex
+	// series last seen values, indexed by series key
+	series map[string]*list.Element
+	// series keys, least recently used last
+	lru    *list.List
+	logger *log.Logger
+}
+
+// entry is the last forwarded state of a series
+type entry struct {
+	key    string
+	values map[string]interface{}
+	// timestamp of the last forwarded event
+	timestamp int64
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &dedup{
+			m:      new(sync.Mutex),
+			series: make(map[string]*list.Element),
+			lru:    list.New(),
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (d *dedup) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, d)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.values = make([]*regexp.Regexp, 0, len(d.Values))
+	for _, reg := range d.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		d.values = append(d.values, re)
+	}
+	if d.MaxSeries <= 0 {
+		d.MaxSeries = defaultMaxSeries
+	}
+	if d.logger.Writer() != io.Discard {
+		b, err := json.Marshal(d)
+		if err != nil {
+			d.logger.Printf("initialized processor '%s': %+v", processorType, d)
+			return nil
+		}
+		d.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (d *dedup) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	d.m.Lock()
+	defer d.m.Unlock()
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		// events without values or with deletes are always forwarded
+		if len(e.Values) == 0 || len(e.Deletes) > 0 {
+			result = append(result, e)
+			continue
+		}
+		if d.duplicate(e) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func (d *dedup) WithLogger(l *log.Logger) {
+	if d.Debug && l != nil {
+		d.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if d.Debug {
+		d.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (d *dedup) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (d *dedup) WithActions(act map[string]map[string]interface{}) {}
+
+func (d *dedup) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// duplicate compares the values of event e with the last forwarded values of its series,
+// it returns true if none of them changed and the heartbeat interval did not pass.
+// Otherwise, it stores the event values as the new series state.
+func (d *dedup) duplicate(e *formatters.EventMsg) bool {
+	values := d.matchingValues(e)
+	if len(values) == 0 {
+		return false
+	}
+	key := seriesKey(e, values)
+	if el, ok := d.series[key]; ok {
+		d.lru.MoveToFront(el)
+		en := el.Value.(*entry)
+		if reflect.DeepEqual(en.values, values) &&
+			(d.Heartbeat <= 0 || e.Timestamp-en.timestamp < int64(d.Heartbeat)) {
+			if d.Debug {
+				d.logger.Printf("series %q: dropping duplicate event with timestamp %d", key, e.Timestamp)
+			}
+			return true
+		}
+		en.values, en.timestamp = values, e.Timestamp
+		return false
+	}
+	d.series[key] = d.lru.PushFront(&entry{key: key, values: values, timestamp: e.Timestamp})
+	for d.lru.Len() > d.MaxSeries {
+		el := d.lru.Back()
+		en := d.lru.Remove(el).(*entry)
+		delete(d.series, en.key)
+		if d.Debug {
+			d.logger.Printf("series %q evicted", en.key)
+		}
+	}
+	return false
+}
+
+// matchingValues returns a copy of the event values matching the configured value names
+func (d *dedup) matchingValues(e *formatters.EventMsg) map[string]interface{} {
+	values := make(map[string]interface{}, len(e.Values))
+	for k, v := range e.Values {
+		if d.match(k) {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+func (d *dedup) match(k string) bool {
+	if len(d.values) == 0 {
+		return true
+	}
+	for _, re := range d.values {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesKey builds the key of the series event e belongs to:
+// its name, its tags sorted by tag name and the names of the compared values.
+func seriesKey(e *formatters.EventMsg, values map[string]interface{}) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	valueNames := make([]string, 0, len(values))
+	for k := range values {
+		valueNames = append(valueNames, k)
+	}
+	sort.Strings(valueNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	for _, k := range valueNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+	}
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+package event_dedup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func ifEvent(ts int64, ifName string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "router1", "interface_name": ifName},
+		Values:    values,
+	}
+}
+
+var sec = int64(time.Second)
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"change_only": {
+		processor: map[string]interface{}{},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(2*sec, "e1", map[string]interface{}{"oper-state": "up"}),
+					ifEvent(2*sec, "e2", map[string]interface{}{"oper-state": "up"}),
+				},
+				output: []*formatters.EventMsg{ifEvent(2*sec, "e2", map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(3*sec, "e1", map[string]interface{}{"oper-state": "down"})},
+				output: []*formatters.EventMsg{ifEvent(3*sec, "e1", map[string]interface{}{"oper-state": "down"})},
+			},
+			// a different set of values is a different series
+			{
+				input:  []*formatters.EventMsg{ifEvent(4*sec, "e1", map[string]interface{}{"admin-state": "enable"})},
+				output: []*formatters.EventMsg{ifEvent(4*sec, "e1", map[string]interface{}{"admin-state": "enable"})},
+			},
+			// events with deletes are always forwarded
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 5 * sec, Deletes: []string{"/interface"}},
+					{Name: "sub1", Timestamp: 5 * sec, Deletes: []string{"/interface"}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 5 * sec, Deletes: []string{"/interface"}},
+					{Name: "sub1", Timestamp: 5 * sec, Deletes: []string{"/interface"}},
+				},
+			},
+		},
+	},
+	"heartbeat": {
+		processor: map[string]interface{}{
+			"heartbeat": "30s",
+		},
+		tests: []item{
+			{
+				input:  []*formatters.EventMsg{ifEvent(10*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{ifEvent(10*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(20*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(40*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{ifEvent(40*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(50*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{},
+			},
+		},
+	},
+	"value_names": {
+		processor: map[string]interface{}{
+			"value-names": []string{"state$"},
+		},
+		tests: []item{
+			{
+				input:  []*formatters.EventMsg{ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up", "last-change": 1})},
+				output: []*formatters.EventMsg{ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up", "last-change": 1})},
+			},
+			// not matching values are not compared
+			{
+				input:  []*formatters.EventMsg{ifEvent(2*sec, "e1", map[string]interface{}{"oper-state": "up", "last-change": 2})},
+				output: []*formatters.EventMsg{},
+			},
+			// events without matching values are forwarded
+			{
+				input:  []*formatters.EventMsg{ifEvent(3*sec, "e1", map[string]interface{}{"in-octets": 1})},
+				output: []*formatters.EventMsg{ifEvent(3*sec, "e1", map[string]interface{}{"in-octets": 1})},
+			},
+			{
+				input:  []*formatters.EventMsg{ifEvent(4*sec, "e1", map[string]interface{}{"in-octets": 1})},
+				output: []*formatters.EventMsg{ifEvent(4*sec, "e1", map[string]interface{}{"in-octets": 1})},
+			},
+		},
+	},
+	"lru_eviction": {
+		processor: map[string]interface{}{
+			"max-series": 2,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up"}),
+					ifEvent(1*sec, "e2", map[string]interface{}{"oper-state": "up"}),
+				},
+				output: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", map[string]interface{}{"oper-state": "up"}),
+					ifEvent(1*sec, "e2", map[string]interface{}{"oper-state": "up"}),
+				},
+			},
+			// e1 becomes the most recently used series
+			{
+				input:  []*formatters.EventMsg{ifEvent(2*sec, "e1", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{},
+			},
+			// e3 evicts e2
+			{
+				input:  []*formatters.EventMsg{ifEvent(3*sec, "e3", map[string]interface{}{"oper-state": "up"})},
+				output: []*formatters.EventMsg{ifEvent(3*sec, "e3", map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(4*sec, "e1", map[string]interface{}{"oper-state": "up"}),
+					ifEvent(4*sec, "e2", map[string]interface{}{"oper-state": "up"}),
+				},
+				output: []*formatters.EventMsg{ifEvent(4*sec, "e2", map[string]interface{}{"oper-state": "up"})},
+			},
+		},
+	},
+}
+
+func TestEventDedup(t *testing.T) {
+	for name, ts := range testset {
+		p := formatters.EventProcessors[processorType]()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Fatalf("%s: failed to initialize processor: %v", name, err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
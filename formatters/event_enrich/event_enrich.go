@@ -0,0 +1,295 @@
+package event_enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType  = "event-enrich"
+	loggingPrefix  = "[" + processorType + "] "
+	defaultTimeout = 10 * time.Second
+)
+
+// enrich adds tags to the event messages from the columns of a lookup table row,
+// the row is selected using the values of the configured key tags.
+type enrich struct {
+	// list of tag names, their values build the lookup key
+	KeyTags []string `mapstructure:"key-tags,omitempty" json:"key-tags,omitempty"`
+	// list of table columns matching the key tags, defaults to the key tags
+	KeyColumns []string `mapstructure:"key-columns,omitempty" json:"key-columns,omitempty"`
+	// list of table columns added as tags, defaults to all the non key columns
+	Columns []string `mapstructure:"columns,omitempty" json:"columns,omitempty"`
+	// overwrite existing tags
+	Overwrite bool `mapstructure:"overwrite,omitempty" json:"overwrite,omitempty"`
+	// table source, a file path or an HTTP URL
+	File string `mapstructure:"file,omitempty" json:"file,omitempty"`
+	URL  string `mapstructure:"url,omitempty" json:"url,omitempty"`
+	// table format, csv or json
+	Format    string `mapstructure:"format,omitempty" json:"format,omitempty"`
+	Delimiter string `mapstructure:"delimiter,omitempty" json:"delimiter,omitempty"`
+	// table reload interval, disabled if 0
+	RefreshInterval time.Duration `mapstructure:"refresh-interval,omitempty" json:"refresh-interval,omitempty"`
+	// HTTP query timeout
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	// HTTP TLS config
+	SkipVerify bool   `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty"`
+	CAFile     string `mapstructure:"ca-file,omitempty" json:"ca-file,omitempty"`
+	CertFile   string `mapstructure:"cert-file,omitempty" json:"cert-file,omitempty"`
+	KeyFile    string `mapstructure:"key-file,omitempty" json:"key-file,omitempty"`
+	// HTTP basicAuth
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"-"`
+	// HTTP bearer token
+	Token string `mapstructure:"token,omitempty" json:"-"`
+	Debug bool   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	delimiter rune
+
+	m     *sync.RWMutex
+	table *table
+	// stops the table refresh and the file watcher
+	cfn    context.CancelFunc
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &enrich{
+			m:      new(sync.RWMutex),
+			table:  &table{rows: make(map[string]map[string]string)},
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *enrich) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	t, err := p.load()
+	if err != nil {
+		if p.File != "" || p.RefreshInterval <= 0 {
+			return fmt.Errorf("failed to load lookup table: %v", err)
+		}
+		// the URL will be queried again after the refresh interval
+		p.logger.Printf("failed to load lookup table: %v", err)
+	} else {
+		p.table = t
+	}
+	var ctx context.Context
+	ctx, p.cfn = context.WithCancel(context.Background())
+	if p.RefreshInterval > 0 {
+		go p.refresh(ctx)
+	}
+	if p.File != "" {
+		err = p.watch(ctx)
+		if err != nil {
+			p.cfn()
+			return err
+		}
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *enrich) setDefaults() error {
+	if len(p.KeyTags) == 0 {
+		return errors.New("missing key-tags")
+	}
+	if len(p.KeyColumns) == 0 {
+		p.KeyColumns = p.KeyTags
+	}
+	if len(p.KeyColumns) != len(p.KeyTags) {
+		return fmt.Errorf("key-columns length %d does not match key-tags length %d", len(p.KeyColumns), len(p.KeyTags))
+	}
+	switch {
+	case p.File == "" && p.URL == "":
+		return errors.New("one of file or url must be set")
+	case p.File != "" && p.URL != "":
+		return errors.New("only one of file or url can be set")
+	}
+	if p.Format == "" {
+		p.Format = "json"
+		if strings.HasSuffix(strings.ToLower(p.File), ".csv") {
+			p.Format = "csv"
+		}
+	}
+	switch p.Format {
+	case "csv", "json":
+	default:
+		return fmt.Errorf("unsupported format %q, expecting csv or json", p.Format)
+	}
+	p.delimiter = ','
+	if p.Delimiter != "" {
+		if utf8.RuneCountInString(p.Delimiter) != 1 {
+			return fmt.Errorf("invalid delimiter %q, expecting a single character", p.Delimiter)
+		}
+		p.delimiter, _ = utf8.DecodeRuneInString(p.Delimiter)
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+func (p *enrich) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		row, ok := p.table.lookup(p.keyValues(e))
+		if !ok {
+			continue
+		}
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(row))
+		}
+		for k, v := range row {
+			if _, ok := e.Tags[k]; ok && !p.Overwrite {
+				continue
+			}
+			e.Tags[k] = v
+		}
+	}
+	return es
+}
+
+// Close stops the table refresh and the file watcher.
+func (p *enrich) Close() error {
+	if p.cfn != nil {
+		p.cfn()
+	}
+	return nil
+}
+
+func (p *enrich) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *enrich) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *enrich) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *enrich) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// keyValues returns the values of the key tags of event e,
+// it returns nil if one of the tags is missing.
+func (p *enrich) keyValues(e *formatters.EventMsg) []string {
+	values := make([]string, 0, len(p.KeyTags))
+	for _, kt := range p.KeyTags {
+		v, ok := e.Tags[kt]
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// reload loads the lookup table and replaces the current one,
+// the current table is kept if the load fails.
+func (p *enrich) reload() {
+	t, err := p.load()
+	if err != nil {
+		p.logger.Printf("failed to reload lookup table: %v", err)
+		return
+	}
+	p.m.Lock()
+	p.table = t
+	p.m.Unlock()
+	if p.Debug {
+		p.logger.Printf("lookup table reloaded: %d row(s)", len(t.rows))
+	}
+}
+
+func (p *enrich) refresh(ctx context.Context) {
+	ticker := time.NewTicker(p.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.reload()
+		}
+	}
+}
+
+// watch reloads the lookup table when the file changes,
+// the file directory is watched to catch the files replaced by a rename.
+// The watcher is closed when ctx is done.
+func (p *enrich) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	file := filepath.Clean(p.File)
+	err = watcher.Add(filepath.Dir(file))
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != file {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					p.reload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				p.logger.Printf("lookup table file watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,174 @@
+package event_enrich
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+const csvTable = `# site inventory
+source,interface_name,site,circuit-id
+router1,ethernet-1/1,par1,C-001
+router1,ethernet-1/2,par1,
+router2,ethernet-1/1,lon1,C-002
+*,*,unknown,
+`
+
+func ifEvent(source, intf string, tags map[string]string) *formatters.EventMsg {
+	e := &formatters.EventMsg{
+		Name:   "sub1",
+		Tags:   map[string]string{"source": source, "interface_name": intf},
+		Values: map[string]interface{}{"in-octets": 1},
+	}
+	for k, v := range tags {
+		e.Tags[k] = v
+	}
+	return e
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEventEnrichCSV(t *testing.T) {
+	path := writeFile(t, "table.csv", csvTable)
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"file":     path,
+		"key-tags": []string{"source", "interface_name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input  *formatters.EventMsg
+		output *formatters.EventMsg
+	}{
+		{
+			input:  ifEvent("router1", "ethernet-1/1", nil),
+			output: ifEvent("router1", "ethernet-1/1", map[string]string{"site": "par1", "circuit-id": "C-001"}),
+		},
+		// empty columns are not added
+		{
+			input:  ifEvent("router1", "ethernet-1/2", nil),
+			output: ifEvent("router1", "ethernet-1/2", map[string]string{"site": "par1"}),
+		},
+		// existing tags are not overwritten
+		{
+			input:  ifEvent("router2", "ethernet-1/1", map[string]string{"site": "lon2"}),
+			output: ifEvent("router2", "ethernet-1/1", map[string]string{"site": "lon2", "circuit-id": "C-002"}),
+		},
+		// default row
+		{
+			input:  ifEvent("router3", "ethernet-1/1", nil),
+			output: ifEvent("router3", "ethernet-1/1", map[string]string{"site": "unknown"}),
+		},
+	}
+	for i, tt := range tests {
+		outs := p.Apply(tt.input)
+		if !reflect.DeepEqual(outs, []*formatters.EventMsg{tt.output}) {
+			t.Errorf("item %d: expected %+v, got: %+v", i, tt.output, outs[0])
+		}
+	}
+}
+
+func TestEventEnrichHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"device": "router1", "region": "eu", "customer": "acme", "asn": 65001},
+			{"device": "router2", "region": "us", "customer": "globex", "asn": 65002}
+		]`))
+	}))
+	defer srv.Close()
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"url":         srv.URL,
+		"key-tags":    []string{"source"},
+		"key-columns": []string{"device"},
+		"columns":     []string{"region", "asn"},
+		"overwrite":   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := p.Apply(
+		ifEvent("router1", "ethernet-1/1", map[string]string{"region": "apac"}),
+		ifEvent("router3", "ethernet-1/1", nil),
+	)
+	expected := []*formatters.EventMsg{
+		ifEvent("router1", "ethernet-1/1", map[string]string{"region": "eu", "asn": "65001"}),
+		ifEvent("router3", "ethernet-1/1", nil),
+	}
+	if !reflect.DeepEqual(outs, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, outs)
+	}
+}
+
+func TestEventEnrichFileReload(t *testing.T) {
+	path := writeFile(t, "table.json", `[{"source": "router1", "site": "par1"}]`)
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"file":     path,
+		"key-tags": []string{"source"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(`[{"source": "router1", "site": "par2"}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+RELOAD:
+	for {
+		outs := p.Apply(ifEvent("router1", "ethernet-1/1", nil))
+		if outs[0].Tags["site"] == "par2" {
+			break RELOAD
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("lookup table not reloaded, got tags %v", outs[0].Tags)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	// the file is not watched once the processor is closed
+	formatters.CloseProcessors([]formatters.EventProcessor{p})
+	err = os.WriteFile(path, []byte(`[{"source": "router1", "site": "par3"}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	outs := p.Apply(ifEvent("router1", "ethernet-1/1", nil))
+	if outs[0].Tags["site"] != "par2" {
+		t.Errorf("lookup table reloaded after close, got tags %v", outs[0].Tags)
+	}
+}
+
+func TestEventEnrichInitErrors(t *testing.T) {
+	path := writeFile(t, "table.csv", csvTable)
+	for name, cfg := range map[string]map[string]interface{}{
+		"no_key_tags":        {"file": path},
+		"no_source":          {"key-tags": []string{"source"}},
+		"file_and_url":       {"key-tags": []string{"source"}, "file": path, "url": "http://localhost"},
+		"key_columns_len":    {"key-tags": []string{"source"}, "key-columns": []string{"a", "b"}, "file": path},
+		"missing_file":       {"key-tags": []string{"source"}, "file": path + ".missing"},
+		"missing_key_column": {"key-tags": []string{"device"}, "file": path},
+		"bad_format":         {"key-tags": []string{"source"}, "file": path, "format": "xml"},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
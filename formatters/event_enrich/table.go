@@ -0,0 +1,212 @@
+package event_enrich
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/karimra/gnmic/utils"
+)
+
+// wildcard is the key column value matching any tag value,
+// the row with all its key columns set to wildcard is the default row.
+const wildcard = "*"
+
+// table is a lookup table of rows indexed by their composite key
+type table struct {
+	rows map[string]map[string]string
+	// columns added to the events not matching any row, nil if not set
+	defaultRow map[string]string
+}
+
+// load reads the lookup table rows from the configured file or URL
+func (p *enrich) load() (*table, error) {
+	var b []byte
+	var err error
+	switch {
+	case p.File != "":
+		b, err = os.ReadFile(p.File)
+	default:
+		b, err = p.fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]string
+	switch p.Format {
+	case "csv":
+		rows, err = p.parseCSV(b)
+	default:
+		rows, err = parseJSON(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.buildTable(rows)
+}
+
+func (p *enrich) fetch() ([]byte, error) {
+	c := resty.New()
+	tlsCfg, err := utils.NewTLSConfig(p.CAFile, p.CertFile, p.KeyFile, p.SkipVerify, false)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		c = c.SetTLSClientConfig(tlsCfg)
+	}
+	c.SetTimeout(p.Timeout)
+	if p.Username != "" && p.Password != "" {
+		c.SetBasicAuth(p.Username, p.Password)
+	}
+	if p.Token != "" {
+		c.SetAuthToken(p.Token)
+	}
+	rsp, err := c.R().Get(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode() != 200 {
+		return nil, fmt.Errorf("unexpected response status: %s", rsp.Status())
+	}
+	return rsp.Body(), nil
+}
+
+// parseCSV parses a CSV table, the first record is the header holding the column names
+func (p *enrich) parseCSV(b []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = p.delimiter
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing CSV header")
+		}
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	rows := make([]map[string]string, 0)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			row[col] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSON parses a JSON table, a list of objects, the object values are converted to strings
+func parseJSON(b []byte) ([]map[string]string, error) {
+	objs := make([]map[string]interface{}, 0)
+	err := json.Unmarshal(b, &objs)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]string, 0, len(objs))
+	for _, obj := range objs {
+		row := make(map[string]string, len(obj))
+		for k, v := range obj {
+			switch v := v.(type) {
+			case nil:
+			case string:
+				row[k] = v
+			default:
+				row[k] = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// buildTable indexes the rows by key and keeps the configured columns only
+func (p *enrich) buildTable(rows []map[string]string) (*table, error) {
+	t := &table{rows: make(map[string]map[string]string, len(rows))}
+	for i, row := range rows {
+		keyValues := make([]string, 0, len(p.KeyColumns))
+		isDefault := true
+		for _, kc := range p.KeyColumns {
+			v, ok := row[kc]
+			if !ok {
+				return nil, fmt.Errorf("row %d: missing key column %q", i+1, kc)
+			}
+			if v != wildcard {
+				isDefault = false
+			}
+			keyValues = append(keyValues, v)
+		}
+		cols := p.columns(row)
+		if isDefault {
+			t.defaultRow = cols
+			continue
+		}
+		key := tableKey(keyValues)
+		if _, ok := t.rows[key]; ok {
+			p.logger.Printf("row %d: duplicate key %v, overriding previous row", i+1, keyValues)
+		}
+		t.rows[key] = cols
+	}
+	return t, nil
+}
+
+// columns returns the columns of row to be added as tags
+func (p *enrich) columns(row map[string]string) map[string]string {
+	cols := make(map[string]string)
+	if len(p.Columns) == 0 {
+		for k, v := range row {
+			if p.isKeyColumn(k) || v == "" {
+				continue
+			}
+			cols[k] = v
+		}
+		return cols
+	}
+	for _, c := range p.Columns {
+		if v, ok := row[c]; ok && v != "" {
+			cols[c] = v
+		}
+	}
+	return cols
+}
+
+func (p *enrich) isKeyColumn(c string) bool {
+	for _, kc := range p.KeyColumns {
+		if kc == c {
+			return true
+		}
+	}
+	return false
+}
+
+func tableKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+func (t *table) lookup(values []string) (map[string]string, bool) {
+	if values != nil {
+		if row, ok := t.rows[tableKey(values)]; ok {
+			return row, true
+		}
+	}
+	if t.defaultRow != nil {
+		return t.defaultRow, true
+	}
+	return nil, false
+}
@@ -0,0 +1,339 @@
+package event_join
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType      = "event-join"
+	loggingPrefix      = "[" + processorType + "] "
+	defaultSourceTag   = "source"
+	defaultCount       = 2
+	defaultTolerance   = 5 * time.Second
+	defaultExpiration  = time.Minute
+	defaultSeparator   = "_"
+	defaultJoinKeyName = "join-key"
+)
+
+// join correlates events coming from different sources,
+// the events with the same key and timestamps within a tolerance
+// are combined into a single event.
+type join struct {
+	Key        string        `mapstructure:"key,omitempty" json:"key,omitempty"`
+	Condition  string        `mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	SourceTag  string        `mapstructure:"source-tag,omitempty" json:"source-tag,omitempty"`
+	Count      int           `mapstructure:"count,omitempty" json:"count,omitempty"`
+	Tolerance  time.Duration `mapstructure:"tolerance,omitempty" json:"tolerance,omitempty"`
+	Expiration time.Duration `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	Name       string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Separator  string        `mapstructure:"separator,omitempty" json:"separator,omitempty"`
+	KeyTag     string        `mapstructure:"key-tag,omitempty" json:"key-tag,omitempty"`
+	Debug      bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	keyCode   *gojq.Code
+	condition *gojq.Code
+
+	m *sync.Mutex
+	// buffered events, indexed by join key then by source
+	buffers   map[string]map[string]*member
+	lastSweep time.Time
+	logger    *log.Logger
+}
+
+// member is a buffered event waiting to be joined
+type member struct {
+	event *formatters.EventMsg
+	// time the event was buffered at, used to expire stale events
+	seen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &join{
+			m:       new(sync.Mutex),
+			buffers: make(map[string]map[string]*member),
+			logger:  log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *join) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.Key = strings.TrimSpace(p.Key)
+	if p.Key == "" {
+		return errors.New("missing key expression")
+	}
+	p.keyCode, err = compile(p.Key)
+	if err != nil {
+		return fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	p.Condition = strings.TrimSpace(p.Condition)
+	if p.Condition != "" {
+		p.condition, err = compile(p.Condition)
+		if err != nil {
+			return fmt.Errorf("failed to parse condition: %v", err)
+		}
+	}
+	if p.Count == 0 {
+		p.Count = defaultCount
+	}
+	if p.Count < 2 {
+		return fmt.Errorf("invalid count %d, expecting at least 2", p.Count)
+	}
+	if p.SourceTag == "" {
+		p.SourceTag = defaultSourceTag
+	}
+	if p.Tolerance <= 0 {
+		p.Tolerance = defaultTolerance
+	}
+	if p.Expiration <= 0 {
+		p.Expiration = defaultExpiration
+	}
+	if p.Separator == "" {
+		p.Separator = defaultSeparator
+	}
+	if p.KeyTag == "" {
+		p.KeyTag = defaultJoinKeyName
+	}
+	p.lastSweep = time.Now()
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func compile(expr string) (*gojq.Code, error) {
+	q, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return gojq.Compile(q)
+}
+
+func (p *join) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := time.Now()
+	if now.Sub(p.lastSweep) >= p.Expiration {
+		p.expire(now)
+	}
+	joined := make([]*formatters.EventMsg, 0)
+	for _, e := range es {
+		if e == nil || len(e.Values) == 0 {
+			continue
+		}
+		source, ok := e.Tags[p.SourceTag]
+		if !ok {
+			continue
+		}
+		if p.condition != nil {
+			ok, err := formatters.CheckCondition(p.condition, e)
+			if err != nil {
+				p.logger.Printf("condition check failed: %v", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		key, err := p.joinKey(e)
+		if err != nil {
+			p.logger.Printf("failed to evaluate key expression: %v", err)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		if je := p.add(key, source, e, now); je != nil {
+			joined = append(joined, je)
+		}
+	}
+	return append(es, joined...)
+}
+
+func (p *join) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *join) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *join) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *join) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// joinKey evaluates the key expression against event e,
+// it returns an empty string if the expression result is null or false.
+func (p *join) joinKey(e *formatters.EventMsg) (string, error) {
+	input := make(map[string]interface{})
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	err = json.Unmarshal(b, &input)
+	if err != nil {
+		return "", err
+	}
+	iter := p.keyCode.Run(input)
+	res, ok := iter.Next()
+	if !ok {
+		return "", nil
+	}
+	switch res := res.(type) {
+	case error:
+		return "", res
+	case nil:
+		return "", nil
+	case bool:
+		if !res {
+			return "", nil
+		}
+		return "true", nil
+	case string:
+		return res, nil
+	default:
+		return fmt.Sprint(res), nil
+	}
+}
+
+// add buffers event e from source under key.
+// It returns the joined event if events from count different sources
+// are buffered under key within the tolerance.
+func (p *join) add(key, source string, e *formatters.EventMsg, now time.Time) *formatters.EventMsg {
+	buf, ok := p.buffers[key]
+	if !ok {
+		buf = make(map[string]*member)
+		p.buffers[key] = buf
+	}
+	// the event is copied since it continues through the pipeline
+	buf[source] = &member{event: copyEvent(e), seen: now}
+	tolerance := int64(p.Tolerance)
+	for s, m := range buf {
+		d := m.event.Timestamp - e.Timestamp
+		if d > tolerance || d < -tolerance {
+			if p.Debug {
+				p.logger.Printf("key %q: discarding event from %q with timestamp %d out of tolerance", key, s, m.event.Timestamp)
+			}
+			delete(buf, s)
+		}
+	}
+	if len(buf) < p.Count {
+		return nil
+	}
+	delete(p.buffers, key)
+	return p.combine(key, buf)
+}
+
+// combine builds the joined event from the buffered events,
+// values are prefixed with their source, tags with the same value in all the events are kept as is,
+// the other tags, except the source tag, are prefixed with their source.
+func (p *join) combine(key string, buf map[string]*member) *formatters.EventMsg {
+	sources := make([]string, 0, len(buf))
+	for s := range buf {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	je := &formatters.EventMsg{
+		Name:   p.Name,
+		Tags:   make(map[string]string),
+		Values: make(map[string]interface{}),
+	}
+	// tags common to all the joined events
+	common := make(map[string]string)
+	for k, v := range buf[sources[0]].event.Tags {
+		common[k] = v
+	}
+	for _, s := range sources[1:] {
+		for k, v := range common {
+			if tv, ok := buf[s].event.Tags[k]; !ok || tv != v {
+				delete(common, k)
+			}
+		}
+	}
+	for _, s := range sources {
+		e := buf[s].event
+		if je.Name == "" {
+			je.Name = e.Name
+		}
+		if e.Timestamp > je.Timestamp {
+			je.Timestamp = e.Timestamp
+		}
+		for k, v := range e.Tags {
+			if _, ok := common[k]; ok {
+				je.Tags[k] = v
+				continue
+			}
+			if k == p.SourceTag {
+				continue
+			}
+			je.Tags[s+p.Separator+k] = v
+		}
+		for k, v := range e.Values {
+			je.Values[s+p.Separator+k] = v
+		}
+	}
+	je.Tags[p.KeyTag] = key
+	return je
+}
+
+// expire deletes the buffered events that were not joined during the last expiration period
+func (p *join) expire(now time.Time) {
+	for key, buf := range p.buffers {
+		for s, m := range buf {
+			if now.Sub(m.seen) >= p.Expiration {
+				if p.Debug {
+					p.logger.Printf("key %q: event from %q expired", key, s)
+				}
+				delete(buf, s)
+			}
+		}
+		if len(buf) == 0 {
+			delete(p.buffers, key)
+		}
+	}
+	p.lastSweep = now
+}
+
+func copyEvent(e *formatters.EventMsg) *formatters.EventMsg {
+	ce := &formatters.EventMsg{
+		Name:      e.Name,
+		Timestamp: e.Timestamp,
+		Tags:      make(map[string]string, len(e.Tags)),
+		Values:    make(map[string]interface{}, len(e.Values)),
+	}
+	for k, v := range e.Tags {
+		ce.Tags[k] = v
+	}
+	for k, v := range e.Values {
+		ce.Values[k] = v
+	}
+	return ce
+}
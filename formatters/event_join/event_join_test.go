@@ -0,0 +1,171 @@
+package event_join
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func linkEvent(ts int64, source, ifName, link string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags: map[string]string{
+			"source":         source,
+			"interface_name": ifName,
+			"link":           link,
+		},
+		Values: values,
+	}
+}
+
+var sec = int64(time.Second)
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"link_ends": {
+		processor: map[string]interface{}{
+			"key":       ".tags.link",
+			"tolerance": "2s",
+			"name":      "link",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					linkEvent(1*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 100}),
+				},
+				output: []*formatters.EventMsg{
+					linkEvent(1*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 100}),
+				},
+			},
+			// the other end of the link, within tolerance
+			{
+				input: []*formatters.EventMsg{
+					linkEvent(2*sec, "spine1", "e2", "l1", map[string]interface{}{"in-octets": 99}),
+				},
+				output: []*formatters.EventMsg{
+					linkEvent(2*sec, "spine1", "e2", "l1", map[string]interface{}{"in-octets": 99}),
+					{
+						Name:      "link",
+						Timestamp: 2 * sec,
+						Tags: map[string]string{
+							"link":                  "l1",
+							"join-key":              "l1",
+							"leaf1_interface_name":  "e1",
+							"spine1_interface_name": "e2",
+						},
+						Values: map[string]interface{}{
+							"leaf1_out-octets": 100,
+							"spine1_in-octets": 99,
+						},
+					},
+				},
+			},
+			// out of tolerance
+			{
+				input: []*formatters.EventMsg{
+					linkEvent(10*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 200}),
+					linkEvent(13*sec, "spine1", "e2", "l1", map[string]interface{}{"in-octets": 199}),
+				},
+				output: []*formatters.EventMsg{
+					linkEvent(10*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 200}),
+					linkEvent(13*sec, "spine1", "e2", "l1", map[string]interface{}{"in-octets": 199}),
+				},
+			},
+			// different keys are not joined
+			{
+				input: []*formatters.EventMsg{
+					linkEvent(20*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 300}),
+					linkEvent(20*sec, "leaf2", "e1", "l2", map[string]interface{}{"out-octets": 300}),
+				},
+				output: []*formatters.EventMsg{
+					linkEvent(20*sec, "leaf1", "e1", "l1", map[string]interface{}{"out-octets": 300}),
+					linkEvent(20*sec, "leaf2", "e1", "l2", map[string]interface{}{"out-octets": 300}),
+				},
+			},
+		},
+	},
+	"lag_members": {
+		processor: map[string]interface{}{
+			"key":       `.tags.lag`,
+			"condition": `.tags.lag != null`,
+			"count":     3,
+			"separator": ":",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r1", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "up"}},
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r2", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "up"}},
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r3"}, Values: map[string]interface{}{"oper-state": "up"}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r1", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "up"}},
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r2", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "up"}},
+					{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r3"}, Values: map[string]interface{}{"oper-state": "up"}},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 2, Tags: map[string]string{"source": "r3", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "down"}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 2, Tags: map[string]string{"source": "r3", "lag": "lag1"}, Values: map[string]interface{}{"oper-state": "down"}},
+					{
+						Name:      "sub1",
+						Timestamp: 2,
+						Tags:      map[string]string{"lag": "lag1", "join-key": "lag1"},
+						Values: map[string]interface{}{
+							"r1:oper-state": "up",
+							"r2:oper-state": "up",
+							"r3:oper-state": "down",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventJoin(t *testing.T) {
+	for name, ts := range testset {
+		p := formatters.EventProcessors[processorType]()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Fatalf("%s: failed to initialize processor: %v", name, err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
+
+func TestEventJoinInitErrors(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"no_key":        {},
+		"bad_key":       {"key": ".tags["},
+		"bad_condition": {"key": ".tags.link", "condition": ".tags["},
+		"bad_count":     {"key": ".tags.link", "count": 1},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -89,3 +89,5 @@ func (o *OverrideTS) WithLogger(l *log.Logger) {
 func (o *OverrideTS) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (o *OverrideTS) WithActions(act map[string]map[string]interface{}) {}
+
+func (o *OverrideTS) WithProcessors(procs map[string]map[string]interface{}) {}
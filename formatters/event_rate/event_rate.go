@@ -0,0 +1,282 @@
+package event_rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType     = "event-rate"
+	loggingPrefix     = "[" + processorType + "] "
+	defaultExpiration = 10 * time.Minute
+)
+
+// rate converts monotonically increasing counters into per second rates
+type rate struct {
+	Values      []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Suffix      string        `mapstructure:"suffix,omitempty" json:"suffix,omitempty"`
+	CounterSize int           `mapstructure:"counter-size,omitempty" json:"counter-size,omitempty"`
+	Expiration  time.Duration `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	Debug       bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	values []*regexp.Regexp
+	// max counter value, 0 if counter wraps are not handled
+	maxValue float64
+
+	m         *sync.Mutex
+	series    map[string]*sample
+	lastSweep time.Time
+	logger    *log.Logger
+}
+
+// sample is the last value seen for a series
+type sample struct {
+	value     float64
+	timestamp int64
+	// time the sample was stored at, used to expire stale series
+	seen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &rate{
+			m:      new(sync.Mutex),
+			series: make(map[string]*sample),
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (r *rate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.values = make([]*regexp.Regexp, 0, len(r.Values))
+	for _, reg := range r.Values {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		r.values = append(r.values, re)
+	}
+	switch r.CounterSize {
+	case 0:
+	case 32, 64:
+		r.maxValue = math.Pow(2, float64(r.CounterSize)) - 1
+	default:
+		return fmt.Errorf("invalid counter-size %d, expecting 32 or 64", r.CounterSize)
+	}
+	if r.Expiration <= 0 {
+		r.Expiration = defaultExpiration
+	}
+	r.lastSweep = time.Now()
+	if r.logger.Writer() != io.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *rate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	r.m.Lock()
+	defer r.m.Unlock()
+	now := time.Now()
+	if now.Sub(r.lastSweep) >= r.Expiration {
+		r.expire(now)
+	}
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if len(e.Values) == 0 {
+			result = append(result, e)
+			continue
+		}
+		var prefix string
+		rates := make(map[string]float64)
+		for k, v := range e.Values {
+			if !r.match(k) {
+				continue
+			}
+			fv, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			if prefix == "" {
+				prefix = seriesPrefix(e)
+			}
+			rv, ok := r.rate(prefix+k, fv, e.Timestamp, now)
+			if ok {
+				rates[k] = rv
+				continue
+			}
+			if r.Suffix == "" {
+				// no rate can be calculated for this value yet
+				delete(e.Values, k)
+			}
+		}
+		for k, rv := range rates {
+			e.Values[k+r.Suffix] = rv
+		}
+		if len(e.Values) == 0 && len(e.Deletes) == 0 {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+func (r *rate) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (r *rate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (r *rate) WithActions(act map[string]map[string]interface{}) {}
+
+func (r *rate) WithProcessors(procs map[string]map[string]interface{}) {}
+
+func (r *rate) match(k string) bool {
+	if len(r.values) == 0 {
+		return true
+	}
+	for _, re := range r.values {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// rate stores the value v of series key and returns its rate of change per second
+// compared to the previously stored value.
+// It returns false if there is no previous value, if the counter was reset
+// or if the sample is not newer than the stored one.
+func (r *rate) rate(key string, v float64, ts int64, now time.Time) (float64, bool) {
+	prev, ok := r.series[key]
+	if !ok {
+		r.series[key] = &sample{value: v, timestamp: ts, seen: now}
+		return 0, false
+	}
+	if ts <= prev.timestamp {
+		if r.Debug {
+			r.logger.Printf("series %q: ignoring sample with timestamp %d older than %d", key, ts, prev.timestamp)
+		}
+		return 0, false
+	}
+	delta := v - prev.value
+	if delta < 0 {
+		if r.maxValue == 0 || prev.value < r.maxValue/2 {
+			// counter reset
+			if r.Debug {
+				r.logger.Printf("series %q: counter reset from %f to %f", key, prev.value, v)
+			}
+			prev.value, prev.timestamp, prev.seen = v, ts, now
+			return 0, false
+		}
+		// counter wrap
+		delta = r.maxValue - prev.value + v + 1
+		if r.Debug {
+			r.logger.Printf("series %q: counter wrapped from %f to %f", key, prev.value, v)
+		}
+	}
+	rv := delta / (float64(ts-prev.timestamp) / float64(time.Second))
+	prev.value, prev.timestamp, prev.seen = v, ts, now
+	return rv, true
+}
+
+// expire deletes the series that were not updated during the last expiration period
+func (r *rate) expire(now time.Time) {
+	for k, s := range r.series {
+		if now.Sub(s.seen) >= r.Expiration {
+			if r.Debug {
+				r.logger.Printf("series %q expired", k)
+			}
+			delete(r.series, k)
+		}
+	}
+	r.lastSweep = now
+}
+
+// seriesPrefix builds the part of the series key common to all the values of event e:
+// its name and tags sorted by tag name.
+func seriesPrefix(e *formatters.EventMsg) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	sb.WriteString(",")
+	return sb.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package event_rate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func counter(ts int64, v interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "router1", "interface_name": "ethernet-1/1"},
+		Values:    map[string]interface{}{"in-octets": v, "oper-state": "up"},
+	}
+}
+
+func rateEvent(ts int64, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "router1", "interface_name": "ethernet-1/1"},
+		Values:    values,
+	}
+}
+
+var sec = int64(time.Second)
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"replace": {
+		processor: map[string]interface{}{
+			"value-names": []string{"octets$"},
+		},
+		tests: []item{
+			{
+				input:  []*formatters.EventMsg{counter(1*sec, uint64(100))},
+				output: []*formatters.EventMsg{rateEvent(1*sec, map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input:  []*formatters.EventMsg{counter(3*sec, "300")},
+				output: []*formatters.EventMsg{rateEvent(3*sec, map[string]interface{}{"in-octets": 100.0, "oper-state": "up"})},
+			},
+			// out of order sample
+			{
+				input:  []*formatters.EventMsg{counter(2*sec, uint64(200))},
+				output: []*formatters.EventMsg{rateEvent(2*sec, map[string]interface{}{"oper-state": "up"})},
+			},
+			// counter reset
+			{
+				input:  []*formatters.EventMsg{counter(4*sec, uint64(10))},
+				output: []*formatters.EventMsg{rateEvent(4*sec, map[string]interface{}{"oper-state": "up"})},
+			},
+			{
+				input:  []*formatters.EventMsg{counter(5*sec, uint64(20))},
+				output: []*formatters.EventMsg{rateEvent(5*sec, map[string]interface{}{"in-octets": 10.0, "oper-state": "up"})},
+			},
+		},
+	},
+	"suffix_and_wrap": {
+		processor: map[string]interface{}{
+			"value-names":  []string{"octets$"},
+			"suffix":       "_rate",
+			"counter-size": 32,
+		},
+		tests: []item{
+			{
+				input:  []*formatters.EventMsg{counter(1*sec, uint64(4294967290))},
+				output: []*formatters.EventMsg{rateEvent(1*sec, map[string]interface{}{"in-octets": uint64(4294967290), "oper-state": "up"})},
+			},
+			{
+				input: []*formatters.EventMsg{counter(2*sec, uint64(4))},
+				output: []*formatters.EventMsg{rateEvent(2*sec, map[string]interface{}{
+					"in-octets": uint64(4), "in-octets_rate": 10.0, "oper-state": "up",
+				})},
+			},
+		},
+	},
+	"drop_empty_events": {
+		processor: map[string]interface{}{},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					rateEvent(1*sec, map[string]interface{}{"a": 1}),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					rateEvent(2*sec, map[string]interface{}{"a": 3}),
+				},
+				output: []*formatters.EventMsg{
+					rateEvent(2*sec, map[string]interface{}{"a": 2.0}),
+				},
+			},
+		},
+	},
+}
+
+func TestEventRate(t *testing.T) {
+	for name, ts := range testset {
+		pi, ok := formatters.EventProcessors[processorType]
+		if !ok {
+			t.Fatalf("event processor %s not found", processorType)
+		}
+		p := pi()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Fatalf("failed to initialize processor: %v", err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
+
+func TestEventRateExpiration(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*rate)
+	err := p.Init(map[string]interface{}{"expiration": "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Apply(rateEvent(1*sec, map[string]interface{}{"a": 1}))
+	if len(p.series) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(p.series))
+	}
+	p.expire(time.Now().Add(2 * time.Minute))
+	if len(p.series) != 0 {
+		t.Errorf("expected the series to be expired, got %d", len(p.series))
+	}
+}
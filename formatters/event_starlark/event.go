@@ -0,0 +1,392 @@
+package event_starlark
+
+import (
+	"fmt"
+	"sort"
+
+	"go.starlark.net/starlark"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+// event is the starlark representation of an event message,
+// its tags, values and deletes are mutable starlark dicts and list.
+type event struct {
+	name      string
+	timestamp int64
+	tags      *starlark.Dict
+	values    *starlark.Dict
+	deletes   *starlark.List
+	frozen    bool
+}
+
+var eventAttrs = []string{"deletes", "name", "tags", "timestamp", "values"}
+
+var (
+	_ starlark.HasAttrs    = (*event)(nil)
+	_ starlark.HasSetField = (*event)(nil)
+)
+
+func (e *event) String() string {
+	return fmt.Sprintf("Event(name=%q, timestamp=%d, tags=%s, values=%s, deletes=%s)",
+		e.name, e.timestamp, e.tags, e.values, e.deletes)
+}
+
+func (e *event) Type() string { return "Event" }
+
+func (e *event) Freeze() {
+	if e.frozen {
+		return
+	}
+	e.frozen = true
+	e.tags.Freeze()
+	e.values.Freeze()
+	e.deletes.Freeze()
+}
+
+func (e *event) Truth() starlark.Bool { return starlark.True }
+
+func (e *event) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", e.Type()) }
+
+func (e *event) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(e.name), nil
+	case "timestamp":
+		return starlark.MakeInt64(e.timestamp), nil
+	case "tags":
+		return e.tags, nil
+	case "values":
+		return e.values, nil
+	case "deletes":
+		return e.deletes, nil
+	}
+	return nil, nil
+}
+
+func (e *event) AttrNames() []string { return eventAttrs }
+
+func (e *event) SetField(name string, v starlark.Value) error {
+	if e.frozen {
+		return fmt.Errorf("cannot set field %q of frozen Event", name)
+	}
+	var ok bool
+	switch name {
+	case "name":
+		var s string
+		s, ok = starlark.AsString(v)
+		if ok {
+			e.name = s
+		}
+	case "timestamp":
+		var i starlark.Int
+		i, ok = v.(starlark.Int)
+		if ok {
+			var ts int64
+			ts, ok = i.Int64()
+			if !ok {
+				return fmt.Errorf("timestamp %s out of range", i)
+			}
+			e.timestamp = ts
+		}
+	case "tags":
+		var d *starlark.Dict
+		d, ok = v.(*starlark.Dict)
+		if ok {
+			e.tags = d
+		}
+	case "values":
+		var d *starlark.Dict
+		d, ok = v.(*starlark.Dict)
+		if ok {
+			e.values = d
+		}
+	case "deletes":
+		var l *starlark.List
+		l, ok = v.(*starlark.List)
+		if ok {
+			e.deletes = l
+		}
+	default:
+		return starlark.NoSuchAttrError(fmt.Sprintf("Event has no .%s field", name))
+	}
+	if !ok {
+		return fmt.Errorf("invalid type %s for Event field %q", v.Type(), name)
+	}
+	return nil
+}
+
+// newEvent is the Event builtin, used by the scripts to create new events:
+// Event(name, timestamp=0, tags={}, values={}, deletes=[])
+func newEvent(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var timestamp int64
+	tags := new(starlark.Dict)
+	values := new(starlark.Dict)
+	deletes := new(starlark.List)
+	err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"name", &name,
+		"timestamp?", &timestamp,
+		"tags?", &tags,
+		"values?", &values,
+		"deletes?", &deletes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event{
+		name:      name,
+		timestamp: timestamp,
+		tags:      tags,
+		values:    values,
+		deletes:   deletes,
+	}, nil
+}
+
+// toEvent converts event message e to its starlark representation
+func toEvent(e *formatters.EventMsg) (*event, error) {
+	sev := &event{
+		name:      e.Name,
+		timestamp: e.Timestamp,
+		tags:      starlark.NewDict(len(e.Tags)),
+		values:    starlark.NewDict(len(e.Values)),
+		deletes:   starlark.NewList(make([]starlark.Value, 0, len(e.Deletes))),
+	}
+	for _, k := range sortedTagNames(e.Tags) {
+		err := sev.tags.SetKey(starlark.String(k), starlark.String(e.Tags[k]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range sortedValueNames(e.Values) {
+		v, err := toStarlark(e.Values[k])
+		if err != nil {
+			return nil, fmt.Errorf("value %q: %v", k, err)
+		}
+		err = sev.values.SetKey(starlark.String(k), v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range e.Deletes {
+		err := sev.deletes.Append(starlark.String(d))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sev, nil
+}
+
+// toEventMsg converts the starlark event sev back to an event message
+func toEventMsg(sev *event) (*formatters.EventMsg, error) {
+	e := &formatters.EventMsg{
+		Name:      sev.name,
+		Timestamp: sev.timestamp,
+	}
+	if sev.tags.Len() > 0 {
+		e.Tags = make(map[string]string, sev.tags.Len())
+		for _, item := range sev.tags.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("tag name %s is not a string", item[0])
+			}
+			v, ok := starlark.AsString(item[1])
+			if !ok {
+				return nil, fmt.Errorf("tag %q value %s is not a string", k, item[1])
+			}
+			e.Tags[k] = v
+		}
+	}
+	if sev.values.Len() > 0 {
+		e.Values = make(map[string]interface{}, sev.values.Len())
+		for _, item := range sev.values.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("value name %s is not a string", item[0])
+			}
+			v, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, fmt.Errorf("value %q: %v", k, err)
+			}
+			e.Values[k] = v
+		}
+	}
+	if sev.deletes.Len() > 0 {
+		e.Deletes = make([]string, 0, sev.deletes.Len())
+		for i := 0; i < sev.deletes.Len(); i++ {
+			d, ok := starlark.AsString(sev.deletes.Index(i))
+			if !ok {
+				return nil, fmt.Errorf("delete %s is not a string", sev.deletes.Index(i))
+			}
+			e.Deletes = append(e.Deletes, d)
+		}
+	}
+	return e, nil
+}
+
+// fromApplyResult converts the value returned by the apply function to a list of event messages,
+// the apply function can return None, an Event or a list or tuple of Events.
+func fromApplyResult(r starlark.Value) ([]*formatters.EventMsg, error) {
+	switch r := r.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *event:
+		e, err := toEventMsg(r)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{e}, nil
+	case starlark.Indexable:
+		if _, ok := r.(starlark.String); ok {
+			break
+		}
+		evs := make([]*formatters.EventMsg, 0, r.Len())
+		for i := 0; i < r.Len(); i++ {
+			sev, ok := r.Index(i).(*event)
+			if !ok {
+				return nil, fmt.Errorf("%s returned a %s containing a %s, expecting Events",
+					applyFuncName, r.Type(), r.Index(i).Type())
+			}
+			e, err := toEventMsg(sev)
+			if err != nil {
+				return nil, err
+			}
+			evs = append(evs, e)
+		}
+		return evs, nil
+	}
+	return nil, fmt.Errorf("%s returned a %s, expecting None, an Event or a list of Events", applyFuncName, r.Type())
+}
+
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case []byte:
+		return starlark.Bytes(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int8:
+		return starlark.MakeInt64(int64(v)), nil
+	case int16:
+		return starlark.MakeInt64(int64(v)), nil
+	case int32:
+		return starlark.MakeInt64(int64(v)), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case uint:
+		return starlark.MakeUint(v), nil
+	case uint8:
+		return starlark.MakeUint64(uint64(v)), nil
+	case uint16:
+		return starlark.MakeUint64(uint64(v)), nil
+	case uint32:
+		return starlark.MakeUint64(uint64(v)), nil
+	case uint64:
+		return starlark.MakeUint64(v), nil
+	case float32:
+		return starlark.Float(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case []interface{}:
+		elems := make([]starlark.Value, 0, len(v))
+		for _, item := range v {
+			sv, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, sv)
+		}
+		return starlark.NewList(elems), nil
+	case map[string]interface{}:
+		d := starlark.NewDict(len(v))
+		for _, k := range sortedValueNames(v) {
+			sv, err := toStarlark(v[k])
+			if err != nil {
+				return nil, err
+			}
+			err = d.SetKey(starlark.String(k), sv)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bytes:
+		return []byte(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		if u, ok := v.Uint64(); ok {
+			return u, nil
+		}
+		return nil, fmt.Errorf("integer %s out of range", v)
+	case starlark.Float:
+		return float64(v), nil
+	case *starlark.List:
+		return fromStarlarkSequence(v)
+	case starlark.Tuple:
+		return fromStarlarkSequence(v)
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", item[0])
+			}
+			iv, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = iv
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %s", v.Type())
+}
+
+func fromStarlarkSequence(v starlark.Indexable) ([]interface{}, error) {
+	l := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		iv, err := fromStarlark(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, iv)
+	}
+	return l, nil
+}
+
+func sortedTagNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedValueNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
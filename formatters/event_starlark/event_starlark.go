@@ -0,0 +1,270 @@
+package event_starlark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	starlarkmath "go.starlark.net/lib/math"
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType = "event-starlark"
+	loggingPrefix = "[" + processorType + "] "
+	applyFuncName = "apply"
+
+	defaultMaxSteps = 10000000
+	defaultTimeout  = 5 * time.Second
+)
+
+// builtinModules are the modules that can be loaded by the scripts without a file
+var builtinModules = map[string]starlark.StringDict{
+	"json.star": {"json": starlarkjson.Module},
+	"math.star": {"math": starlarkmath.Module},
+	"time.star": {"time": starlarktime.Module},
+}
+
+// starlarkProc runs a starlark script on the received event messages
+type starlarkProc struct {
+	Source    string        `mapstructure:"source,omitempty" json:"source,omitempty"`
+	Script    string        `mapstructure:"script,omitempty" json:"script,omitempty"`
+	ModuleDir string        `mapstructure:"module-dir,omitempty" json:"module-dir,omitempty"`
+	MaxSteps  uint64        `mapstructure:"max-steps,omitempty" json:"max-steps,omitempty"`
+	Timeout   time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Debug     bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	// the script state and loaded modules are not safe for concurrent use
+	m       *sync.Mutex
+	applyFn starlark.Value
+	// state kept across calls, available to the scripts as the global dict "cache"
+	cache *starlark.Dict
+	// loaded modules, by module name
+	modules map[string]*module
+	logger  *log.Logger
+}
+
+type module struct {
+	globals starlark.StringDict
+	err     error
+}
+
+func init() {
+	// allow the scripts to use while loops, recursion and top level if/for statements
+	resolve.AllowRecursion = true
+	resolve.AllowGlobalReassign = true
+
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &starlarkProc{
+			m:       new(sync.Mutex),
+			modules: make(map[string]*module),
+			cache:   starlark.NewDict(0),
+			logger:  log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *starlarkProc) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	var src interface{}
+	filename := processorType + ".star"
+	switch {
+	case p.Source != "" && p.Script != "":
+		return errors.New("only one of source or script can be set")
+	case p.Source != "":
+		src = p.Source
+	case p.Script != "":
+		filename = p.Script
+	default:
+		return errors.New("one of source or script must be set")
+	}
+	if p.ModuleDir == "" && p.Script != "" {
+		p.ModuleDir = filepath.Dir(p.Script)
+	}
+	if p.MaxSteps == 0 {
+		p.MaxSteps = defaultMaxSteps
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultTimeout
+	}
+	globals, err := starlark.ExecFile(p.newThread(), filename, src, p.predeclared())
+	if err != nil {
+		return fmt.Errorf("failed to load script: %v", describe(err))
+	}
+	applyFn, ok := globals[applyFuncName]
+	if !ok {
+		return fmt.Errorf("script does not define an %q function", applyFuncName)
+	}
+	if _, ok := applyFn.(starlark.Callable); !ok {
+		return fmt.Errorf("%q is not a function: %s", applyFuncName, applyFn.Type())
+	}
+	p.applyFn = applyFn
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *starlarkProc) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	result := make([]*formatters.EventMsg, 0, len(es))
+	batch := make([]*formatters.EventMsg, 0, len(es))
+	sevs := make([]starlark.Value, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		sev, err := toEvent(e)
+		if err != nil {
+			// the event is passed through unchanged
+			p.logger.Printf("failed to convert event %q with tags %v: %v", e.Name, e.Tags, err)
+			result = append(result, e)
+			continue
+		}
+		batch = append(batch, e)
+		sevs = append(sevs, sev)
+	}
+	if len(sevs) == 0 {
+		return result
+	}
+	evs, err := p.applyEvents(sevs)
+	if err == nil {
+		return append(result, evs...)
+	}
+	if len(batch) == 1 {
+		p.logger.Printf("failed to apply script on event %q with tags %v: %v", batch[0].Name, batch[0].Tags, err)
+		return append(result, batch...)
+	}
+	// the script is applied to each event of the failed batch separately,
+	// so that only the failing events are passed through unchanged.
+	for _, e := range batch {
+		sev, err := toEvent(e)
+		if err != nil {
+			p.logger.Printf("failed to convert event %q with tags %v: %v", e.Name, e.Tags, err)
+			result = append(result, e)
+			continue
+		}
+		evs, err := p.applyEvents([]starlark.Value{sev})
+		if err != nil {
+			p.logger.Printf("failed to apply script on event %q with tags %v: %v", e.Name, e.Tags, err)
+			result = append(result, e)
+			continue
+		}
+		result = append(result, evs...)
+	}
+	return result
+}
+
+// applyEvents calls the script apply function with the list of events sevs,
+// and returns the events it returned.
+// The call is aborted if it exceeds the configured max-steps or timeout.
+func (p *starlarkProc) applyEvents(sevs []starlark.Value) ([]*formatters.EventMsg, error) {
+	thread := p.newThread()
+	thread.SetMaxExecutionSteps(p.MaxSteps)
+	timer := time.AfterFunc(p.Timeout, func() {
+		thread.Cancel(fmt.Sprintf("timeout after %s", p.Timeout))
+	})
+	defer timer.Stop()
+	r, err := starlark.Call(thread, p.applyFn, starlark.Tuple{starlark.NewList(sevs)}, nil)
+	if err != nil {
+		return nil, errors.New(describe(err))
+	}
+	return fromApplyResult(r)
+}
+
+// newThread returns a starlark thread used to load the script or call its apply function,
+// a new thread is used for each call since the threads steps count is cumulative
+// and a cancelled thread cannot be reused.
+func (p *starlarkProc) newThread() *starlark.Thread {
+	return &starlark.Thread{
+		Name:  processorType,
+		Print: func(_ *starlark.Thread, msg string) { p.logger.Print(msg) },
+		Load:  p.load,
+	}
+}
+
+func (p *starlarkProc) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *starlarkProc) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *starlarkProc) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *starlarkProc) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// load implements the starlark load statement,
+// module is either one of the builtin modules or a file path relative to the module-dir.
+func (p *starlarkProc) load(thread *starlark.Thread, name string) (starlark.StringDict, error) {
+	if m, ok := builtinModules[name]; ok {
+		return m, nil
+	}
+	m, ok := p.modules[name]
+	if ok {
+		if m == nil {
+			return nil, fmt.Errorf("cycle in load graph at module %q", name)
+		}
+		return m.globals, m.err
+	}
+	// mark the module as being loaded to detect cycles
+	p.modules[name] = nil
+	filename := name
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(p.ModuleDir, filename)
+	}
+	thread = &starlark.Thread{
+		Name:  name,
+		Print: thread.Print,
+		Load:  p.load,
+	}
+	globals, err := starlark.ExecFile(thread, filename, nil, p.predeclared())
+	p.modules[name] = &module{globals: globals, err: err}
+	return globals, err
+}
+
+// predeclared returns the names predeclared in the scripts and the loaded modules
+func (p *starlarkProc) predeclared() starlark.StringDict {
+	return starlark.StringDict{
+		"Event": starlark.NewBuiltin("Event", newEvent),
+		"cache": p.cache,
+	}
+}
+
+// describe returns the error message of err including the starlark backtrace if any
+func describe(err error) string {
+	evalErr := new(starlark.EvalError)
+	if errors.As(err, &evalErr) {
+		return strings.TrimSpace(evalErr.Backtrace())
+	}
+	return err.Error()
+}
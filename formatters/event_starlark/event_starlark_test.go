@@ -0,0 +1,290 @@
+package event_starlark
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"passthrough": {
+		processor: map[string]interface{}{
+			"source": `
+def apply(events):
+  return events
+`,
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 42,
+						Tags:      map[string]string{"tag1": "1"},
+						Values:    map[string]interface{}{"value": int64(1), "state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 42,
+						Tags:      map[string]string{"tag1": "1"},
+						Values:    map[string]interface{}{"value": int64(1), "state": "up"},
+					},
+				},
+			},
+		},
+	},
+	"mutate": {
+		processor: map[string]interface{}{
+			"source": `
+def apply(events):
+  for event in events:
+    event.name = event.name.upper()
+    event.timestamp = event.timestamp * 1000
+    event.tags["interface"] = event.tags.pop("interface_name")
+    event.values["speed_mbps"] = event.values.pop("speed") / 1000000
+  return events
+`,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"speed": uint64(10000000)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "SUB1",
+						Timestamp: 1000,
+						Tags:      map[string]string{"interface": "ethernet-1/1"},
+						Values:    map[string]interface{}{"speed_mbps": 10.0},
+					},
+				},
+			},
+		},
+	},
+	"state_and_drop": {
+		processor: map[string]interface{}{
+			"source": `
+def apply(events):
+  # emit an event every 2 received events with the sum of their values
+  result = []
+  for event in events:
+    cache["count"] = cache.get("count", 0) + 1
+    cache["sum"] = cache.get("sum", 0) + event.values["v"]
+    if cache["count"] < 2:
+      continue
+    result.append(Event("sum", timestamp=event.timestamp, values={"v": cache["sum"]}))
+    cache.clear()
+  return result
+`,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 1, Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Timestamp: 2, Values: map[string]interface{}{"v": 2}},
+					{Name: "sub1", Timestamp: 3, Values: map[string]interface{}{"v": 3}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sum", Timestamp: 2, Values: map[string]interface{}{"v": int64(3)}},
+				},
+			},
+		},
+	},
+	"batch": {
+		processor: map[string]interface{}{
+			"source": `
+def apply(events):
+  # replace the batch with a single event counting its events
+  return Event("count", values={"events": len(events)})
+`,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": 1}},
+					{Name: "sub1", Values: map[string]interface{}{"v": 2}},
+					{Name: "sub1", Values: map[string]interface{}{"v": 3}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "count", Values: map[string]interface{}{"events": int64(3)}},
+				},
+			},
+		},
+	},
+	"error": {
+		processor: map[string]interface{}{
+			"source": `
+def apply(events):
+  for event in events:
+    event.values["double"] = event.values["v"] * 2
+  return events
+`,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": 1}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": int64(1), "double": int64(2)}},
+				},
+			},
+			{
+				// only the failing event is passed through unchanged
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": 1}},
+					{Name: "sub1", Values: map[string]interface{}{"x": 1}},
+					{Name: "sub1", Values: map[string]interface{}{"v": 2}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": int64(1), "double": int64(2)}},
+					{Name: "sub1", Values: map[string]interface{}{"x": 1}},
+					{Name: "sub1", Values: map[string]interface{}{"v": int64(2), "double": int64(4)}},
+				},
+			},
+		},
+	},
+	"builtin_module": {
+		processor: map[string]interface{}{
+			"source": `
+load("math.star", "math")
+
+def apply(events):
+  for event in events:
+    event.values["v"] = math.floor(event.values["v"])
+  return events
+`,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": 1.5}},
+				},
+				output: []*formatters.EventMsg{
+					{Name: "sub1", Values: map[string]interface{}{"v": int64(1)}},
+				},
+			},
+		},
+	},
+}
+
+func TestEventStarlark(t *testing.T) {
+	for name, ts := range testset {
+		pi, ok := formatters.EventProcessors[processorType]
+		if !ok {
+			t.Fatalf("event processor %s not found", processorType)
+		}
+		p := pi()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Fatalf("%s: failed to initialize processor: %v", name, err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
+
+func TestEventStarlarkScriptFile(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "helpers.star"), []byte(`
+def rename(d, old, new):
+  if old in d:
+    d[new] = d.pop(old)
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "script.star")
+	err = os.WriteFile(script, []byte(`
+load("helpers.star", "rename")
+
+def apply(events):
+  for event in events:
+    rename(event.tags, "source", "device")
+  return events
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := formatters.EventProcessors[processorType]()
+	err = p.Init(map[string]interface{}{"script": script})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := p.Apply(&formatters.EventMsg{Name: "sub1", Tags: map[string]string{"source": "router1"}})
+	expected := []*formatters.EventMsg{{Name: "sub1", Tags: map[string]string{"device": "router1"}}}
+	if !reflect.DeepEqual(outs, expected) {
+		t.Errorf("expected %+v, got: %+v", expected, outs)
+	}
+}
+
+func TestEventStarlarkLimits(t *testing.T) {
+	src := `
+def apply(events):
+  while True:
+    pass
+`
+	for name, cfg := range map[string]map[string]interface{}{
+		"max_steps": {"source": src, "max-steps": 1000, "timeout": "1m"},
+		"timeout":   {"source": src, "max-steps": uint64(math.MaxUint64), "timeout": "10ms"},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		err := p.Init(cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		in := &formatters.EventMsg{Name: "sub1", Values: map[string]interface{}{"v": 1}}
+		outs := p.Apply(in)
+		if len(outs) != 1 || outs[0] != in {
+			t.Errorf("%s: expected the event to be passed through, got: %+v", name, outs)
+		}
+	}
+}
+
+func TestEventStarlarkInitErrors(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"no_script":       {},
+		"source_and_file": {"source": "def apply(e): return e", "script": "script.star"},
+		"no_apply":        {"source": "def transform(e): return e"},
+		"apply_not_func":  {"source": "apply = 1"},
+		"syntax_error":    {"source": "def apply(e) return e"},
+		"missing_module":  {"source": "load(\"missing.star\", \"x\")"},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -193,6 +193,8 @@ func (s *Strings) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (s *Strings) WithActions(act map[string]map[string]interface{}) {}
 
+func (s *Strings) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func (s *Strings) applyValueTransformations(e *formatters.EventMsg, k string, v interface{}) {
 	for _, trans := range s.Transforms {
 		for _, t := range trans {
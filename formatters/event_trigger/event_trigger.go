@@ -150,6 +150,8 @@ func (p *Trigger) WithActions(acts map[string]map[string]interface{}) {
 	p.acts = acts
 }
 
+func (p *Trigger) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func (p *Trigger) initializeAction(cfg map[string]interface{}) error {
 	if len(cfg) == 0 {
 		return errors.New("missing action definition")
@@ -0,0 +1,300 @@
+package event_units
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	processorType  = "event-units"
+	loggingPrefix  = "[" + processorType + "] "
+	defaultUnitTag = "unit"
+)
+
+// unitsConv converts values to a target unit, based on a list of rules
+// and optionally on the units statements of the YANG schema.
+type unitsConv struct {
+	Rules     []*rule  `mapstructure:"rules,omitempty" json:"rules,omitempty"`
+	YangFiles []string `mapstructure:"yang-files,omitempty" json:"yang-files,omitempty"`
+	YangDirs  []string `mapstructure:"yang-dirs,omitempty" json:"yang-dirs,omitempty"`
+	UnitTag   string   `mapstructure:"unit-tag,omitempty" json:"unit-tag,omitempty"`
+	Debug     bool     `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	// schema leaves units, indexed by path
+	yangUnits map[string]string
+	logger    *log.Logger
+}
+
+// rule converts the values matching its value names from unit From to unit To,
+// or by applying Scale and Offset.
+type rule struct {
+	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	From       string   `mapstructure:"from,omitempty" json:"from,omitempty"`
+	To         string   `mapstructure:"to,omitempty" json:"to,omitempty"`
+	Scale      *float64 `mapstructure:"scale,omitempty" json:"scale,omitempty"`
+	Offset     float64  `mapstructure:"offset,omitempty" json:"offset,omitempty"`
+	Unit       string   `mapstructure:"unit,omitempty" json:"unit,omitempty"`
+
+	valueNames []*regexp.Regexp
+	from       string
+	to         string
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &unitsConv{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *unitsConv) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.Rules) == 0 {
+		return errors.New("missing rules")
+	}
+	for i, r := range p.Rules {
+		if r == nil {
+			return fmt.Errorf("rule #%d: empty rule", i)
+		}
+		err = r.init(len(p.YangFiles) > 0)
+		if err != nil {
+			return fmt.Errorf("rule #%d: %v", i, err)
+		}
+	}
+	if p.UnitTag == "" {
+		p.UnitTag = defaultUnitTag
+	}
+	if len(p.YangFiles) > 0 {
+		p.yangUnits, err = loadYangUnits(p.YangFiles, p.YangDirs)
+		if err != nil {
+			return err
+		}
+		p.logger.Printf("loaded the units of %d YANG leaves", len(p.yangUnits))
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+// init validates the rule and compiles its value names,
+// a rule without source unit relies on the YANG units, so it requires yang-files.
+func (r *rule) init(withYang bool) error {
+	r.valueNames = make([]*regexp.Regexp, 0, len(r.ValueNames))
+	for _, reg := range r.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		r.valueNames = append(r.valueNames, re)
+	}
+	if r.Scale != nil {
+		if r.From != "" || r.To != "" {
+			return errors.New("scale cannot be combined with from and to")
+		}
+		if len(r.valueNames) == 0 {
+			return errors.New("missing value-names")
+		}
+		return nil
+	}
+	if r.To == "" {
+		return errors.New("missing target unit or scale")
+	}
+	r.to = unitName(r.To)
+	if r.to == "" {
+		return fmt.Errorf("unknown unit %q", r.To)
+	}
+	if r.From != "" {
+		r.from = unitName(r.From)
+		if r.from == "" {
+			return fmt.Errorf("unknown unit %q", r.From)
+		}
+		if units[r.from].family != units[r.to].family {
+			return fmt.Errorf("cannot convert %q to %q", r.From, r.To)
+		}
+		if len(r.valueNames) == 0 {
+			return errors.New("missing value-names")
+		}
+	} else if !withYang {
+		return errors.New("missing source unit, from is required when no yang-files are configured")
+	}
+	if r.Unit == "" {
+		r.Unit = r.to
+	}
+	return nil
+}
+
+func (p *unitsConv) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil || len(e.Values) == 0 {
+			continue
+		}
+		converted := make(map[string]string)
+		for k, v := range e.Values {
+			for _, r := range p.Rules {
+				if !r.match(k) {
+					continue
+				}
+				fv, ok := toFloat(v)
+				if !ok {
+					break
+				}
+				nv, u, ok := p.convert(r, k, fv)
+				if !ok {
+					continue
+				}
+				e.Values[k] = nv
+				converted[k] = u
+				break
+			}
+		}
+		p.setUnitTags(e, converted)
+	}
+	return es
+}
+
+func (p *unitsConv) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *unitsConv) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *unitsConv) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *unitsConv) WithProcessors(procs map[string]map[string]interface{}) {}
+
+// convert applies rule r to value v named k,
+// it returns the converted value and its unit.
+func (p *unitsConv) convert(r *rule, k string, v float64) (float64, string, bool) {
+	if r.Scale != nil {
+		return v**r.Scale + r.Offset, r.Unit, true
+	}
+	from := r.from
+	if from == "" {
+		yu, ok := p.yangUnits[schemaPath(k)]
+		if !ok {
+			return 0, "", false
+		}
+		from = unitName(yu)
+		if from == "" {
+			if p.Debug {
+				p.logger.Printf("value %q: unknown YANG unit %q", k, yu)
+			}
+			return 0, "", false
+		}
+	}
+	nv, ok := convert(v, from, r.to)
+	if !ok {
+		if p.Debug {
+			p.logger.Printf("value %q: cannot convert %v from %q to %q", k, v, from, r.to)
+		}
+		return 0, "", false
+	}
+	return nv, r.Unit, true
+}
+
+// setUnitTags sets the unit tag of event e,
+// if the converted values do not share the same unit,
+// a tag named $value_name_$unit_tag is set for each converted value.
+func (p *unitsConv) setUnitTags(e *formatters.EventMsg, converted map[string]string) {
+	if len(converted) == 0 {
+		return
+	}
+	if e.Tags == nil {
+		e.Tags = make(map[string]string)
+	}
+	names := make([]string, 0, len(converted))
+	for k := range converted {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	same := true
+	for _, k := range names[1:] {
+		if converted[k] != converted[names[0]] {
+			same = false
+			break
+		}
+	}
+	if same {
+		e.Tags[p.UnitTag] = converted[names[0]]
+		return
+	}
+	for _, k := range names {
+		e.Tags[k+"_"+p.UnitTag] = converted[k]
+	}
+}
+
+func (r *rule) match(k string) bool {
+	if len(r.valueNames) == 0 {
+		return true
+	}
+	for _, re := range r.valueNames {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
@@ -0,0 +1,249 @@
+package event_units
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"scale": {
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"value-names": []string{"temperature/instant$"},
+					"scale":       0.1,
+					"unit":        "celsius",
+				},
+			},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"/components/component/temperature/instant": 455, "state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"unit": "celsius"},
+						Values: map[string]interface{}{"/components/component/temperature/instant": 45.5, "state": "up"},
+					},
+				},
+			},
+		},
+	},
+	"from_to": {
+		processor: map[string]interface{}{
+			"unit-tag": "units",
+			"rules": []interface{}{
+				map[string]interface{}{
+					"value-names": []string{"power$"},
+					"from":        "mW",
+					"to":          "dBm",
+				},
+				map[string]interface{}{
+					"value-names": []string{"speed$"},
+					"from":        "Mbps",
+					"to":          "Gbps",
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "r1"},
+						Values: map[string]interface{}{"input-power": "10"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "r1", "units": "dBm"},
+						Values: map[string]interface{}{"input-power": 10.0},
+					},
+				},
+			},
+			// different units
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"input-power": 1, "port-speed": uint32(100000)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"input-power_units": "dBm", "port-speed_units": "Gbps"},
+						Values: map[string]interface{}{"input-power": 0.0, "port-speed": 100.0},
+					},
+				},
+			},
+			// not convertible
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"input-power": 0, "output-power": "n/a"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"input-power": 0, "output-power": "n/a"},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventUnits(t *testing.T) {
+	for name, ts := range testset {
+		p := formatters.EventProcessors[processorType]()
+		err := p.Init(ts.processor)
+		if err != nil {
+			t.Fatalf("%s: failed to initialize processor: %v", name, err)
+		}
+		for i, item := range ts.tests {
+			outs := p.Apply(item.input...)
+			if !reflect.DeepEqual(outs, item.output) {
+				t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+			}
+		}
+	}
+}
+
+const testModule = `module test-optics {
+  namespace "urn:test:optics";
+  prefix optics;
+
+  container optics {
+    list port {
+      key name;
+      leaf name {
+        type string;
+      }
+      leaf input-power {
+        type decimal64 {
+          fraction-digits 2;
+        }
+        units "dBm";
+      }
+      leaf temperature {
+        type int32;
+        units "fahrenheit";
+      }
+    }
+  }
+}
+`
+
+func TestEventUnitsYang(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "test-optics.yang")
+	err := os.WriteFile(f, []byte(testModule), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := formatters.EventProcessors[processorType]()
+	err = p.Init(map[string]interface{}{
+		"yang-files": []string{f},
+		"rules": []interface{}{
+			map[string]interface{}{"to": "mW"},
+			map[string]interface{}{"to": "celsius"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := p.Apply(&formatters.EventMsg{
+		Values: map[string]interface{}{
+			"/optics:optics/port[name=1/1]/input-power": 20.0,
+			"/optics:optics/port[name=1/1]/temperature": 212,
+			"/optics:optics/port[name=1/1]/name":        "1/1",
+		},
+	})
+	e := outs[0]
+	if v := e.Values["/optics:optics/port[name=1/1]/input-power"]; v != 100.0 {
+		t.Errorf("unexpected input-power value: %v", v)
+	}
+	if v := e.Values["/optics:optics/port[name=1/1]/temperature"].(float64); math.Abs(v-100) > 1e-9 {
+		t.Errorf("unexpected temperature value: %v", v)
+	}
+	expectedTags := map[string]string{
+		"/optics:optics/port[name=1/1]/input-power_unit": "mW",
+		"/optics:optics/port[name=1/1]/temperature_unit": "celsius",
+	}
+	if !reflect.DeepEqual(e.Tags, expectedTags) {
+		t.Errorf("expected tags %v, got %v", expectedTags, e.Tags)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	for _, tt := range []struct {
+		v        float64
+		from, to string
+		expected float64
+		ok       bool
+	}{
+		{v: 0, from: "dBm", to: "mW", expected: 1, ok: true},
+		{v: 1000, from: "uW", to: "dBm", expected: 0, ok: true},
+		{v: 0, from: "mW", to: "dBm", ok: false},
+		{v: 32, from: "fahrenheit", to: "celsius", expected: 0, ok: true},
+		{v: 0, from: "celsius", to: "K", expected: 273.15, ok: true},
+		{v: 1, from: "bytes", to: "bits", expected: 8, ok: true},
+		{v: 1500, from: "ms", to: "s", expected: 1.5, ok: true},
+		{v: 1, from: "s", to: "bps", ok: false},
+	} {
+		v, ok := convert(tt.v, tt.from, tt.to)
+		if ok != tt.ok || math.Abs(v-tt.expected) > 1e-9 {
+			t.Errorf("convert(%v, %q, %q): expected %v, %v, got %v, %v", tt.v, tt.from, tt.to, tt.expected, tt.ok, v, ok)
+		}
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	for u, expected := range map[string]string{
+		"dBm":           "dBm",
+		"DBM":           "dBm",
+		"milli-watts":   "mW",
+		"Celsius":       "celsius",
+		"bits/second":   "bps",
+		"gbps":          "Gbps",
+		"unknown-units": "",
+	} {
+		if n := unitName(u); n != expected {
+			t.Errorf("unitName(%q): expected %q, got %q", u, expected, n)
+		}
+	}
+}
+
+func TestEventUnitsInitErrors(t *testing.T) {
+	for name, rules := range map[string][]interface{}{
+		"no_rules":          {},
+		"unknown_unit":      {map[string]interface{}{"to": "parsec"}},
+		"different_family":  {map[string]interface{}{"value-names": []string{"."}, "from": "mW", "to": "celsius"}},
+		"scale_and_to":      {map[string]interface{}{"value-names": []string{"."}, "scale": 2, "to": "mW"}},
+		"from_no_values":    {map[string]interface{}{"from": "mW", "to": "dBm"}},
+		"no_target":         {map[string]interface{}{"value-names": []string{"."}}},
+		"no_source_no_yang": {map[string]interface{}{"value-names": []string{"."}, "to": "dBm"}},
+	} {
+		p := formatters.EventProcessors[processorType]()
+		if err := p.Init(map[string]interface{}{"rules": rules}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
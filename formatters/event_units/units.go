@@ -0,0 +1,122 @@
+package event_units
+
+import (
+	"math"
+	"strings"
+)
+
+// unit is a unit of measure, converted to and from its family base unit:
+// base = value * factor + offset
+type unit struct {
+	family string
+	factor float64
+	offset float64
+	// logarithmic power unit, relative to 1 mW
+	dBm bool
+}
+
+var units = map[string]*unit{
+	// power, base W
+	"W":   {family: "power", factor: 1},
+	"mW":  {family: "power", factor: 1e-3},
+	"uW":  {family: "power", factor: 1e-6},
+	"dBm": {family: "power", dBm: true},
+	// temperature, base K
+	"K":          {family: "temperature", factor: 1},
+	"celsius":    {family: "temperature", factor: 1, offset: 273.15},
+	"fahrenheit": {family: "temperature", factor: 5.0 / 9.0, offset: 459.67 * 5.0 / 9.0},
+	// data rate, base bps
+	"bps":  {family: "rate", factor: 1},
+	"kbps": {family: "rate", factor: 1e3},
+	"Mbps": {family: "rate", factor: 1e6},
+	"Gbps": {family: "rate", factor: 1e9},
+	"Tbps": {family: "rate", factor: 1e12},
+	// data size, base bit
+	"bits":   {family: "size", factor: 1},
+	"bytes":  {family: "size", factor: 8},
+	"KBytes": {family: "size", factor: 8e3},
+	"MBytes": {family: "size", factor: 8e6},
+	"GBytes": {family: "size", factor: 8e9},
+	// time, base s
+	"ns": {family: "time", factor: 1e-9},
+	"us": {family: "time", factor: 1e-6},
+	"ms": {family: "time", factor: 1e-3},
+	"s":  {family: "time", factor: 1},
+}
+
+// aliases maps the units names commonly found in YANG modules to the units names above
+var aliases = map[string]string{
+	"watts":               "W",
+	"milliwatts":          "mW",
+	"milli-watts":         "mW",
+	"microwatts":          "uW",
+	"micro-watts":         "uW",
+	"dbm":                 "dBm",
+	"kelvin":              "K",
+	"c":                   "celsius",
+	"degrees-celsius":     "celsius",
+	"degrees celsius":     "celsius",
+	"f":                   "fahrenheit",
+	"degrees-fahrenheit":  "fahrenheit",
+	"bits-per-second":     "bps",
+	"bits/second":         "bps",
+	"bits per second":     "bps",
+	"kilobits-per-second": "kbps",
+	"kbits/s":             "kbps",
+	"megabits-per-second": "Mbps",
+	"mbits/s":             "Mbps",
+	"gigabits-per-second": "Gbps",
+	"gbits/s":             "Gbps",
+	"octets":              "bytes",
+	"nanoseconds":         "ns",
+	"microseconds":        "us",
+	"milliseconds":        "ms",
+	"seconds":             "s",
+}
+
+// unitName returns the canonical name of unit u,
+// it returns an empty string if the unit is not known.
+func unitName(u string) string {
+	u = strings.TrimSpace(u)
+	if _, ok := units[u]; ok {
+		return u
+	}
+	if n, ok := aliases[strings.ToLower(u)]; ok {
+		return n
+	}
+	for n := range units {
+		if strings.EqualFold(n, u) {
+			return n
+		}
+	}
+	return ""
+}
+
+// convert converts value v from unit from to unit to,
+// both units must be canonical names of the same family.
+func convert(v float64, from, to string) (float64, bool) {
+	fu, ok := units[from]
+	if !ok {
+		return 0, false
+	}
+	tu, ok := units[to]
+	if !ok || fu.family != tu.family {
+		return 0, false
+	}
+	if from == to {
+		return v, true
+	}
+	var base float64
+	if fu.dBm {
+		base = math.Pow(10, v/10) * 1e-3
+	} else {
+		base = v*fu.factor + fu.offset
+	}
+	if tu.dBm {
+		if base <= 0 {
+			return 0, false
+		}
+		return 10 * math.Log10(base/1e-3), true
+	}
+	return (base - tu.offset) / tu.factor, true
+}
@@ -0,0 +1,99 @@
+package event_units
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// loadYangUnits reads the YANG modules in files, using dirs to resolve their imports,
+// and returns the units of the schema leaves indexed by their path.
+// The paths do not contain modules prefixes nor list keys.
+func loadYangUnits(files, dirs []string) (map[string]string, error) {
+	ms := yang.NewModules()
+	for _, dirpath := range dirs {
+		expanded, err := yang.PathsWithModules(dirpath)
+		if err != nil {
+			return nil, err
+		}
+		ms.AddPath(expanded...)
+	}
+	yfiles, err := utils.FindYangFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range yfiles {
+		if err := ms.Read(name); err != nil {
+			return nil, err
+		}
+	}
+	if errs := ms.Process(); len(errs) > 0 {
+		return nil, fmt.Errorf("yang processing failed: %v", errs)
+	}
+	result := make(map[string]string)
+	done := make(map[string]struct{})
+	for _, m := range ms.Modules {
+		if _, ok := done[m.Name]; ok {
+			continue
+		}
+		done[m.Name] = struct{}{}
+		e := yang.ToEntry(m)
+		for _, child := range e.Dir {
+			collectUnits(child, "", result)
+		}
+	}
+	return result, nil
+}
+
+func collectUnits(e *yang.Entry, parent string, result map[string]string) {
+	p := parent
+	// choice and case statements are not part of the data path
+	if !e.IsChoice() && !e.IsCase() {
+		p = parent + "/" + e.Name
+	}
+	if u := entryUnits(e); u != "" {
+		result[p] = u
+	}
+	for _, child := range e.Dir {
+		collectUnits(child, p, result)
+	}
+}
+
+// entryUnits returns the units of entry e, set by a deviation,
+// by the leaf or leaf-list units statement or by its typedef.
+func entryUnits(e *yang.Entry) string {
+	if e.Units != "" {
+		return e.Units
+	}
+	switch n := e.Node.(type) {
+	case *yang.Leaf:
+		if n.Units != nil {
+			return n.Units.Name
+		}
+	case *yang.LeafList:
+		if n.Units != nil {
+			return n.Units.Name
+		}
+	}
+	if e.Type != nil {
+		return e.Type.Units
+	}
+	return ""
+}
+
+var keysRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// schemaPath strips the modules prefixes and the list keys from the value name p
+func schemaPath(p string) string {
+	p = keysRegex.ReplaceAllString(p, "")
+	elems := strings.Split(strings.Trim(p, "/"), "/")
+	for i, e := range elems {
+		if idx := strings.Index(e, ":"); idx >= 0 {
+			elems[i] = e[idx+1:]
+		}
+	}
+	return "/" + strings.Join(elems, "/")
+}
@@ -96,6 +96,8 @@ func (vt *ValueTag) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (vt *ValueTag) WithActions(act map[string]map[string]interface{}) {}
 
+func (vt *ValueTag) WithProcessors(procs map[string]map[string]interface{}) {}
+
 func checkKeys(a map[string]string, b map[string]string) bool {
 	for k, v := range a {
 		if vv, ok := b[k]; ok {
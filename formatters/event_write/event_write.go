@@ -225,3 +225,5 @@ func (p *Write) write(e *formatters.EventMsg) error {
 func (p *Write) WithTargets(tcs map[string]*types.TargetConfig) {}
 
 func (p *Write) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *Write) WithProcessors(procs map[string]map[string]interface{}) {}
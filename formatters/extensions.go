@@ -0,0 +1,146 @@
+package formatters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	flattener "github.com/karimra/go-map-flattener"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+)
+
+// registered extensions message descriptors,
+// keyed by target name, subscription name and extension ID.
+var registeredExtensions = struct {
+	m  *sync.RWMutex
+	md map[extensionKey]*desc.MessageDescriptor
+}{
+	m:  new(sync.RWMutex),
+	md: make(map[extensionKey]*desc.MessageDescriptor),
+}
+
+type extensionKey struct {
+	target       string
+	subscription string
+	id           int32
+}
+
+// RegisterExtension registers the proto message descriptor md used to decode
+// the gNMI registered extensions with ID id received from target in the responses to subscription.
+// An empty subscription name registers the descriptor for all the target responses.
+func RegisterExtension(target, subscription string, id int32, md *desc.MessageDescriptor) {
+	registeredExtensions.m.Lock()
+	defer registeredExtensions.m.Unlock()
+	registeredExtensions.md[extensionKey{target: target, subscription: subscription, id: id}] = md
+}
+
+// UnregisterExtensions removes the proto message descriptors registered for target.
+func UnregisterExtensions(target string) {
+	registeredExtensions.m.Lock()
+	defer registeredExtensions.m.Unlock()
+	for k := range registeredExtensions.md {
+		if k.target == target {
+			delete(registeredExtensions.md, k)
+		}
+	}
+}
+
+// registeredExtension returns the descriptor registered for the subscription,
+// or the one registered for all the target responses.
+func registeredExtension(target, subscription string, id int32) *desc.MessageDescriptor {
+	registeredExtensions.m.RLock()
+	defer registeredExtensions.m.RUnlock()
+	if md, ok := registeredExtensions.md[extensionKey{target: target, subscription: subscription, id: id}]; ok {
+		return md
+	}
+	return registeredExtensions.md[extensionKey{target: target, id: id}]
+}
+
+// ExtensionsTags returns the gNMI extensions exts received from target
+// in the responses to subscription as a map of event tags.
+// Registered extensions are decoded using the proto message registered with their ID for that target and subscription,
+// if no message is registered or the decoding fails, the extension bytes are base64 encoded.
+func ExtensionsTags(target, subscription string, exts []*gnmi_ext.Extension) map[string]string {
+	if len(exts) == 0 {
+		return nil
+	}
+	tags := make(map[string]string)
+	for _, ext := range exts {
+		switch ext := ext.GetExt().(type) {
+		case *gnmi_ext.Extension_MasterArbitration:
+			ma := ext.MasterArbitration
+			if ma.GetRole() != nil {
+				tags["ext_master_arbitration_role"] = ma.GetRole().GetId()
+			}
+			if ma.GetElectionId() != nil {
+				tags["ext_master_arbitration_election_id"] = uint128String(ma.GetElectionId())
+			}
+		case *gnmi_ext.Extension_History:
+			switch h := ext.History.GetRequest().(type) {
+			case *gnmi_ext.History_SnapshotTime:
+				tags["ext_history_snapshot_time"] = time.Unix(0, h.SnapshotTime).UTC().Format(time.RFC3339Nano)
+			case *gnmi_ext.History_Range:
+				tags["ext_history_range_start"] = time.Unix(0, h.Range.GetStart()).UTC().Format(time.RFC3339Nano)
+				tags["ext_history_range_end"] = time.Unix(0, h.Range.GetEnd()).UTC().Format(time.RFC3339Nano)
+			}
+		case *gnmi_ext.Extension_RegisteredExt:
+			id := int32(ext.RegisteredExt.GetId())
+			prefix := fmt.Sprintf("ext_%d", id)
+			rtags, err := decodeRegisteredExtension(prefix, registeredExtension(target, subscription, id), ext.RegisteredExt.GetMsg())
+			if err != nil {
+				tags[prefix] = base64.StdEncoding.EncodeToString(ext.RegisteredExt.GetMsg())
+				continue
+			}
+			for k, v := range rtags {
+				tags[k] = v
+			}
+		}
+	}
+	return tags
+}
+
+func decodeRegisteredExtension(prefix string, md *desc.MessageDescriptor, b []byte) (map[string]string, error) {
+	if md == nil {
+		return nil, fmt.Errorf("no proto message registered")
+	}
+	m := dynamic.NewMessage(md)
+	err := m.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	err = json.Unmarshal(jsonData, &value)
+	if err != nil {
+		return nil, err
+	}
+	f := flattener.NewFlattener()
+	f.SetPrefix(prefix)
+	values, err := f.Flatten(value)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		tags[k] = fmt.Sprint(v)
+	}
+	return tags, nil
+}
+
+func uint128String(u *gnmi_ext.Uint128) string {
+	if u.GetHigh() == 0 {
+		return fmt.Sprintf("%d", u.GetLow())
+	}
+	n := new(big.Int).SetUint64(u.GetHigh())
+	n.Lsh(n, 64)
+	n.Or(n, new(big.Int).SetUint64(u.GetLow()))
+	return n.String()
+}
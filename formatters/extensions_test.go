@@ -0,0 +1,121 @@
+package formatters
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+)
+
+const testExtensionProto = `
+syntax = "proto3";
+package test;
+message CommitInfo {
+  string commit_id = 1;
+  uint64 sequence = 2;
+}
+`
+
+func TestExtensionsTags(t *testing.T) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"commit.proto": testExtensionProto}),
+	}
+	fds, err := p.ParseFiles("commit.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fds[0].FindMessage("test.CommitInfo")
+	RegisterExtension("t1", "sub1", 1001, md)
+	defer UnregisterExtensions("t1")
+
+	m := dynamic.NewMessage(md)
+	m.SetFieldByName("commit_id", "abc")
+	m.SetFieldByName("sequence", uint64(42))
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+		Extension: []*gnmi_ext.Extension{
+			{
+				Ext: &gnmi_ext.Extension_MasterArbitration{
+					MasterArbitration: &gnmi_ext.MasterArbitration{
+						Role:       &gnmi_ext.Role{Id: "role1"},
+						ElectionId: &gnmi_ext.Uint128{High: 1, Low: 2},
+					},
+				},
+			},
+			{
+				Ext: &gnmi_ext.Extension_History{
+					History: &gnmi_ext.History{
+						Request: &gnmi_ext.History_SnapshotTime{SnapshotTime: 0},
+					},
+				},
+			},
+			{
+				Ext: &gnmi_ext.Extension_RegisteredExt{
+					RegisteredExt: &gnmi_ext.RegisteredExtension{Id: 1001, Msg: b},
+				},
+			},
+			{
+				Ext: &gnmi_ext.Extension_RegisteredExt{
+					RegisteredExt: &gnmi_ext.RegisteredExtension{Id: 1002, Msg: []byte("raw")},
+				},
+			},
+		},
+	}
+	evs, err := ResponseToEventMsgs("sub1", rsp, map[string]string{"source": "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(evs))
+	}
+	expected := map[string]string{
+		"ext_master_arbitration_role":        "role1",
+		"ext_master_arbitration_election_id": "18446744073709551618",
+		"ext_history_snapshot_time":          "1970-01-01T00:00:00Z",
+		"ext_1001/commitId":                  "abc",
+		"ext_1001/sequence":                  "42",
+		"ext_1002":                           base64.StdEncoding.EncodeToString([]byte("raw")),
+	}
+	for k, v := range expected {
+		if evs[0].Tags[k] != v {
+			t.Errorf("tag %q: expected %q, got %q", k, v, evs[0].Tags[k])
+		}
+	}
+	// the descriptor is not used for another target or subscription
+	for _, src := range []struct{ target, sub string }{{"t2", "sub1"}, {"t1", "sub2"}} {
+		evs, err = ResponseToEventMsgs(src.sub, rsp, map[string]string{"source": src.target})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := evs[0].Tags["ext_1001"]; v != base64.StdEncoding.EncodeToString(b) {
+			t.Errorf("target %q subscription %q: expected the extension bytes, got %q", src.target, src.sub, v)
+		}
+	}
+	// a descriptor registered for all the target responses
+	RegisterExtension("t2", "", 1001, md)
+	defer UnregisterExtensions("t2")
+	evs, err = ResponseToEventMsgs("sub2", rsp, map[string]string{"source": "t2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := evs[0].Tags["ext_1001/commitId"]; v != "abc" {
+		t.Errorf("expected tag %q value %q, got %q", "ext_1001/commitId", "abc", v)
+	}
+}
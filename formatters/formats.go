@@ -100,6 +100,18 @@ func (o *MarshalOptions) Marshal(msg proto.Message, meta map[string]string, eps
 	}
 }
 
+// MarshalEvents marshals the events emitted asynchronously by the event processors,
+// only the event format is supported.
+func (o *MarshalOptions) MarshalEvents(evs []*EventMsg) ([]byte, error) {
+	if o.Format != "event" {
+		return nil, fmt.Errorf("format %q not supported for emitted events", o.Format)
+	}
+	if o.Multiline {
+		return json.MarshalIndent(evs, "", o.Indent)
+	}
+	return json.Marshal(evs)
+}
+
 func (o *MarshalOptions) OverrideTimestamp(msg proto.Message) proto.Message {
 	if o.OverrideTS {
 		ts := time.Now().UnixNano()
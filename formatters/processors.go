@@ -1,6 +1,7 @@
 package formatters
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -30,6 +31,15 @@ var EventProcessorTypes = []string{
 	"event-group-by",
 	"event-data-convert",
 	"event-value-tag",
+	"event-rate",
+	"event-aggregate",
+	"event-starlark",
+	"event-enrich",
+	"event-combine",
+	"event-alarm",
+	"event-dedup",
+	"event-join",
+	"event-units",
 }
 
 type Initializer func() EventProcessor
@@ -46,6 +56,62 @@ type EventProcessor interface {
 	WithTargets(map[string]*types.TargetConfig)
 	WithLogger(l *log.Logger)
 	WithActions(act map[string]map[string]interface{})
+	WithProcessors(procs map[string]map[string]interface{})
+}
+
+// EventEmitter is implemented by the event processors that emit events asynchronously,
+// i.e not as the result of a call to Apply, e.g at the end of a time window.
+type EventEmitter interface {
+	// StartEmitting starts the processor emitting logic,
+	// the emitted events are passed to fn until ctx is done.
+	StartEmitting(ctx context.Context, fn func(...*EventMsg))
+}
+
+// StartEmitters starts the processors in eps implementing EventEmitter.
+// The events emitted by a processor go through the processors following it in eps,
+// before being passed to fn.
+func StartEmitters(ctx context.Context, eps []EventProcessor, fn func(...*EventMsg)) {
+	for i, ep := range eps {
+		em, ok := ep.(EventEmitter)
+		if !ok {
+			continue
+		}
+		next := eps[i+1:]
+		em.StartEmitting(ctx, func(es ...*EventMsg) {
+			for _, p := range next {
+				es = p.Apply(es...)
+			}
+			if len(es) == 0 {
+				return
+			}
+			fn(es...)
+		})
+	}
+}
+
+// EventProcessorCloser is implemented by the event processors running goroutines
+// or holding resources, e.g a file watcher, that must be released once the processor is not used anymore.
+type EventProcessorCloser interface {
+	Close() error
+}
+
+// CloseProcessors closes the processors in eps implementing EventProcessorCloser.
+func CloseProcessors(eps []EventProcessor) {
+	for _, ep := range eps {
+		if c, ok := ep.(EventProcessorCloser); ok {
+			c.Close()
+		}
+	}
+}
+
+// HasEmitters returns true if one of the processors in eps implements EventEmitter.
+func HasEmitters(eps []EventProcessor) bool {
+	for _, ep := range eps {
+		if _, ok := ep.(EventEmitter); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func DecodeConfig(src, dst interface{}) error {
@@ -79,6 +145,12 @@ func WithActions(acts map[string]map[string]interface{}) Option {
 	}
 }
 
+func WithProcessors(procs map[string]map[string]interface{}) Option {
+	return func(p EventProcessor) {
+		p.WithProcessors(procs)
+	}
+}
+
 func CheckCondition(code *gojq.Code, e *EventMsg) (bool, error) {
 	var res interface{}
 	if code != nil {
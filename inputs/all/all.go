@@ -2,6 +2,7 @@ package all
 
 import (
 	_ "github.com/karimra/gnmic/inputs/kafka_input"
+	_ "github.com/karimra/gnmic/inputs/mqtt_input"
 	_ "github.com/karimra/gnmic/inputs/nats_input"
 	_ "github.com/karimra/gnmic/inputs/stan_input"
 )
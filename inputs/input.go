@@ -23,6 +23,7 @@ var InputTypes = []string{
 	"nats",
 	"stan",
 	"kafka",
+	"mqtt",
 }
 
 var Inputs = map[string]Initializer{}
@@ -202,6 +202,7 @@ START:
 func (k *KafkaInput) Close() error {
 	k.cfn()
 	k.wg.Wait()
+	formatters.CloseProcessors(k.evps)
 	return nil
 }
 
@@ -247,7 +248,7 @@ func (k *KafkaInput) SetEventProcessors(ps map[string]map[string]interface{}, lo
 			}
 			if in, ok := formatters.EventProcessors[epType]; ok {
 				ep := in()
-				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs), formatters.WithProcessors(ps))
 				if err != nil {
 					k.logger.Printf("failed initializing event processor %q of type=%q: %v", epName, epType, err)
 					continue
@@ -0,0 +1,369 @@
+package mqtt_input
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/inputs"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	loggingPrefix         = "[mqtt_input] "
+	defaultAddress        = "localhost:1883"
+	defaultTopic          = "telemetry/#"
+	defaultFormat         = "event"
+	defaultNumWorkers     = 1
+	defaultBufferSize     = 100
+	defaultConnectTimeout = 10 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+	mqttConnectWait       = 2 * time.Second
+)
+
+func init() {
+	inputs.Register("mqtt", func() inputs.Input {
+		return &MqttInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// MqttInput //
+type MqttInput struct {
+	Cfg    *Config
+	cfn    context.CancelFunc
+	logger *log.Logger
+	client mqtt.Client
+	msgs   chan mqtt.Message
+
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name            string        `mapstructure:"name,omitempty"`
+	Address         string        `mapstructure:"address,omitempty"`
+	ClientID        string        `mapstructure:"client-id,omitempty"`
+	Username        string        `mapstructure:"username,omitempty"`
+	Password        string        `mapstructure:"password,omitempty"`
+	TLS             *tlsConfig    `mapstructure:"tls,omitempty"`
+	Topic           string        `mapstructure:"topic,omitempty"`
+	QoS             byte          `mapstructure:"qos,omitempty"`
+	CleanSession    bool          `mapstructure:"clean-session,omitempty"`
+	KeepAlive       time.Duration `mapstructure:"keep-alive,omitempty"`
+	ConnectTimeout  time.Duration `mapstructure:"connect-timeout,omitempty"`
+	ConnectTimeWait time.Duration `mapstructure:"connect-time-wait,omitempty"`
+	Format          string        `mapstructure:"format,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty"`
+	Outputs         []string      `mapstructure:"outputs,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty"`
+}
+
+type tlsConfig struct {
+	CAFile     string `mapstructure:"ca-file,omitempty"`
+	CertFile   string `mapstructure:"cert-file,omitempty"`
+	KeyFile    string `mapstructure:"key-file,omitempty"`
+	SkipVerify bool   `mapstructure:"skip-verify,omitempty"`
+}
+
+// Start //
+func (m *MqttInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, m.Cfg)
+	if err != nil {
+		return err
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	m.msgs = make(chan mqtt.Message, m.Cfg.BufferSize)
+	ctx, m.cfn = context.WithCancel(ctx)
+	m.client, err = m.createClient(ctx)
+	if err != nil {
+		m.cfn()
+		return err
+	}
+	m.logger.Printf("input starting with config: %+v", m.Cfg)
+	// the client keeps retrying to connect in the background,
+	// the subscription is (re)created in the OnConnect handler.
+	m.client.Connect()
+	m.wg.Add(m.Cfg.NumWorkers)
+	for i := 0; i < m.Cfg.NumWorkers; i++ {
+		go m.worker(ctx, i)
+	}
+	return nil
+}
+
+func (m *MqttInput) createClient(ctx context.Context) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		SetClientID(m.Cfg.ClientID).
+		SetCleanSession(m.Cfg.CleanSession).
+		SetKeepAlive(m.Cfg.KeepAlive).
+		SetConnectTimeout(m.Cfg.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.Cfg.ConnectTimeWait).
+		SetOnConnectHandler(func(c mqtt.Client) { m.subscribe(ctx, c) }).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to MQTT broker lost: %v", err)
+		})
+	scheme := "tcp"
+	if m.Cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			m.Cfg.TLS.CAFile,
+			m.Cfg.TLS.CertFile,
+			m.Cfg.TLS.KeyFile,
+			m.Cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+		scheme = "ssl"
+	}
+	address := m.Cfg.Address
+	if !strings.Contains(address, "://") {
+		address = scheme + "://" + address
+	}
+	opts.AddBroker(address)
+	if m.Cfg.Username != "" {
+		opts.SetUsername(m.Cfg.Username)
+		opts.SetPassword(m.Cfg.Password)
+	}
+	return mqtt.NewClient(opts), nil
+}
+
+// subscribe subscribes to the configured topic,
+// the received messages are dropped once ctx is done.
+func (m *MqttInput) subscribe(ctx context.Context, c mqtt.Client) {
+	m.logger.Printf("connected to MQTT broker %s, subscribing to topic %q", m.Cfg.Address, m.Cfg.Topic)
+	token := c.Subscribe(m.Cfg.Topic, m.Cfg.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+		select {
+		case <-ctx.Done():
+		case m.msgs <- msg:
+		}
+	})
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			m.logger.Printf("failed to subscribe to topic %q: %v", m.Cfg.Topic, err)
+		}
+	}()
+}
+
+func (m *MqttInput) worker(ctx context.Context, idx int) {
+	defer m.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	m.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.msgs:
+			if len(msg.Payload()) == 0 {
+				continue
+			}
+			if m.Cfg.Debug {
+				m.logger.Printf("%s received msg, topic=%s, len=%d, data=%s", workerLogPrefix, msg.Topic(), len(msg.Payload()), string(msg.Payload()))
+			}
+			m.handleMsg(ctx, msg.Topic(), msg.Payload())
+		}
+	}
+}
+
+func (m *MqttInput) handleMsg(ctx context.Context, topic string, payload []byte) {
+	switch m.Cfg.Format {
+	case "event":
+		evMsgs := make([]*formatters.EventMsg, 1)
+		err := json.Unmarshal(payload, &evMsgs)
+		if err != nil {
+			if m.Cfg.Debug {
+				m.logger.Printf("failed to unmarshal event msg: %v", err)
+			}
+			return
+		}
+		for _, p := range m.evps {
+			evMsgs = p.Apply(evMsgs...)
+		}
+		for _, o := range m.outputs {
+			for _, ev := range evMsgs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	case "proto":
+		protoMsg := new(gnmi.SubscribeResponse)
+		err := proto.Unmarshal(payload, protoMsg)
+		if err != nil {
+			if m.Cfg.Debug {
+				m.logger.Printf("failed to unmarshal proto msg: %v", err)
+			}
+			return
+		}
+		meta := topicMeta(m.Cfg.Topic, topic)
+		for _, o := range m.outputs {
+			o.Write(ctx, protoMsg, meta)
+		}
+	}
+}
+
+// topicMeta derives the subscription name and source from a topic
+// published by an mqtt output using a `subscription.target*` topic-format:
+// <topic>/<subscription-name>/<target>[/<path>].
+// The number of levels making up <topic> is taken from the subscribed topic filter,
+// i.e the levels preceding the first wildcard.
+func topicMeta(filter, topic string) outputs.Meta {
+	meta := outputs.Meta{}
+	rootLevels := 0
+	for _, l := range strings.Split(filter, "/") {
+		if l == "+" || l == "#" {
+			break
+		}
+		rootLevels++
+	}
+	levels := strings.Split(topic, "/")
+	if len(levels) < rootLevels+2 {
+		return meta
+	}
+	meta["subscription-name"] = levels[rootLevels]
+	meta["source"] = levels[rootLevels+1]
+	return meta
+}
+
+// Close //
+func (m *MqttInput) Close() error {
+	if m.cfn == nil {
+		return nil
+	}
+	m.cfn()
+	m.wg.Wait()
+	formatters.CloseProcessors(m.evps)
+	if m.client.IsConnected() {
+		m.client.Unsubscribe(m.Cfg.Topic).WaitTimeout(time.Second)
+		m.client.Disconnect(uint(time.Second / time.Millisecond))
+	}
+	return nil
+}
+
+// SetLogger //
+func (m *MqttInput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (m *MqttInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(m.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			m.outputs = append(m.outputs, o)
+		}
+		return
+	}
+	for _, name := range m.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			m.outputs = append(m.outputs, o)
+		}
+	}
+}
+
+func (m *MqttInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(m.Cfg.Name)
+	sb.WriteString("-mqtt-sub")
+	m.Cfg.Name = sb.String()
+}
+
+func (m *MqttInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig) {
+	for _, epName := range m.Cfg.EventProcessors {
+		if epCfg, ok := ps[epName]; ok {
+			epType := ""
+			for k := range epCfg {
+				epType = k
+				break
+			}
+			if in, ok := formatters.EventProcessors[epType]; ok {
+				ep := in()
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs), formatters.WithProcessors(ps))
+				if err != nil {
+					m.logger.Printf("failed initializing event processor %q of type=%q: %v", epName, epType, err)
+					continue
+				}
+				m.evps = append(m.evps, ep)
+				m.logger.Printf("added event processor %q of type=%q to mqtt input", epName, epType)
+			}
+		}
+	}
+}
+
+// helper functions
+
+func (m *MqttInput) setDefaults() error {
+	if m.Cfg.Format == "" {
+		m.Cfg.Format = defaultFormat
+	}
+	m.Cfg.Format = strings.ToLower(m.Cfg.Format)
+	if !(m.Cfg.Format == "event" || m.Cfg.Format == "proto") {
+		return fmt.Errorf("unsupported input format")
+	}
+	if m.Cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos value %d, expecting 0, 1 or 2", m.Cfg.QoS)
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if m.Cfg.ClientID == "" {
+		m.Cfg.ClientID = m.Cfg.Name
+	}
+	if m.Cfg.Topic == "" {
+		m.Cfg.Topic = defaultTopic
+	}
+	if m.Cfg.Address == "" {
+		m.Cfg.Address = defaultAddress
+	}
+	if m.Cfg.KeepAlive <= 0 {
+		m.Cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.Cfg.ConnectTimeout <= 0 {
+		m.Cfg.ConnectTimeout = defaultConnectTimeout
+	}
+	if m.Cfg.ConnectTimeWait <= 0 {
+		m.Cfg.ConnectTimeWait = mqttConnectWait
+	}
+	if m.Cfg.NumWorkers <= 0 {
+		m.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if m.Cfg.BufferSize <= 0 {
+		m.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
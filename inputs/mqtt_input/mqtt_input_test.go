@@ -0,0 +1,38 @@
+package mqtt_input
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/karimra/gnmic/outputs"
+)
+
+func TestTopicMeta(t *testing.T) {
+	tests := []struct {
+		filter   string
+		topic    string
+		expected outputs.Meta
+	}{
+		{
+			filter:   "telemetry/#",
+			topic:    "telemetry/sub1/router1/interfaces/interface",
+			expected: outputs.Meta{"subscription-name": "sub1", "source": "router1"},
+		},
+		{
+			filter:   "gnmic/telemetry/+/+",
+			topic:    "gnmic/telemetry/sub1/router1",
+			expected: outputs.Meta{"subscription-name": "sub1", "source": "router1"},
+		},
+		{
+			filter:   "telemetry",
+			topic:    "telemetry",
+			expected: outputs.Meta{},
+		},
+	}
+	for _, tt := range tests {
+		got := topicMeta(tt.filter, tt.topic)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("filter=%q topic=%q: expected %v, got %v", tt.filter, tt.topic, tt.expected, got)
+		}
+	}
+}
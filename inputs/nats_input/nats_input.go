@@ -193,6 +193,7 @@ START:
 func (n *NatsInput) Close() error {
 	n.cfn()
 	n.wg.Wait()
+	formatters.CloseProcessors(n.evps)
 	return nil
 }
 
@@ -240,7 +241,7 @@ func (n *NatsInput) SetEventProcessors(ps map[string]map[string]interface{}, log
 			}
 			if in, ok := formatters.EventProcessors[epType]; ok {
 				ep := in()
-				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs), formatters.WithProcessors(ps))
 				if err != nil {
 					n.logger.Printf("failed initializing event processor %q of type=%q: %v", epName, epType, err)
 					continue
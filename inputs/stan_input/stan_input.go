@@ -126,6 +126,7 @@ CRCONN:
 func (s *StanInput) Close() error {
 	s.cfn()
 	s.wg.Wait()
+	formatters.CloseProcessors(s.evps)
 	return nil
 }
 
@@ -171,7 +172,7 @@ func (s *StanInput) SetEventProcessors(ps map[string]map[string]interface{}, log
 			}
 			if in, ok := formatters.EventProcessors[epType]; ok {
 				ep := in()
-				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs), formatters.WithProcessors(ps))
 				if err != nil {
 					s.logger.Printf("failed initializing event processor %q of type=%q: %v", epName, epType, err)
 					continue
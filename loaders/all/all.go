@@ -2,7 +2,10 @@ package all
 
 import (
 	_ "github.com/karimra/gnmic/loaders/consul_loader"
+	_ "github.com/karimra/gnmic/loaders/dns_loader"
 	_ "github.com/karimra/gnmic/loaders/docker_loader"
 	_ "github.com/karimra/gnmic/loaders/file_loader"
 	_ "github.com/karimra/gnmic/loaders/http_loader"
+	_ "github.com/karimra/gnmic/loaders/k8s_loader"
+	_ "github.com/karimra/gnmic/loaders/netbox_loader"
 )
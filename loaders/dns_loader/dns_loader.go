@@ -0,0 +1,595 @@
+package dns_loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/actions"
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/miekg/dns"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	loggingPrefix      = "[dns_loader] "
+	loaderType         = "dns"
+	defaultInterval    = 30 * time.Second
+	defaultMinTTL      = 5 * time.Second
+	defaultTimeout     = 5 * time.Second
+	defaultRecordType  = "SRV"
+	defaultResolvConf  = "/etc/resolv.conf"
+	defaultDNSPort     = "53"
+	recordTypeSRV      = "SRV"
+	recordTypeA        = "A"
+	recordTypeAAAA     = "AAAA"
+	maxActionsDuration = 1 * time.Minute
+)
+
+func init() {
+	loaders.Register(loaderType, func() loaders.TargetLoader {
+		return &dnsLoader{
+			cfg:         new(cfg),
+			m:           new(sync.Mutex),
+			lastTargets: make(map[string]*types.TargetConfig),
+			logger:      log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type dnsLoader struct {
+	cfg    *cfg
+	client *dns.Client
+
+	m              *sync.Mutex
+	lastTargets    map[string]*types.TargetConfig
+	targetConfigFn func(*types.TargetConfig) error
+	logger         *log.Logger
+	//
+	vars          map[string]interface{}
+	actionsConfig map[string]map[string]interface{}
+	addActions    []actions.Action
+	delActions    []actions.Action
+	numActions    int
+}
+
+type cfg struct {
+	// DNS servers addresses, defaults to the nameservers in /etc/resolv.conf
+	Servers []string `json:"servers,omitempty" mapstructure:"servers,omitempty"`
+	// records to resolve
+	Records []*record `json:"records,omitempty" mapstructure:"records,omitempty"`
+	// interval between the resolutions of a record when its answers
+	// do not have a TTL, or when its resolution fails
+	Interval time.Duration `json:"interval,omitempty" mapstructure:"interval,omitempty"`
+	// minimum time between two resolutions of the same record
+	MinTTL time.Duration `json:"min-ttl,omitempty" mapstructure:"min-ttl,omitempty"`
+	// maximum time between two resolutions of the same record, 0 means no limit
+	MaxTTL time.Duration `json:"max-ttl,omitempty" mapstructure:"max-ttl,omitempty"`
+	// DNS queries timeout
+	Timeout time.Duration `json:"timeout,omitempty" mapstructure:"timeout,omitempty"`
+	// target config applied to all the discovered targets
+	Config map[string]interface{} `json:"config,omitempty" mapstructure:"config,omitempty"`
+	// enable debug mode for more logging messages
+	Debug bool `json:"debug,omitempty" mapstructure:"debug,omitempty"`
+	// if true, registers dnsLoader prometheus metrics with the provided
+	// prometheus registry
+	EnableMetrics bool `json:"enable-metrics,omitempty" mapstructure:"enable-metrics,omitempty"`
+	// variables definitions to be passed to the actions
+	Vars map[string]interface{}
+	// variable file, values in this file will be overwritten by
+	// the ones defined in Vars
+	VarsFile string `mapstructure:"vars-file,omitempty"`
+	// list of Actions to run on new target discovery
+	OnAdd []string `json:"on-add,omitempty" mapstructure:"on-add,omitempty"`
+	// list of Actions to run on target removal
+	OnDelete []string `json:"on-delete,omitempty" mapstructure:"on-delete,omitempty"`
+}
+
+type record struct {
+	// record name, e.g: _gnmi._tcp.pop1.example.net
+	Name string `json:"name,omitempty" mapstructure:"name,omitempty"`
+	// record type: SRV, A or AAAA
+	Type string `json:"type,omitempty" mapstructure:"type,omitempty"`
+	// gNMI port of the A and AAAA records targets
+	Port string `json:"port,omitempty" mapstructure:"port,omitempty"`
+	// target config applied to the targets discovered with this record,
+	// it overrides the global config fields
+	Config map[string]interface{} `json:"config,omitempty" mapstructure:"config,omitempty"`
+
+	// targets built from the last successful resolution
+	targets map[string]*types.TargetConfig
+	// time of the next resolution
+	next time.Time
+}
+
+func (d *dnsLoader) Init(ctx context.Context, cfg map[string]interface{}, logger *log.Logger, opts ...loaders.Option) error {
+	err := loaders.DecodeConfig(cfg, d.cfg)
+	if err != nil {
+		return err
+	}
+	err = d.setDefaults()
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if logger != nil {
+		d.logger.SetOutput(logger.Writer())
+		d.logger.SetFlags(logger.Flags())
+	}
+	d.client = &dns.Client{Timeout: d.cfg.Timeout}
+	err = d.readVars(ctx)
+	if err != nil {
+		return err
+	}
+	for _, actName := range d.cfg.OnAdd {
+		if cfg, ok := d.actionsConfig[actName]; ok {
+			a, err := d.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			d.addActions = append(d.addActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	for _, actName := range d.cfg.OnDelete {
+		if cfg, ok := d.actionsConfig[actName]; ok {
+			a, err := d.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			d.delActions = append(d.delActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	d.numActions = len(d.addActions) + len(d.delActions)
+	d.logger.Printf("initialized loader type %q: %s", loaderType, d)
+	return nil
+}
+
+func (d *dnsLoader) setDefaults() error {
+	if len(d.cfg.Records) == 0 {
+		return errors.New("missing records")
+	}
+	for i, r := range d.cfg.Records {
+		if r.Name == "" {
+			return fmt.Errorf("record #%d: missing name", i)
+		}
+		r.Name = dns.Fqdn(r.Name)
+		if r.Type == "" {
+			r.Type = defaultRecordType
+		}
+		r.Type = strings.ToUpper(r.Type)
+		switch r.Type {
+		case recordTypeSRV, recordTypeA, recordTypeAAAA:
+		default:
+			return fmt.Errorf("record %q: unsupported type %q", r.Name, r.Type)
+		}
+	}
+	if len(d.cfg.Servers) == 0 {
+		cc, err := dns.ClientConfigFromFile(defaultResolvConf)
+		if err != nil {
+			return fmt.Errorf("no servers configured and failed to read %s: %v", defaultResolvConf, err)
+		}
+		for _, s := range cc.Servers {
+			d.cfg.Servers = append(d.cfg.Servers, net.JoinHostPort(s, cc.Port))
+		}
+	}
+	for i, s := range d.cfg.Servers {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			d.cfg.Servers[i] = net.JoinHostPort(s, defaultDNSPort)
+		}
+	}
+	if d.cfg.Interval <= 0 {
+		d.cfg.Interval = defaultInterval
+	}
+	if d.cfg.MinTTL <= 0 {
+		d.cfg.MinTTL = defaultMinTTL
+	}
+	if d.cfg.MaxTTL > 0 && d.cfg.MaxTTL < d.cfg.MinTTL {
+		d.cfg.MaxTTL = d.cfg.MinTTL
+	}
+	if d.cfg.Timeout <= 0 {
+		d.cfg.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+func (d *dnsLoader) Start(ctx context.Context) chan *loaders.TargetOperation {
+	opChan := make(chan *loaders.TargetOperation)
+	go func() {
+		defer close(opChan)
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				d.logger.Printf("%q context done: %v", loaderType, ctx.Err())
+				return
+			case <-timer.C:
+				next := d.update(ctx, opChan)
+				timer.Reset(time.Until(next))
+			}
+		}
+	}()
+	return opChan
+}
+
+func (d *dnsLoader) RunOnce(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	d.logger.Printf("querying %q targets", loaderType)
+	readTargets := make(map[string]*types.TargetConfig)
+	for _, r := range d.cfg.Records {
+		tcs, _, err := d.resolve(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		for n, tc := range tcs {
+			readTargets[n] = tc
+		}
+	}
+	if d.cfg.Debug {
+		d.logger.Printf("dns loader discovered %d target(s)", len(readTargets))
+	}
+	return readTargets, nil
+}
+
+func (d *dnsLoader) String() string {
+	b, err := json.Marshal(d.cfg)
+	if err != nil {
+		return fmt.Sprintf("%+v", d.cfg)
+	}
+	return string(b)
+}
+
+// update resolves the records due for resolution,
+// sends the resulting target operation to opChan and
+// returns the time of the next resolution.
+func (d *dnsLoader) update(ctx context.Context, opChan chan *loaders.TargetOperation) time.Time {
+	now := time.Now()
+	for _, r := range d.cfg.Records {
+		if r.next.After(now) {
+			continue
+		}
+		tcs, ttl, err := d.resolve(ctx, r)
+		if err != nil {
+			d.logger.Printf("failed to resolve record %q: %v", r.Name, err)
+			r.next = now.Add(d.cfg.Interval)
+			continue
+		}
+		r.targets = tcs
+		r.next = now.Add(d.recordTTL(ttl))
+		if d.cfg.Debug {
+			d.logger.Printf("record %q resolved to %d target(s), next resolution at %s", r.Name, len(tcs), r.next)
+		}
+	}
+	readTargets := make(map[string]*types.TargetConfig)
+	next := time.Time{}
+	for _, r := range d.cfg.Records {
+		for n, tc := range r.targets {
+			readTargets[n] = tc
+		}
+		if next.IsZero() || r.next.Before(next) {
+			next = r.next
+		}
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		d.updateTargets(ctx, readTargets, opChan)
+	}
+	return next
+}
+
+// recordTTL returns the time to wait before resolving a record again,
+// given the TTL of its answers.
+func (d *dnsLoader) recordTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = d.cfg.Interval
+	}
+	if ttl < d.cfg.MinTTL {
+		ttl = d.cfg.MinTTL
+	}
+	if d.cfg.MaxTTL > 0 && ttl > d.cfg.MaxTTL {
+		ttl = d.cfg.MaxTTL
+	}
+	return ttl
+}
+
+// resolve queries the record and builds a target config per answer.
+// It returns the built target configs and the lowest TTL of the answers.
+func (d *dnsLoader) resolve(ctx context.Context, r *record) (map[string]*types.TargetConfig, time.Duration, error) {
+	dnsLoaderQueriesTotal.WithLabelValues(loaderType, r.Type).Add(1)
+	rsp, err := d.query(ctx, r.Name, dns.StringToType[r.Type])
+	if err != nil {
+		dnsLoaderFailedQueries.WithLabelValues(loaderType, r.Type, fmt.Sprintf("%v", err)).Add(1)
+		return nil, 0, err
+	}
+	tcs := make(map[string]*types.TargetConfig)
+	var ttl uint32
+	setTTL := func(h *dns.RR_Header) {
+		if ttl == 0 || h.Ttl < ttl {
+			ttl = h.Ttl
+		}
+	}
+	for _, rr := range rsp.Answer {
+		var name, address string
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			if r.Type != recordTypeSRV {
+				continue
+			}
+			setTTL(rr.Header())
+			// the same host can expose several gNMI servers on different ports
+			address = net.JoinHostPort(strings.TrimSuffix(rr.Target, "."), strconv.Itoa(int(rr.Port)))
+			name = address
+		case *dns.A:
+			if r.Type != recordTypeA {
+				continue
+			}
+			setTTL(rr.Header())
+			name = rr.A.String()
+			address = name
+		case *dns.AAAA:
+			if r.Type != recordTypeAAAA {
+				continue
+			}
+			setTTL(rr.Header())
+			name = rr.AAAA.String()
+			address = name
+		default:
+			continue
+		}
+		if r.Type != recordTypeSRV && r.Port != "" {
+			address = net.JoinHostPort(address, r.Port)
+		}
+		tc, err := d.buildTarget(r, name, address)
+		if err != nil {
+			return nil, 0, err
+		}
+		tcs[tc.Name] = tc
+	}
+	return tcs, time.Duration(ttl) * time.Second, nil
+}
+
+// query sends the DNS query to the configured servers in order,
+// until one of them answers.
+func (d *dnsLoader) query(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = true
+	var err error
+	for _, server := range d.cfg.Servers {
+		var rsp *dns.Msg
+		rsp, _, err = d.client.ExchangeContext(ctx, m, server)
+		if err != nil {
+			continue
+		}
+		if rsp.Truncated {
+			tcpClient := &dns.Client{Net: "tcp", Timeout: d.cfg.Timeout}
+			rsp, _, err = tcpClient.ExchangeContext(ctx, m, server)
+			if err != nil {
+				continue
+			}
+		}
+		switch rsp.Rcode {
+		case dns.RcodeSuccess, dns.RcodeNameError:
+			// a non existing name has no targets
+			return rsp, nil
+		default:
+			err = fmt.Errorf("server %s: %s", server, dns.RcodeToString[rsp.Rcode])
+		}
+	}
+	return nil, err
+}
+
+func (d *dnsLoader) buildTarget(r *record, name, address string) (*types.TargetConfig, error) {
+	tc := new(types.TargetConfig)
+	if d.cfg.Config != nil {
+		err := mapstructure.Decode(d.cfg.Config, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode config map: %v", err)
+		}
+	}
+	if r.Config != nil {
+		err := mapstructure.Decode(r.Config, tc)
+		if err != nil {
+			return nil, fmt.Errorf("record %q: failed to decode config map: %v", r.Name, err)
+		}
+	}
+	tc.Name = name
+	tc.Address = address
+	return tc, nil
+}
+
+func (d *dnsLoader) updateTargets(ctx context.Context, tcs map[string]*types.TargetConfig, opChan chan *loaders.TargetOperation) {
+	var err error
+	for _, tc := range tcs {
+		err = d.targetConfigFn(tc)
+		if err != nil {
+			d.logger.Printf("failed running target config fn on target %q", tc.Name)
+		}
+	}
+	d.m.Lock()
+	targetOp := loaders.Diff(d.lastTargets, tcs)
+	d.m.Unlock()
+	targetOp, err = d.runActions(ctx, tcs, targetOp)
+	if err != nil {
+		d.logger.Printf("failed to run actions: %v", err)
+		return
+	}
+	numAdds := len(targetOp.Add)
+	numDels := len(targetOp.Del)
+	defer func() {
+		dnsLoaderLoadedTargets.WithLabelValues(loaderType).Set(float64(numAdds))
+		dnsLoaderDeletedTargets.WithLabelValues(loaderType).Set(float64(numDels))
+	}()
+	if numAdds+numDels == 0 {
+		return
+	}
+	d.m.Lock()
+	for _, add := range targetOp.Add {
+		d.lastTargets[add.Name] = add
+	}
+	for _, del := range targetOp.Del {
+		delete(d.lastTargets, del)
+	}
+	d.m.Unlock()
+	if d.cfg.Debug {
+		b, err := json.MarshalIndent(targetOp, "", "  ")
+		if err != nil {
+			d.logger.Printf("discovery diff result: %v", targetOp)
+		} else {
+			d.logger.Printf("discovery diff result:\n%s", string(b))
+		}
+	}
+	select {
+	case <-ctx.Done():
+	case opChan <- targetOp:
+	}
+}
+
+func (d *dnsLoader) readVars(ctx context.Context) error {
+	if d.cfg.VarsFile == "" {
+		d.vars = d.cfg.Vars
+		return nil
+	}
+	b, err := utils.ReadFile(ctx, d.cfg.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	d.vars = utils.MergeMaps(v, d.cfg.Vars)
+	return nil
+}
+
+func (d *dnsLoader) initializeAction(cfg map[string]interface{}) (actions.Action, error) {
+	if len(cfg) == 0 {
+		return nil, errors.New("missing action definition")
+	}
+	if actType, ok := cfg["type"]; ok {
+		switch actType := actType.(type) {
+		case string:
+			if in, ok := actions.Actions[actType]; ok {
+				act := in()
+				err := act.Init(cfg, actions.WithLogger(d.logger), actions.WithTargets(nil))
+				if err != nil {
+					return nil, err
+				}
+
+				return act, nil
+			}
+			return nil, fmt.Errorf("unknown action type %q", actType)
+		default:
+			return nil, fmt.Errorf("unexpected action field type %T", actType)
+		}
+	}
+	return nil, errors.New("missing type field under action")
+}
+
+func (d *dnsLoader) runActions(ctx context.Context, tcs map[string]*types.TargetConfig, targetOp *loaders.TargetOperation) (*loaders.TargetOperation, error) {
+	if d.numActions == 0 {
+		return targetOp, nil
+	}
+	opChan := make(chan *loaders.TargetOperation)
+	// some actions are defined,
+	doneCh := make(chan struct{})
+	result := &loaders.TargetOperation{
+		Add: make([]*types.TargetConfig, 0, len(targetOp.Add)),
+		Del: make([]string, 0, len(targetOp.Del)),
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxActionsDuration)
+	defer cancel()
+	// start gathering goroutine
+	go func() {
+		for op := range opChan {
+			result.Add = append(result.Add, op.Add...)
+			result.Del = append(result.Del, op.Del...)
+		}
+		close(doneCh)
+	}()
+	// create waitGroup and add the number of target operations to it
+	wg := new(sync.WaitGroup)
+	wg.Add(len(targetOp.Add) + len(targetOp.Del))
+	// run OnAdd actions
+	for _, tAdd := range targetOp.Add {
+		go func(tc *types.TargetConfig) {
+			defer wg.Done()
+			err := d.runOnAddActions(ctx, tc.Name, tcs)
+			if err != nil {
+				d.logger.Printf("failed running OnAdd actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Add: []*types.TargetConfig{tc}}
+		}(tAdd)
+	}
+	// run OnDelete actions
+	for _, tDel := range targetOp.Del {
+		go func(name string) {
+			defer wg.Done()
+			err := d.runOnDeleteActions(ctx, name)
+			if err != nil {
+				d.logger.Printf("failed running OnDelete actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Del: []string{name}}
+		}(tDel)
+	}
+	wg.Wait()
+	close(opChan)
+	<-doneCh //wait for gathering goroutine to finish
+	return result, nil
+}
+
+func (d *dnsLoader) runOnAddActions(ctx context.Context, tName string, tcs map[string]*types.TargetConfig) error {
+	aCtx := &actions.Context{
+		Input:   tName,
+		Env:     make(map[string]interface{}),
+		Vars:    d.vars,
+		Targets: tcs,
+	}
+	for _, act := range d.addActions {
+		d.logger.Printf("running action %q for target %q", act.NName(), tName)
+		res, err := act.Run(ctx, aCtx)
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+
+		aCtx.Env[act.NName()] = utils.Convert(res)
+		if d.cfg.Debug {
+			d.logger.Printf("action %q, target %q result: %+v", act.NName(), tName, res)
+			b, _ := json.MarshalIndent(aCtx, "", "  ")
+			d.logger.Printf("action %q context:\n%s", act.NName(), string(b))
+		}
+	}
+	return nil
+}
+
+func (d *dnsLoader) runOnDeleteActions(ctx context.Context, tName string) error {
+	env := make(map[string]interface{})
+	for _, act := range d.delActions {
+		res, err := act.Run(ctx, &actions.Context{Input: tName, Env: env, Vars: d.vars})
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+		env[act.NName()] = res
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package dns_loader
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var dnsLoaderLoadedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "dns_loader",
+	Name:      "number_of_loaded_targets",
+	Help:      "Number of new targets successfully loaded",
+}, []string{"loader_type"})
+
+var dnsLoaderDeletedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "dns_loader",
+	Name:      "number_of_deleted_targets",
+	Help:      "Number of targets successfully deleted",
+}, []string{"loader_type"})
+
+var dnsLoaderFailedQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "dns_loader",
+	Name:      "number_of_failed_dns_queries",
+	Help:      "Number of times a DNS query failed",
+}, []string{"loader_type", "record_type", "error"})
+
+var dnsLoaderQueriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "dns_loader",
+	Name:      "number_of_dns_queries_total",
+	Help:      "Number of times the loader sent a DNS query",
+}, []string{"loader_type", "record_type"})
+
+func initMetrics() {
+	dnsLoaderLoadedTargets.WithLabelValues(loaderType).Set(0)
+	dnsLoaderDeletedTargets.WithLabelValues(loaderType).Set(0)
+	dnsLoaderFailedQueries.WithLabelValues(loaderType, "", "").Add(0)
+	dnsLoaderQueriesTotal.WithLabelValues(loaderType, "").Add(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	if reg == nil {
+		return nil
+	}
+	initMetrics()
+	var err error
+	if err = reg.Register(dnsLoaderLoadedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(dnsLoaderDeletedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(dnsLoaderFailedQueries); err != nil {
+		return err
+	}
+	if err = reg.Register(dnsLoaderQueriesTotal); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package dns_loader
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+	"github.com/miekg/dns"
+)
+
+// testServer is a DNS server answering with the records in rrs
+type testServer struct {
+	m   *sync.Mutex
+	rrs []dns.RR
+	srv *dns.Server
+}
+
+func newTestServer(t *testing.T, rrs ...string) *testServer {
+	ts := &testServer{m: new(sync.Mutex)}
+	ts.setRecords(t, rrs...)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	ts.srv = &dns.Server{
+		PacketConn:        pc,
+		Handler:           dns.HandlerFunc(ts.serveDNS),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go ts.srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { ts.srv.Shutdown() })
+	return ts
+}
+
+func (ts *testServer) setRecords(t *testing.T, rrs ...string) {
+	ts.m.Lock()
+	defer ts.m.Unlock()
+	ts.rrs = make([]dns.RR, 0, len(rrs))
+	for _, s := range rrs {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.rrs = append(ts.rrs, rr)
+	}
+}
+
+func (ts *testServer) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ts.m.Lock()
+	defer ts.m.Unlock()
+	rsp := new(dns.Msg)
+	rsp.SetReply(req)
+	q := req.Question[0]
+	for _, rr := range ts.rrs {
+		if rr.Header().Name == q.Name && rr.Header().Rrtype == q.Qtype {
+			rsp.Answer = append(rsp.Answer, rr)
+		}
+	}
+	if len(rsp.Answer) == 0 {
+		rsp.Rcode = dns.RcodeNameError
+	}
+	w.WriteMsg(rsp)
+}
+
+func (ts *testServer) addr() string {
+	return ts.srv.PacketConn.LocalAddr().String()
+}
+
+func TestRunOnce(t *testing.T) {
+	ts := newTestServer(t,
+		"_gnmi._tcp.pop1.example.net. 60 IN SRV 10 10 57400 r1.pop1.example.net.",
+		"_gnmi._tcp.pop1.example.net. 30 IN SRV 10 10 6030 r2.pop1.example.net.",
+		"proxy.example.net. 300 IN A 192.0.2.1",
+		"proxy.example.net. 300 IN AAAA 2001:db8::1",
+	)
+	l := loaders.Loaders[loaderType]()
+	err := l.Init(context.TODO(), map[string]interface{}{
+		"servers": []string{ts.addr()},
+		"config": map[string]interface{}{
+			"outputs": []string{"prom"},
+		},
+		"records": []interface{}{
+			map[string]interface{}{
+				"name": "_gnmi._tcp.pop1.example.net",
+				"config": map[string]interface{}{
+					"subscriptions": []string{"sub1"},
+				},
+			},
+			map[string]interface{}{
+				"name": "proxy.example.net",
+				"type": "a",
+				"port": "57401",
+			},
+			map[string]interface{}{
+				"name": "missing.example.net",
+				"type": "AAAA",
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs, err := l.RunOnce(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*types.TargetConfig{
+		"r1.pop1.example.net:57400": {
+			Name:          "r1.pop1.example.net:57400",
+			Address:       "r1.pop1.example.net:57400",
+			Subscriptions: []string{"sub1"},
+			Outputs:       []string{"prom"},
+		},
+		"r2.pop1.example.net:6030": {
+			Name:          "r2.pop1.example.net:6030",
+			Address:       "r2.pop1.example.net:6030",
+			Subscriptions: []string{"sub1"},
+			Outputs:       []string{"prom"},
+		},
+		"192.0.2.1": {
+			Name:    "192.0.2.1",
+			Address: "192.0.2.1:57401",
+			Outputs: []string{"prom"},
+		},
+	}
+	if !reflect.DeepEqual(tcs, expected) {
+		t.Errorf("expected %s, got %s", expected, tcs)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ts := newTestServer(t,
+		"_gnmi._tcp.pop1.example.net. 60 IN SRV 10 10 57400 r1.pop1.example.net.",
+		"_gnmi._tcp.pop1.example.net. 30 IN SRV 10 10 57400 r2.pop1.example.net.",
+	)
+	l := loaders.Loaders[loaderType]().(*dnsLoader)
+	l.WithTargetsDefaults(func(tc *types.TargetConfig) error { return nil })
+	err := l.Init(context.TODO(), map[string]interface{}{
+		"servers": []string{ts.addr()},
+		"min-ttl": "10s",
+		"records": []interface{}{
+			map[string]interface{}{"name": "_gnmi._tcp.pop1.example.net"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opChan := make(chan *loaders.TargetOperation, 1)
+	start := time.Now()
+	next := l.update(context.TODO(), opChan)
+	// the record is resolved again when its lowest TTL expires
+	if d := next.Sub(start); d < 30*time.Second || d > 31*time.Second {
+		t.Errorf("unexpected next resolution in %s", d)
+	}
+	op := <-opChan
+	names := make([]string, 0, len(op.Add))
+	for _, tc := range op.Add {
+		names = append(names, tc.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"r1.pop1.example.net:57400", "r2.pop1.example.net:57400"}) || len(op.Del) != 0 {
+		t.Fatalf("unexpected target operation: %+v", op)
+	}
+	// the record is not resolved again before its TTL expires
+	ts.setRecords(t, "_gnmi._tcp.pop1.example.net. 1 IN SRV 10 10 57400 r1.pop1.example.net.")
+	l.update(context.TODO(), opChan)
+	select {
+	case op := <-opChan:
+		t.Fatalf("unexpected target operation before TTL expiry: %+v", op)
+	default:
+	}
+	// force the TTL expiry
+	l.cfg.Records[0].next = time.Time{}
+	start = time.Now()
+	next = l.update(context.TODO(), opChan)
+	// the TTL is lower than min-ttl
+	if d := next.Sub(start); d < 10*time.Second || d > 11*time.Second {
+		t.Errorf("unexpected next resolution in %s", d)
+	}
+	op = <-opChan
+	if !reflect.DeepEqual(op.Del, []string{"r2.pop1.example.net:57400"}) || len(op.Add) != 0 {
+		t.Fatalf("unexpected target operation: %+v", op)
+	}
+}
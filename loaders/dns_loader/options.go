@@ -0,0 +1,23 @@
+package dns_loader
+
+import (
+	"github.com/karimra/gnmic/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (d *dnsLoader) RegisterMetrics(reg *prometheus.Registry) {
+	if !d.cfg.EnableMetrics {
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		d.logger.Printf("failed to register metrics: %v", err)
+	}
+}
+
+func (d *dnsLoader) WithActions(acts map[string]map[string]interface{}) {
+	d.actionsConfig = acts
+}
+
+func (d *dnsLoader) WithTargetsDefaults(fn func(tc *types.TargetConfig) error) {
+	d.targetConfigFn = fn
+}
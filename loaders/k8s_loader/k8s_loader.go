@@ -0,0 +1,637 @@
+package k8s_loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/karimra/gnmic/actions"
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	loggingPrefix           = "[k8s_loader] "
+	loaderType              = "k8s"
+	defaultNamespace        = "default"
+	defaultResource         = "pods"
+	defaultAnnotationPrefix = "gnmic/"
+
+	resourcePods     = "pods"
+	resourceServices = "services"
+)
+
+// annotations that are not target config fields
+const (
+	portAnnotation = "port"
+)
+
+// target config fields set from a comma separated annotation value
+var listFields = map[string]struct{}{
+	"subscriptions": {},
+	"outputs":       {},
+	"tags":          {},
+	"proto-files":   {},
+	"proto-dirs":    {},
+}
+
+func init() {
+	loaders.Register(loaderType, func() loaders.TargetLoader {
+		return &k8sLoader{
+			cfg:     new(cfg),
+			m:       new(sync.Mutex),
+			targets: make(map[string]*types.TargetConfig),
+			logger:  log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type k8sLoader struct {
+	cfg       *cfg
+	clientset kubernetes.Interface
+	dynClient dynamic.Interface
+	gvr       schema.GroupVersionResource
+
+	m *sync.Mutex
+	// known targets, indexed by the key (namespace/name)
+	// of the object they were built from
+	targets        map[string]*types.TargetConfig
+	targetConfigFn func(*types.TargetConfig) error
+	logger         *log.Logger
+	//
+	vars          map[string]interface{}
+	actionsConfig map[string]map[string]interface{}
+	addActions    []actions.Action
+	delActions    []actions.Action
+}
+
+type cfg struct {
+	// path to a kubeconfig file, if not set the in-cluster config is used
+	Kubeconfig string `json:"kubeconfig,omitempty" mapstructure:"kubeconfig,omitempty"`
+	// namespace to watch, defaults to "default", "*" watches all namespaces
+	Namespace string `json:"namespace,omitempty" mapstructure:"namespace,omitempty"`
+	// kind of objects to watch: pods, services or the plural name of a custom resource
+	Resource string `json:"resource,omitempty" mapstructure:"resource,omitempty"`
+	// custom resource API group and version
+	Group   string `json:"group,omitempty" mapstructure:"group,omitempty"`
+	Version string `json:"version,omitempty" mapstructure:"version,omitempty"`
+	// label selector used to filter the watched objects
+	LabelSelector string `json:"label-selector,omitempty" mapstructure:"label-selector,omitempty"`
+	// prefix of the annotations used to build the target configs
+	AnnotationPrefix string `json:"annotation-prefix,omitempty" mapstructure:"annotation-prefix,omitempty"`
+	// default gNMI port, used if the objects do not have a port annotation
+	Port string `json:"port,omitempty" mapstructure:"port,omitempty"`
+	// target config template applied to all the discovered targets
+	Config map[string]interface{} `json:"config,omitempty" mapstructure:"config,omitempty"`
+	// enable debug mode for more logging messages
+	Debug bool `json:"debug,omitempty" mapstructure:"debug,omitempty"`
+	// if true, registers k8sLoader prometheus metrics with the provided
+	// prometheus registry
+	EnableMetrics bool `json:"enable-metrics,omitempty" mapstructure:"enable-metrics,omitempty"`
+	// variables definitions to be passed to the actions
+	Vars map[string]interface{}
+	// variable file, values in this file will be overwritten by
+	// the ones defined in Vars
+	VarsFile string `mapstructure:"vars-file,omitempty"`
+	// list of Actions to run on new target discovery
+	OnAdd []string `json:"on-add,omitempty" mapstructure:"on-add,omitempty"`
+	// list of Actions to run on target removal
+	OnDelete []string `json:"on-delete,omitempty" mapstructure:"on-delete,omitempty"`
+}
+
+func (k *k8sLoader) Init(ctx context.Context, cfg map[string]interface{}, logger *log.Logger, opts ...loaders.Option) error {
+	err := loaders.DecodeConfig(cfg, k.cfg)
+	if err != nil {
+		return err
+	}
+	err = k.setDefaults()
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	if logger != nil {
+		k.logger.SetOutput(logger.Writer())
+		k.logger.SetFlags(logger.Flags())
+	}
+	if k.clientset == nil && k.dynClient == nil {
+		err = k.createClients()
+		if err != nil {
+			return err
+		}
+	}
+	err = k.readVars(ctx)
+	if err != nil {
+		return err
+	}
+	for _, actName := range k.cfg.OnAdd {
+		if cfg, ok := k.actionsConfig[actName]; ok {
+			a, err := k.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			k.addActions = append(k.addActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	for _, actName := range k.cfg.OnDelete {
+		if cfg, ok := k.actionsConfig[actName]; ok {
+			a, err := k.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			k.delActions = append(k.delActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	k.logger.Printf("initialized loader type %q: %s", loaderType, k)
+	return nil
+}
+
+func (k *k8sLoader) setDefaults() error {
+	if k.cfg.Namespace == "" {
+		k.cfg.Namespace = defaultNamespace
+	}
+	if k.cfg.Namespace == "*" {
+		k.cfg.Namespace = metav1.NamespaceAll
+	}
+	if k.cfg.Resource == "" {
+		k.cfg.Resource = defaultResource
+	}
+	if k.cfg.AnnotationPrefix == "" {
+		k.cfg.AnnotationPrefix = defaultAnnotationPrefix
+	}
+	k.cfg.Resource = strings.ToLower(k.cfg.Resource)
+	switch k.cfg.Resource {
+	case resourcePods, resourceServices:
+	default:
+		if k.cfg.Version == "" {
+			return fmt.Errorf("missing version for custom resource %q", k.cfg.Resource)
+		}
+		k.gvr = schema.GroupVersionResource{
+			Group:    k.cfg.Group,
+			Version:  k.cfg.Version,
+			Resource: k.cfg.Resource,
+		}
+	}
+	return nil
+}
+
+func (k *k8sLoader) createClients() error {
+	var restConfig *rest.Config
+	var err error
+	if k.cfg.Kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", k.cfg.Kubeconfig)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return err
+	}
+	k.clientset, err = kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	k.dynClient, err = dynamic.NewForConfig(restConfig)
+	return err
+}
+
+func (k *k8sLoader) Start(ctx context.Context) chan *loaders.TargetOperation {
+	opChan := make(chan *loaders.TargetOperation)
+	evCh := make(chan *watchEvent)
+	informer := k.newInformer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			k.sendEvent(ctx, evCh, &watchEvent{obj: obj})
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			k.sendEvent(ctx, evCh, &watchEvent{obj: obj})
+		},
+		DeleteFunc: func(obj interface{}) {
+			k.sendEvent(ctx, evCh, &watchEvent{obj: obj, deleted: true})
+		},
+	})
+	go informer.Run(ctx.Done())
+	go func() {
+		defer close(opChan)
+		for {
+			select {
+			case <-ctx.Done():
+				k.logger.Printf("%q context done: %v", loaderType, ctx.Err())
+				return
+			case ev := <-evCh:
+				op := k.handleEvent(ctx, ev)
+				if op == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case opChan <- op:
+				}
+			}
+		}
+	}()
+	return opChan
+}
+
+func (k *k8sLoader) RunOnce(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	k.logger.Printf("querying %q targets", loaderType)
+	objs, err := k.list(ctx)
+	if err != nil {
+		k8sLoaderFailedListRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+		return nil, err
+	}
+	readTargets := make(map[string]*types.TargetConfig)
+	for _, obj := range objs {
+		tc, err := k.buildTarget(obj)
+		if err != nil {
+			k.logger.Printf("%v", err)
+			continue
+		}
+		if tc == nil {
+			continue
+		}
+		readTargets[tc.Name] = tc
+	}
+	if k.cfg.Debug {
+		k.logger.Printf("k8s loader discovered %d target(s)", len(readTargets))
+	}
+	return readTargets, nil
+}
+
+func (k *k8sLoader) String() string {
+	b, err := json.Marshal(k.cfg)
+	if err != nil {
+		return fmt.Sprintf("%+v", k.cfg)
+	}
+	return string(b)
+}
+
+type watchEvent struct {
+	obj     interface{}
+	deleted bool
+}
+
+func (k *k8sLoader) newInformer() cache.SharedIndexInformer {
+	tweak := func(opts *metav1.ListOptions) {
+		opts.LabelSelector = k.cfg.LabelSelector
+	}
+	switch k.cfg.Resource {
+	case resourcePods:
+		return informers.NewSharedInformerFactoryWithOptions(k.clientset, 0,
+			informers.WithNamespace(k.cfg.Namespace),
+			informers.WithTweakListOptions(tweak),
+		).Core().V1().Pods().Informer()
+	case resourceServices:
+		return informers.NewSharedInformerFactoryWithOptions(k.clientset, 0,
+			informers.WithNamespace(k.cfg.Namespace),
+			informers.WithTweakListOptions(tweak),
+		).Core().V1().Services().Informer()
+	default:
+		return dynamicinformer.NewFilteredDynamicSharedInformerFactory(k.dynClient, 0,
+			k.cfg.Namespace, tweak,
+		).ForResource(k.gvr).Informer()
+	}
+}
+
+func (k *k8sLoader) list(ctx context.Context) ([]interface{}, error) {
+	opts := metav1.ListOptions{LabelSelector: k.cfg.LabelSelector}
+	k8sLoaderListRequestsTotal.WithLabelValues(loaderType).Add(1)
+	objs := make([]interface{}, 0)
+	switch k.cfg.Resource {
+	case resourcePods:
+		l, err := k.clientset.CoreV1().Pods(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	case resourceServices:
+		l, err := k.clientset.CoreV1().Services(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	default:
+		l, err := k.dynClient.Resource(k.gvr).Namespace(k.cfg.Namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	}
+	return objs, nil
+}
+
+func (k *k8sLoader) sendEvent(ctx context.Context, evCh chan *watchEvent, ev *watchEvent) {
+	select {
+	case <-ctx.Done():
+	case evCh <- ev:
+	}
+}
+
+// handleEvent builds the target config from the watched object and
+// returns the target operation to send, if any.
+// An update of a known target is sent as a delete followed by an add.
+func (k *k8sLoader) handleEvent(ctx context.Context, ev *watchEvent) *loaders.TargetOperation {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(ev.obj)
+	if err != nil {
+		k.logger.Printf("failed to get object key: %v", err)
+		return nil
+	}
+	k8sLoaderWatchEvents.WithLabelValues(loaderType, fmt.Sprintf("%t", ev.deleted)).Add(1)
+	var tc *types.TargetConfig
+	if !ev.deleted {
+		tc, err = k.buildTarget(ev.obj)
+		if err != nil {
+			k.logger.Printf("%v", err)
+			return nil
+		}
+	}
+	k.m.Lock()
+	defer k.m.Unlock()
+	old, known := k.targets[key]
+	if tc != nil && known && reflect.DeepEqual(old, tc) {
+		return nil
+	}
+	op := &loaders.TargetOperation{
+		Add: make([]*types.TargetConfig, 0, 1),
+		Del: make([]string, 0, 1),
+	}
+	if known {
+		delete(k.targets, key)
+		err = k.runOnDeleteActions(ctx, old.Name)
+		if err != nil {
+			k.logger.Printf("failed running OnDelete actions: %v", err)
+		}
+		op.Del = append(op.Del, old.Name)
+	}
+	if tc != nil {
+		k.targets[key] = tc
+		err = k.runOnAddActions(ctx, tc.Name)
+		if err != nil {
+			delete(k.targets, key)
+			k.logger.Printf("failed running OnAdd actions: %v", err)
+		} else {
+			op.Add = append(op.Add, tc)
+		}
+	}
+	k8sLoaderLoadedTargets.WithLabelValues(loaderType).Set(float64(len(k.targets)))
+	if len(op.Add)+len(op.Del) == 0 {
+		return nil
+	}
+	if k.cfg.Debug {
+		b, err := json.MarshalIndent(op, "", "  ")
+		if err != nil {
+			k.logger.Printf("object %q target operation: %v", key, op)
+		} else {
+			k.logger.Printf("object %q target operation:\n%s", key, string(b))
+		}
+	}
+	return op
+}
+
+// buildTarget builds a target config from a pod, a service or a custom resource.
+// It returns a nil target config if the object is not ready to be used as a target.
+func (k *k8sLoader) buildTarget(obj interface{}) (*types.TargetConfig, error) {
+	var meta metav1.Object
+	var host string
+	var spec map[string]interface{}
+	switch obj := obj.(type) {
+	case *corev1.Pod:
+		if obj.DeletionTimestamp != nil || obj.Status.Phase != corev1.PodRunning {
+			return nil, nil
+		}
+		meta = obj
+		host = obj.Status.PodIP
+	case *corev1.Service:
+		meta = obj
+		host = obj.Spec.ClusterIP
+		if host == "" || host == corev1.ClusterIPNone {
+			// headless service
+			host = fmt.Sprintf("%s.%s.svc", obj.Name, obj.Namespace)
+		}
+	case *unstructured.Unstructured:
+		meta = obj
+		var ok bool
+		spec, ok = obj.Object["spec"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s %q: missing spec", obj.GetKind(), obj.GetName())
+		}
+	default:
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	tc := new(types.TargetConfig)
+	if k.cfg.Config != nil {
+		err := mapstructure.Decode(k.cfg.Config, tc)
+		if err != nil {
+			k.logger.Printf("failed to decode config map: %v", err)
+		}
+	}
+	if spec != nil {
+		err := decodeTargetConfig(spec, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %q spec: %v", meta.GetName(), err)
+		}
+	}
+	fields, port := k.annotationFields(meta.GetAnnotations())
+	err := decodeTargetConfig(fields, tc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %q annotations: %v", meta.GetName(), err)
+	}
+	if tc.Name == "" {
+		tc.Name = meta.GetName()
+		// objects with the same name may exist in different namespaces
+		if k.cfg.Namespace == metav1.NamespaceAll {
+			tc.Name = meta.GetNamespace() + "/" + meta.GetName()
+		}
+	}
+	if tc.Address == "" {
+		if host == "" {
+			if k.cfg.Debug {
+				k.logger.Printf("%q no address found", tc.Name)
+			}
+			return nil, nil
+		}
+		tc.Address = host
+		if port == "" {
+			port = k.cfg.Port
+		}
+		if port != "" {
+			tc.Address = net.JoinHostPort(host, port)
+		}
+	}
+	if k.targetConfigFn != nil {
+		err = k.targetConfigFn(tc)
+		if err != nil {
+			k.logger.Printf("failed running target config fn on target %q", tc.Name)
+		}
+	}
+	if k.cfg.Debug {
+		k.logger.Printf("discovered target config %s from object %s/%s", tc, meta.GetNamespace(), meta.GetName())
+	}
+	return tc, nil
+}
+
+// annotationFields returns the target config fields set with the annotations
+// prefixed with the configured annotation prefix, as well as the port annotation value.
+func (k *k8sLoader) annotationFields(annotations map[string]string) (map[string]interface{}, string) {
+	fields := make(map[string]interface{})
+	var port string
+	for ak, av := range annotations {
+		if !strings.HasPrefix(ak, k.cfg.AnnotationPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(ak, k.cfg.AnnotationPrefix)
+		if name == portAnnotation {
+			port = av
+			continue
+		}
+		if _, ok := listFields[name]; ok {
+			items := strings.Split(av, ",")
+			vals := make([]string, 0, len(items))
+			for _, item := range items {
+				item = strings.TrimSpace(item)
+				if item != "" {
+					vals = append(vals, item)
+				}
+			}
+			sort.Strings(vals)
+			fields[name] = vals
+			continue
+		}
+		fields[name] = av
+	}
+	return fields, port
+}
+
+func decodeTargetConfig(src map[string]interface{}, tc *types.TargetConfig) error {
+	decoder, err := mapstructure.NewDecoder(
+		&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           tc,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(src)
+}
+
+func (k *k8sLoader) readVars(ctx context.Context) error {
+	if k.cfg.VarsFile == "" {
+		k.vars = k.cfg.Vars
+		return nil
+	}
+	b, err := utils.ReadFile(ctx, k.cfg.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	k.vars = utils.MergeMaps(v, k.cfg.Vars)
+	return nil
+}
+
+func (k *k8sLoader) initializeAction(cfg map[string]interface{}) (actions.Action, error) {
+	if len(cfg) == 0 {
+		return nil, errors.New("missing action definition")
+	}
+	if actType, ok := cfg["type"]; ok {
+		switch actType := actType.(type) {
+		case string:
+			if in, ok := actions.Actions[actType]; ok {
+				act := in()
+				err := act.Init(cfg, actions.WithLogger(k.logger), actions.WithTargets(nil))
+				if err != nil {
+					return nil, err
+				}
+
+				return act, nil
+			}
+			return nil, fmt.Errorf("unknown action type %q", actType)
+		default:
+			return nil, fmt.Errorf("unexpected action field type %T", actType)
+		}
+	}
+	return nil, errors.New("missing type field under action")
+}
+
+// runOnAddActions runs the OnAdd actions for target tName,
+// must be called with k.m locked.
+func (k *k8sLoader) runOnAddActions(ctx context.Context, tName string) error {
+	if len(k.addActions) == 0 {
+		return nil
+	}
+	tcs := make(map[string]*types.TargetConfig, len(k.targets))
+	for _, tc := range k.targets {
+		tcs[tc.Name] = tc
+	}
+	aCtx := &actions.Context{
+		Input:   tName,
+		Env:     make(map[string]interface{}),
+		Vars:    k.vars,
+		Targets: tcs,
+	}
+	for _, act := range k.addActions {
+		k.logger.Printf("running action %q for target %q", act.NName(), tName)
+		res, err := act.Run(ctx, aCtx)
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+		aCtx.Env[act.NName()] = utils.Convert(res)
+		if k.cfg.Debug {
+			k.logger.Printf("action %q, target %q result: %+v", act.NName(), tName, res)
+			b, _ := json.MarshalIndent(aCtx, "", "  ")
+			k.logger.Printf("action %q context:\n%s", act.NName(), string(b))
+		}
+	}
+	return nil
+}
+
+func (k *k8sLoader) runOnDeleteActions(ctx context.Context, tName string) error {
+	env := make(map[string]interface{})
+	for _, act := range k.delActions {
+		res, err := act.Run(ctx, &actions.Context{Input: tName, Env: env, Vars: k.vars})
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+		env[act.NName()] = res
+	}
+	return nil
+}
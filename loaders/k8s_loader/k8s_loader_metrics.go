@@ -0,0 +1,60 @@
+package k8s_loader
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var k8sLoaderLoadedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_loaded_targets",
+	Help:      "Number of targets currently loaded from watched objects",
+}, []string{"loader_type"})
+
+var k8sLoaderWatchEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_watch_events_total",
+	Help:      "Number of watch events received by the loader",
+}, []string{"loader_type", "deleted"})
+
+var k8sLoaderFailedListRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_failed_k8s_list",
+	Help:      "Number of times a k8s list failed",
+}, []string{"loader_type", "error"})
+
+var k8sLoaderListRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "k8s_loader",
+	Name:      "number_of_k8s_list_total",
+	Help:      "Number of times the loader sent a k8s list request",
+}, []string{"loader_type"})
+
+func initMetrics() {
+	k8sLoaderLoadedTargets.WithLabelValues(loaderType).Set(0)
+	k8sLoaderWatchEvents.WithLabelValues(loaderType, "false").Add(0)
+	k8sLoaderWatchEvents.WithLabelValues(loaderType, "true").Add(0)
+	k8sLoaderFailedListRequests.WithLabelValues(loaderType, "").Add(0)
+	k8sLoaderListRequestsTotal.WithLabelValues(loaderType).Add(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	if reg == nil {
+		return nil
+	}
+	initMetrics()
+	var err error
+	if err = reg.Register(k8sLoaderLoadedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderWatchEvents); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderFailedListRequests); err != nil {
+		return err
+	}
+	if err = reg.Register(k8sLoaderListRequestsTotal); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package k8s_loader
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(name, ip string, labels, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: ip,
+		},
+	}
+}
+
+func newTestLoader(t *testing.T, cfg map[string]interface{}, init func(l *k8sLoader)) *k8sLoader {
+	l := loaders.Loaders[loaderType]().(*k8sLoader)
+	init(l)
+	err := l.Init(context.TODO(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func receiveOp(t *testing.T, opChan chan *loaders.TargetOperation) *loaders.TargetOperation {
+	select {
+	case op := <-opChan:
+		return op
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a target operation")
+	}
+	return nil
+}
+
+func TestBuildTarget(t *testing.T) {
+	l := newTestLoader(t, map[string]interface{}{
+		"port": "57400",
+		"config": map[string]interface{}{
+			"outputs": []string{"prom"},
+		},
+	}, func(l *k8sLoader) { l.clientset = fake.NewSimpleClientset() })
+
+	skipVerify := true
+	tests := []struct {
+		name string
+		obj  interface{}
+		tc   *types.TargetConfig
+	}{
+		{
+			name: "pod_default_port",
+			obj:  newPod("r1", "10.0.0.1", nil, nil),
+			tc: &types.TargetConfig{
+				Name:    "r1",
+				Address: "10.0.0.1:57400",
+				Outputs: []string{"prom"},
+			},
+		},
+		{
+			name: "pod_annotations",
+			obj: newPod("r1", "10.0.0.1", nil, map[string]string{
+				"gnmic/port":          "6030",
+				"gnmic/skip-verify":   "true",
+				"gnmic/subscriptions": "sub2, sub1",
+				"gnmic/timeout":       "30s",
+				"other/port":          "1",
+			}),
+			tc: &types.TargetConfig{
+				Name:          "r1",
+				Address:       "10.0.0.1:6030",
+				SkipVerify:    &skipVerify,
+				Subscriptions: []string{"sub1", "sub2"},
+				Outputs:       []string{"prom"},
+				Timeout:       30 * time.Second,
+			},
+		},
+		{
+			name: "pod_not_running",
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "r1", Namespace: "default"},
+				Status:     corev1.PodStatus{Phase: corev1.PodPending},
+			},
+		},
+		{
+			name: "headless_service",
+			obj: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "gnmi"},
+				Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+			},
+			tc: &types.TargetConfig{
+				Name:    "proxy",
+				Address: "proxy.gnmi.svc:57400",
+				Outputs: []string{"prom"},
+			},
+		},
+		{
+			name: "custom_resource",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "gnmic.dev/v1",
+				"kind":       "Target",
+				"metadata":   map[string]interface{}{"name": "r2", "namespace": "default"},
+				"spec": map[string]interface{}{
+					"address":       "r2.lab:57400",
+					"subscriptions": []interface{}{"sub1"},
+				},
+			}},
+			tc: &types.TargetConfig{
+				Name:          "r2",
+				Address:       "r2.lab:57400",
+				Subscriptions: []string{"sub1"},
+				Outputs:       []string{"prom"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := l.buildTarget(tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc, tt.tc) {
+				t.Errorf("expected %+v, got %+v", tt.tc, tc)
+			}
+		})
+	}
+}
+
+func TestBuildTargetAllNamespaces(t *testing.T) {
+	l := newTestLoader(t, map[string]interface{}{
+		"namespace": "*",
+	}, func(l *k8sLoader) { l.clientset = fake.NewSimpleClientset() })
+	p1 := newPod("r1", "10.0.0.1", nil, nil)
+	p2 := newPod("r1", "10.0.0.2", nil, nil)
+	p2.Namespace = "lab"
+	tc1, err := l.buildTarget(p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc2, err := l.buildTarget(p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc1.Name != "default/r1" || tc2.Name != "lab/r1" {
+		t.Errorf("unexpected target names %q and %q", tc1.Name, tc2.Name)
+	}
+}
+
+func TestPodsWatch(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("r1", "10.0.0.1", map[string]string{"app": "srl"}, nil),
+		newPod("other", "10.0.0.9", map[string]string{"app": "web"}, nil),
+	)
+	l := newTestLoader(t, map[string]interface{}{
+		"label-selector": "app=srl",
+	}, func(l *k8sLoader) { l.clientset = client })
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	opChan := l.Start(ctx)
+
+	op := receiveOp(t, opChan)
+	if len(op.Add) != 1 || op.Add[0].Name != "r1" || op.Add[0].Address != "10.0.0.1" {
+		t.Fatalf("unexpected add operation: %+v", op)
+	}
+	// update the pod annotations
+	pods := client.CoreV1().Pods("default")
+	pod := newPod("r1", "10.0.0.1", map[string]string{"app": "srl"}, map[string]string{"gnmic/port": "57400"})
+	_, err := pods.Update(ctx, pod, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = receiveOp(t, opChan)
+	if !reflect.DeepEqual(op.Del, []string{"r1"}) || len(op.Add) != 1 || op.Add[0].Address != "10.0.0.1:57400" {
+		t.Fatalf("unexpected update operation: %+v", op)
+	}
+	// add a second pod
+	_, err = pods.Create(ctx, newPod("r2", "10.0.0.2", map[string]string{"app": "srl"}, nil), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = receiveOp(t, opChan)
+	if len(op.Add) != 1 || op.Add[0].Name != "r2" {
+		t.Fatalf("unexpected add operation: %+v", op)
+	}
+	// delete the first pod
+	err = pods.Delete(ctx, "r1", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = receiveOp(t, opChan)
+	if !reflect.DeepEqual(op.Del, []string{"r1"}) || len(op.Add) != 0 {
+		t.Fatalf("unexpected delete operation: %+v", op)
+	}
+
+	tcs, err := l.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tcs) != 1 || tcs["r2"] == nil {
+		t.Errorf("unexpected RunOnce result: %+v", tcs)
+	}
+}
+
+func TestCustomResourceWatch(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "gnmic.dev", Version: "v1", Resource: "targets"}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gnmic.dev/v1",
+		"kind":       "Target",
+		"metadata":   map[string]interface{}{"name": "r1", "namespace": "default"},
+		"spec":       map[string]interface{}{"address": "r1.lab:57400"},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "TargetList"}, obj)
+	l := newTestLoader(t, map[string]interface{}{
+		"resource": "targets",
+		"group":    "gnmic.dev",
+		"version":  "v1",
+	}, func(l *k8sLoader) { l.dynClient = client })
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	opChan := l.Start(ctx)
+
+	op := receiveOp(t, opChan)
+	if len(op.Add) != 1 || op.Add[0].Name != "r1" || op.Add[0].Address != "r1.lab:57400" {
+		t.Fatalf("unexpected add operation: %+v", op)
+	}
+	err := client.Resource(gvr).Namespace("default").Delete(ctx, "r1", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = receiveOp(t, opChan)
+	if !reflect.DeepEqual(op.Del, []string{"r1"}) {
+		t.Fatalf("unexpected delete operation: %+v", op)
+	}
+}
@@ -0,0 +1,23 @@
+package k8s_loader
+
+import (
+	"github.com/karimra/gnmic/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (k *k8sLoader) RegisterMetrics(reg *prometheus.Registry) {
+	if !k.cfg.EnableMetrics {
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		k.logger.Printf("failed to register metrics: %v", err)
+	}
+}
+
+func (k *k8sLoader) WithActions(acts map[string]map[string]interface{}) {
+	k.actionsConfig = acts
+}
+
+func (k *k8sLoader) WithTargetsDefaults(fn func(tc *types.TargetConfig) error) {
+	k.targetConfigFn = fn
+}
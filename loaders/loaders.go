@@ -39,6 +39,9 @@ var LoadersTypes = []string{
 	"consul",
 	"docker",
 	"http",
+	"k8s",
+	"netbox",
+	"dns",
 }
 
 func Register(name string, initFn Initializer) {
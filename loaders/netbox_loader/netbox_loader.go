@@ -0,0 +1,720 @@
+package netbox_loader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/karimra/gnmic/actions"
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	loggingPrefix   = "[netbox_loader] "
+	loaderType      = "netbox"
+	devicesPath     = "/api/dcim/devices/"
+	defaultInterval = 1 * time.Minute
+	defaultTimeout  = 50 * time.Second
+	defaultPageSize = 100
+)
+
+// custom field mapped to the port of the target address
+const portField = "port"
+
+// target config fields set from a comma separated custom field value
+var listFields = map[string]struct{}{
+	"subscriptions": {},
+	"outputs":       {},
+	"tags":          {},
+	"proto-files":   {},
+	"proto-dirs":    {},
+}
+
+func init() {
+	loaders.Register(loaderType, func() loaders.TargetLoader {
+		return &netboxLoader{
+			cfg:         &cfg{},
+			m:           new(sync.RWMutex),
+			lastTargets: make(map[string]*types.TargetConfig),
+			logger:      log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type netboxLoader struct {
+	cfg            *cfg
+	m              *sync.RWMutex
+	lastTargets    map[string]*types.TargetConfig
+	targetConfigFn func(*types.TargetConfig) error
+	logger         *log.Logger
+	//
+	tpl           *template.Template
+	vars          map[string]interface{}
+	actionsConfig map[string]map[string]interface{}
+	addActions    []actions.Action
+	delActions    []actions.Action
+	numActions    int
+}
+
+type cfg struct {
+	// the NetBox server URL, must include http or https as a prefix
+	URL string `json:"url,omitempty" mapstructure:"url,omitempty"`
+	// NetBox API token
+	Token string `json:"token,omitempty" mapstructure:"token,omitempty"`
+	// server query interval
+	Interval time.Duration `json:"interval,omitempty" mapstructure:"interval,omitempty"`
+	// query timeout
+	Timeout time.Duration `json:"timeout,omitempty" mapstructure:"timeout,omitempty"`
+	// TLS config
+	SkipVerify bool   `json:"skip-verify,omitempty" mapstructure:"skip-verify,omitempty"`
+	CAFile     string `json:"ca-file,omitempty" mapstructure:"ca-file,omitempty"`
+	CertFile   string `json:"cert-file,omitempty" mapstructure:"cert-file,omitempty"`
+	KeyFile    string `json:"key-file,omitempty" mapstructure:"key-file,omitempty"`
+	// devices filters, a device matches if it matches one of the values of each filter
+	Site   []string `json:"site,omitempty" mapstructure:"site,omitempty"`
+	Role   []string `json:"role,omitempty" mapstructure:"role,omitempty"`
+	Tag    []string `json:"tag,omitempty" mapstructure:"tag,omitempty"`
+	Status []string `json:"status,omitempty" mapstructure:"status,omitempty"`
+	// additional devices query parameters
+	Filters map[string]string `json:"filters,omitempty" mapstructure:"filters,omitempty"`
+	// default gNMI port, used if the device does not have a port custom field
+	Port string `json:"port,omitempty" mapstructure:"port,omitempty"`
+	// target config applied to all the discovered targets
+	Config map[string]interface{} `json:"config,omitempty" mapstructure:"config,omitempty"`
+	// target configs applied to the devices matching a platform or a role
+	Mappings []*mapping `json:"mappings,omitempty" mapstructure:"mappings,omitempty"`
+	// NetBox custom fields names mapped to target config fields names
+	CustomFields map[string]string `json:"custom-fields,omitempty" mapstructure:"custom-fields,omitempty"`
+	// a Go text template that can be used to transform the devices
+	// read from NetBox into gNMIc's expected format.
+	Template string `json:"template,omitempty" mapstructure:"template,omitempty"`
+	// time to wait before the first NetBox query
+	StartDelay time.Duration `json:"start-delay,omitempty" mapstructure:"start-delay,omitempty"`
+	// if true, registers netboxLoader prometheus metrics with the provided
+	// prometheus registry
+	EnableMetrics bool `json:"enable-metrics,omitempty" mapstructure:"enable-metrics,omitempty"`
+	// enable Debug
+	Debug bool `json:"debug,omitempty" mapstructure:"debug,omitempty"`
+	// variables definitions to be passed to the actions
+	Vars map[string]interface{}
+	// variable file, values in this file will be overwritten by
+	// the ones defined in Vars
+	VarsFile string `mapstructure:"vars-file,omitempty"`
+	// list of Actions to run on new target discovery
+	OnAdd []string `json:"on-add,omitempty" mapstructure:"on-add,omitempty"`
+	// list of Actions to run on target removal
+	OnDelete []string `json:"on-delete,omitempty" mapstructure:"on-delete,omitempty"`
+}
+
+type mapping struct {
+	// platforms slugs
+	Platform []string `json:"platform,omitempty" mapstructure:"platform,omitempty"`
+	// roles slugs
+	Role []string `json:"role,omitempty" mapstructure:"role,omitempty"`
+	// target config applied to the matching devices
+	Config map[string]interface{} `json:"config,omitempty" mapstructure:"config,omitempty"`
+}
+
+// device is the subset of a NetBox device used to build a target config
+type device struct {
+	ID           int                    `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	PrimaryIP    *ipAddress             `json:"primary_ip,omitempty"`
+	Platform     *nestedObject          `json:"platform,omitempty"`
+	Role         *nestedObject          `json:"role,omitempty"`
+	DeviceRole   *nestedObject          `json:"device_role,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+type ipAddress struct {
+	Address string `json:"address,omitempty"`
+}
+
+type nestedObject struct {
+	Slug string `json:"slug,omitempty"`
+}
+
+type devicesPage struct {
+	Next    string            `json:"next,omitempty"`
+	Results []json.RawMessage `json:"results,omitempty"`
+}
+
+func (n *netboxLoader) Init(ctx context.Context, cfg map[string]interface{}, logger *log.Logger, opts ...loaders.Option) error {
+	err := loaders.DecodeConfig(cfg, n.cfg)
+	if err != nil {
+		return err
+	}
+	err = n.setDefaults()
+	if err != nil {
+		return err
+	}
+	for _, o := range opts {
+		o(n)
+	}
+	if logger != nil {
+		n.logger.SetOutput(logger.Writer())
+		n.logger.SetFlags(logger.Flags())
+	}
+	if n.cfg.Template != "" {
+		n.tpl, err = utils.CreateTemplate("netbox-loader-template", n.cfg.Template)
+		if err != nil {
+			return err
+		}
+	}
+	err = n.readVars(ctx)
+	if err != nil {
+		return err
+	}
+	for _, actName := range n.cfg.OnAdd {
+		if cfg, ok := n.actionsConfig[actName]; ok {
+			a, err := n.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			n.addActions = append(n.addActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	for _, actName := range n.cfg.OnDelete {
+		if cfg, ok := n.actionsConfig[actName]; ok {
+			a, err := n.initializeAction(cfg)
+			if err != nil {
+				return err
+			}
+			n.delActions = append(n.delActions, a)
+			continue
+		}
+		return fmt.Errorf("unknown action name %q", actName)
+	}
+	n.numActions = len(n.addActions) + len(n.delActions)
+	n.logger.Printf("initialized loader type %q: %s", loaderType, n)
+	return nil
+}
+
+func (n *netboxLoader) Start(ctx context.Context) chan *loaders.TargetOperation {
+	opChan := make(chan *loaders.TargetOperation)
+	ticker := time.NewTicker(n.cfg.Interval)
+	go func() {
+		defer close(opChan)
+		defer ticker.Stop()
+		time.Sleep(n.cfg.StartDelay)
+		n.update(ctx, opChan)
+		for {
+			select {
+			case <-ctx.Done():
+				n.logger.Printf("%q context done: %v", loaderType, ctx.Err())
+				return
+			case <-ticker.C:
+				n.update(ctx, opChan)
+			}
+		}
+	}()
+	return opChan
+}
+
+func (n *netboxLoader) RunOnce(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	readTargets, err := n.getTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n.cfg.Debug {
+		n.logger.Printf("netbox loader discovered %d target(s)", len(readTargets))
+	}
+	return readTargets, nil
+}
+
+func (n *netboxLoader) String() string {
+	c := *n.cfg
+	if c.Token != "" {
+		c.Token = "****"
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("%+v", c)
+	}
+	return string(b)
+}
+
+func (n *netboxLoader) update(ctx context.Context, opChan chan *loaders.TargetOperation) {
+	readTargets, err := n.getTargets(ctx)
+	if err != nil {
+		n.logger.Printf("failed to read targets from NetBox: %v", err)
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		n.updateTargets(ctx, readTargets, opChan)
+	}
+}
+
+func (n *netboxLoader) setDefaults() error {
+	if n.cfg.URL == "" {
+		return errors.New("missing URL")
+	}
+	n.cfg.URL = strings.TrimSuffix(n.cfg.URL, "/")
+	if n.cfg.Interval <= 0 {
+		n.cfg.Interval = defaultInterval
+	}
+	if n.cfg.Timeout <= 0 {
+		n.cfg.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+// query returns the devices list URL query parameters
+func (n *netboxLoader) query() url.Values {
+	q := url.Values{}
+	for _, v := range n.cfg.Site {
+		q.Add("site", v)
+	}
+	for _, v := range n.cfg.Role {
+		q.Add("role", v)
+	}
+	for _, v := range n.cfg.Tag {
+		q.Add("tag", v)
+	}
+	for _, v := range n.cfg.Status {
+		q.Add("status", v)
+	}
+	for k, v := range n.cfg.Filters {
+		q.Add(k, v)
+	}
+	q.Set("limit", strconv.Itoa(defaultPageSize))
+	return q
+}
+
+// getDevices queries the NetBox devices matching the configured filters,
+// following the result pages.
+func (n *netboxLoader) getDevices(ctx context.Context) ([]json.RawMessage, error) {
+	c := resty.New()
+	tlsCfg, err := utils.NewTLSConfig(n.cfg.CAFile, n.cfg.CertFile, n.cfg.KeyFile, n.cfg.SkipVerify, false)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		c = c.SetTLSClientConfig(tlsCfg)
+	}
+	c.SetTimeout(n.cfg.Timeout)
+	c.SetHeader("Accept", "application/json")
+	if n.cfg.Token != "" {
+		c.SetHeader("Authorization", "Token "+n.cfg.Token)
+	}
+	devices := make([]json.RawMessage, 0)
+	next := n.cfg.URL + devicesPath + "?" + n.query().Encode()
+	start := time.Now()
+	for next != "" {
+		netboxLoaderRequestsTotal.WithLabelValues(loaderType).Add(1)
+		rsp, err := c.R().SetContext(ctx).Get(next)
+		if err != nil {
+			return nil, err
+		}
+		if rsp.StatusCode() != 200 {
+			return nil, fmt.Errorf("failed request, code=%d", rsp.StatusCode())
+		}
+		page := new(devicesPage)
+		err = json.Unmarshal(rsp.Body(), page)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, page.Results...)
+		next = page.Next
+	}
+	netboxLoaderRequestDuration.WithLabelValues(loaderType).Set(float64(time.Since(start).Nanoseconds()))
+	return devices, nil
+}
+
+func (n *netboxLoader) getTargets(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	devices, err := n.getDevices(ctx)
+	if err != nil {
+		netboxLoaderFailedRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+		return nil, err
+	}
+	if n.tpl != nil {
+		return n.templateTargets(devices)
+	}
+	result := make(map[string]*types.TargetConfig)
+	for _, raw := range devices {
+		d := new(device)
+		err = json.Unmarshal(raw, d)
+		if err != nil {
+			return nil, err
+		}
+		tc, err := n.buildTarget(d)
+		if err != nil {
+			n.logger.Printf("%v", err)
+			continue
+		}
+		if tc == nil {
+			continue
+		}
+		result[tc.Name] = tc
+	}
+	if n.cfg.Debug {
+		n.logger.Printf("result: %s", result)
+	}
+	return result, nil
+}
+
+// templateTargets executes the configured template with the list of devices as input,
+// the template output is expected to be a YAML or JSON dict of target configs.
+func (n *netboxLoader) templateTargets(devices []json.RawMessage) (map[string]*types.TargetConfig, error) {
+	input := make([]interface{}, 0, len(devices))
+	for _, raw := range devices {
+		var d interface{}
+		err := json.Unmarshal(raw, &d)
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, d)
+	}
+	buf := new(bytes.Buffer)
+	err := n.tpl.Execute(buf, input)
+	if err != nil {
+		netboxLoaderFailedRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+		return nil, err
+	}
+	result := make(map[string]*types.TargetConfig)
+	err = yaml.Unmarshal(buf.Bytes(), result)
+	if err != nil {
+		netboxLoaderFailedRequests.WithLabelValues(loaderType, fmt.Sprintf("%v", err)).Add(1)
+		return nil, err
+	}
+	// properly initialize address and name if not set
+	for name, t := range result {
+		if t == nil && name != "" {
+			result[name] = &types.TargetConfig{
+				Name:    name,
+				Address: name,
+			}
+			continue
+		}
+		if t.Name == "" {
+			t.Name = name
+		}
+		if t.Address == "" {
+			t.Address = name
+		}
+	}
+	return result, nil
+}
+
+// buildTarget builds a target config from a NetBox device.
+// It returns a nil target config if the device does not have a primary IP address.
+func (n *netboxLoader) buildTarget(d *device) (*types.TargetConfig, error) {
+	name := d.Name
+	if name == "" {
+		name = fmt.Sprintf("device-%d", d.ID)
+	}
+	tc := new(types.TargetConfig)
+	if n.cfg.Config != nil {
+		err := decodeTargetConfig(n.cfg.Config, tc)
+		if err != nil {
+			return nil, fmt.Errorf("device %q: failed to decode config map: %v", name, err)
+		}
+	}
+	for _, m := range n.cfg.Mappings {
+		if !m.match(d) {
+			continue
+		}
+		err := decodeTargetConfig(m.Config, tc)
+		if err != nil {
+			return nil, fmt.Errorf("device %q: failed to decode mapping config: %v", name, err)
+		}
+	}
+	fields, port := n.customFields(d.CustomFields)
+	err := decodeTargetConfig(fields, tc)
+	if err != nil {
+		return nil, fmt.Errorf("device %q: failed to decode custom fields: %v", name, err)
+	}
+	if tc.Name == "" {
+		tc.Name = name
+	}
+	if tc.Address == "" {
+		if d.PrimaryIP == nil || d.PrimaryIP.Address == "" {
+			if n.cfg.Debug {
+				n.logger.Printf("device %q has no primary IP address", name)
+			}
+			return nil, nil
+		}
+		// strip the prefix length
+		tc.Address = strings.SplitN(d.PrimaryIP.Address, "/", 2)[0]
+		if port == "" {
+			port = n.cfg.Port
+		}
+		if port != "" {
+			tc.Address = net.JoinHostPort(tc.Address, port)
+		}
+	}
+	return tc, nil
+}
+
+// customFields returns the target config fields set with the device custom fields
+// listed in the custom-fields mapping, as well as the port value.
+func (n *netboxLoader) customFields(cfs map[string]interface{}) (map[string]interface{}, string) {
+	fields := make(map[string]interface{})
+	var port string
+	for cf, field := range n.cfg.CustomFields {
+		v, ok := cfs[cf]
+		if !ok || v == nil {
+			continue
+		}
+		if field == portField {
+			port = fmt.Sprintf("%v", v)
+			continue
+		}
+		if _, ok := listFields[field]; ok {
+			if s, ok := v.(string); ok {
+				items := strings.Split(s, ",")
+				vals := make([]string, 0, len(items))
+				for _, item := range items {
+					item = strings.TrimSpace(item)
+					if item != "" {
+						vals = append(vals, item)
+					}
+				}
+				sort.Strings(vals)
+				fields[field] = vals
+				continue
+			}
+		}
+		fields[field] = v
+	}
+	return fields, port
+}
+
+func (m *mapping) match(d *device) bool {
+	if len(m.Platform) > 0 {
+		if d.Platform == nil || !contains(m.Platform, d.Platform.Slug) {
+			return false
+		}
+	}
+	if len(m.Role) > 0 {
+		role := d.Role
+		if role == nil {
+			// NetBox versions prior to 3.6
+			role = d.DeviceRole
+		}
+		if role == nil || !contains(m.Role, role.Slug) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeTargetConfig(src map[string]interface{}, tc *types.TargetConfig) error {
+	decoder, err := mapstructure.NewDecoder(
+		&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           tc,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(src)
+}
+
+func (n *netboxLoader) updateTargets(ctx context.Context, tcs map[string]*types.TargetConfig, opChan chan *loaders.TargetOperation) {
+	var err error
+	for _, tc := range tcs {
+		err = n.targetConfigFn(tc)
+		if err != nil {
+			n.logger.Printf("failed running target config fn on target %q", tc.Name)
+		}
+	}
+	n.m.RLock()
+	targetOp := loaders.Diff(n.lastTargets, tcs)
+	n.m.RUnlock()
+	targetOp, err = n.runActions(ctx, tcs, targetOp)
+	if err != nil {
+		n.logger.Printf("failed to run actions: %v", err)
+		return
+	}
+	numAdds := len(targetOp.Add)
+	numDels := len(targetOp.Del)
+	defer func() {
+		netboxLoaderLoadedTargets.WithLabelValues(loaderType).Set(float64(numAdds))
+		netboxLoaderDeletedTargets.WithLabelValues(loaderType).Set(float64(numDels))
+	}()
+	if numAdds+numDels == 0 {
+		return
+	}
+	n.m.Lock()
+	for _, t := range targetOp.Add {
+		if _, ok := n.lastTargets[t.Name]; !ok {
+			n.lastTargets[t.Name] = t
+		}
+	}
+	for _, name := range targetOp.Del {
+		delete(n.lastTargets, name)
+	}
+	n.m.Unlock()
+	select {
+	case <-ctx.Done():
+	case opChan <- targetOp:
+	}
+}
+
+func (n *netboxLoader) readVars(ctx context.Context) error {
+	if n.cfg.VarsFile == "" {
+		n.vars = n.cfg.Vars
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Interval)
+	defer cancel()
+	b, err := utils.ReadFile(ctx, n.cfg.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	n.vars = utils.MergeMaps(v, n.cfg.Vars)
+	return nil
+}
+
+func (n *netboxLoader) initializeAction(cfg map[string]interface{}) (actions.Action, error) {
+	if len(cfg) == 0 {
+		return nil, errors.New("missing action definition")
+	}
+	if actType, ok := cfg["type"]; ok {
+		switch actType := actType.(type) {
+		case string:
+			if in, ok := actions.Actions[actType]; ok {
+				act := in()
+				err := act.Init(cfg, actions.WithLogger(n.logger), actions.WithTargets(nil))
+				if err != nil {
+					return nil, err
+				}
+
+				return act, nil
+			}
+			return nil, fmt.Errorf("unknown action type %q", actType)
+		default:
+			return nil, fmt.Errorf("unexpected action field type %T", actType)
+		}
+	}
+	return nil, errors.New("missing type field under action")
+}
+
+func (n *netboxLoader) runActions(ctx context.Context, tcs map[string]*types.TargetConfig, targetOp *loaders.TargetOperation) (*loaders.TargetOperation, error) {
+	if n.numActions == 0 {
+		return targetOp, nil
+	}
+	opChan := make(chan *loaders.TargetOperation)
+	// some actions are defined,
+	doneCh := make(chan struct{})
+	result := &loaders.TargetOperation{
+		Add: make([]*types.TargetConfig, 0, len(targetOp.Add)),
+		Del: make([]string, 0, len(targetOp.Del)),
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Interval)
+	defer cancel()
+	// start operation gathering goroutine
+	go func() {
+		for op := range opChan {
+			result.Add = append(result.Add, op.Add...)
+			result.Del = append(result.Del, op.Del...)
+		}
+		close(doneCh)
+	}()
+	// create waitGroup and add the number of target operations to it
+	wg := new(sync.WaitGroup)
+	wg.Add(len(targetOp.Add) + len(targetOp.Del))
+	// run OnAdd actions
+	for _, tAdd := range targetOp.Add {
+		go func(tc *types.TargetConfig) {
+			defer wg.Done()
+			err := n.runOnAddActions(ctx, tc.Name, tcs)
+			if err != nil {
+				n.logger.Printf("failed running OnAdd actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Add: []*types.TargetConfig{tc}}
+		}(tAdd)
+	}
+	// run OnDelete actions
+	for _, tDel := range targetOp.Del {
+		go func(name string) {
+			defer wg.Done()
+			err := n.runOnDeleteActions(ctx, name)
+			if err != nil {
+				n.logger.Printf("failed running OnDelete actions: %v", err)
+				return
+			}
+			opChan <- &loaders.TargetOperation{Del: []string{name}}
+		}(tDel)
+	}
+	wg.Wait()
+	close(opChan)
+	<-doneCh //wait for gathering goroutine to finish
+	return result, nil
+}
+
+func (n *netboxLoader) runOnAddActions(ctx context.Context, tName string, tcs map[string]*types.TargetConfig) error {
+	aCtx := &actions.Context{
+		Input:   tName,
+		Env:     make(map[string]interface{}),
+		Vars:    n.vars,
+		Targets: tcs,
+	}
+	for _, act := range n.addActions {
+		n.logger.Printf("running action %q for target %q", act.NName(), tName)
+		res, err := act.Run(ctx, aCtx)
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+
+		aCtx.Env[act.NName()] = utils.Convert(res)
+		if n.cfg.Debug {
+			n.logger.Printf("action %q, target %q result: %+v", act.NName(), tName, res)
+			b, _ := json.MarshalIndent(aCtx, "", "  ")
+			n.logger.Printf("action %q context:\n%s", act.NName(), string(b))
+		}
+	}
+	return nil
+}
+
+func (n *netboxLoader) runOnDeleteActions(ctx context.Context, tName string) error {
+	env := make(map[string]interface{})
+	for _, act := range n.delActions {
+		res, err := act.Run(ctx, &actions.Context{Input: tName, Env: env, Vars: n.vars})
+		if err != nil {
+			return fmt.Errorf("action %q for target %q failed: %v", act.NName(), tName, err)
+		}
+		env[act.NName()] = res
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package netbox_loader
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var netboxLoaderLoadedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "netbox_loader",
+	Name:      "number_of_loaded_targets",
+	Help:      "Number of new targets successfully loaded",
+}, []string{"loader_type"})
+
+var netboxLoaderDeletedTargets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "netbox_loader",
+	Name:      "number_of_deleted_targets",
+	Help:      "Number of targets successfully deleted",
+}, []string{"loader_type"})
+
+var netboxLoaderFailedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "netbox_loader",
+	Name:      "number_of_failed_netbox_requests",
+	Help:      "Number of times a NetBox devices query failed",
+}, []string{"loader_type", "error"})
+
+var netboxLoaderRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "netbox_loader",
+	Name:      "number_of_netbox_requests_total",
+	Help:      "Number of times the loader sent a NetBox API request",
+}, []string{"loader_type"})
+
+var netboxLoaderRequestDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "netbox_loader",
+	Name:      "netbox_query_duration_ns",
+	Help:      "Duration of a NetBox devices query in ns, including all the result pages",
+}, []string{"loader_type"})
+
+func initMetrics() {
+	netboxLoaderLoadedTargets.WithLabelValues(loaderType).Set(0)
+	netboxLoaderDeletedTargets.WithLabelValues(loaderType).Set(0)
+	netboxLoaderFailedRequests.WithLabelValues(loaderType, "").Add(0)
+	netboxLoaderRequestsTotal.WithLabelValues(loaderType).Add(0)
+	netboxLoaderRequestDuration.WithLabelValues(loaderType).Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	if reg == nil {
+		return nil
+	}
+	initMetrics()
+	var err error
+	if err = reg.Register(netboxLoaderLoadedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(netboxLoaderDeletedTargets); err != nil {
+		return err
+	}
+	if err = reg.Register(netboxLoaderFailedRequests); err != nil {
+		return err
+	}
+	if err = reg.Register(netboxLoaderRequestsTotal); err != nil {
+		return err
+	}
+	if err = reg.Register(netboxLoaderRequestDuration); err != nil {
+		return err
+	}
+	return nil
+}
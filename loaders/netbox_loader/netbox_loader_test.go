@@ -0,0 +1,197 @@
+package netbox_loader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/loaders"
+	"github.com/karimra/gnmic/types"
+)
+
+var testDevices = []map[string]interface{}{
+	{
+		"id":         1,
+		"name":       "leaf1",
+		"primary_ip": map[string]interface{}{"address": "10.0.0.1/32"},
+		"platform":   map[string]interface{}{"slug": "srlinux"},
+		"role":       map[string]interface{}{"slug": "leaf"},
+		"custom_fields": map[string]interface{}{
+			"gnmi_port":          57400,
+			"gnmi_subscriptions": "interfaces, bgp",
+		},
+	},
+	{
+		"id":          2,
+		"name":        "spine1",
+		"primary_ip":  map[string]interface{}{"address": "2001:db8::2/128"},
+		"platform":    map[string]interface{}{"slug": "eos"},
+		"device_role": map[string]interface{}{"slug": "spine"},
+		"custom_fields": map[string]interface{}{
+			"gnmi_port":          nil,
+			"gnmi_subscriptions": nil,
+		},
+	},
+	{
+		"id":   3,
+		"name": "no-ip",
+	},
+}
+
+// newTestServer returns a NetBox API stand-in serving the devices one per page
+func newTestServer(t *testing.T, devices []map[string]interface{}) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != devicesPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("site") != "dc1" || r.URL.Query().Get("status") != "active" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := map[string]interface{}{
+			"count":   len(devices),
+			"results": devices[offset : offset+1],
+		}
+		if offset+1 < len(devices) {
+			q := r.URL.Query()
+			q.Set("offset", strconv.Itoa(offset+1))
+			page["next"] = srv.URL + devicesPath + "?" + q.Encode()
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	return srv
+}
+
+func TestRunOnce(t *testing.T) {
+	srv := newTestServer(t, testDevices)
+	defer srv.Close()
+
+	l := loaders.Loaders[loaderType]()
+	err := l.Init(context.TODO(), map[string]interface{}{
+		"url":    srv.URL,
+		"token":  "secret",
+		"site":   []string{"dc1"},
+		"status": []string{"active"},
+		"config": map[string]interface{}{
+			"insecure": true,
+		},
+		"mappings": []interface{}{
+			map[string]interface{}{
+				"platform": []string{"srlinux"},
+				"config": map[string]interface{}{
+					"outputs":       []string{"prom"},
+					"subscriptions": []string{"system"},
+				},
+			},
+			map[string]interface{}{
+				"role": []string{"spine"},
+				"config": map[string]interface{}{
+					"outputs": []string{"kafka"},
+				},
+			},
+		},
+		"custom-fields": map[string]string{
+			"gnmi_port":          "port",
+			"gnmi_subscriptions": "subscriptions",
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs, err := l.RunOnce(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insecure := true
+	expected := map[string]*types.TargetConfig{
+		"leaf1": {
+			Name:          "leaf1",
+			Address:       "10.0.0.1:57400",
+			Insecure:      &insecure,
+			Subscriptions: []string{"bgp", "interfaces"},
+			Outputs:       []string{"prom"},
+		},
+		"spine1": {
+			Name:     "spine1",
+			Address:  "2001:db8::2",
+			Insecure: &insecure,
+			Outputs:  []string{"kafka"},
+		},
+	}
+	if !reflect.DeepEqual(tcs, expected) {
+		t.Errorf("expected %s, got %s", expected, tcs)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	srv := newTestServer(t, testDevices[:2])
+	defer srv.Close()
+
+	l := loaders.Loaders[loaderType]()
+	err := l.Init(context.TODO(), map[string]interface{}{
+		"url":    srv.URL,
+		"token":  "secret",
+		"site":   []string{"dc1"},
+		"status": []string{"active"},
+		"template": `{{- range . }}
+{{ .name }}:
+  address: {{ .name }}.lab:6030
+{{- end }}`,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs, err := l.RunOnce(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*types.TargetConfig{
+		"leaf1":  {Name: "leaf1", Address: "leaf1.lab:6030"},
+		"spine1": {Name: "spine1", Address: "spine1.lab:6030"},
+	}
+	if !reflect.DeepEqual(tcs, expected) {
+		t.Errorf("expected %s, got %s", expected, tcs)
+	}
+}
+
+func TestStart(t *testing.T) {
+	devices := []map[string]interface{}{testDevices[0]}
+	srv := newTestServer(t, devices)
+	defer srv.Close()
+
+	l := loaders.Loaders[loaderType]()
+	l.WithTargetsDefaults(func(tc *types.TargetConfig) error { return nil })
+	err := l.Init(context.TODO(), map[string]interface{}{
+		"url":      srv.URL,
+		"token":    "secret",
+		"site":     []string{"dc1"},
+		"status":   []string{"active"},
+		"port":     "6030",
+		"interval": "1h",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	opChan := l.Start(ctx)
+	select {
+	case op := <-opChan:
+		if len(op.Add) != 1 || op.Add[0].Name != "leaf1" || len(op.Del) != 0 {
+			t.Errorf("unexpected target operation: %+v", op)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a target operation")
+	}
+}
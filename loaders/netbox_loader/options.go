@@ -0,0 +1,23 @@
+package netbox_loader
+
+import (
+	"github.com/karimra/gnmic/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (n *netboxLoader) RegisterMetrics(reg *prometheus.Registry) {
+	if !n.cfg.EnableMetrics {
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		n.logger.Printf("failed to register metrics: %v", err)
+	}
+}
+
+func (n *netboxLoader) WithActions(acts map[string]map[string]interface{}) {
+	n.actionsConfig = acts
+}
+
+func (n *netboxLoader) WithTargetsDefaults(fn func(tc *types.TargetConfig) error) {
+	n.targetConfigFn = fn
+}
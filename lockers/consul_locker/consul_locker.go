@@ -209,6 +209,18 @@ func (c *ConsulLocker) Unlock(ctx context.Context, key string) error {
 	return fmt.Errorf("unlock failed: unknown key %q", key)
 }
 
+func (c *ConsulLocker) Put(ctx context.Context, key string, val []byte) error {
+	wrOpts := new(api.WriteOptions)
+	_, err := c.client.KV().Put(&api.KVPair{Key: key, Value: val}, wrOpts.WithContext(ctx))
+	return err
+}
+
+func (c *ConsulLocker) Delete(ctx context.Context, key string) error {
+	wrOpts := new(api.WriteOptions)
+	_, err := c.client.KV().Delete(key, wrOpts.WithContext(ctx))
+	return err
+}
+
 func (c *ConsulLocker) Stop() error {
 	c.m.Lock()
 	defer c.m.Unlock()
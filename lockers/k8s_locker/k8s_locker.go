@@ -280,6 +280,46 @@ func (k *k8sLocker) unlock(ctx context.Context, key string) error {
 	return nil
 }
 
+// Put stores the key value in a lease without holder, it is not renewed
+// and is returned by List until it is deleted.
+// The value is stored as a label value, it is limited to 63 characters.
+func (k *k8sLocker) Put(ctx context.Context, key string, val []byte) error {
+	nkey := strings.ReplaceAll(key, "/", "-")
+	l := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				origKeyName: key,
+			},
+			Name:      nkey,
+			Namespace: k.Cfg.Namespace,
+			Labels: map[string]string{
+				"app": "gnmic",
+				nkey:  string(val),
+			},
+		},
+	}
+	ol, err := k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).Get(ctx, nkey, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).Create(ctx, l, metav1.CreateOptions{})
+		return err
+	}
+	l.SetResourceVersion(ol.GetResourceVersion())
+	_, err = k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).Update(ctx, l, metav1.UpdateOptions{})
+	return err
+}
+
+func (k *k8sLocker) Delete(ctx context.Context, key string) error {
+	nkey := strings.ReplaceAll(key, "/", "-")
+	err := k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).Delete(ctx, nkey, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
 func (k *k8sLocker) Stop() error {
 	k.m.Lock()
 	defer k.m.Unlock()
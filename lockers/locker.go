@@ -25,6 +25,10 @@ type Locker interface {
 	Deregister(string) error
 
 	List(context.Context, string) (map[string]string, error)
+	// Put sets a key value which is not bound to a lock:
+	// it is kept until it is deleted, even if the instance stops.
+	Put(context.Context, string, []byte) error
+	Delete(context.Context, string) error
 	GetServices(ctx context.Context, serviceName string, tags []string) ([]*Service, error)
 	WatchServices(ctx context.Context, serviceName string, tags []string, ch chan<- []*Service, dur time.Duration) error
 
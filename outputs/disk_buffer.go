@@ -0,0 +1,728 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultBufferMaxSize     = 1024 * 1024 * 1024 // 1GiB
+	defaultBufferSegmentSize = 64 * 1024 * 1024   // 64MiB
+	defaultBufferMaxRetries  = 10
+	// number of acknowledged records after which the read position is persisted
+	bufferCursorSyncEvery = 100
+
+	segmentFileSuffix = ".seg"
+	cursorFileName    = "cursor"
+	recordHeaderSize  = 8 // 4 bytes length + 4 bytes crc32
+)
+
+// BufferedEmittedMetaKey is the buffered messages metadata key marking the events
+// emitted by the event processors, they are not processed again when read from the buffer.
+const BufferedEmittedMetaKey = "__emitted"
+
+var ErrBufferClosed = errors.New("buffer closed")
+
+// BufferedOutputTypes are the output types supporting the `buffer` configuration.
+var BufferedOutputTypes = map[string]struct{}{
+	"influxdb":         {},
+	"kafka":            {},
+	"prometheus_write": {},
+}
+
+// BufferConfig is the configuration of a disk backed write-ahead buffer,
+// it can be added to an output configuration under the `buffer` field.
+type BufferConfig struct {
+	// directory where the buffer segments are stored,
+	// an existing buffer found in this directory is replayed.
+	Path string `mapstructure:"path,omitempty" json:"path,omitempty"`
+	// maximum size in bytes of the buffer on disk,
+	// when reached, the oldest segment is dropped.
+	MaxSize int64 `mapstructure:"max-size,omitempty" json:"max-size,omitempty"`
+	// size in bytes after which a new segment file is created.
+	SegmentSize int64 `mapstructure:"segment-size,omitempty" json:"segment-size,omitempty"`
+	// number of retries of a failed write before the messages are dropped,
+	// a negative value retries forever.
+	MaxRetries int `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+}
+
+type segment struct {
+	index uint64
+	size  int64
+}
+
+// DiskBuffer is a disk backed FIFO queue split into segment files.
+// Records are read with Read or ReadBatch and removed from the queue with Ack,
+// the records read but not acknowledged are read again after a restart,
+// which gives an at-least-once delivery.
+type DiskBuffer struct {
+	cfg *BufferConfig
+
+	m        *sync.Mutex
+	segments []*segment // ordered from oldest to newest
+	size     int64
+	writer   *os.File
+	reader   *os.File
+	// position of the next record to read
+	readSegment uint64
+	readOffset  int64
+	// position of the oldest record not acknowledged, persisted in the cursor file
+	ackSegment uint64
+	ackOffset  int64
+	unsynced   int
+	dropped    uint64
+	notify     chan struct{}
+	closed     bool
+	closeChan  chan struct{}
+}
+
+// NewDiskBuffer creates a DiskBuffer using the configuration cfg,
+// the segments already present in the buffer directory are replayed
+// starting from the last persisted read position.
+func NewDiskBuffer(cfg *BufferConfig) (*DiskBuffer, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, errors.New("missing buffer path")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultBufferMaxSize
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = defaultBufferSegmentSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultBufferMaxRetries
+	}
+	if cfg.SegmentSize > cfg.MaxSize {
+		cfg.SegmentSize = cfg.MaxSize
+	}
+	err := os.MkdirAll(cfg.Path, 0755)
+	if err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		cfg:       cfg,
+		m:         new(sync.Mutex),
+		notify:    make(chan struct{}, 1),
+		closeChan: make(chan struct{}),
+	}
+	err = b.loadSegments()
+	if err != nil {
+		return nil, err
+	}
+	err = b.loadCursor()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Write appends data to the buffer as a single record.
+func (b *DiskBuffer) Write(data []byte) error {
+	rec := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(data))
+	copy(rec[recordHeaderSize:], data)
+
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.closed {
+		return ErrBufferClosed
+	}
+	last := b.segments[len(b.segments)-1]
+	if last.size > 0 && last.size+int64(len(rec)) > b.cfg.SegmentSize {
+		err := b.rotate()
+		if err != nil {
+			return err
+		}
+		last = b.segments[len(b.segments)-1]
+	}
+	n, err := b.writer.Write(rec)
+	last.size += int64(n)
+	b.size += int64(n)
+	if err != nil {
+		return err
+	}
+	b.enforceMaxSize()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Read returns the next record,
+// it blocks until a record is available, ctx is done or the buffer is closed.
+func (b *DiskBuffer) Read(ctx context.Context) ([]byte, error) {
+	recs, err := b.ReadBatch(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return recs[0], nil
+}
+
+// ReadBatch returns up to max records,
+// it blocks until at least one record is available, ctx is done or the buffer is closed.
+func (b *DiskBuffer) ReadBatch(ctx context.Context, max int) ([][]byte, error) {
+	for {
+		b.m.Lock()
+		if b.closed {
+			b.m.Unlock()
+			return nil, ErrBufferClosed
+		}
+		recs := make([][]byte, 0, max)
+		for len(recs) < max {
+			data, err := b.readRecord()
+			if err != nil {
+				b.m.Unlock()
+				return nil, err
+			}
+			if data == nil {
+				break
+			}
+			recs = append(recs, data)
+		}
+		b.m.Unlock()
+		if len(recs) > 0 {
+			return recs, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-b.closeChan:
+			return nil, ErrBufferClosed
+		case <-b.notify:
+		}
+	}
+}
+
+// Ack removes the records read so far from the buffer.
+func (b *DiskBuffer) Ack() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.ackSegment == b.readSegment && b.ackOffset == b.readOffset {
+		return nil
+	}
+	b.ackSegment, b.ackOffset = b.readSegment, b.readOffset
+	// remove the fully acknowledged segments
+	var removed bool
+	for len(b.segments) > 1 && b.segments[0].index < b.ackSegment {
+		b.removeSegment(b.segments[0].index)
+		removed = true
+	}
+	b.unsynced++
+	if removed || b.unsynced >= bufferCursorSyncEvery {
+		return b.saveCursor()
+	}
+	return nil
+}
+
+// Rewind moves the read position back to the oldest record not acknowledged.
+func (b *DiskBuffer) Rewind() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.moveReader(b.ackSegment)
+	b.readOffset = b.ackOffset
+}
+
+// Size returns the size in bytes of the buffer segments.
+func (b *DiskBuffer) Size() int64 {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.size
+}
+
+// Dropped returns the number of segments dropped because the buffer max size was reached.
+func (b *DiskBuffer) Dropped() uint64 {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.dropped
+}
+
+// Close persists the read position and closes the segment files.
+func (b *DiskBuffer) Close() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	close(b.closeChan)
+	err := b.saveCursor()
+	if b.reader != nil {
+		b.reader.Close()
+	}
+	if b.writer != nil {
+		b.writer.Sync()
+		b.writer.Close()
+	}
+	return err
+}
+
+// readRecord reads the record at the current read position,
+// it returns a nil slice if no record is available.
+// it assumes the lock is acquired.
+func (b *DiskBuffer) readRecord() ([]byte, error) {
+	for {
+		seg := b.segment(b.readSegment)
+		if seg == nil {
+			// the read segment was dropped, move to the oldest one
+			b.moveReader(b.segments[0].index)
+			continue
+		}
+		if b.readOffset >= seg.size {
+			next := b.nextSegment(seg.index)
+			if next == nil {
+				return nil, nil
+			}
+			b.moveReader(next.index)
+			continue
+		}
+		if b.reader == nil {
+			f, err := os.Open(b.segmentPath(seg.index))
+			if err != nil {
+				return nil, err
+			}
+			b.reader = f
+		}
+		hdr := make([]byte, recordHeaderSize)
+		_, err := b.reader.ReadAt(hdr, b.readOffset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read buffer record header: %v", err)
+		}
+		l := int64(binary.BigEndian.Uint32(hdr[0:4]))
+		if b.readOffset+recordHeaderSize+l > seg.size {
+			// corrupted record, skip the rest of the segment
+			b.readOffset = seg.size
+			continue
+		}
+		data := make([]byte, l)
+		_, err = b.reader.ReadAt(data, b.readOffset+recordHeaderSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read buffer record: %v", err)
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(hdr[4:8]) {
+			b.readOffset = seg.size
+			continue
+		}
+		b.readOffset += int64(recordHeaderSize + len(data))
+		return data, nil
+	}
+}
+
+func (b *DiskBuffer) moveReader(index uint64) {
+	if b.reader != nil {
+		b.reader.Close()
+		b.reader = nil
+	}
+	b.readSegment = index
+	b.readOffset = 0
+}
+
+// rotate syncs and closes the current write segment and creates a new one.
+func (b *DiskBuffer) rotate() error {
+	err := b.writer.Sync()
+	if err != nil {
+		return err
+	}
+	err = b.writer.Close()
+	if err != nil {
+		return err
+	}
+	index := b.segments[len(b.segments)-1].index + 1
+	b.writer, err = os.OpenFile(b.segmentPath(index), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	b.segments = append(b.segments, &segment{index: index})
+	return nil
+}
+
+// enforceMaxSize drops the oldest segments until the buffer size
+// is lower than the configured max size, the segment being written is never dropped.
+func (b *DiskBuffer) enforceMaxSize() {
+	for b.size > b.cfg.MaxSize && len(b.segments) > 1 {
+		oldest := b.segments[0].index
+		if oldest == b.readSegment {
+			b.moveReader(b.segments[1].index)
+		}
+		if oldest == b.ackSegment {
+			b.ackSegment, b.ackOffset = b.segments[1].index, 0
+		}
+		b.removeSegment(oldest)
+		b.dropped++
+	}
+}
+
+func (b *DiskBuffer) removeSegment(index uint64) {
+	for i, seg := range b.segments {
+		if seg.index != index {
+			continue
+		}
+		if index == b.readSegment && b.reader != nil {
+			b.reader.Close()
+			b.reader = nil
+		}
+		os.Remove(b.segmentPath(index))
+		b.size -= seg.size
+		b.segments = append(b.segments[:i], b.segments[i+1:]...)
+		return
+	}
+}
+
+func (b *DiskBuffer) segment(index uint64) *segment {
+	for _, seg := range b.segments {
+		if seg.index == index {
+			return seg
+		}
+	}
+	return nil
+}
+
+func (b *DiskBuffer) nextSegment(index uint64) *segment {
+	for _, seg := range b.segments {
+		if seg.index > index {
+			return seg
+		}
+	}
+	return nil
+}
+
+func (b *DiskBuffer) segmentPath(index uint64) string {
+	return filepath.Join(b.cfg.Path, fmt.Sprintf("%020d%s", index, segmentFileSuffix))
+}
+
+// loadSegments lists the existing segments, truncates the last one
+// after its last valid record and opens it for writing.
+func (b *DiskBuffer) loadSegments() error {
+	entries, err := os.ReadDir(b.cfg.Path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentFileSuffix) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		b.segments = append(b.segments, &segment{index: index, size: fi.Size()})
+	}
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].index < b.segments[j].index
+	})
+	if len(b.segments) == 0 {
+		b.segments = append(b.segments, &segment{index: 0})
+	}
+	last := b.segments[len(b.segments)-1]
+	last.size, err = validSize(b.segmentPath(last.index))
+	if err != nil {
+		return err
+	}
+	b.writer, err = os.OpenFile(b.segmentPath(last.index), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	err = b.writer.Truncate(last.size)
+	if err != nil {
+		return err
+	}
+	_, err = b.writer.Seek(last.size, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	for _, seg := range b.segments {
+		b.size += seg.size
+	}
+	b.readSegment = b.segments[0].index
+	b.ackSegment = b.readSegment
+	return nil
+}
+
+// validSize returns the size of the segment file up to its last complete and valid record.
+func validSize(name string) (int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	hdr := make([]byte, recordHeaderSize)
+	for {
+		_, err = f.ReadAt(hdr, offset)
+		if err != nil {
+			return offset, nil
+		}
+		l := int64(binary.BigEndian.Uint32(hdr[0:4]))
+		if offset+recordHeaderSize+l > fi.Size() {
+			return offset, nil
+		}
+		data := make([]byte, l)
+		_, err = f.ReadAt(data, offset+recordHeaderSize)
+		if err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(hdr[4:8]) {
+			return offset, nil
+		}
+		offset += int64(recordHeaderSize + len(data))
+	}
+}
+
+func (b *DiskBuffer) loadCursor() error {
+	data, err := os.ReadFile(filepath.Join(b.cfg.Path, cursorFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var index uint64
+	var offset int64
+	_, err = fmt.Sscanf(string(data), "%d %d", &index, &offset)
+	if err != nil {
+		return fmt.Errorf("invalid buffer cursor file: %v", err)
+	}
+	seg := b.segment(index)
+	if seg == nil {
+		// the segment was consumed or dropped
+		return nil
+	}
+	b.readSegment, b.ackSegment = index, index
+	if offset <= seg.size {
+		b.readOffset, b.ackOffset = offset, offset
+	}
+	// drop the segments preceding the read segment
+	for b.segments[0].index < index {
+		b.removeSegment(b.segments[0].index)
+	}
+	return nil
+}
+
+// saveCursor persists the read position.
+// it assumes the lock is acquired.
+func (b *DiskBuffer) saveCursor() error {
+	b.unsynced = 0
+	name := filepath.Join(b.cfg.Path, cursorFileName)
+	tmp := name + ".tmp"
+	err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d", b.ackSegment, b.ackOffset)), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+const (
+	bufferedSubscribeResponse byte = iota
+	bufferedEvent
+)
+
+// EncodeBufferedMsg encodes a proto message or an event along with its metadata
+// into a DiskBuffer record.
+func EncodeBufferedMsg(msg proto.Message, ev *formatters.EventMsg, meta Meta) ([]byte, error) {
+	var kind byte
+	var payload []byte
+	var err error
+	switch {
+	case msg != nil:
+		rsp, ok := msg.(*gnmi.SubscribeResponse)
+		if !ok {
+			return nil, fmt.Errorf("unexpected message type %T", msg)
+		}
+		kind = bufferedSubscribeResponse
+		payload, err = proto.Marshal(rsp)
+	case ev != nil:
+		kind = bufferedEvent
+		payload, err = json.Marshal(&bufferedEventMsg{EventMsg: ev, ValueTypes: valueTypes(ev.Values)})
+	default:
+		return nil, errors.New("missing message")
+	}
+	if err != nil {
+		return nil, err
+	}
+	mb, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	rec := make([]byte, 5, 5+len(mb)+len(payload))
+	rec[0] = kind
+	binary.BigEndian.PutUint32(rec[1:5], uint32(len(mb)))
+	rec = append(rec, mb...)
+	return append(rec, payload...), nil
+}
+
+// DecodeBufferedMsg decodes a DiskBuffer record created with EncodeBufferedMsg,
+// it returns either a *gnmi.SubscribeResponse or an *formatters.EventMsg, and the message metadata.
+func DecodeBufferedMsg(rec []byte) (*gnmi.SubscribeResponse, *formatters.EventMsg, Meta, error) {
+	if len(rec) < 5 {
+		return nil, nil, nil, errors.New("buffered message too short")
+	}
+	ml := int(binary.BigEndian.Uint32(rec[1:5]))
+	if len(rec) < 5+ml {
+		return nil, nil, nil, errors.New("invalid buffered message metadata length")
+	}
+	meta := make(Meta)
+	err := json.Unmarshal(rec[5:5+ml], &meta)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	payload := rec[5+ml:]
+	switch rec[0] {
+	case bufferedSubscribeResponse:
+		rsp := new(gnmi.SubscribeResponse)
+		err = proto.Unmarshal(payload, rsp)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return rsp, nil, meta, nil
+	case bufferedEvent:
+		bev := &bufferedEventMsg{EventMsg: new(formatters.EventMsg)}
+		dec := json.NewDecoder(bytes.NewReader(payload))
+		dec.UseNumber()
+		err = dec.Decode(bev)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for k, v := range bev.Values {
+			bev.Values[k] = typedValue(v, bev.ValueTypes[k])
+		}
+		return nil, bev.EventMsg, meta, nil
+	}
+	return nil, nil, nil, fmt.Errorf("unknown buffered message kind %d", rec[0])
+}
+
+// DecodeBufferedEvents decodes a DiskBuffer record created with EncodeBufferedMsg
+// and converts it into events, applying the event processors evps,
+// unless the record is an event already emitted by them.
+func DecodeBufferedEvents(rec []byte, evps ...formatters.EventProcessor) ([]*formatters.EventMsg, Meta, error) {
+	rsp, ev, meta, err := DecodeBufferedMsg(rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rsp != nil {
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		evs, err := formatters.ResponseToEventMsgs(measName, rsp, meta, evps...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return evs, meta, nil
+	}
+	evs := []*formatters.EventMsg{ev}
+	if meta[BufferedEmittedMetaKey] == "true" {
+		return evs, meta, nil
+	}
+	for _, proc := range evps {
+		evs = proc.Apply(evs...)
+	}
+	return evs, meta, nil
+}
+
+// bufferedEventMsg is the buffered form of an event,
+// it carries the types of the numeric values, which JSON does not preserve.
+type bufferedEventMsg struct {
+	*formatters.EventMsg
+	ValueTypes map[string]string `json:"value-types,omitempty"`
+}
+
+// valueTypes returns the type names of the numeric values.
+func valueTypes(values map[string]interface{}) map[string]string {
+	vt := make(map[string]string)
+	for k, v := range values {
+		switch v.(type) {
+		case int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64,
+			float32:
+			vt[k] = fmt.Sprintf("%T", v)
+		}
+	}
+	return vt
+}
+
+// typedValue converts a value decoded with json.Decoder.UseNumber
+// back to its type typ, numbers without a type are returned as float64.
+func typedValue(v interface{}, typ string) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		switch typ {
+		case "int", "int8", "int16", "int32", "int64":
+			i, err := strconv.ParseInt(v.String(), 10, 64)
+			if err != nil {
+				break
+			}
+			switch typ {
+			case "int":
+				return int(i)
+			case "int8":
+				return int8(i)
+			case "int16":
+				return int16(i)
+			case "int32":
+				return int32(i)
+			}
+			return i
+		case "uint", "uint8", "uint16", "uint32", "uint64":
+			u, err := strconv.ParseUint(v.String(), 10, 64)
+			if err != nil {
+				break
+			}
+			switch typ {
+			case "uint":
+				return uint(u)
+			case "uint8":
+				return uint8(u)
+			case "uint16":
+				return uint16(u)
+			case "uint32":
+				return uint32(u)
+			}
+			return u
+		case "float32":
+			f, err := strconv.ParseFloat(v.String(), 32)
+			if err != nil {
+				break
+			}
+			return float32(f)
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = typedValue(v[i], "")
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = typedValue(v[k], "")
+		}
+	}
+	return v
+}
@@ -0,0 +1,217 @@
+package outputs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDiskBufferReplay(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewDiskBuffer(&BufferConfig{Path: dir, SegmentSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		err = b.Write([]byte(fmt.Sprintf("msg%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentFileSuffix))
+	if len(segs) < 2 {
+		t.Fatalf("expected the segments to be rotated, got %d segment(s)", len(segs))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	recs, err := b.ReadBatch(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 4 || string(recs[3]) != "msg3" {
+		t.Fatalf("unexpected batch: %q", recs)
+	}
+	err = b.Ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// read without ack
+	_, err = b.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen, the non acknowledged records are replayed
+	b, err = NewDiskBuffer(&BufferConfig{Path: dir, SegmentSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for i := 4; i < 10; i++ {
+		rec, err := b.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rec) != fmt.Sprintf("msg%d", i) {
+			t.Fatalf("expected msg%d, got %q", i, rec)
+		}
+	}
+	err = b.Ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, _ = filepath.Glob(filepath.Join(dir, "*"+segmentFileSuffix))
+	if len(segs) != 1 {
+		t.Errorf("expected the acknowledged segments to be deleted, got %d segments", len(segs))
+	}
+	// blocked read is released by a write
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Write([]byte("late"))
+	}()
+	rec, err := b.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rec) != "late" {
+		t.Errorf("expected %q, got %q", "late", rec)
+	}
+}
+
+func TestDiskBufferMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewDiskBuffer(&BufferConfig{Path: dir, SegmentSize: 32, MaxSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for i := 0; i < 20; i++ {
+		err = b.Write([]byte(fmt.Sprintf("msg%02d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.Size() > 64 {
+		t.Errorf("buffer size %d exceeds the max size", b.Size())
+	}
+	if b.Dropped() == 0 {
+		t.Errorf("expected dropped segments")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rec, err := b.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rec) == "msg00" {
+		t.Errorf("expected the oldest records to be dropped")
+	}
+}
+
+func TestDiskBufferTruncatedSegment(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewDiskBuffer(&BufferConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Write([]byte("msg0"))
+	b.Write([]byte("msg1"))
+	b.Close()
+	// simulate a partially written record
+	name := filepath.Join(dir, fmt.Sprintf("%020d%s", 0, segmentFileSuffix))
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 10, 1, 2})
+	f.Close()
+
+	b, err = NewDiskBuffer(&BufferConfig{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.Write([]byte("msg2"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	recs, err := b.ReadBatch(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || string(recs[2]) != "msg2" {
+		t.Errorf("unexpected records: %q", recs)
+	}
+}
+
+func TestBufferedMsg(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{Timestamp: 42},
+		},
+	}
+	rec, err := EncodeBufferedMsg(rsp, nil, Meta{"source": "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drsp, ev, meta, err := DecodeBufferedMsg(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev != nil || !proto.Equal(rsp, drsp) || meta["source"] != "t1" {
+		t.Errorf("unexpected decoded message: %v, %v, %v", drsp, ev, meta)
+	}
+	rec, err = EncodeBufferedMsg(nil, &formatters.EventMsg{Name: "ev1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drsp, ev, _, err = DecodeBufferedMsg(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drsp != nil || ev == nil || ev.Name != "ev1" {
+		t.Errorf("unexpected decoded event: %v", ev)
+	}
+}
+
+func TestBufferedEventValueTypes(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "ev1",
+		Timestamp: 42,
+		Values: map[string]interface{}{
+			"in-octets":  int64(9007199254740993),
+			"out-octets": uint64(18446744073709551615),
+			"mtu":        uint32(1500),
+			"temp":       float64(42),
+			"rate":       float32(0.5),
+			"name":       "eth0",
+			"up":         true,
+		},
+	}
+	rec, err := EncodeBufferedMsg(nil, ev, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, dev, _, err := DecodeBufferedMsg(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Name != ev.Name || dev.Timestamp != ev.Timestamp {
+		t.Errorf("unexpected decoded event: %+v", dev)
+	}
+	for k, v := range ev.Values {
+		if dev.Values[k] != v {
+			t.Errorf("value %q: expected %v (%T), got %v (%T)", k, v, v, dev.Values[k], dev.Values[k])
+		}
+	}
+}
@@ -92,6 +92,7 @@ func (f *File) SetEventProcessors(ps map[string]map[string]interface{},
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
 					formatters.WithActions(acts),
+					formatters.WithProcessors(ps),
 				)
 				if err != nil {
 					f.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
@@ -192,6 +193,13 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 		f.msgTpl = f.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if formatters.HasEmitters(f.evps) {
+		if f.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, f.evps, f.writeEvents)
+		} else {
+			f.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", f.Cfg.Format)
+		}
+	}
 	f.logger.Printf("initialized file output: %s", f.String())
 	go func() {
 		<-ctx.Done()
@@ -229,7 +237,24 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 		numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "marshal_error").Inc()
 		return
 	}
+	f.write(b)
+}
+
+// writeEvents writes the events emitted by the event processors.
+func (f *File) writeEvents(evs ...*formatters.EventMsg) {
+	b, err := f.mo.MarshalEvents(evs)
+	if err != nil {
+		if f.Cfg.Debug {
+			f.logger.Printf("failed marshaling events: %v", err)
+		}
+		numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "marshal_error").Inc()
+		return
+	}
+	f.write(b)
+}
 
+func (f *File) write(b []byte) {
+	var err error
 	if f.msgTpl != nil && len(b) > 0 {
 		b, err = outputs.ExecTemplate(b, f.msgTpl)
 		if err != nil {
@@ -258,6 +283,7 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 // Close //
 func (f *File) Close() error {
 	f.logger.Printf("closing file '%s' output", f.file.Name())
+	formatters.CloseProcessors(f.evps)
 	return f.file.Close()
 }
 
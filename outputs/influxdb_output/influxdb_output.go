@@ -4,15 +4,19 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"net/http"
 	"strings"
 	"text/template"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/karimra/gnmic/cache"
 	"github.com/karimra/gnmic/formatters"
 	"github.com/karimra/gnmic/outputs"
@@ -29,6 +33,7 @@ const (
 	defaultFlushTimer        = 10 * time.Second
 	defaultHealthCheckPeriod = 30 * time.Second
 	defaultCacheFlushTimer   = 5 * time.Second
+	bufferRetryTimer         = 5 * time.Second
 
 	numWorkers    = 1
 	loggingPrefix = "[influxdb_output:%s] "
@@ -63,25 +68,28 @@ type InfluxDBOutput struct {
 	gnmiCache   cache.Cache
 	cacheTicker *time.Ticker
 	done        chan struct{}
+
+	buffer *outputs.DiskBuffer
 }
 type Config struct {
-	URL                string        `mapstructure:"url,omitempty"`
-	Org                string        `mapstructure:"org,omitempty"`
-	Bucket             string        `mapstructure:"bucket,omitempty"`
-	Token              string        `mapstructure:"token,omitempty"`
-	BatchSize          uint          `mapstructure:"batch-size,omitempty"`
-	FlushTimer         time.Duration `mapstructure:"flush-timer,omitempty"`
-	UseGzip            bool          `mapstructure:"use-gzip,omitempty"`
-	EnableTLS          bool          `mapstructure:"enable-tls,omitempty"`
-	HealthCheckPeriod  time.Duration `mapstructure:"health-check-period,omitempty"`
-	Debug              bool          `mapstructure:"debug,omitempty"`
-	AddTarget          string        `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string        `mapstructure:"target-template,omitempty"`
-	EventProcessors    []string      `mapstructure:"event-processors,omitempty"`
-	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty"`
-	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
-	CacheConfig        *cache.Config `mapstructure:"cache,omitempty"`
-	CacheFlushTimer    time.Duration `mapstructure:"cache-flush-timer,omitempty"`
+	URL                string                `mapstructure:"url,omitempty"`
+	Org                string                `mapstructure:"org,omitempty"`
+	Bucket             string                `mapstructure:"bucket,omitempty"`
+	Token              string                `mapstructure:"token,omitempty"`
+	BatchSize          uint                  `mapstructure:"batch-size,omitempty"`
+	FlushTimer         time.Duration         `mapstructure:"flush-timer,omitempty"`
+	UseGzip            bool                  `mapstructure:"use-gzip,omitempty"`
+	EnableTLS          bool                  `mapstructure:"enable-tls,omitempty"`
+	HealthCheckPeriod  time.Duration         `mapstructure:"health-check-period,omitempty"`
+	Debug              bool                  `mapstructure:"debug,omitempty"`
+	AddTarget          string                `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                `mapstructure:"target-template,omitempty"`
+	EventProcessors    []string              `mapstructure:"event-processors,omitempty"`
+	EnableMetrics      bool                  `mapstructure:"enable-metrics,omitempty"`
+	OverrideTimestamps bool                  `mapstructure:"override-timestamps,omitempty"`
+	CacheConfig        *cache.Config         `mapstructure:"cache,omitempty"`
+	CacheFlushTimer    time.Duration         `mapstructure:"cache-flush-timer,omitempty"`
+	Buffer             *outputs.BufferConfig `mapstructure:"buffer,omitempty"`
 }
 
 func (k *InfluxDBOutput) String() string {
@@ -115,7 +123,8 @@ func (i *InfluxDBOutput) SetEventProcessors(ps map[string]map[string]interface{}
 				err := ep.Init(epCfg[epType],
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					i.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -153,6 +162,15 @@ func (i *InfluxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 	if i.Cfg.HealthCheckPeriod == 0 {
 		i.Cfg.HealthCheckPeriod = defaultHealthCheckPeriod
 	}
+	if i.Cfg.Buffer != nil {
+		if i.Cfg.CacheConfig != nil {
+			return fmt.Errorf("buffer and cache cannot be used together")
+		}
+		i.buffer, err = outputs.NewDiskBuffer(i.Cfg.Buffer)
+		if err != nil {
+			return fmt.Errorf("failed to create buffer: %v", err)
+		}
+	}
 	if i.Cfg.CacheConfig != nil {
 		if i.Cfg.CacheFlushTimer == 0 {
 			i.Cfg.CacheFlushTimer = defaultCacheFlushTimer
@@ -198,9 +216,32 @@ CRCLIENT:
 	go i.healthCheck(ctx)
 	i.logger.Printf("initialized influxdb client: %s", i.String())
 
-	for k := 0; k < numWorkers; k++ {
-		go i.worker(ctx, k)
+	if i.buffer != nil {
+		go i.bufferWorker(ctx)
+	} else {
+		for k := 0; k < numWorkers; k++ {
+			go i.worker(ctx, k)
+		}
 	}
+	formatters.StartEmitters(ctx, i.evps, func(evs ...*formatters.EventMsg) {
+		if i.buffer != nil {
+			// the emitted events already went through the event processors,
+			// they are marked as such in the buffer.
+			for _, ev := range evs {
+				i.writeBuffer(nil, ev, outputs.Meta{outputs.BufferedEmittedMetaKey: "true"})
+			}
+			return
+		}
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case <-i.reset:
+				return
+			case i.eventChan <- ev:
+			}
+		}
+	})
 	go func() {
 		<-ctx.Done()
 		i.Close()
@@ -227,6 +268,10 @@ func (i *InfluxDBOutput) Write(ctx context.Context, rsp proto.Message, meta outp
 			i.gnmiCache.Write(ctx, measName, rsp)
 			return
 		}
+		if i.buffer != nil {
+			i.writeBuffer(rsp, nil, meta)
+			return
+		}
 		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, i.evps...)
 		if err != nil {
 			i.logger.Printf("failed to convert message to event: %v", err)
@@ -251,6 +296,10 @@ func (i *InfluxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg
 	case <-i.reset:
 		return
 	default:
+		if i.buffer != nil {
+			i.writeBuffer(nil, ev, nil)
+			return
+		}
 		var evs = []*formatters.EventMsg{ev}
 		for _, proc := range i.evps {
 			evs = proc.Apply(evs...)
@@ -267,6 +316,10 @@ func (i *InfluxDBOutput) Close() error {
 		i.stopCache()
 	}
 	i.cancelFn()
+	formatters.CloseProcessors(i.evps)
+	if i.buffer != nil {
+		i.buffer.Close()
+	}
 	i.logger.Printf("closed.")
 	return nil
 }
@@ -343,17 +396,7 @@ START:
 			if len(ev.Values) == 0 {
 				continue
 			}
-			for n, v := range ev.Values {
-				switch v := v.(type) {
-				case *gnmi.Decimal64:
-					ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
-				}
-			}
-			if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
-				ev.Timestamp = time.Now().UnixNano()
-			}
-			i.convertUints(ev)
-			writer.WritePoint(influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp)))
+			writer.WritePoint(i.eventToPoint(ev))
 		case <-i.reset:
 			firstStart = false
 			i.logger.Printf("resetting worker-%d...", idx)
@@ -364,6 +407,120 @@ START:
 	}
 }
 
+func (i *InfluxDBOutput) eventToPoint(ev *formatters.EventMsg) *write.Point {
+	for n, v := range ev.Values {
+		switch v := v.(type) {
+		case *gnmi.Decimal64:
+			ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
+		}
+	}
+	if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
+		ev.Timestamp = time.Now().UnixNano()
+	}
+	i.convertUints(ev)
+	return influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp))
+}
+
+func (i *InfluxDBOutput) writeBuffer(rsp proto.Message, ev *formatters.EventMsg, meta outputs.Meta) {
+	rec, err := outputs.EncodeBufferedMsg(rsp, ev, meta)
+	if err != nil {
+		i.logger.Printf("failed to encode message: %v", err)
+		return
+	}
+	err = i.buffer.Write(rec)
+	if err != nil {
+		i.logger.Printf("failed to write message to buffer: %v", err)
+	}
+}
+
+// bufferWorker reads batches of messages from the buffer and writes them to influxdb,
+// a batch is acknowledged once it is successfully written.
+// Transport errors, server errors and rate limiting are retried up to
+// the buffer max-retries, other errors drop the batch.
+func (i *InfluxDBOutput) bufferWorker(ctx context.Context) {
+	i.logger.Printf("starting buffer worker")
+	writer := i.client.WriteAPIBlocking(i.Cfg.Org, i.Cfg.Bucket)
+	for {
+		recs, err := i.buffer.ReadBatch(ctx, int(i.Cfg.BatchSize))
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, outputs.ErrBufferClosed) {
+				i.logger.Printf("buffer worker terminating...")
+				return
+			}
+			i.logger.Printf("failed to read from buffer: %v", err)
+			time.Sleep(bufferRetryTimer)
+			continue
+		}
+		points := make([]*write.Point, 0, len(recs))
+		for _, rec := range recs {
+			points = append(points, i.bufferedPoints(rec)...)
+		}
+		for attempt := 1; len(points) > 0; attempt++ {
+			err = writer.WritePoint(ctx, points...)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if !retryableWriteError(err) {
+				i.logger.Printf("buffer worker write error: %v, dropping %d point(s)", err, len(points))
+				break
+			}
+			if i.Cfg.Buffer.MaxRetries >= 0 && attempt > i.Cfg.Buffer.MaxRetries {
+				i.logger.Printf("buffer worker write error: %v, max retries reached, dropping %d point(s)", err, len(points))
+				break
+			}
+			i.logger.Printf("buffer worker write error: %v, retrying in %s", err, bufferRetryTimer)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(bufferRetryTimer):
+			}
+		}
+		err = i.buffer.Ack()
+		if err != nil {
+			i.logger.Printf("failed to acknowledge buffered messages: %v", err)
+		}
+	}
+}
+
+// retryableWriteError returns true if the write error is a transport error,
+// a server error or a rate limiting response.
+func retryableWriteError(err error) bool {
+	var herr *influxhttp.Error
+	if !errors.As(err, &herr) {
+		return true
+	}
+	switch {
+	case herr.StatusCode == 0:
+		return true
+	case herr.StatusCode == http.StatusTooManyRequests:
+		return true
+	case herr.StatusCode >= http.StatusInternalServerError:
+		return true
+	}
+	return false
+}
+
+// bufferedPoints converts a buffered message into influxdb points,
+// applying the output event processors.
+func (i *InfluxDBOutput) bufferedPoints(rec []byte) []*write.Point {
+	evs, _, err := outputs.DecodeBufferedEvents(rec, i.evps...)
+	if err != nil {
+		i.logger.Printf("failed to decode buffered message: %v", err)
+		return nil
+	}
+	points := make([]*write.Point, 0, len(evs))
+	for _, ev := range evs {
+		if len(ev.Values) == 0 {
+			continue
+		}
+		points = append(points, i.eventToPoint(ev))
+	}
+	return points
+}
+
 func (i *InfluxDBOutput) SetName(name string)                             {}
 func (i *InfluxDBOutput) SetClusterName(name string)                      {}
 func (i *InfluxDBOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
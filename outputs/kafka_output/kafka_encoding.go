@@ -0,0 +1,251 @@
+package kafka_output
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// avroEventSchema is the Avro schema registered for events,
+// values are stored as a union of the supported scalar types.
+const avroEventSchema = `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "gnmic",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "timestamp", "type": "long"},
+    {"name": "tags", "type": {"type": "map", "values": "string"}},
+    {"name": "values", "type": {"type": "map", "values": ["null", "boolean", "long", "double", "string"]}},
+    {"name": "deletes", "type": {"type": "array", "items": "string"}}
+  ]
+}`
+
+// protobufEventSchema is the Protobuf schema registered for events,
+// Event must remain the first message of the schema.
+const protobufEventSchema = `syntax = "proto3";
+package gnmic;
+
+message Event {
+  string name = 1;
+  int64 timestamp = 2;
+  map<string, string> tags = 3;
+  map<string, Value> values = 4;
+  repeated string deletes = 5;
+}
+
+message Value {
+  oneof value {
+    bool bool_val = 1;
+    int64 int_val = 2;
+    double double_val = 3;
+    string string_val = 4;
+  }
+}
+`
+
+// avro union branches of the values map
+const (
+	avroNull = iota
+	avroBoolean
+	avroLong
+	avroDouble
+	avroString
+)
+
+// encodeEvent encodes ev using the configured schema registry encoding
+func encodeEvent(encoding string, ev *formatters.EventMsg) []byte {
+	switch encoding {
+	case encodingProtobuf:
+		return encodeProtobufEvent(ev)
+	default:
+		return encodeAvroEvent(ev)
+	}
+}
+
+func encodeAvroEvent(ev *formatters.EventMsg) []byte {
+	b := make([]byte, 0, 128)
+	b = appendAvroString(b, ev.Name)
+	b = appendAvroLong(b, ev.Timestamp)
+	// tags
+	tagNames := sortedTagNames(ev.Tags)
+	if len(tagNames) > 0 {
+		b = appendAvroLong(b, int64(len(tagNames)))
+		for _, k := range tagNames {
+			b = appendAvroString(b, k)
+			b = appendAvroString(b, ev.Tags[k])
+		}
+	}
+	b = appendAvroLong(b, 0)
+	// values
+	valueNames := sortedValueNames(ev.Values)
+	if len(valueNames) > 0 {
+		b = appendAvroLong(b, int64(len(valueNames)))
+		for _, k := range valueNames {
+			b = appendAvroString(b, k)
+			switch v := scalarValue(ev.Values[k]).(type) {
+			case nil:
+				b = appendAvroLong(b, avroNull)
+			case bool:
+				b = appendAvroLong(b, avroBoolean)
+				if v {
+					b = append(b, 1)
+				} else {
+					b = append(b, 0)
+				}
+			case int64:
+				b = appendAvroLong(b, avroLong)
+				b = appendAvroLong(b, v)
+			case float64:
+				b = appendAvroLong(b, avroDouble)
+				var buf [8]byte
+				binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+				b = append(b, buf[:]...)
+			case string:
+				b = appendAvroLong(b, avroString)
+				b = appendAvroString(b, v)
+			}
+		}
+	}
+	b = appendAvroLong(b, 0)
+	// deletes
+	if len(ev.Deletes) > 0 {
+		b = appendAvroLong(b, int64(len(ev.Deletes)))
+		for _, d := range ev.Deletes {
+			b = appendAvroString(b, d)
+		}
+	}
+	return appendAvroLong(b, 0)
+}
+
+func appendAvroLong(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = appendAvroLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+func encodeProtobufEvent(ev *formatters.EventMsg) []byte {
+	b := make([]byte, 0, 128)
+	if ev.Name != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, ev.Name)
+	}
+	if ev.Timestamp != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(ev.Timestamp))
+	}
+	for _, k := range sortedTagNames(ev.Tags) {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, ev.Tags[k])
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	for _, k := range sortedValueNames(ev.Values) {
+		var val []byte
+		switch v := scalarValue(ev.Values[k]).(type) {
+		case bool:
+			val = protowire.AppendTag(val, 1, protowire.VarintType)
+			val = protowire.AppendVarint(val, protowire.EncodeBool(v))
+		case int64:
+			val = protowire.AppendTag(val, 2, protowire.VarintType)
+			val = protowire.AppendVarint(val, uint64(v))
+		case float64:
+			val = protowire.AppendTag(val, 3, protowire.Fixed64Type)
+			val = protowire.AppendFixed64(val, math.Float64bits(v))
+		case string:
+			val = protowire.AppendTag(val, 4, protowire.BytesType)
+			val = protowire.AppendString(val, v)
+		}
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, val)
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	for _, d := range ev.Deletes {
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendString(b, d)
+	}
+	return b
+}
+
+// scalarValue converts an event value to one of nil, bool, int64, float64 or string.
+// Values that do not map to a scalar are JSON encoded.
+func scalarValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		return v
+	case string:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return scalarValue(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return float64(v)
+		}
+		return int64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case *gnmi.Decimal64:
+		return float64(v.Digits) / math.Pow10(int(v.Precision))
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func sortedTagNames(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedValueNames(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
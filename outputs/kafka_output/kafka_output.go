@@ -19,6 +19,7 @@ import (
 	"github.com/karimra/gnmic/outputs"
 	"github.com/karimra/gnmic/types"
 	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/protobuf/proto"
 )
@@ -31,6 +32,7 @@ const (
 	defaultFormat           = "event"
 	defaultRecoveryWaitTime = 10 * time.Second
 	defaultAddress          = "localhost:9092"
+	bufferBatchSize         = 100
 	loggingPrefix           = "[kafka_output:%s] "
 )
 
@@ -51,17 +53,24 @@ type KafkaOutput struct {
 	mo       *formatters.MarshalOptions
 	cancelFn context.CancelFunc
 	msgChan  chan *outputs.ProtoMsg
+	evChan   chan []*formatters.EventMsg
 	wg       *sync.WaitGroup
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
 	msgTpl    *template.Template
+	keyTpl    *template.Template
+	topicTpl  *template.Template
+	registry  *schemaRegistry
+	buffer    *outputs.DiskBuffer
 }
 
 // Config //
 type Config struct {
 	Address            string        `mapstructure:"address,omitempty"`
 	Topic              string        `mapstructure:"topic,omitempty"`
+	TopicTemplate      string        `mapstructure:"topic-template,omitempty"`
+	KeyTemplate        string        `mapstructure:"key-template,omitempty"`
 	Name               string        `mapstructure:"name,omitempty"`
 	SASL               *sasl         `mapstructure:"sasl,omitempty"`
 	TLS                *tlsConfig    `mapstructure:"tls,omitempty"`
@@ -78,6 +87,9 @@ type Config struct {
 	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
 	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty"`
 	EventProcessors    []string      `mapstructure:"event-processors,omitempty"`
+
+	SchemaRegistry *schemaRegistryConfig `mapstructure:"schema-registry,omitempty"`
+	Buffer         *outputs.BufferConfig `mapstructure:"buffer,omitempty"`
 }
 type sasl struct {
 	User      string `mapstructure:"user,omitempty"`
@@ -125,6 +137,7 @@ func (k *KafkaOutput) SetEventProcessors(ps map[string]map[string]interface{},
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
 					formatters.WithActions(acts),
+					formatters.WithProcessors(ps),
 				)
 				if err != nil {
 					k.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
@@ -158,6 +171,7 @@ func (k *KafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 		return err
 	}
 	k.msgChan = make(chan *outputs.ProtoMsg, uint(k.Cfg.BufferSize))
+	k.evChan = make(chan []*formatters.EventMsg)
 	k.mo = &formatters.MarshalOptions{
 		Format:     k.Cfg.Format,
 		OverrideTS: k.Cfg.OverrideTimestamps,
@@ -181,16 +195,70 @@ func (k *KafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 		k.msgTpl = k.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if k.Cfg.KeyTemplate != "" {
+		k.keyTpl, err = utils.CreateTemplate("key-template", k.Cfg.KeyTemplate)
+		if err != nil {
+			return err
+		}
+		k.keyTpl = k.keyTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	if k.Cfg.TopicTemplate != "" {
+		k.topicTpl, err = utils.CreateTemplate("topic-template", k.Cfg.TopicTemplate)
+		if err != nil {
+			return err
+		}
+		k.topicTpl = k.topicTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	if k.Cfg.SchemaRegistry != nil {
+		k.registry, err = newSchemaRegistry(k.Cfg.SchemaRegistry)
+		if err != nil {
+			return err
+		}
+	}
+
 	config, err := k.createConfig()
 	if err != nil {
 		return err
 	}
+	if k.Cfg.Buffer != nil {
+		k.buffer, err = outputs.NewDiskBuffer(k.Cfg.Buffer)
+		if err != nil {
+			return fmt.Errorf("failed to create buffer: %v", err)
+		}
+	}
 	ctx, k.cancelFn = context.WithCancel(ctx)
-	k.wg.Add(k.Cfg.NumWorkers)
-	for i := 0; i < k.Cfg.NumWorkers; i++ {
-		cfg := *config
-		cfg.ClientID = fmt.Sprintf("%s-%d", config.ClientID, i)
-		go k.worker(ctx, i, &cfg)
+	if k.buffer != nil {
+		k.wg.Add(1)
+		go k.bufferWorker(ctx, config)
+	} else {
+		k.wg.Add(k.Cfg.NumWorkers)
+		for i := 0; i < k.Cfg.NumWorkers; i++ {
+			cfg := *config
+			cfg.ClientID = fmt.Sprintf("%s-%d", config.ClientID, i)
+			go k.worker(ctx, i, &cfg)
+		}
+	}
+	if formatters.HasEmitters(k.evps) {
+		if k.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, k.evps, func(evs ...*formatters.EventMsg) {
+				if k.buffer != nil {
+					// the emitted events already went through the event processors,
+					// they are marked as such in the buffer.
+					for _, ev := range evs {
+						k.writeBuffer(nil, ev, outputs.Meta{outputs.BufferedEmittedMetaKey: "true"})
+					}
+					return
+				}
+				select {
+				case <-ctx.Done():
+				case k.evChan <- evs:
+				}
+			})
+		} else {
+			k.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", k.Cfg.Format)
+		}
 	}
 	go func() {
 		<-ctx.Done()
@@ -227,6 +295,18 @@ func (k *KafkaOutput) setDefaults() error {
 	if k.Cfg.Name == "" {
 		k.Cfg.Name = "gnmic-" + uuid.New().String()
 	}
+	if k.Cfg.SchemaRegistry != nil {
+		if k.Cfg.Format != "event" {
+			return fmt.Errorf("schema-registry requires format 'event', got '%s'", k.Cfg.Format)
+		}
+		if k.Cfg.MsgTemplate != "" {
+			return errors.New("msg-template cannot be used together with schema-registry")
+		}
+		err := k.Cfg.SchemaRegistry.setDefaults()
+		if err != nil {
+			return err
+		}
+	}
 	if k.Cfg.SASL == nil {
 		return nil
 	}
@@ -248,6 +328,10 @@ func (k *KafkaOutput) Write(ctx context.Context, rsp proto.Message, meta outputs
 		return
 	}
 
+	if k.buffer != nil {
+		k.writeBuffer(rsp, nil, meta)
+		return
+	}
 	wctx, cancel := context.WithTimeout(ctx, k.Cfg.Timeout)
 	defer cancel()
 
@@ -272,6 +356,10 @@ func (k *KafkaOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 func (k *KafkaOutput) Close() error {
 	k.cancelFn()
 	k.wg.Wait()
+	formatters.CloseProcessors(k.evps)
+	if k.buffer != nil {
+		k.buffer.Close()
+	}
 	return nil
 }
 
@@ -301,68 +389,361 @@ CRPROD:
 	defer producer.Close()
 	k.logger.Printf("%s initialized kafka producer: %s", workerLogPrefix, k.String())
 	for {
+		var msgs []*sarama.ProducerMessage
 		select {
 		case <-ctx.Done():
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), k.Cfg.AddTarget, k.targetTpl)
+			msgs, err = k.messages(ctx, m.GetMsg(), m.GetMeta())
 			if err != nil {
-				k.logger.Printf("failed to add target to the response: %v", err)
+				k.messagesFailed(workerLogPrefix, config.ClientID, err)
+				continue
 			}
-			b, err := k.mo.Marshal(pmsg, m.GetMeta(), k.evps...)
+		case evs := <-k.evChan:
+			msgs, err = k.eventMessages(ctx, evs)
 			if err != nil {
-				if k.Cfg.Debug {
-					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
-				}
-				if k.Cfg.EnableMetrics {
-					kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "marshal_error").Inc()
-				}
+				k.messagesFailed(workerLogPrefix, config.ClientID, err)
 				continue
 			}
+		}
+		if len(msgs) == 0 {
+			continue
+		}
 
-			if k.msgTpl != nil && len(b) > 0 {
-				b, err = outputs.ExecTemplate(b, k.msgTpl)
-				if err != nil {
-					if k.Cfg.Debug {
-						log.Printf("failed to execute template: %v", err)
-					}
-					kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "template_error").Inc()
-					return
-				}
+		var start time.Time
+		if k.Cfg.EnableMetrics {
+			start = time.Now()
+		}
+		err = producer.SendMessages(msgs)
+		if err != nil {
+			if k.Cfg.Debug {
+				k.logger.Printf("%s failed to send kafka msgs: %v", workerLogPrefix, err)
 			}
-
-			msg := &sarama.ProducerMessage{
-				Topic: k.Cfg.Topic,
-				Value: sarama.ByteEncoder(b),
+			if k.Cfg.EnableMetrics {
+				kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "send_error").Inc()
+			}
+			producer.Close()
+			time.Sleep(k.Cfg.RecoveryWaitTime)
+			goto CRPROD
+		}
+		if k.Cfg.EnableMetrics {
+			kafkaSendDuration.WithLabelValues(config.ClientID).Set(float64(time.Since(start).Nanoseconds()))
+			for _, msg := range msgs {
+				kafkaNumberOfSentMsgs.WithLabelValues(config.ClientID).Inc()
+				kafkaNumberOfSentBytes.WithLabelValues(config.ClientID).Add(float64(msg.Value.Length()))
 			}
+		}
+	}
+}
 
+func (k *KafkaOutput) writeBuffer(rsp proto.Message, ev *formatters.EventMsg, meta outputs.Meta) {
+	rec, err := outputs.EncodeBufferedMsg(rsp, ev, meta)
+	if err != nil {
+		k.logger.Printf("failed to encode message: %v", err)
+		return
+	}
+	err = k.buffer.Write(rec)
+	if err != nil {
+		k.logger.Printf("failed to write message to buffer: %v", err)
+	}
+}
+
+// bufferWorker reads batches of messages from the buffer and sends them to kafka,
+// a batch is acknowledged once it is successfully sent.
+// Failed sends are retried up to the buffer max-retries.
+func (k *KafkaOutput) bufferWorker(ctx context.Context, config *sarama.Config) {
+	defer k.wg.Done()
+	workerLogPrefix := "buffer-worker"
+	k.logger.Printf("%s starting", workerLogPrefix)
+	var producer sarama.SyncProducer
+	var err error
+	for {
+		producer, err = sarama.NewSyncProducer(strings.Split(k.Cfg.Address, ","), config)
+		if err == nil {
+			break
+		}
+		k.logger.Printf("%s failed to create kafka producer: %v", workerLogPrefix, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(k.Cfg.RecoveryWaitTime):
+		}
+	}
+	defer producer.Close()
+	k.logger.Printf("%s initialized kafka producer: %s", workerLogPrefix, k.String())
+	for {
+		recs, err := k.buffer.ReadBatch(ctx, bufferBatchSize)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, outputs.ErrBufferClosed) {
+				k.logger.Printf("%s shutting down", workerLogPrefix)
+				return
+			}
+			k.logger.Printf("%s failed to read from buffer: %v", workerLogPrefix, err)
+			time.Sleep(k.Cfg.RecoveryWaitTime)
+			continue
+		}
+		msgs := make([]*sarama.ProducerMessage, 0, len(recs))
+		for _, rec := range recs {
+			msgs = append(msgs, k.bufferedMessages(ctx, workerLogPrefix, config.ClientID, rec)...)
+		}
+		for attempt := 1; len(msgs) > 0; attempt++ {
 			var start time.Time
 			if k.Cfg.EnableMetrics {
 				start = time.Now()
 			}
-			_, _, err = producer.SendMessage(msg)
-			if err != nil {
-				if k.Cfg.Debug {
-					k.logger.Printf("%s failed to send a kafka msg to topic '%s': %v", workerLogPrefix, k.Cfg.Topic, err)
-				}
+			err = producer.SendMessages(msgs)
+			if err == nil {
 				if k.Cfg.EnableMetrics {
-					kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "send_error").Inc()
+					kafkaSendDuration.WithLabelValues(config.ClientID).Set(float64(time.Since(start).Nanoseconds()))
+					for _, msg := range msgs {
+						kafkaNumberOfSentMsgs.WithLabelValues(config.ClientID).Inc()
+						kafkaNumberOfSentBytes.WithLabelValues(config.ClientID).Add(float64(msg.Value.Length()))
+					}
 				}
-				producer.Close()
-				time.Sleep(k.Cfg.RecoveryWaitTime)
-				goto CRPROD
+				break
+			}
+			if ctx.Err() != nil {
+				return
 			}
 			if k.Cfg.EnableMetrics {
-				kafkaSendDuration.WithLabelValues(config.ClientID).Set(float64(time.Since(start).Nanoseconds()))
-				kafkaNumberOfSentMsgs.WithLabelValues(config.ClientID).Inc()
-				kafkaNumberOfSentBytes.WithLabelValues(config.ClientID).Add(float64(len(b)))
+				kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "send_error").Inc()
+			}
+			if k.Cfg.Buffer.MaxRetries >= 0 && attempt > k.Cfg.Buffer.MaxRetries {
+				k.logger.Printf("%s failed to send kafka msgs: %v, max retries reached, dropping %d msg(s)", workerLogPrefix, err, len(msgs))
+				break
 			}
+			k.logger.Printf("%s failed to send kafka msgs: %v, retrying in %s", workerLogPrefix, err, k.Cfg.RecoveryWaitTime)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(k.Cfg.RecoveryWaitTime):
+			}
+		}
+		err = k.buffer.Ack()
+		if err != nil {
+			k.logger.Printf("%s failed to acknowledge buffered messages: %v", workerLogPrefix, err)
 		}
 	}
 }
 
+// bufferedMessages builds the kafka messages of a buffered gNMI message or emitted event.
+func (k *KafkaOutput) bufferedMessages(ctx context.Context, workerLogPrefix, clientID string, rec []byte) []*sarama.ProducerMessage {
+	rsp, ev, meta, err := outputs.DecodeBufferedMsg(rec)
+	if err != nil {
+		k.logger.Printf("%s failed to decode buffered message: %v", workerLogPrefix, err)
+		return nil
+	}
+	var msgs []*sarama.ProducerMessage
+	if rsp != nil {
+		msgs, err = k.messages(ctx, rsp, meta)
+	} else {
+		msgs, err = k.eventMessages(ctx, []*formatters.EventMsg{ev})
+	}
+	if err != nil {
+		k.messagesFailed(workerLogPrefix, clientID, err)
+		return nil
+	}
+	return msgs
+}
+
+// msgError is an error building the kafka messages of a gNMI message or of emitted events,
+// reason is the label of the failed messages metric.
+type msgError struct {
+	reason string
+	err    error
+}
+
+func (e *msgError) Error() string { return e.err.Error() }
+
+func (k *KafkaOutput) messagesFailed(workerLogPrefix, clientID string, err error) {
+	if k.Cfg.Debug {
+		k.logger.Printf("%s %v", workerLogPrefix, err)
+	}
+	if !k.Cfg.EnableMetrics {
+		return
+	}
+	reason := "encoding_error"
+	var merr *msgError
+	if errors.As(err, &merr) {
+		reason = merr.reason
+	}
+	kafkaNumberOfFailSendMsgs.WithLabelValues(clientID, reason).Inc()
+}
+
+// messages builds the kafka messages of the gNMI message pmsg.
+func (k *KafkaOutput) messages(ctx context.Context, pmsg proto.Message, meta outputs.Meta) ([]*sarama.ProducerMessage, error) {
+	var err error
+	if k.Cfg.AddTarget != "" {
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, meta, k.Cfg.AddTarget, k.targetTpl)
+		if err != nil {
+			k.logger.Printf("failed to add target to the response: %v", err)
+		}
+	}
+	// with a schema registry or format event, each event is written as a separate message
+	if k.registry != nil || k.Cfg.Format == "event" {
+		evs, err := k.responseEvents(pmsg, meta)
+		if err != nil {
+			return nil, &msgError{reason: "marshal_error", err: fmt.Errorf("failed converting msg to events: %v", err)}
+		}
+		msgs := make([]*sarama.ProducerMessage, 0, len(evs))
+		for _, ev := range evs {
+			pm, err := k.eventMessage(ctx, meta, ev)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, pm)
+		}
+		return msgs, nil
+	}
+	b, err := k.mo.Marshal(pmsg, meta, k.evps...)
+	if err != nil {
+		return nil, &msgError{reason: "marshal_error", err: fmt.Errorf("failed marshaling proto msg: %v", err)}
+	}
+	if k.msgTpl != nil && len(b) > 0 {
+		b, err = outputs.ExecTemplate(b, k.msgTpl)
+		if err != nil {
+			return nil, &msgError{reason: "template_error", err: fmt.Errorf("failed to execute template: %v", err)}
+		}
+	}
+	msg, err := k.producerMessage(meta, nil, b)
+	if err != nil {
+		return nil, &msgError{reason: "template_error", err: fmt.Errorf("failed to execute key or topic template: %v", err)}
+	}
+	return []*sarama.ProducerMessage{msg}, nil
+}
+
+// producerMessage builds a kafka message with value b,
+// its topic and key are derived from the message meta and event ev (if not nil)
+// using the configured topic and key templates.
+func (k *KafkaOutput) producerMessage(meta outputs.Meta, ev *formatters.EventMsg, b []byte) (*sarama.ProducerMessage, error) {
+	msg := &sarama.ProducerMessage{
+		Topic: k.Cfg.Topic,
+		Value: sarama.ByteEncoder(b),
+	}
+	if k.topicTpl == nil && k.keyTpl == nil {
+		return msg, nil
+	}
+	in := templateInput(meta, ev)
+	if k.topicTpl != nil {
+		topic, err := execTemplate(k.topicTpl, in)
+		if err != nil {
+			return nil, err
+		}
+		if topic != "" {
+			msg.Topic = topic
+		}
+	}
+	if k.keyTpl != nil {
+		key, err := execTemplate(k.keyTpl, in)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			msg.Key = sarama.StringEncoder(key)
+		}
+	}
+	return msg, nil
+}
+
+// responseEvents converts msg to events, applying the event processors.
+func (k *KafkaOutput) responseEvents(msg proto.Message, meta outputs.Meta) ([]*formatters.EventMsg, error) {
+	msg = k.mo.OverrideTimestamp(msg)
+	rsp, ok := msg.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := rsp.GetResponse().(*gnmi.SubscribeResponse_Update); !ok {
+		return nil, nil
+	}
+	subscriptionName, ok := meta["subscription-name"]
+	if !ok {
+		subscriptionName = "default"
+	}
+	return formatters.ResponseToEventMsgs(subscriptionName, rsp, meta, k.evps...)
+}
+
+// registryMessage encodes the event ev in a kafka message using the schema registry wire format.
+func (k *KafkaOutput) registryMessage(ctx context.Context, meta outputs.Meta, ev *formatters.EventMsg) (*sarama.ProducerMessage, error) {
+	pm, err := k.producerMessage(meta, ev, nil)
+	if err != nil {
+		return nil, err
+	}
+	id, err := k.registry.schemaID(ctx, pm.Topic)
+	if err != nil {
+		return nil, err
+	}
+	pm.Value = sarama.ByteEncoder(k.registry.encode(id, encodeEvent(k.Cfg.SchemaRegistry.Encoding, ev)))
+	return pm, nil
+}
+
+// eventMessages builds the kafka messages of the events emitted asynchronously by the event processors,
+// one message per event.
+func (k *KafkaOutput) eventMessages(ctx context.Context, evs []*formatters.EventMsg) ([]*sarama.ProducerMessage, error) {
+	msgs := make([]*sarama.ProducerMessage, 0, len(evs))
+	for _, ev := range evs {
+		pm, err := k.eventMessage(ctx, outputs.EventMeta(ev), ev)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, pm)
+	}
+	return msgs, nil
+}
+
+// eventMessage builds the kafka message carrying the single event ev,
+// encoded using the schema registry wire format if configured.
+func (k *KafkaOutput) eventMessage(ctx context.Context, meta outputs.Meta, ev *formatters.EventMsg) (*sarama.ProducerMessage, error) {
+	if k.registry != nil {
+		pm, err := k.registryMessage(ctx, meta, ev)
+		if err != nil {
+			return nil, &msgError{reason: "encoding_error", err: fmt.Errorf("failed encoding event: %v", err)}
+		}
+		return pm, nil
+	}
+	b, err := k.mo.MarshalEvents([]*formatters.EventMsg{ev})
+	if err != nil {
+		return nil, &msgError{reason: "marshal_error", err: fmt.Errorf("failed marshaling event: %v", err)}
+	}
+	if k.msgTpl != nil && len(b) > 0 {
+		b, err = outputs.ExecTemplate(b, k.msgTpl)
+		if err != nil {
+			return nil, &msgError{reason: "template_error", err: fmt.Errorf("failed to execute template: %v", err)}
+		}
+	}
+	pm, err := k.producerMessage(meta, ev, b)
+	if err != nil {
+		return nil, &msgError{reason: "template_error", err: fmt.Errorf("failed to execute key or topic template: %v", err)}
+	}
+	return pm, nil
+}
+
+// templateInput returns the data the key and topic templates are executed with:
+// the message meta, overridden by the event tags if the kafka message carries a single event.
+func templateInput(meta outputs.Meta, ev *formatters.EventMsg) map[string]string {
+	in := make(map[string]string, len(meta))
+	for k, v := range meta {
+		in[k] = v
+	}
+	if ev == nil {
+		return in
+	}
+	for k, v := range ev.Tags {
+		in[k] = v
+	}
+	in["name"] = ev.Name
+	return in
+}
+
+func execTemplate(tpl *template.Template, in map[string]string) (string, error) {
+	sb := new(strings.Builder)
+	err := tpl.Execute(sb, in)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
 func (k *KafkaOutput) SetName(name string) {
 	sb := strings.Builder{}
 	if name != "" {
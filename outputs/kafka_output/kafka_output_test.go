@@ -0,0 +1,259 @@
+package kafka_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"text/template"
+
+	"github.com/Shopify/sarama"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var testEvent = &formatters.EventMsg{
+	Name:      "a",
+	Timestamp: 1,
+	Tags:      map[string]string{"k": "v"},
+	Values:    map[string]interface{}{"x": uint64(2)},
+}
+
+func TestEncodeAvroEvent(t *testing.T) {
+	expected := []byte{
+		0x02, 'a', // name
+		0x02,                          // timestamp
+		0x02, 0x02, 'k', 0x02, 'v', 0, // tags
+		0x02, 0x02, 'x', 0x04, 0x04, 0, // values, union branch long
+		0, // deletes
+	}
+	if got := encodeAvroEvent(testEvent); !bytes.Equal(got, expected) {
+		t.Errorf("unexpected avro encoding:\n got: %v\nwant: %v", got, expected)
+	}
+}
+
+func TestEncodeProtobufEvent(t *testing.T) {
+	expected := []byte{
+		0x0a, 0x01, 'a', // name
+		0x10, 0x01, // timestamp
+		0x1a, 0x06, 0x0a, 0x01, 'k', 0x12, 0x01, 'v', // tags
+		0x22, 0x07, 0x0a, 0x01, 'x', 0x12, 0x02, 0x10, 0x02, // values, int_val
+	}
+	if got := encodeProtobufEvent(testEvent); !bytes.Equal(got, expected) {
+		t.Errorf("unexpected protobuf encoding:\n got: %v\nwant: %v", got, expected)
+	}
+}
+
+func TestProducerMessage(t *testing.T) {
+	k := &KafkaOutput{
+		Cfg: &Config{Topic: "telemetry"},
+	}
+	k.keyTpl = template.Must(template.New("key-template").Parse(`{{ index . "source" }}`))
+	k.topicTpl = template.Must(template.New("topic-template").
+		Parse(`{{ if index . "interface_name" }}interfaces{{ end }}`))
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+
+	msg, err := k.producerMessage(meta, nil, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "telemetry" {
+		t.Errorf("expected the default topic, got %q", msg.Topic)
+	}
+	if msg.Key != sarama.StringEncoder("router1") {
+		t.Errorf("unexpected key %v", msg.Key)
+	}
+
+	ev := &formatters.EventMsg{Tags: map[string]string{"source": "router2", "interface_name": "ethernet-1/1"}}
+	msg, err = k.producerMessage(meta, ev, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "interfaces" {
+		t.Errorf("expected topic from template, got %q", msg.Topic)
+	}
+	if msg.Key != sarama.StringEncoder("router2") {
+		t.Errorf("unexpected key %v", msg.Key)
+	}
+}
+
+func TestRegistryMessages(t *testing.T) {
+	registrations := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/subjects/telemetry-value/versions" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		req := new(registerSchemaRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.SchemaType != "PROTOBUF" {
+			http.Error(w, "unexpected schema type", http.StatusBadRequest)
+			return
+		}
+		registrations++
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer srv.Close()
+
+	k := &KafkaOutput{
+		Cfg: &Config{
+			Topic:          "telemetry",
+			Format:         "event",
+			SchemaRegistry: &schemaRegistryConfig{URL: srv.URL, Encoding: "protobuf"},
+		},
+		mo: &formatters.MarshalOptions{Format: "event"},
+	}
+	err := k.setDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.registry, err = newSchemaRegistry(k.Cfg.SchemaRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 1,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+	for i := 0; i < 2; i++ {
+		msgs, err := k.messages(context.TODO(), rsp, meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("expected 1 msg, got %d", len(msgs))
+		}
+		b, _ := msgs[0].Value.Encode()
+		header := []byte{0, 0, 0, 0, 7, 0}
+		if !bytes.HasPrefix(b, header) {
+			t.Errorf("unexpected wire format header: %v", b[:len(header)])
+		}
+	}
+	if registrations != 1 {
+		t.Errorf("expected the schema to be registered once, got %d", registrations)
+	}
+}
+
+func TestEventFormatMessages(t *testing.T) {
+	k := &KafkaOutput{
+		Cfg: &Config{Topic: "telemetry", Format: "event"},
+		mo:  &formatters.MarshalOptions{Format: "event"},
+	}
+	// the key template has access to the event tags
+	k.keyTpl = template.Must(template.New("key-template").Parse(`{{ index . "source" }}/{{ index . "interface_name" }}`))
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 1,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "e1"}}, {Name: "mtu"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1500}},
+					},
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "e2"}}, {Name: "mtu"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 9000}},
+					},
+				},
+			},
+		},
+	}
+	meta := outputs.Meta{"source": "router1", "subscription-name": "sub1"}
+	msgs, err := k.messages(context.TODO(), rsp, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one message per event
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 msgs, got %d", len(msgs))
+	}
+	keys := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		b, _ := msg.Key.Encode()
+		keys = append(keys, string(b))
+		v, _ := msg.Value.Encode()
+		evs := make([]*formatters.EventMsg, 0)
+		err = json.Unmarshal(v, &evs)
+		if err != nil {
+			t.Fatalf("failed to unmarshal msg value: %v", err)
+		}
+		if len(evs) != 1 {
+			t.Errorf("expected 1 event, got %d", len(evs))
+		}
+	}
+	sort.Strings(keys)
+	if keys[0] != "router1/e1" || keys[1] != "router1/e2" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestBufferedMessages(t *testing.T) {
+	k := &KafkaOutput{
+		Cfg: &Config{Topic: "telemetry", Format: "event"},
+		mo:  &formatters.MarshalOptions{Format: "event"},
+	}
+	k.keyTpl = template.Must(template.New("key-template").Parse(`{{ index . "source" }}`))
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 1,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+	rspRec, err := outputs.EncodeBufferedMsg(rsp, nil, outputs.Meta{"source": "router1", "subscription-name": "sub1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1,
+		Tags:      map[string]string{"source": "router2", "subscription-name": "sub1"},
+		Values:    map[string]interface{}{"a_avg": 1.5},
+	}
+	evRec, err := outputs.EncodeBufferedMsg(nil, ev, outputs.Meta{outputs.BufferedEmittedMetaKey: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rec, key := range map[string]string{string(rspRec): "router1", string(evRec): "router2"} {
+		msgs := k.bufferedMessages(context.TODO(), "test", "test", []byte(rec))
+		if len(msgs) != 1 {
+			t.Fatalf("expected 1 msg, got %d", len(msgs))
+		}
+		b, _ := msgs[0].Key.Encode()
+		if string(b) != key {
+			t.Errorf("expected key %q, got %q", key, b)
+		}
+		v, _ := msgs[0].Value.Encode()
+		evs := make([]*formatters.EventMsg, 0)
+		err = json.Unmarshal(v, &evs)
+		if err != nil {
+			t.Fatalf("failed to unmarshal msg value: %v", err)
+		}
+		if len(evs) != 1 {
+			t.Errorf("expected 1 event, got %d", len(evs))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package kafka_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karimra/gnmic/utils"
+)
+
+const (
+	encodingAvro     = "avro"
+	encodingProtobuf = "protobuf"
+
+	defaultSchemaRegistryTimeout = 10 * time.Second
+	schemaRegistryContentType    = "application/vnd.schemaregistry.v1+json"
+	// the first byte of a message encoded using the schema registry wire format
+	schemaRegistryMagicByte = 0x0
+)
+
+type schemaRegistryConfig struct {
+	URL      string        `mapstructure:"url,omitempty"`
+	Username string        `mapstructure:"username,omitempty"`
+	Password string        `mapstructure:"password,omitempty"`
+	TLS      *tlsConfig    `mapstructure:"tls,omitempty"`
+	Timeout  time.Duration `mapstructure:"timeout,omitempty"`
+	// avro or protobuf
+	Encoding string `mapstructure:"encoding,omitempty"`
+	// subject the schema is registered under,
+	// defaults to $topic-value (TopicNameStrategy)
+	Subject string `mapstructure:"subject,omitempty"`
+}
+
+// schemaRegistry registers the event schema under the subjects
+// messages are produced for and caches the returned schema IDs.
+type schemaRegistry struct {
+	cfg    *schemaRegistryConfig
+	client *http.Client
+
+	m   *sync.Mutex
+	ids map[string]int
+}
+
+func newSchemaRegistry(cfg *schemaRegistryConfig) (*schemaRegistry, error) {
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	return &schemaRegistry{
+		cfg:    cfg,
+		client: client,
+		m:      new(sync.Mutex),
+		ids:    make(map[string]int),
+	}, nil
+}
+
+func (s *schemaRegistryConfig) setDefaults() error {
+	if s.URL == "" {
+		return fmt.Errorf("missing schema-registry url")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = defaultSchemaRegistryTimeout
+	}
+	s.Encoding = strings.ToLower(s.Encoding)
+	switch s.Encoding {
+	case "":
+		s.Encoding = encodingAvro
+	case encodingAvro, encodingProtobuf:
+	default:
+		return fmt.Errorf("unsupported schema-registry encoding %q, expecting %q or %q", s.Encoding, encodingAvro, encodingProtobuf)
+	}
+	return nil
+}
+
+func (s *schemaRegistry) subject(topic string) string {
+	if s.cfg.Subject != "" {
+		return s.cfg.Subject
+	}
+	return topic + "-value"
+}
+
+// schemaID returns the ID of the event schema registered under the subject
+// derived from topic, the schema is registered on first use.
+func (s *schemaRegistry) schemaID(ctx context.Context, topic string) (int, error) {
+	subject := s.subject(topic)
+	s.m.Lock()
+	defer s.m.Unlock()
+	if id, ok := s.ids[subject]; ok {
+		return id, nil
+	}
+	id, err := s.register(ctx, subject)
+	if err != nil {
+		return 0, err
+	}
+	s.ids[subject] = id
+	return id, nil
+}
+
+type registerSchemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type registerSchemaResponse struct {
+	ID int `json:"id"`
+}
+
+func (s *schemaRegistry) register(ctx context.Context, subject string) (int, error) {
+	req := registerSchemaRequest{}
+	switch s.cfg.Encoding {
+	case encodingAvro:
+		req.Schema = avroEventSchema
+	case encodingProtobuf:
+		req.Schema = protobufEventSchema
+		req.SchemaType = "PROTOBUF"
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	u := fmt.Sprintf("%s/subjects/%s/versions", strings.TrimSuffix(s.cfg.URL, "/"), url.PathEscape(subject))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewBuffer(b))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", schemaRegistryContentType)
+	httpReq.Header.Set("Accept", schemaRegistryContentType)
+	if s.cfg.Username != "" {
+		httpReq.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	rsp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to register schema under subject %q: status=%s: %s", subject, rsp.Status, string(body))
+	}
+	regRsp := new(registerSchemaResponse)
+	err = json.Unmarshal(body, regRsp)
+	if err != nil {
+		return 0, err
+	}
+	return regRsp.ID, nil
+}
+
+// encode returns payload prefixed with the schema registry wire format header:
+// the magic byte followed by the 4 bytes schema ID.
+// Protobuf payloads carry the message indexes after the schema ID,
+// the event message being the first message in the schema its indexes are encoded as a single 0.
+func (s *schemaRegistry) encode(id int, payload []byte) []byte {
+	b := make([]byte, 5, 6+len(payload))
+	b[0] = schemaRegistryMagicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	if s.cfg.Encoding == encodingProtobuf {
+		b = append(b, 0)
+	}
+	return append(b, payload...)
+}
@@ -0,0 +1,56 @@
+package mqtt_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var mqttNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "mqtt_output",
+	Name:      "number_of_mqtt_msgs_sent_success_total",
+	Help:      "Number of msgs successfully sent by gnmic mqtt output",
+}, []string{"publisher_id", "topic"})
+
+var mqttNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "mqtt_output",
+	Name:      "number_of_written_mqtt_bytes_total",
+	Help:      "Number of bytes written by gnmic mqtt output",
+}, []string{"publisher_id", "topic"})
+
+var mqttNumberOfFailSendMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "mqtt_output",
+	Name:      "number_of_mqtt_msgs_sent_fail_total",
+	Help:      "Number of failed msgs sent by gnmic mqtt output",
+}, []string{"publisher_id", "reason"})
+
+var mqttSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "mqtt_output",
+	Name:      "msg_send_duration_ns",
+	Help:      "gnmic mqtt output send duration in ns",
+}, []string{"publisher_id"})
+
+func initMetrics() {
+	mqttNumberOfSentMsgs.WithLabelValues("", "").Add(0)
+	mqttNumberOfSentBytes.WithLabelValues("", "").Add(0)
+	mqttNumberOfFailSendMsgs.WithLabelValues("", "").Add(0)
+	mqttSendDuration.WithLabelValues("").Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(mqttNumberOfSentMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttNumberOfSentBytes); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttNumberOfFailSendMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttSendDuration); err != nil {
+		return err
+	}
+	return nil
+}
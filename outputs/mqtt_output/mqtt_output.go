@@ -0,0 +1,676 @@
+package mqtt_output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultAddress        = "localhost:1883"
+	defaultTopic          = "telemetry"
+	defaultFormat         = "event"
+	defaultNumWorkers     = 1
+	defaultWriteTimeout   = 5 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+	mqttConnectWait       = 2 * time.Second
+	loggingPrefix         = "[mqtt_output:%s] "
+)
+
+type topicFormat string
+
+const (
+	topicFormat_Static                = "static"
+	topicFormat_SubTarget             = "subscription.target"
+	topicFormat_SubTargetPath         = "subscription.target.path"
+	topicFormat_SubTargetPathWithKeys = "subscription.target.pathKeys"
+)
+
+func init() {
+	outputs.Register("mqtt", func() outputs.Output {
+		return &mqttOutput{
+			Cfg:    &config{},
+			wg:     new(sync.WaitGroup),
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type mqttOutput struct {
+	Cfg      *config
+	client   mqtt.Client
+	cancelFn context.CancelFunc
+	msgChan  chan *outputs.ProtoMsg
+	wg       *sync.WaitGroup
+	logger   *log.Logger
+	mo       *formatters.MarshalOptions
+	evps     []formatters.EventProcessor
+
+	targetTpl *template.Template
+	msgTpl    *template.Template
+}
+
+type config struct {
+	Name               string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address            string        `mapstructure:"address,omitempty" json:"address,omitempty"`
+	ClientID           string        `mapstructure:"client-id,omitempty" json:"client-id,omitempty"`
+	Username           string        `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password           string        `mapstructure:"password,omitempty" json:"-"`
+	TLS                *tls          `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Topic              string        `mapstructure:"topic,omitempty" json:"topic,omitempty"`
+	TopicFormat        topicFormat   `mapstructure:"topic-format,omitempty" json:"topic-format,omitempty"`
+	QoS                byte          `mapstructure:"qos,omitempty" json:"qos,omitempty"`
+	Retain             bool          `mapstructure:"retain,omitempty" json:"retain,omitempty"`
+	CleanSession       bool          `mapstructure:"clean-session,omitempty" json:"clean-session,omitempty"`
+	KeepAlive          time.Duration `mapstructure:"keep-alive,omitempty" json:"keep-alive,omitempty"`
+	ConnectTimeout     time.Duration `mapstructure:"connect-timeout,omitempty" json:"connect-timeout,omitempty"`
+	ConnectTimeWait    time.Duration `mapstructure:"connect-time-wait,omitempty" json:"connect-time-wait,omitempty"`
+	Format             string        `mapstructure:"format,omitempty" json:"format,omitempty"`
+	AddTarget          string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	MsgTemplate        string        `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	NumWorkers         int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	WriteTimeout       time.Duration `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	EventProcessors    []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+}
+
+type tls struct {
+	CAFile     string `mapstructure:"ca-file,omitempty" json:"ca-file,omitempty"`
+	CertFile   string `mapstructure:"cert-file,omitempty" json:"cert-file,omitempty"`
+	KeyFile    string `mapstructure:"key-file,omitempty" json:"key-file,omitempty"`
+	SkipVerify bool   `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty"`
+}
+
+func (m *mqttOutput) String() string {
+	b, err := json.Marshal(m.Cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (m *mqttOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (m *mqttOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) {
+	for _, epName := range m.Cfg.EventProcessors {
+		if epCfg, ok := ps[epName]; ok {
+			epType := ""
+			for k := range epCfg {
+				epType = k
+				break
+			}
+			if in, ok := formatters.EventProcessors[epType]; ok {
+				ep := in()
+				err := ep.Init(epCfg[epType],
+					formatters.WithLogger(logger),
+					formatters.WithTargets(tcs),
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
+				if err != nil {
+					m.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
+					continue
+				}
+				m.evps = append(m.evps, ep)
+				m.logger.Printf("added event processor '%s' of type=%s to mqtt output", epName, epType)
+				continue
+			}
+			m.logger.Printf("%q event processor has an unknown type=%q", epName, epType)
+			continue
+		}
+		m.logger.Printf("%q event processor not found!", epName)
+	}
+}
+
+func (m *mqttOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, m.Cfg)
+	if err != nil {
+		return err
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = name
+	}
+	m.logger.SetPrefix(fmt.Sprintf(loggingPrefix, m.Cfg.Name))
+
+	for _, opt := range opts {
+		opt(m)
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	if formatters.HasEmitters(m.evps) {
+		switch m.Cfg.TopicFormat {
+		case topicFormat_SubTargetPath, topicFormat_SubTargetPathWithKeys:
+			return fmt.Errorf("event processors emitting events asynchronously are not supported with topic-format %q", m.Cfg.TopicFormat)
+		}
+	}
+
+	m.msgChan = make(chan *outputs.ProtoMsg)
+	initMetrics()
+	m.mo = &formatters.MarshalOptions{
+		Format:     m.Cfg.Format,
+		OverrideTS: m.Cfg.OverrideTimestamps,
+	}
+	if m.Cfg.TargetTemplate == "" {
+		m.targetTpl = outputs.DefaultTargetTemplate
+	} else {
+		m.targetTpl, err = utils.CreateTemplate("target-template", m.Cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		m.targetTpl = m.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	if m.Cfg.MsgTemplate != "" {
+		m.msgTpl, err = utils.CreateTemplate("msg-template", m.Cfg.MsgTemplate)
+		if err != nil {
+			return err
+		}
+		m.msgTpl = m.msgTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	m.client, err = m.createClient()
+	if err != nil {
+		return err
+	}
+	// the client keeps retrying to connect in the background
+	m.client.Connect()
+
+	ctx, m.cancelFn = context.WithCancel(ctx)
+	m.wg.Add(m.Cfg.NumWorkers)
+	for i := 0; i < m.Cfg.NumWorkers; i++ {
+		go m.worker(ctx, i)
+	}
+	if formatters.HasEmitters(m.evps) {
+		if m.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, m.evps, m.publishEvents)
+		} else {
+			m.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", m.Cfg.Format)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		m.Close()
+	}()
+	m.logger.Printf("initialized mqtt output: %s", m.String())
+	return nil
+}
+
+func (m *mqttOutput) setDefaults() error {
+	if m.Cfg.Format == "" {
+		m.Cfg.Format = defaultFormat
+	}
+	if !(m.Cfg.Format == "event" || m.Cfg.Format == "protojson" || m.Cfg.Format == "proto" || m.Cfg.Format == "json") {
+		return fmt.Errorf("unsupported output format '%s' for output type MQTT", m.Cfg.Format)
+	}
+	if m.Cfg.TopicFormat == "" {
+		m.Cfg.TopicFormat = topicFormat_Static
+	}
+	switch m.Cfg.TopicFormat {
+	case topicFormat_Static,
+		topicFormat_SubTarget,
+		topicFormat_SubTargetPath,
+		topicFormat_SubTargetPathWithKeys:
+	default:
+		return fmt.Errorf("unknown topic-format value: %v", m.Cfg.TopicFormat)
+	}
+	if m.Cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos value %d, expecting 0, 1 or 2", m.Cfg.QoS)
+	}
+	if m.Cfg.Address == "" {
+		m.Cfg.Address = defaultAddress
+	}
+	if m.Cfg.Topic == "" {
+		m.Cfg.Topic = defaultTopic
+	}
+	if m.Cfg.ClientID == "" {
+		m.Cfg.ClientID = "gnmic-" + uuid.New().String()
+	}
+	if m.Cfg.KeepAlive <= 0 {
+		m.Cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.Cfg.ConnectTimeout <= 0 {
+		m.Cfg.ConnectTimeout = defaultConnectTimeout
+	}
+	if m.Cfg.ConnectTimeWait <= 0 {
+		m.Cfg.ConnectTimeWait = mqttConnectWait
+	}
+	if m.Cfg.NumWorkers <= 0 {
+		m.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if m.Cfg.WriteTimeout <= 0 {
+		m.Cfg.WriteTimeout = defaultWriteTimeout
+	}
+	return nil
+}
+
+func (m *mqttOutput) createClient() (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		SetClientID(m.Cfg.ClientID).
+		SetCleanSession(m.Cfg.CleanSession).
+		SetKeepAlive(m.Cfg.KeepAlive).
+		SetConnectTimeout(m.Cfg.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.Cfg.ConnectTimeWait).
+		SetOnConnectHandler(func(mqtt.Client) {
+			m.logger.Printf("connected to MQTT broker %s", m.Cfg.Address)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to MQTT broker lost: %v", err)
+		})
+	scheme := "tcp"
+	if m.Cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			m.Cfg.TLS.CAFile,
+			m.Cfg.TLS.CertFile,
+			m.Cfg.TLS.KeyFile,
+			m.Cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+		scheme = "ssl"
+	}
+	address := m.Cfg.Address
+	if !strings.Contains(address, "://") {
+		address = scheme + "://" + address
+	}
+	opts.AddBroker(address)
+	if m.Cfg.Username != "" {
+		opts.SetUsername(m.Cfg.Username)
+		opts.SetPassword(m.Cfg.Password)
+	}
+	return mqtt.NewClient(opts), nil
+}
+
+func (m *mqttOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil || m.mo == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, m.Cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case m.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if m.Cfg.Debug {
+			m.logger.Printf("writing expired after %s, MQTT output might not be initialized", m.Cfg.WriteTimeout)
+		}
+		if m.Cfg.EnableMetrics {
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.Cfg.Name, "timeout").Inc()
+		}
+		return
+	}
+}
+
+func (m *mqttOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if m.client == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range m.evps {
+			evs = proc.Apply(evs...)
+		}
+		m.publishEvents(evs...)
+	}
+}
+
+// publishEvents publishes the events evs grouped by topic,
+// each topic receives a single message with its events.
+func (m *mqttOutput) publishEvents(evs ...*formatters.EventMsg) {
+	topics, byTopic, err := m.eventsByTopic(evs)
+	if err != nil {
+		if m.Cfg.Debug {
+			m.logger.Printf("failed to get topic name: %v", err)
+		}
+		if m.Cfg.EnableMetrics {
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.Cfg.Name, "topic_name_error").Inc()
+		}
+		return
+	}
+	for _, topic := range topics {
+		b, err := m.mo.MarshalEvents(byTopic[topic])
+		if err != nil {
+			if m.Cfg.Debug {
+				m.logger.Printf("failed to marshal events: %v", err)
+			}
+			if m.Cfg.EnableMetrics {
+				mqttNumberOfFailSendMsgs.WithLabelValues(m.Cfg.Name, "marshal_error").Inc()
+			}
+			continue
+		}
+		if m.msgTpl != nil {
+			b, err = outputs.ExecTemplate(b, m.msgTpl)
+			if err != nil {
+				if m.Cfg.Debug {
+					m.logger.Printf("failed to execute template: %v", err)
+				}
+				if m.Cfg.EnableMetrics {
+					mqttNumberOfFailSendMsgs.WithLabelValues(m.Cfg.Name, "template_error").Inc()
+				}
+				continue
+			}
+		}
+		m.publish(m.Cfg.Name, topic, b)
+	}
+}
+
+// eventsByTopic groups the events evs by topic,
+// the topics are returned in the order of their first event.
+// The events carry no gNMI path, the path based topic formats use the subscription and target levels only.
+func (m *mqttOutput) eventsByTopic(evs []*formatters.EventMsg) ([]string, map[string][]*formatters.EventMsg, error) {
+	topics := make([]string, 0)
+	byTopic := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		topic, err := m.topicName(nil, outputs.EventMeta(ev))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := byTopic[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		byTopic[topic] = append(byTopic[topic], ev)
+	}
+	return topics, byTopic, nil
+}
+
+func (m *mqttOutput) Close() error {
+	if m.cancelFn == nil {
+		return nil
+	}
+	m.cancelFn()
+	m.wg.Wait()
+	formatters.CloseProcessors(m.evps)
+	if m.client != nil && m.client.IsConnected() {
+		m.client.Disconnect(uint(time.Second / time.Millisecond))
+	}
+	return nil
+}
+
+func (m *mqttOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !m.Cfg.EnableMetrics {
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		m.logger.Printf("failed to register metric: %+v", err)
+	}
+}
+
+func (m *mqttOutput) SetName(name string) {
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = name
+	}
+}
+
+func (m *mqttOutput) SetClusterName(string) {}
+
+func (m *mqttOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+func (m *mqttOutput) worker(ctx context.Context, i int) {
+	defer m.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", i)
+	publisherID := fmt.Sprintf("%s-%d", m.Cfg.Name, i)
+	m.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Printf("%s shutting down", workerLogPrefix)
+			return
+		case pm := <-m.msgChan:
+			var err error
+			pmsg := pm.GetMsg()
+			if m.Cfg.AddTarget != "" {
+				pmsg, err = outputs.AddSubscriptionTarget(pmsg, pm.GetMeta(), m.Cfg.AddTarget, m.targetTpl)
+				if err != nil {
+					m.logger.Printf("failed to add target to the response: %v", err)
+				}
+			}
+			var rs []proto.Message
+			switch m.Cfg.TopicFormat {
+			case topicFormat_Static, topicFormat_SubTarget:
+				rs = []proto.Message{pmsg}
+			case topicFormat_SubTargetPath, topicFormat_SubTargetPathWithKeys:
+				switch rsp := pmsg.(type) {
+				case *gnmi.SubscribeResponse:
+					switch rsp := rsp.Response.(type) {
+					case *gnmi.SubscribeResponse_Update:
+						rs = splitSubscribeResponse(rsp)
+					}
+				}
+			}
+			for _, r := range rs {
+				b, err := m.mo.Marshal(r, pm.GetMeta(), m.evps...)
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
+					}
+					if m.Cfg.EnableMetrics {
+						mqttNumberOfFailSendMsgs.WithLabelValues(publisherID, "marshal_error").Inc()
+					}
+					continue
+				}
+				if len(b) == 0 {
+					continue
+				}
+				if m.msgTpl != nil {
+					b, err = outputs.ExecTemplate(b, m.msgTpl)
+					if err != nil {
+						if m.Cfg.Debug {
+							m.logger.Printf("%s failed to execute template: %v", workerLogPrefix, err)
+						}
+						if m.Cfg.EnableMetrics {
+							mqttNumberOfFailSendMsgs.WithLabelValues(publisherID, "template_error").Inc()
+						}
+						continue
+					}
+				}
+				topic, err := m.topicName(r, pm.GetMeta())
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed to get topic name: %v", workerLogPrefix, err)
+					}
+					if m.Cfg.EnableMetrics {
+						mqttNumberOfFailSendMsgs.WithLabelValues(publisherID, "topic_name_error").Inc()
+					}
+					continue
+				}
+				m.publish(publisherID, topic, b)
+			}
+		}
+	}
+}
+
+func (m *mqttOutput) publish(publisherID, topic string, b []byte) {
+	var start time.Time
+	if m.Cfg.EnableMetrics {
+		start = time.Now()
+	}
+	token := m.client.Publish(topic, m.Cfg.QoS, m.Cfg.Retain, b)
+	if !token.WaitTimeout(m.Cfg.WriteTimeout) {
+		if m.Cfg.Debug {
+			m.logger.Printf("%s publish to topic '%s' timed out", publisherID, topic)
+		}
+		if m.Cfg.EnableMetrics {
+			mqttNumberOfFailSendMsgs.WithLabelValues(publisherID, "timeout").Inc()
+		}
+		return
+	}
+	if err := token.Error(); err != nil {
+		if m.Cfg.Debug {
+			m.logger.Printf("%s failed to publish to topic '%s': %v", publisherID, topic, err)
+		}
+		if m.Cfg.EnableMetrics {
+			mqttNumberOfFailSendMsgs.WithLabelValues(publisherID, "publish_error").Inc()
+		}
+		return
+	}
+	if m.Cfg.EnableMetrics {
+		mqttSendDuration.WithLabelValues(publisherID).Set(float64(time.Since(start).Nanoseconds()))
+		mqttNumberOfSentMsgs.WithLabelValues(publisherID, topic).Inc()
+		mqttNumberOfSentBytes.WithLabelValues(publisherID, topic).Add(float64(len(b)))
+	}
+}
+
+// topicName builds the topic a message is published to based on the configured topic-format.
+func (m *mqttOutput) topicName(msg proto.Message, meta outputs.Meta) (string, error) {
+	sb := new(strings.Builder)
+	sb.WriteString(m.Cfg.Topic)
+	if m.Cfg.TopicFormat == topicFormat_Static {
+		return sb.String(), nil
+	}
+	sb.WriteString("/")
+	if sub, ok := meta["subscription-name"]; ok {
+		sb.WriteString(sanitizeTopicLevel(sub))
+		sb.WriteString("/")
+	}
+	tsb := new(strings.Builder)
+	err := m.targetTpl.Execute(tsb, meta)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(sanitizeTopicLevel(tsb.String()))
+	if m.Cfg.TopicFormat == topicFormat_SubTarget {
+		return sb.String(), nil
+	}
+	keys := m.Cfg.TopicFormat == topicFormat_SubTargetPathWithKeys
+	switch rsp := msg.(type) {
+	case *gnmi.SubscribeResponse:
+		switch rsp := rsp.Response.(type) {
+		case *gnmi.SubscribeResponse_Update:
+			prefixTopic := gNMIPathToTopic(rsp.Update.GetPrefix(), keys)
+			var pathTopic string
+			switch {
+			case len(rsp.Update.GetUpdate()) > 0:
+				pathTopic = gNMIPathToTopic(rsp.Update.GetUpdate()[0].GetPath(), keys)
+			case len(rsp.Update.GetDelete()) > 0:
+				pathTopic = gNMIPathToTopic(rsp.Update.GetDelete()[0], keys)
+			}
+			if prefixTopic != "" {
+				sb.WriteString("/")
+				sb.WriteString(prefixTopic)
+			}
+			if pathTopic != "" {
+				sb.WriteString("/")
+				sb.WriteString(pathTopic)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+func splitSubscribeResponse(m *gnmi.SubscribeResponse_Update) []proto.Message {
+	if m == nil || m.Update == nil {
+		return nil
+	}
+	rs := make([]proto.Message, 0, len(m.Update.GetUpdate())+len(m.Update.GetDelete()))
+	for _, upd := range m.Update.GetUpdate() {
+		rs = append(rs, &gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: m.Update.GetTimestamp(),
+					Prefix:    m.Update.GetPrefix(),
+					Update:    []*gnmi.Update{upd},
+				},
+			},
+		})
+	}
+	for _, del := range m.Update.GetDelete() {
+		rs = append(rs, &gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{
+				Update: &gnmi.Notification{
+					Timestamp: m.Update.GetTimestamp(),
+					Prefix:    m.Update.GetPrefix(),
+					Delete:    []*gnmi.Path{del},
+				},
+			},
+		})
+	}
+	return rs
+}
+
+func gNMIPathToTopic(p *gnmi.Path, keys bool) string {
+	if p == nil {
+		return ""
+	}
+	levels := make([]string, 0, len(p.GetElem())+1)
+	if p.GetOrigin() != "" {
+		levels = append(levels, sanitizeTopicLevel(p.GetOrigin()))
+	}
+	for _, e := range p.GetElem() {
+		sb := new(strings.Builder)
+		sb.WriteString(sanitizeTopicLevel(e.GetName()))
+		if keys && len(e.GetKey()) > 0 {
+			// sort keys by name
+			kNames := make([]string, 0, len(e.GetKey()))
+			for k := range e.GetKey() {
+				kNames = append(kNames, k)
+			}
+			sort.Strings(kNames)
+			for _, k := range kNames {
+				fmt.Fprintf(sb, "{%s=%s}", k, sanitizeTopicLevel(e.GetKey()[k]))
+			}
+		}
+		levels = append(levels, sb.String())
+	}
+	return strings.Join(levels, "/")
+}
+
+const (
+	slashReplChar    = "^"
+	spaceReplChar    = "~"
+	wildcardReplChar = "_"
+)
+
+var (
+	regSlash    = regexp.MustCompile(`/`)
+	regSpace    = regexp.MustCompile(`\s`)
+	regWildcard = regexp.MustCompile(`[+#]`)
+)
+
+// sanitizeTopicLevel replaces the characters that cannot be used
+// within an MQTT topic level.
+func sanitizeTopicLevel(s string) string {
+	s = regSlash.ReplaceAllString(s, slashReplChar)
+	s = regSpace.ReplaceAllString(s, spaceReplChar)
+	return regWildcard.ReplaceAllString(s, wildcardReplChar)
+}
@@ -0,0 +1,120 @@
+package mqtt_output
+
+import (
+	"testing"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var testResponse = &gnmi.SubscribeResponse{
+	Response: &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{
+			Timestamp: 42,
+			Prefix: &gnmi.Path{
+				Origin: "openconfig",
+			},
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{
+						Elem: []*gnmi.PathElem{
+							{Name: "interfaces"},
+							{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+							{Name: "state"},
+							{Name: "counters"},
+							{Name: "in-octets"},
+						},
+					},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1}},
+				},
+			},
+		},
+	},
+}
+
+func TestTopicName(t *testing.T) {
+	meta := outputs.Meta{
+		"subscription-name": "sub1",
+		"source":            "router1:57400",
+	}
+	tests := map[topicFormat]string{
+		topicFormat_Static:                "telemetry",
+		topicFormat_SubTarget:             "telemetry/sub1/router1",
+		topicFormat_SubTargetPath:         "telemetry/sub1/router1/openconfig/interfaces/interface/state/counters/in-octets",
+		topicFormat_SubTargetPathWithKeys: "telemetry/sub1/router1/openconfig/interfaces/interface{name=ethernet-1^1}/state/counters/in-octets",
+	}
+	for format, expected := range tests {
+		m := &mqttOutput{
+			Cfg: &config{
+				Topic:       "telemetry",
+				TopicFormat: format,
+			},
+			targetTpl: outputs.DefaultTargetTemplate,
+		}
+		got, err := m.topicName(testResponse, meta)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, got)
+		}
+	}
+}
+
+func TestEventsByTopic(t *testing.T) {
+	m := &mqttOutput{
+		Cfg: &config{
+			Topic:       "gnmic/telemetry",
+			TopicFormat: topicFormat_SubTargetPath,
+		},
+		targetTpl: outputs.DefaultTargetTemplate,
+	}
+	ev := &formatters.EventMsg{
+		Name: "sub1",
+		Tags: map[string]string{
+			"subscription-name": "sub 1",
+			"source":            "router#1:57400",
+		},
+	}
+	ev2 := &formatters.EventMsg{
+		Name: "sub1",
+		Tags: map[string]string{
+			"subscription-name": "sub 1",
+			"source":            "router2",
+		},
+	}
+	// the events of a batch are grouped by topic,
+	// built from the subscription name and the target template
+	topics, byTopic, err := m.eventsByTopic([]*formatters.EventMsg{ev, ev2, ev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"gnmic/telemetry/sub~1/router_1", "gnmic/telemetry/sub~1/router2"}
+	if len(topics) != 2 || topics[0] != expected[0] || topics[1] != expected[1] {
+		t.Fatalf("expected topics %v, got %v", expected, topics)
+	}
+	if len(byTopic[topics[0]]) != 2 || len(byTopic[topics[1]]) != 1 {
+		t.Errorf("unexpected events grouping: %v", byTopic)
+	}
+	// the events topic matches the topic of a message with the same metadata
+	topic, err := m.topicName(nil, outputs.Meta{"subscription-name": "sub 1", "source": "router2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic != topics[1] {
+		t.Errorf("expected %q, got %q", topic, topics[1])
+	}
+}
+
+func TestSplitSubscribeResponse(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{
+			Update: []*gnmi.Update{{}, {}},
+			Delete: []*gnmi.Path{{}},
+		},
+	}
+	if got := len(splitSubscribeResponse(rsp)); got != 3 {
+		t.Errorf("expected 3 messages, got %d", got)
+	}
+}
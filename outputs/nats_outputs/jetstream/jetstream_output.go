@@ -102,6 +102,7 @@ type jetstreamOutput struct {
 	ctx      context.Context
 	cancelFn context.CancelFunc
 	msgChan  chan *outputs.ProtoMsg
+	evChan   chan []*formatters.EventMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
 	mo       *formatters.MarshalOptions
@@ -128,8 +129,15 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 	if err != nil {
 		return err
 	}
+	if formatters.HasEmitters(n.evps) {
+		switch n.Cfg.SubjectFormat {
+		case subjectFormat_SubTargetPath, subjectFormat_SubTargetPathWithKeys:
+			return fmt.Errorf("event processors emitting events asynchronously are not supported with subject-format %q", n.Cfg.SubjectFormat)
+		}
+	}
 
 	n.msgChan = make(chan *outputs.ProtoMsg)
+	n.evChan = make(chan []*formatters.EventMsg)
 	initMetrics()
 	n.mo = &formatters.MarshalOptions{
 		Format:     n.Cfg.Format,
@@ -161,6 +169,13 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go n.worker(ctx, i, &cfg)
 	}
+	if formatters.HasEmitters(n.evps) {
+		if n.Cfg.Format == "event" {
+			formatters.StartEmitters(n.ctx, n.evps, n.writeEvents)
+		} else {
+			n.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", n.Cfg.Format)
+		}
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -247,6 +262,7 @@ func (n *jetstreamOutput) WriteEvent(ctx context.Context, ev *formatters.EventMs
 func (n *jetstreamOutput) Close() error {
 	n.cancelFn()
 	n.wg.Wait()
+	formatters.CloseProcessors(n.evps)
 	return nil
 }
 
@@ -298,7 +314,8 @@ func (n *jetstreamOutput) SetEventProcessors(ps map[string]map[string]interface{
 				err := ep.Init(epCfg[epType],
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					n.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -428,32 +445,107 @@ CRCONN:
 					}
 					continue
 				}
-				var start time.Time
+				err = n.publish(js, cfg, workerLogPrefix, subject, b)
+				if err != nil {
+					natsConn.Close()
+					time.Sleep(cfg.ConnectTimeWait)
+					goto CRCONN
+				}
+			}
+		case evs := <-n.evChan:
+			subjects, bySubject, err := n.eventsBySubject(evs)
+			if err != nil {
+				if n.Cfg.Debug {
+					n.logger.Printf("%s failed to get subject name: %v", workerLogPrefix, err)
+				}
 				if n.Cfg.EnableMetrics {
-					start = time.Now()
+					jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "subject_name_error").Inc()
 				}
-				_, err = js.Publish(subject, b)
+				continue
+			}
+			for _, subject := range subjects {
+				b, err := n.mo.MarshalEvents(bySubject[subject])
 				if err != nil {
 					if n.Cfg.Debug {
-						n.logger.Printf("%s failed to write to subject '%s': %v", workerLogPrefix, subject, err)
+						n.logger.Printf("%s failed marshaling events: %v", workerLogPrefix, err)
 					}
 					if n.Cfg.EnableMetrics {
-						jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "publish_error").Inc()
+						jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "marshal_error").Inc()
+					}
+					continue
+				}
+				if n.msgTpl != nil && len(b) > 0 {
+					b, err = outputs.ExecTemplate(b, n.msgTpl)
+					if err != nil {
+						if n.Cfg.Debug {
+							n.logger.Printf("%s failed to execute template: %v", workerLogPrefix, err)
+						}
+						if n.Cfg.EnableMetrics {
+							jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "template_error").Inc()
+						}
+						continue
 					}
+				}
+				err = n.publish(js, cfg, workerLogPrefix, subject, b)
+				if err != nil {
 					natsConn.Close()
 					time.Sleep(cfg.ConnectTimeWait)
 					goto CRCONN
 				}
-				if n.Cfg.EnableMetrics {
-					jetStreamSendDuration.WithLabelValues(cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
-					jetStreamNumberOfSentMsgs.WithLabelValues(cfg.Name, subject).Inc()
-					jetStreamNumberOfSentBytes.WithLabelValues(cfg.Name, subject).Add(float64(len(b)))
-				}
 			}
 		}
 	}
 }
 
+func (n *jetstreamOutput) publish(js nats.JetStreamContext, cfg *config, workerLogPrefix, subject string, b []byte) error {
+	var start time.Time
+	if n.Cfg.EnableMetrics {
+		start = time.Now()
+	}
+	_, err := js.Publish(subject, b)
+	if err != nil {
+		if n.Cfg.Debug {
+			n.logger.Printf("%s failed to write to subject '%s': %v", workerLogPrefix, subject, err)
+		}
+		if n.Cfg.EnableMetrics {
+			jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "publish_error").Inc()
+		}
+		return err
+	}
+	if n.Cfg.EnableMetrics {
+		jetStreamSendDuration.WithLabelValues(cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		jetStreamNumberOfSentMsgs.WithLabelValues(cfg.Name, subject).Inc()
+		jetStreamNumberOfSentBytes.WithLabelValues(cfg.Name, subject).Add(float64(len(b)))
+	}
+	return nil
+}
+
+// writeEvents passes the events emitted by the event processors to the workers.
+func (n *jetstreamOutput) writeEvents(evs ...*formatters.EventMsg) {
+	select {
+	case <-n.ctx.Done():
+	case n.evChan <- evs:
+	}
+}
+
+// eventsBySubject groups the events evs by subject,
+// the subjects are returned in the order of their first event.
+func (n *jetstreamOutput) eventsBySubject(evs []*formatters.EventMsg) ([]string, map[string][]*formatters.EventMsg, error) {
+	subjects := make([]string, 0)
+	bySubject := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		subject, err := n.subjectName(nil, outputs.EventMeta(ev))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := bySubject[subject]; !ok {
+			subjects = append(subjects, subject)
+		}
+		bySubject[subject] = append(bySubject[subject], ev)
+	}
+	return subjects, bySubject, nil
+}
+
 // Dial //
 func (n *jetstreamOutput) Dial(network, address string) (net.Conn, error) {
 	ctx, cancel := context.WithCancel(n.ctx)
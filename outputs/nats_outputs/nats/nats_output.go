@@ -49,6 +49,7 @@ type NatsOutput struct {
 	ctx      context.Context
 	cancelFn context.CancelFunc
 	msgChan  chan *outputs.ProtoMsg
+	evChan   chan []*formatters.EventMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
 	mo       *formatters.MarshalOptions
@@ -110,7 +111,8 @@ func (n *NatsOutput) SetEventProcessors(ps map[string]map[string]interface{},
 				err := ep.Init(epCfg[epType],
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					n.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -146,6 +148,7 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	}
 
 	n.msgChan = make(chan *outputs.ProtoMsg)
+	n.evChan = make(chan []*formatters.EventMsg)
 	initMetrics()
 	n.mo = &formatters.MarshalOptions{
 		Format:     n.Cfg.Format,
@@ -176,6 +179,13 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go n.worker(ctx, i, &cfg)
 	}
+	if formatters.HasEmitters(n.evps) {
+		if n.Cfg.Format == "event" {
+			formatters.StartEmitters(n.ctx, n.evps, n.writeEvents)
+		} else {
+			n.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", n.Cfg.Format)
+		}
+	}
 
 	go func() {
 		<-ctx.Done()
@@ -238,11 +248,20 @@ func (n *NatsOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 
 func (n *NatsOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
+// writeEvents passes the events emitted by the event processors to the workers.
+func (n *NatsOutput) writeEvents(evs ...*formatters.EventMsg) {
+	select {
+	case <-n.ctx.Done():
+	case n.evChan <- evs:
+	}
+}
+
 // Close //
 func (n *NatsOutput) Close() error {
 	//	n.conn.Close()
 	n.cancelFn()
 	n.wg.Wait()
+	formatters.CloseProcessors(n.evps)
 	return nil
 }
 
@@ -357,32 +376,86 @@ CRCONN:
 				}
 			}
 
-			subject := n.subjectName(cfg, m.GetMeta())
-			var start time.Time
-			if n.Cfg.EnableMetrics {
-				start = time.Now()
-			}
-			err = natsConn.Publish(subject, b)
+			err = n.publish(natsConn, cfg, workerLogPrefix, n.subjectName(cfg, m.GetMeta()), b)
 			if err != nil {
-				if n.Cfg.Debug {
-					n.logger.Printf("%s failed to write to nats subject '%s': %v", workerLogPrefix, subject, err)
-				}
-				if n.Cfg.EnableMetrics {
-					NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "publish_error").Inc()
-				}
 				natsConn.Close()
 				time.Sleep(cfg.ConnectTimeWait)
 				goto CRCONN
 			}
-			if n.Cfg.EnableMetrics {
-				NatsSendDuration.WithLabelValues(cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
-				NatsNumberOfSentMsgs.WithLabelValues(cfg.Name, subject).Inc()
-				NatsNumberOfSentBytes.WithLabelValues(cfg.Name, subject).Add(float64(len(b)))
+		case evs := <-n.evChan:
+			subjects, bySubject := n.eventsBySubject(cfg, evs)
+			for _, subject := range subjects {
+				b, err := n.mo.MarshalEvents(bySubject[subject])
+				if err != nil {
+					if n.Cfg.Debug {
+						n.logger.Printf("%s failed marshaling events: %v", workerLogPrefix, err)
+					}
+					if n.Cfg.EnableMetrics {
+						NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "marshal_error").Inc()
+					}
+					continue
+				}
+				if n.msgTpl != nil && len(b) > 0 {
+					b, err = outputs.ExecTemplate(b, n.msgTpl)
+					if err != nil {
+						if n.Cfg.Debug {
+							n.logger.Printf("%s failed to execute template: %v", workerLogPrefix, err)
+						}
+						if n.Cfg.EnableMetrics {
+							NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "template_error").Inc()
+						}
+						continue
+					}
+				}
+				err = n.publish(natsConn, cfg, workerLogPrefix, subject, b)
+				if err != nil {
+					natsConn.Close()
+					time.Sleep(cfg.ConnectTimeWait)
+					goto CRCONN
+				}
 			}
 		}
 	}
 }
 
+func (n *NatsOutput) publish(natsConn *nats.Conn, cfg *Config, workerLogPrefix, subject string, b []byte) error {
+	var start time.Time
+	if n.Cfg.EnableMetrics {
+		start = time.Now()
+	}
+	err := natsConn.Publish(subject, b)
+	if err != nil {
+		if n.Cfg.Debug {
+			n.logger.Printf("%s failed to write to nats subject '%s': %v", workerLogPrefix, subject, err)
+		}
+		if n.Cfg.EnableMetrics {
+			NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "publish_error").Inc()
+		}
+		return err
+	}
+	if n.Cfg.EnableMetrics {
+		NatsSendDuration.WithLabelValues(cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		NatsNumberOfSentMsgs.WithLabelValues(cfg.Name, subject).Inc()
+		NatsNumberOfSentBytes.WithLabelValues(cfg.Name, subject).Add(float64(len(b)))
+	}
+	return nil
+}
+
+// eventsBySubject groups the events evs by subject,
+// the subjects are returned in the order of their first event.
+func (n *NatsOutput) eventsBySubject(cfg *Config, evs []*formatters.EventMsg) ([]string, map[string][]*formatters.EventMsg) {
+	subjects := make([]string, 0)
+	bySubject := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		subject := n.subjectName(cfg, outputs.EventMeta(ev))
+		if _, ok := bySubject[subject]; !ok {
+			subjects = append(subjects, subject)
+		}
+		bySubject[subject] = append(bySubject[subject], ev)
+	}
+	return subjects, bySubject
+}
+
 func (n *NatsOutput) subjectName(c *Config, meta outputs.Meta) string {
 	if c.SubjectPrefix != "" {
 		ssb := strings.Builder{}
@@ -52,6 +52,7 @@ type StanOutput struct {
 	cancelFn context.CancelFunc
 	logger   *log.Logger
 	msgChan  chan *outputs.ProtoMsg
+	evChan   chan []*formatters.EventMsg
 	wg       *sync.WaitGroup
 	mo       *formatters.MarshalOptions
 	evps     []formatters.EventProcessor
@@ -112,7 +113,8 @@ func (s *StanOutput) SetEventProcessors(ps map[string]map[string]interface{},
 				ep := in()
 				err := ep.Init(epCfg[epType], formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					s.logger.Printf("failed initializing event processor %q of type=%q: %v", epName, epType, err)
 					continue
@@ -145,6 +147,7 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		return err
 	}
 	s.msgChan = make(chan *outputs.ProtoMsg)
+	s.evChan = make(chan []*formatters.EventMsg)
 
 	s.mo = &formatters.MarshalOptions{
 		Format:     s.Cfg.Format,
@@ -167,6 +170,18 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go s.worker(ctx, i, &cfg)
 	}
+	if formatters.HasEmitters(s.evps) {
+		if s.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, s.evps, func(evs ...*formatters.EventMsg) {
+				select {
+				case <-ctx.Done():
+				case s.evChan <- evs:
+				}
+			})
+		} else {
+			s.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", s.Cfg.Format)
+		}
+	}
 
 	s.logger.Printf("initialized stan producer: %s", s.String())
 	go func() {
@@ -253,6 +268,7 @@ func (s *StanOutput) RegisterMetrics(reg *prometheus.Registry) {
 func (s *StanOutput) Close() error {
 	s.cancelFn()
 	s.wg.Wait()
+	formatters.CloseProcessors(s.evps)
 	return nil
 }
 
@@ -326,30 +342,73 @@ CRCONN:
 				}
 				continue
 			}
-			subject := s.subjectName(c, m.GetMeta())
-			start := time.Now()
-			err = stanConn.Publish(subject, b)
+			err = s.publish(stanConn, c, workerLogPrefix, s.subjectName(c, m.GetMeta()), b)
 			if err != nil {
-				if s.Cfg.Debug {
-					s.logger.Printf("%s failed to write to STAN subject %q: %v", workerLogPrefix, subject, err)
-				}
-				if s.Cfg.EnableMetrics {
-					StanNumberOfFailSendMsgs.WithLabelValues(c.Name, "publish_error").Inc()
-				}
 				stanConn.Close()
 				stanConn.NatsConn().Close()
 				time.Sleep(c.RecoveryWaitTime)
 				goto CRCONN
 			}
-			if s.Cfg.EnableMetrics {
-				StanSendDuration.WithLabelValues(c.Name).Set(float64(time.Since(start).Nanoseconds()))
-				StanNumberOfSentMsgs.WithLabelValues(c.Name, subject).Inc()
-				StanNumberOfSentBytes.WithLabelValues(c.Name, subject).Add(float64(len(b)))
+		case evs := <-s.evChan:
+			subjects, bySubject := s.eventsBySubject(c, evs)
+			for _, subject := range subjects {
+				b, err := s.mo.MarshalEvents(bySubject[subject])
+				if err != nil {
+					if s.Cfg.Debug {
+						s.logger.Printf("%s failed marshaling events: %v", workerLogPrefix, err)
+					}
+					if s.Cfg.EnableMetrics {
+						StanNumberOfFailSendMsgs.WithLabelValues(c.Name, "marshal_error").Inc()
+					}
+					continue
+				}
+				err = s.publish(stanConn, c, workerLogPrefix, subject, b)
+				if err != nil {
+					stanConn.Close()
+					stanConn.NatsConn().Close()
+					time.Sleep(c.RecoveryWaitTime)
+					goto CRCONN
+				}
 			}
 		}
 	}
 }
 
+func (s *StanOutput) publish(stanConn stan.Conn, c *Config, workerLogPrefix, subject string, b []byte) error {
+	start := time.Now()
+	err := stanConn.Publish(subject, b)
+	if err != nil {
+		if s.Cfg.Debug {
+			s.logger.Printf("%s failed to write to STAN subject %q: %v", workerLogPrefix, subject, err)
+		}
+		if s.Cfg.EnableMetrics {
+			StanNumberOfFailSendMsgs.WithLabelValues(c.Name, "publish_error").Inc()
+		}
+		return err
+	}
+	if s.Cfg.EnableMetrics {
+		StanSendDuration.WithLabelValues(c.Name).Set(float64(time.Since(start).Nanoseconds()))
+		StanNumberOfSentMsgs.WithLabelValues(c.Name, subject).Inc()
+		StanNumberOfSentBytes.WithLabelValues(c.Name, subject).Add(float64(len(b)))
+	}
+	return nil
+}
+
+// eventsBySubject groups the events evs by subject,
+// the subjects are returned in the order of their first event.
+func (s *StanOutput) eventsBySubject(c *Config, evs []*formatters.EventMsg) ([]string, map[string][]*formatters.EventMsg) {
+	subjects := make([]string, 0)
+	bySubject := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		subject := s.subjectName(c, outputs.EventMeta(ev))
+		if _, ok := bySubject[subject]; !ok {
+			subjects = append(subjects, subject)
+		}
+		bySubject[subject] = append(bySubject[subject], ev)
+	}
+	return subjects, bySubject
+}
+
 func (s *StanOutput) subjectName(c *Config, meta outputs.Meta) string {
 	if c.SubjectPrefix != "" {
 		ssb := strings.Builder{}
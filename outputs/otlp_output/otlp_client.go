@@ -0,0 +1,159 @@
+package otlp_output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/utils"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const userAgent = "gNMIc otlp"
+
+var backoff = 100 * time.Millisecond
+
+// exporter sends OTLP metrics to a collector.
+type exporter interface {
+	export(ctx context.Context, rms []*metricspb.ResourceMetrics) error
+	close() error
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	headers metadata.MD
+	timeout time.Duration
+}
+
+func (o *otlpOutput) newGRPCExporter(ctx context.Context) (exporter, error) {
+	opts := []grpc.DialOption{grpc.WithUserAgent(userAgent)}
+	if o.Cfg.TLS == nil {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		tlsCfg, err := utils.NewTLSConfig(
+			o.Cfg.TLS.CAFile,
+			o.Cfg.TLS.CertFile,
+			o.Cfg.TLS.KeyFile,
+			o.Cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	}
+	// the connection is established in the background
+	conn, err := grpc.DialContext(ctx, o.Cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %q: %v", o.Cfg.Endpoint, err)
+	}
+	return &grpcExporter{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(o.Cfg.Headers),
+		timeout: o.Cfg.Timeout,
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, rms []*metricspb.ResourceMetrics) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	_, err := e.client.Export(ctx, &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: rms})
+	return err
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+func (o *otlpOutput) newHTTPExporter() (exporter, error) {
+	c := &http.Client{
+		Timeout: o.Cfg.Timeout,
+	}
+	scheme := "http"
+	if o.Cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			o.Cfg.TLS.CAFile,
+			o.Cfg.TLS.CertFile,
+			o.Cfg.TLS.KeyFile,
+			o.Cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+		scheme = "https"
+	}
+	endpoint := o.Cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = scheme + "://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint %q: %v", o.Cfg.Endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultHTTPPath
+	}
+	return &httpExporter{
+		client:  c,
+		url:     u.String(),
+		headers: o.Cfg.Headers,
+	}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, rms []*metricspb.ResourceMetrics) error {
+	b, err := proto.Marshal(&colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: rms})
+	if err != nil {
+		return fmt.Errorf("failed to marshal export request: %v", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewBuffer(b))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", userAgent)
+	for k, v := range e.headers {
+		httpReq.Header.Add(k, v)
+	}
+	rsp, err := e.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		msg, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("export response failed, code=%d, body=%s", rsp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,276 @@
+package otlp_output
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// batch groups the metrics built from a set of events
+// by resource, a resource being identified by the events resource tags.
+type batch struct {
+	o             *otlpOutput
+	resources     map[string]*resourceBatch
+	order         []string
+	numDataPoints int
+}
+
+type resourceBatch struct {
+	key        string
+	attributes []*commonpb.KeyValue
+	metrics    map[string]*metricspb.Metric
+	order      []string
+}
+
+// seriesStart is the start time of a cumulative sum series
+type seriesStart struct {
+	// start time of the series, the timestamp of its first data point
+	// or of the first data point following a counter reset
+	ts uint64
+	// last value of the series, used to detect counter resets
+	value float64
+	// time the series was last updated at, used to expire stale series
+	seen time.Time
+}
+
+func (o *otlpOutput) newBatch() *batch {
+	return &batch{
+		o:         o,
+		resources: make(map[string]*resourceBatch),
+	}
+}
+
+// add converts the event values to OTLP data points,
+// the event tags listed in `resource-tags` become resource attributes,
+// the remaining tags become data point attributes.
+func (b *batch) add(ev *formatters.EventMsg) {
+	if len(ev.Values) == 0 {
+		return
+	}
+	rb := b.resource(ev)
+	tags := make(map[string]string, len(ev.Tags))
+	for k, v := range ev.Tags {
+		if b.o.isResourceTag(k) {
+			continue
+		}
+		tags[k] = v
+	}
+	labels := b.o.mb.GetLabels(&formatters.EventMsg{Tags: tags, Values: ev.Values})
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, stringKeyValue(l.Name, l.Value))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+
+	ts := ev.Timestamp
+	if ts <= 0 {
+		ts = time.Now().UnixNano()
+	}
+	for k, v := range ev.Values {
+		dp := &metricspb.NumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: uint64(ts),
+		}
+		if !setDataPointValue(dp, v) {
+			continue
+		}
+		name := b.o.mb.MetricName(ev.Name, k)
+		m, ok := rb.metrics[name]
+		if !ok {
+			m = b.o.newMetric(name, k)
+			rb.metrics[name] = m
+			rb.order = append(rb.order, name)
+		}
+		switch d := m.Data.(type) {
+		case *metricspb.Metric_Gauge:
+			d.Gauge.DataPoints = append(d.Gauge.DataPoints, dp)
+		case *metricspb.Metric_Sum:
+			dp.StartTimeUnixNano = b.o.startTime(rb.key+name+"|"+attrsKey(attrs), dp)
+			d.Sum.DataPoints = append(d.Sum.DataPoints, dp)
+		}
+		b.numDataPoints++
+	}
+}
+
+func (b *batch) resource(ev *formatters.EventMsg) *resourceBatch {
+	attrs := make([]*commonpb.KeyValue, 0, len(b.o.Cfg.ResourceTags)+len(b.o.Cfg.ResourceAttributes))
+	for k, v := range b.o.Cfg.ResourceAttributes {
+		attrs = append(attrs, stringKeyValue(k, v))
+	}
+	for _, k := range b.o.Cfg.ResourceTags {
+		if v, ok := ev.Tags[k]; ok {
+			attrs = append(attrs, stringKeyValue(k, v))
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	key := attrsKey(attrs)
+	rb, ok := b.resources[key]
+	if !ok {
+		rb = &resourceBatch{
+			key:        key,
+			attributes: attrs,
+			metrics:    make(map[string]*metricspb.Metric),
+		}
+		b.resources[key] = rb
+		b.order = append(b.order, key)
+	}
+	return rb
+}
+
+func (b *batch) resourceMetrics() []*metricspb.ResourceMetrics {
+	rms := make([]*metricspb.ResourceMetrics, 0, len(b.order))
+	for _, key := range b.order {
+		rb := b.resources[key]
+		metrics := make([]*metricspb.Metric, 0, len(rb.order))
+		for _, name := range rb.order {
+			metrics = append(metrics, rb.metrics[name])
+		}
+		rms = append(rms, &metricspb.ResourceMetrics{
+			Resource: &resourcepb.Resource{Attributes: rb.attributes},
+			ScopeMetrics: []*metricspb.ScopeMetrics{
+				{
+					Scope:   &commonpb.InstrumentationScope{Name: defaultScopeName},
+					Metrics: metrics,
+				},
+			},
+		})
+	}
+	return rms
+}
+
+// startTime returns the start time of the cumulative sum series key with data point dp,
+// the series start time is the timestamp of its first data point and is reset
+// when the series value decreases.
+func (o *otlpOutput) startTime(key string, dp *metricspb.NumberDataPoint) uint64 {
+	v := dp.GetAsDouble()
+	if _, ok := dp.Value.(*metricspb.NumberDataPoint_AsInt); ok {
+		v = float64(dp.GetAsInt())
+	}
+	st, ok := o.seriesStarts[key]
+	if !ok {
+		st = &seriesStart{ts: dp.TimeUnixNano}
+		o.seriesStarts[key] = st
+	} else if v < st.value {
+		// counter reset
+		st.ts = dp.TimeUnixNano
+	} else if dp.TimeUnixNano < st.ts {
+		st.ts = dp.TimeUnixNano
+	}
+	st.value, st.seen = v, time.Now()
+	return st.ts
+}
+
+// expireSeries deletes the cumulative sum series start times
+// that were not updated during the last expiration period.
+func (o *otlpOutput) expireSeries(now time.Time) {
+	for k, st := range o.seriesStarts {
+		if now.Sub(st.seen) >= seriesExpiration {
+			delete(o.seriesStarts, k)
+		}
+	}
+	o.lastSweep = now
+}
+
+// attrsKey builds a key from the sorted attributes attrs
+func attrsKey(attrs []*commonpb.KeyValue) string {
+	sb := new(strings.Builder)
+	for _, kv := range attrs {
+		sb.WriteString(kv.Key)
+		sb.WriteString("=")
+		sb.WriteString(kv.GetValue().GetStringValue())
+		sb.WriteString(",")
+	}
+	return sb.String()
+}
+
+func (o *otlpOutput) isResourceTag(k string) bool {
+	for _, rt := range o.Cfg.ResourceTags {
+		if rt == k {
+			return true
+		}
+	}
+	return false
+}
+
+// newMetric creates a monotonic cumulative sum if the value name matches
+// one of the configured counter patterns, a gauge otherwise.
+func (o *otlpOutput) newMetric(name, valueName string) *metricspb.Metric {
+	for _, re := range o.counters {
+		if re.MatchString(valueName) {
+			return &metricspb.Metric{
+				Name: name,
+				Data: &metricspb.Metric_Sum{
+					Sum: &metricspb.Sum{
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+					},
+				},
+			}
+		}
+	}
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}},
+	}
+}
+
+// setDataPointValue sets the data point value from v,
+// integers are kept as integers, it returns false if v is not a number.
+func setDataPointValue(dp *metricspb.NumberDataPoint, v interface{}) bool {
+	switch v := v.(type) {
+	case int:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int64:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+	case uint:
+		return setDataPointValue(dp, uint64(v))
+	case uint8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint64:
+		if v > math.MaxInt64 {
+			dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+			return true
+		}
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case float32:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+	case float64:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: f}
+	case *gnmi.Decimal64:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v.Digits) / math.Pow10(int(v.Precision))}
+	default:
+		return false
+	}
+	return true
+}
+
+func stringKeyValue(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+	}
+}
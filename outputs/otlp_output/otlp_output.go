@@ -0,0 +1,391 @@
+package otlp_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	promcom "github.com/karimra/gnmic/outputs/prometheus_output"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	outputType           = "otlp"
+	loggingPrefix        = "[otlp_output:%s] "
+	defaultTimeout       = 10 * time.Second
+	defaultInterval      = 10 * time.Second
+	defaultBatchSize     = 500
+	defaultBufferSize    = 1000
+	defaultProtocol      = "grpc"
+	defaultHTTPPath      = "/v1/metrics"
+	defaultScopeName     = "gnmic"
+	defaultServiceName   = "gnmic"
+	serviceNameAttribute = "service.name"
+	// cumulative sums series not updated for this duration are forgotten,
+	// they get a new start time if they are received again.
+	seriesExpiration = 10 * time.Minute
+)
+
+var defaultResourceTags = []string{"source", "target", "subscription-name"}
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &otlpOutput{
+			Cfg:    &config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type otlpOutput struct {
+	Cfg    *config
+	logger *log.Logger
+
+	eventChan chan *formatters.EventMsg
+	mb        *promcom.MetricBuilder
+	counters  []*regexp.Regexp
+	exporter  exporter
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+	// cumulative sums series start times, keyed by resource, metric name and attributes,
+	// only accessed from the worker goroutine after Init
+	seriesStarts map[string]*seriesStart
+	lastSweep    time.Time
+}
+
+type config struct {
+	Name               string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Endpoint           string            `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Protocol           string            `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Timeout            time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers            map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	TLS                *tls              `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Interval           time.Duration     `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	BatchSize          int               `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize         int               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxRetries         int               `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	ResourceTags       []string          `mapstructure:"resource-tags,omitempty" json:"resource-tags,omitempty"`
+	ResourceAttributes map[string]string `mapstructure:"resource-attributes,omitempty" json:"resource-attributes,omitempty"`
+	CounterPatterns    []string          `mapstructure:"counter-patterns,omitempty" json:"counter-patterns,omitempty"`
+	Debug              bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	MetricPrefix           string   `mapstructure:"metric-prefix,omitempty" json:"metric-prefix,omitempty"`
+	AppendSubscriptionName bool     `mapstructure:"append-subscription-name,omitempty" json:"append-subscription-name,omitempty"`
+	AddTarget              string   `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate         string   `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	StringsAsLabels        bool     `mapstructure:"strings-as-labels,omitempty" json:"strings-as-labels,omitempty"`
+	EventProcessors        []string `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+}
+
+type tls struct {
+	CAFile     string `mapstructure:"ca-file,omitempty" json:"ca-file,omitempty"`
+	CertFile   string `mapstructure:"cert-file,omitempty" json:"cert-file,omitempty"`
+	KeyFile    string `mapstructure:"key-file,omitempty" json:"key-file,omitempty"`
+	SkipVerify bool   `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty"`
+}
+
+func (o *otlpOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, o.Cfg)
+	if err != nil {
+		return err
+	}
+	if o.Cfg.Endpoint == "" {
+		return errors.New("missing endpoint field")
+	}
+	if o.Cfg.Name == "" {
+		o.Cfg.Name = name
+	}
+	o.logger.SetPrefix(fmt.Sprintf(loggingPrefix, o.Cfg.Name))
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.Cfg.TargetTemplate == "" {
+		o.targetTpl = outputs.DefaultTargetTemplate
+	} else if o.Cfg.AddTarget != "" {
+		o.targetTpl, err = utils.CreateTemplate("target-template", o.Cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		o.targetTpl = o.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = o.setDefaults()
+	if err != nil {
+		return err
+	}
+	o.counters = make([]*regexp.Regexp, 0, len(o.Cfg.CounterPatterns))
+	for _, p := range o.Cfg.CounterPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("failed to compile counter pattern %q: %v", p, err)
+		}
+		o.counters = append(o.counters, re)
+	}
+
+	o.mb = &promcom.MetricBuilder{
+		Prefix:                 o.Cfg.MetricPrefix,
+		AppendSubscriptionName: o.Cfg.AppendSubscriptionName,
+		StringsAsLabels:        o.Cfg.StringsAsLabels,
+	}
+	o.eventChan = make(chan *formatters.EventMsg, o.Cfg.BufferSize)
+	o.seriesStarts = make(map[string]*seriesStart)
+	o.lastSweep = time.Now()
+
+	switch o.Cfg.Protocol {
+	case "grpc":
+		o.exporter, err = o.newGRPCExporter(ctx)
+	case "http":
+		o.exporter, err = o.newHTTPExporter()
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, o.cfn = context.WithCancel(ctx)
+	go o.worker(ctx)
+	formatters.StartEmitters(ctx, o.evps, func(evs ...*formatters.EventMsg) {
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case o.eventChan <- ev:
+			}
+		}
+	})
+	o.logger.Printf("initialized otlp output %s: %s", o.Cfg.Name, o.String())
+	return nil
+}
+
+func (o *otlpOutput) setDefaults() error {
+	o.Cfg.Protocol = strings.ToLower(o.Cfg.Protocol)
+	switch o.Cfg.Protocol {
+	case "":
+		o.Cfg.Protocol = defaultProtocol
+	case "grpc", "http":
+	default:
+		return fmt.Errorf("unknown protocol %q, expecting 'grpc' or 'http'", o.Cfg.Protocol)
+	}
+	if o.Cfg.Timeout <= 0 {
+		o.Cfg.Timeout = defaultTimeout
+	}
+	if o.Cfg.Interval <= 0 {
+		o.Cfg.Interval = defaultInterval
+	}
+	if o.Cfg.BatchSize <= 0 {
+		o.Cfg.BatchSize = defaultBatchSize
+	}
+	if o.Cfg.BufferSize <= 0 {
+		o.Cfg.BufferSize = defaultBufferSize
+	}
+	if o.Cfg.ResourceTags == nil {
+		o.Cfg.ResourceTags = defaultResourceTags
+	}
+	if o.Cfg.ResourceAttributes == nil {
+		o.Cfg.ResourceAttributes = make(map[string]string)
+	}
+	if _, ok := o.Cfg.ResourceAttributes[serviceNameAttribute]; !ok {
+		o.Cfg.ResourceAttributes[serviceNameAttribute] = defaultServiceName
+	}
+	return nil
+}
+
+func (o *otlpOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+	switch rsp := rsp.(type) {
+	case *gnmi.SubscribeResponse:
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		if o.Cfg.AddTarget != "" {
+			var err error
+			rsp, err = outputs.AddSubscriptionTarget(rsp, meta, o.Cfg.AddTarget, o.targetTpl)
+			if err != nil {
+				o.logger.Printf("failed to add target to the response: %v", err)
+			}
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, o.evps...)
+		if err != nil {
+			o.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			select {
+			case <-ctx.Done():
+				return
+			case o.eventChan <- ev:
+			}
+		}
+	}
+}
+
+func (o *otlpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range o.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case o.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (o *otlpOutput) Close() error {
+	if o.cfn == nil {
+		return nil
+	}
+	o.cfn()
+	formatters.CloseProcessors(o.evps)
+	if o.exporter != nil {
+		return o.exporter.close()
+	}
+	return nil
+}
+
+func (o *otlpOutput) RegisterMetrics(_ *prometheus.Registry) {}
+
+func (o *otlpOutput) String() string {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (o *otlpOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && o.logger != nil {
+		o.logger.SetOutput(logger.Writer())
+		o.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (o *otlpOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) {
+	for _, epName := range o.Cfg.EventProcessors {
+		if epCfg, ok := ps[epName]; ok {
+			epType := ""
+			for k := range epCfg {
+				epType = k
+				break
+			}
+			if in, ok := formatters.EventProcessors[epType]; ok {
+				ep := in()
+				err := ep.Init(epCfg[epType],
+					formatters.WithLogger(logger),
+					formatters.WithTargets(tcs),
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
+				if err != nil {
+					o.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
+					continue
+				}
+				o.evps = append(o.evps, ep)
+				o.logger.Printf("added event processor '%s' of type=%s to otlp output", epName, epType)
+				continue
+			}
+			o.logger.Printf("%q event processor has an unknown type=%q", epName, epType)
+			continue
+		}
+		o.logger.Printf("%q event processor not found!", epName)
+	}
+}
+
+func (o *otlpOutput) SetName(name string) {
+	if o.Cfg.Name == "" {
+		o.Cfg.Name = name
+	}
+}
+
+func (o *otlpOutput) SetClusterName(_ string) {}
+
+func (o *otlpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+// worker converts the received events to OTLP metrics and exports them
+// when the batch size is reached or at each interval.
+func (o *otlpOutput) worker(ctx context.Context) {
+	ticker := time.NewTicker(o.Cfg.Interval)
+	defer ticker.Stop()
+	b := o.newBatch()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-o.eventChan:
+			if o.Cfg.Debug {
+				o.logger.Printf("got event to batch: %+v", ev)
+			}
+			b.add(ev)
+			if b.numDataPoints >= o.Cfg.BatchSize {
+				if o.Cfg.Debug {
+					o.logger.Printf("batch size reached, exporting")
+				}
+				o.export(ctx, b.resourceMetrics())
+				b = o.newBatch()
+			}
+		case now := <-ticker.C:
+			if now.Sub(o.lastSweep) >= seriesExpiration {
+				o.expireSeries(now)
+			}
+			if b.numDataPoints == 0 {
+				continue
+			}
+			if o.Cfg.Debug {
+				o.logger.Printf("interval reached, exporting")
+			}
+			o.export(ctx, b.resourceMetrics())
+			b = o.newBatch()
+		}
+	}
+}
+
+func (o *otlpOutput) export(ctx context.Context, rms []*metricspb.ResourceMetrics) {
+	var err error
+	for i := 0; i <= o.Cfg.MaxRetries; i++ {
+		err = o.exporter.export(ctx, rms)
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		o.logger.Printf("failed to export metrics: %v", err)
+		if i == o.Cfg.MaxRetries {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
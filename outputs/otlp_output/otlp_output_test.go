@@ -0,0 +1,231 @@
+package otlp_output
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestOutput(t *testing.T, cfg map[string]interface{}) *otlpOutput {
+	o := &otlpOutput{
+		Cfg:    &config{},
+		logger: log.New(io.Discard, "", 0),
+	}
+	err := o.Init(context.TODO(), "otlp1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func TestBatch(t *testing.T) {
+	o := newTestOutput(t, map[string]interface{}{
+		"endpoint":          "localhost:4317",
+		"strings-as-labels": true,
+		"counter-patterns":  []string{"counters/"},
+		"resource-attributes": map[string]string{
+			"site": "dc1",
+		},
+	})
+	b := o.newBatch()
+	b.add(&formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags: map[string]string{
+			"source":            "router1",
+			"subscription-name": "sub1",
+			"interface_name":    "ethernet-1/1",
+		},
+		Values: map[string]interface{}{
+			"/interface/statistics/counters/in-octets": uint64(100),
+			"/interface/oper-state":                    "up",
+			"/interface/statistics/load":               "1.5",
+		},
+	})
+	b.add(&formatters.EventMsg{
+		Name: "sub1",
+		Tags: map[string]string{
+			"source":            "router2",
+			"subscription-name": "sub1",
+		},
+		Values: map[string]interface{}{
+			"/interface/statistics/counters/in-octets": int64(200),
+		},
+	})
+	if b.numDataPoints != 3 {
+		t.Fatalf("expected 3 data points, got %d", b.numDataPoints)
+	}
+	rms := b.resourceMetrics()
+	if len(rms) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(rms))
+	}
+	rattrs := make(map[string]string)
+	for _, kv := range rms[0].GetResource().GetAttributes() {
+		rattrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	expected := map[string]string{
+		"service.name":      "gnmic",
+		"site":              "dc1",
+		"source":            "router1",
+		"subscription-name": "sub1",
+	}
+	for k, v := range expected {
+		if rattrs[k] != v {
+			t.Errorf("expected resource attribute %s=%q, got %q", k, v, rattrs[k])
+		}
+	}
+	metrics := make(map[string]*metricspb.Metric)
+	for _, m := range rms[0].GetScopeMetrics()[0].GetMetrics() {
+		metrics[m.GetName()] = m
+	}
+	counter, ok := metrics["interface_statistics_counters_in_octets"]
+	if !ok {
+		t.Fatalf("missing counter metric, got %v", metrics)
+	}
+	if !counter.GetSum().GetIsMonotonic() {
+		t.Errorf("expected a monotonic sum, got %v", counter)
+	}
+	dp := counter.GetSum().GetDataPoints()[0]
+	if dp.GetAsInt() != 100 || dp.GetTimeUnixNano() != 42 || dp.GetStartTimeUnixNano() != 42 {
+		t.Errorf("unexpected data point: %v", dp)
+	}
+	attrs := make(map[string]string)
+	for _, kv := range dp.GetAttributes() {
+		attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if attrs["interface_name"] != "ethernet-1/1" || attrs["oper_state"] != "up" {
+		t.Errorf("unexpected data point attributes: %v", attrs)
+	}
+	if _, ok := attrs["source"]; ok {
+		t.Errorf("resource tag found in data point attributes: %v", attrs)
+	}
+	gauge, ok := metrics["interface_statistics_load"]
+	if !ok {
+		t.Fatalf("missing gauge metric, got %v", metrics)
+	}
+	if gauge.GetGauge().GetDataPoints()[0].GetAsDouble() != 1.5 {
+		t.Errorf("unexpected gauge: %v", gauge)
+	}
+}
+
+func TestSumStartTime(t *testing.T) {
+	o := newTestOutput(t, map[string]interface{}{
+		"endpoint":         "localhost:4317",
+		"counter-patterns": []string{"counters/"},
+	})
+	counterEvent := func(ts int64, v uint64) *formatters.EventMsg {
+		return &formatters.EventMsg{
+			Name:      "sub1",
+			Timestamp: ts,
+			Tags:      map[string]string{"source": "router1"},
+			Values:    map[string]interface{}{"/counters/in-octets": v},
+		}
+	}
+	tests := []struct {
+		ts    int64
+		value uint64
+		start uint64
+	}{
+		// the start time is the first seen data point timestamp
+		{ts: 10, value: 100, start: 10},
+		{ts: 20, value: 150, start: 10},
+		// the start time is reset when the counter decreases
+		{ts: 30, value: 5, start: 30},
+		{ts: 40, value: 10, start: 30},
+	}
+	for i, tt := range tests {
+		b := o.newBatch()
+		b.add(counterEvent(tt.ts, tt.value))
+		dp := b.resourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()[0].GetSum().GetDataPoints()[0]
+		if dp.GetStartTimeUnixNano() != tt.start {
+			t.Errorf("item %d: expected start time %d, got %d", i, tt.start, dp.GetStartTimeUnixNano())
+		}
+	}
+	// stale series are forgotten
+	o.expireSeries(time.Now().Add(seriesExpiration))
+	if len(o.seriesStarts) != 0 {
+		t.Errorf("expected the series to be expired, got %d", len(o.seriesStarts))
+	}
+}
+
+func TestHTTPExport(t *testing.T) {
+	reqs := make(chan *colmetricspb.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultHTTPPath || r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		req := new(colmetricspb.ExportMetricsServiceRequest)
+		if err := proto.Unmarshal(b, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reqs <- req
+	}))
+	defer server.Close()
+
+	o := newTestOutput(t, map[string]interface{}{
+		"endpoint":   server.URL,
+		"protocol":   "http",
+		"batch-size": 2,
+		"headers":    map[string]string{"X-Token": "abc"},
+	})
+	for i := 0; i < 2; i++ {
+		o.WriteEvent(context.TODO(), &formatters.EventMsg{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "router1"},
+			Values: map[string]interface{}{"value": i},
+		})
+	}
+	select {
+	case req := <-reqs:
+		dps := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()[0].GetGauge().GetDataPoints()
+		if len(dps) != 2 {
+			t.Errorf("expected 2 data points, got %d", len(dps))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the export request")
+	}
+}
+
+// failingExporter fails all the exports
+type failingExporter struct{}
+
+func (failingExporter) export(context.Context, []*metricspb.ResourceMetrics) error {
+	return errors.New("unavailable")
+}
+
+func (failingExporter) close() error { return nil }
+
+func TestExportRetryCancel(t *testing.T) {
+	o := &otlpOutput{
+		Cfg:      &config{MaxRetries: 1000},
+		logger:   log.New(io.Discard, "", 0),
+		exporter: failingExporter{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.export(ctx, nil)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("export retries not stopped on cancel")
+	}
+}
@@ -50,6 +50,9 @@ var OutputTypes = map[string]struct{}{
 	"udp":              {},
 	"gnmi":             {},
 	"jetstream":        {},
+	"otlp":             {},
+	"sql":              {},
+	"mqtt":             {},
 }
 
 func Register(name string, initFn Initializer) {
@@ -108,6 +111,19 @@ func AddSubscriptionTarget(msg proto.Message, meta Meta, addTarget string, tpl *
 	return nil, nil
 }
 
+// EventMeta returns the meta of the message the event ev was built from,
+// as found in its "source" and "subscription-name" tags.
+// It is used to name the topics or subjects of the events emitted asynchronously by the event processors.
+func EventMeta(ev *formatters.EventMsg) Meta {
+	meta := make(Meta, 2)
+	for _, k := range []string{"source", "subscription-name"} {
+		if v, ok := ev.Tags[k]; ok {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
 func ExecTemplate(content []byte, tpl *template.Template) ([]byte, error) {
 	var input interface{}
 	err := json.Unmarshal(content, &input)
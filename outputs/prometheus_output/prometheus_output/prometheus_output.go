@@ -143,7 +143,8 @@ func (p *prometheusOutput) SetEventProcessors(ps map[string]map[string]interface
 					epCfg[epType],
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					p.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -231,6 +232,15 @@ func (p *prometheusOutput) Init(ctx context.Context, name string, cfg map[string
 	wctx, wcancel := context.WithCancel(ctx)
 	go p.worker(wctx)
 	go p.expireMetricsPeriodic(wctx)
+	formatters.StartEmitters(wctx, p.evps, func(evs ...*formatters.EventMsg) {
+		for _, ev := range evs {
+			select {
+			case <-wctx.Done():
+				return
+			case p.eventChan <- ev:
+			}
+		}
+	})
 	go func() {
 		defer p.wg.Done()
 		err = p.server.Serve(listener)
@@ -309,6 +319,7 @@ func (p *prometheusOutput) Close() error {
 	if p.gnmiCache != nil {
 		p.gnmiCache.Stop()
 	}
+	formatters.CloseProcessors(p.evps)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	err = p.server.Shutdown(ctx)
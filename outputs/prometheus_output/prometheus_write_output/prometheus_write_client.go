@@ -3,6 +3,7 @@ package prometheus_write_output
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 
 	gogoproto "github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/karimra/gnmic/outputs"
 	"github.com/karimra/gnmic/utils"
 	"github.com/prometheus/prometheus/prompb"
 )
@@ -148,11 +150,112 @@ RETRY:
 		if err != nil {
 			return err
 		}
-		return fmt.Errorf("write response failed, code=%d, body=%s", rsp.StatusCode, string(msg))
+		return &responseError{code: rsp.StatusCode, body: string(msg)}
 	}
 	return nil
 }
 
+// responseError is returned by writeRequest when the remote responds with a status code >= 300.
+type responseError struct {
+	code int
+	body string
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("write response failed, code=%d, body=%s", e.code, e.body)
+}
+
+// retryableWriteError returns true if the write error is a transport error,
+// a server error or a rate limiting response.
+func retryableWriteError(err error) bool {
+	var rerr *responseError
+	if !errors.As(err, &rerr) {
+		return true
+	}
+	return rerr.code == http.StatusTooManyRequests || rerr.code >= http.StatusInternalServerError
+}
+
+// bufferWorker reads batches of messages from the buffer and writes them to the remote address
+// in chunks of at most `max-time-series-per-write` time series,
+// a batch is acknowledged once all its chunks are written.
+// Transport errors, server errors and rate limiting are retried up to
+// the buffer max-retries, other errors drop the chunk.
+func (p *promWriteOutput) bufferWorker(ctx context.Context) {
+	p.logger.Printf("starting buffer worker")
+	for {
+		recs, err := p.buffer.ReadBatch(ctx, p.Cfg.BufferSize)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, outputs.ErrBufferClosed) {
+				p.logger.Printf("buffer worker terminating...")
+				return
+			}
+			p.logger.Printf("failed to read from buffer: %v", err)
+			time.Sleep(bufferRetryTimer)
+			continue
+		}
+		pts := make([]prompb.TimeSeries, 0, len(recs))
+		for _, rec := range recs {
+			evs, _, err := outputs.DecodeBufferedEvents(rec, p.evps...)
+			if err != nil {
+				p.logger.Printf("failed to decode buffered message: %v", err)
+				continue
+			}
+			for _, ev := range evs {
+				for _, ts := range p.mb.TimeSeriesFromEvent(ev) {
+					p.cacheMetadata(ts.Name)
+					pts = append(pts, *ts.TS)
+				}
+			}
+		}
+		for start := 0; start < len(pts); start += p.Cfg.MaxTimeSeriesPerWrite {
+			end := start + p.Cfg.MaxTimeSeriesPerWrite
+			if end > len(pts) {
+				end = len(pts)
+			}
+			if !p.writeBufferedChunk(ctx, pts[start:end]) {
+				return
+			}
+		}
+		err = p.buffer.Ack()
+		if err != nil {
+			p.logger.Printf("failed to acknowledge buffered messages: %v", err)
+		}
+	}
+}
+
+// writeBufferedChunk writes the time series chunk with retries,
+// it returns false if ctx is done before the chunk is written or dropped.
+func (p *promWriteOutput) writeBufferedChunk(ctx context.Context, chunk []prompb.TimeSeries) bool {
+	for attempt := 1; ; attempt++ {
+		if p.Cfg.Debug {
+			p.logger.Printf("writing a %d time series chunk", len(chunk))
+		}
+		err := p.writeRequest(ctx, &prompb.WriteRequest{
+			Timeseries: chunk,
+		})
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !retryableWriteError(err) {
+			p.logger.Printf("buffer worker write error: %v, dropping %d time series", err, len(chunk))
+			return true
+		}
+		if p.Cfg.Buffer.MaxRetries >= 0 && attempt > p.Cfg.Buffer.MaxRetries {
+			p.logger.Printf("buffer worker write error: %v, max retries reached, dropping %d time series", err, len(chunk))
+			return true
+		}
+		p.logger.Printf("buffer worker write error: %v, retrying in %s", err, bufferRetryTimer)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(bufferRetryTimer):
+		}
+	}
+}
+
 // metadataWriter writes the cached metadata entries to the remote address each `metadata.interval`
 func (p *promWriteOutput) metadataWriter(ctx context.Context) {
 	if p.Cfg.Metadata == nil || !p.Cfg.Metadata.Include {
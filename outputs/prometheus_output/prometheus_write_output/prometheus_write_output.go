@@ -34,6 +34,7 @@ const (
 	defaultMaxTSPerWrite              = 500
 	defaultMaxMetaDataEntriesPerWrite = 500
 	defaultMetricHelp                 = "gNMIc generated metric"
+	bufferRetryTimer                  = 5 * time.Second
 	userAgent                         = "gNMIc prometheus write"
 )
 
@@ -67,6 +68,7 @@ type promWriteOutput struct {
 	evps      []formatters.EventProcessor
 	targetTpl *template.Template
 	cfn       context.CancelFunc
+	buffer    *outputs.DiskBuffer
 	// TODO:
 	// gnmiCache *cache.GnmiOutputCache
 }
@@ -92,6 +94,8 @@ type config struct {
 	TargetTemplate         string   `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
 	StringsAsLabels        bool     `mapstructure:"strings-as-labels,omitempty" json:"strings-as-labels,omitempty"`
 	EventProcessors        []string `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+
+	Buffer *outputs.BufferConfig `mapstructure:"buffer,omitempty" json:"buffer,omitempty"`
 }
 
 type auth struct {
@@ -161,11 +165,38 @@ func (p *promWriteOutput) Init(ctx context.Context, name string, cfg map[string]
 	if err != nil {
 		return err
 	}
+	if p.Cfg.Buffer != nil {
+		p.buffer, err = outputs.NewDiskBuffer(p.Cfg.Buffer)
+		if err != nil {
+			return fmt.Errorf("failed to create buffer: %v", err)
+		}
+	}
 
 	ctx, p.cfn = context.WithCancel(ctx)
-	go p.worker(ctx)
-	go p.writer(ctx)
+	if p.buffer != nil {
+		go p.bufferWorker(ctx)
+	} else {
+		go p.worker(ctx)
+		go p.writer(ctx)
+	}
 	go p.metadataWriter(ctx)
+	formatters.StartEmitters(ctx, p.evps, func(evs ...*formatters.EventMsg) {
+		if p.buffer != nil {
+			// the emitted events already went through the event processors,
+			// they are marked as such in the buffer.
+			for _, ev := range evs {
+				p.writeBuffer(nil, ev, outputs.Meta{outputs.BufferedEmittedMetaKey: "true"})
+			}
+			return
+		}
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case p.eventChan <- ev:
+			}
+		}
+	})
 	p.logger.Printf("initialized prometheus write output %s: %s", p.Cfg.Name, p.String())
 	return nil
 }
@@ -185,6 +216,10 @@ func (p *promWriteOutput) Write(ctx context.Context, rsp proto.Message, meta out
 		if err != nil {
 			p.logger.Printf("failed to add target to the response: %v", err)
 		}
+		if p.buffer != nil {
+			p.writeBuffer(rsp, nil, meta)
+			return
+		}
 
 		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, p.evps...)
 		if err != nil {
@@ -206,6 +241,10 @@ func (p *promWriteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMs
 	case <-ctx.Done():
 		return
 	default:
+		if p.buffer != nil {
+			p.writeBuffer(nil, ev, nil)
+			return
+		}
 		var evs = []*formatters.EventMsg{ev}
 		for _, proc := range p.evps {
 			evs = proc.Apply(evs...)
@@ -221,6 +260,10 @@ func (p *promWriteOutput) Close() error {
 		return nil
 	}
 	p.cfn()
+	formatters.CloseProcessors(p.evps)
+	if p.buffer != nil {
+		p.buffer.Close()
+	}
 	return nil
 }
 
@@ -257,7 +300,8 @@ func (p *promWriteOutput) SetEventProcessors(ps map[string]map[string]interface{
 				err := ep.Init(epCfg[epType],
 					formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					p.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -302,16 +346,7 @@ func (p *promWriteOutput) worker(ctx context.Context) {
 					p.buffDrainCh <- struct{}{}
 				}
 				// populate metadata cache
-				p.m.Lock()
-				if p.Cfg.Debug {
-					p.logger.Printf("saving metrics metadata")
-				}
-				p.metadataCache[pts.Name] = prompb.MetricMetadata{
-					Type:             prompb.MetricMetadata_COUNTER,
-					MetricFamilyName: pts.Name,
-					Help:             defaultMetricHelp,
-				}
-				p.m.Unlock()
+				p.cacheMetadata(pts.Name)
 				// write time series to buffer
 				if p.Cfg.Debug {
 					p.logger.Printf("writing TimeSeries to buffer")
@@ -322,6 +357,31 @@ func (p *promWriteOutput) worker(ctx context.Context) {
 	}
 }
 
+func (p *promWriteOutput) cacheMetadata(name string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.Cfg.Debug {
+		p.logger.Printf("saving metrics metadata")
+	}
+	p.metadataCache[name] = prompb.MetricMetadata{
+		Type:             prompb.MetricMetadata_COUNTER,
+		MetricFamilyName: name,
+		Help:             defaultMetricHelp,
+	}
+}
+
+func (p *promWriteOutput) writeBuffer(rsp proto.Message, ev *formatters.EventMsg, meta outputs.Meta) {
+	rec, err := outputs.EncodeBufferedMsg(rsp, ev, meta)
+	if err != nil {
+		p.logger.Printf("failed to encode message: %v", err)
+		return
+	}
+	err = p.buffer.Write(rec)
+	if err != nil {
+		p.logger.Printf("failed to write message to buffer: %v", err)
+	}
+}
+
 func (p *promWriteOutput) setDefaults() error {
 	if p.Cfg.Timeout <= 0 {
 		p.Cfg.Timeout = defaultTimeout
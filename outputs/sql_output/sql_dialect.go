@@ -0,0 +1,161 @@
+package sql_output
+
+import (
+	"fmt"
+	"strings"
+)
+
+type columnType int
+
+const (
+	columnString columnType = iota
+	columnInt
+	columnFloat
+	columnBool
+	columnTime
+)
+
+// dialect holds the SQL syntax differences between the supported databases.
+type dialect struct {
+	// database/sql driver name
+	driver string
+	// column types
+	types map[columnType]string
+	// nullable wraps a column type to allow NULL values
+	nullable func(string) string
+	// placeholder returns the i-th (starting at 1) query argument placeholder
+	placeholder func(i int) string
+	// tableOptions are appended to the CREATE TABLE statement
+	tableOptions func(timestampColumn, nameColumn string) string
+	// supports ADD COLUMN IF NOT EXISTS
+	addColumnIfNotExists bool
+	// booleans are stored as integers
+	boolAsInt bool
+}
+
+var dialects = map[string]*dialect{
+	"sqlite": {
+		driver: "sqlite",
+		types: map[columnType]string{
+			columnString: "TEXT",
+			columnInt:    "INTEGER",
+			columnFloat:  "REAL",
+			columnBool:   "INTEGER",
+			columnTime:   "DATETIME",
+		},
+		nullable:     func(t string) string { return t },
+		placeholder:  func(int) string { return "?" },
+		tableOptions: func(string, string) string { return "" },
+		boolAsInt:    true,
+	},
+	"postgres": {
+		driver: "postgres",
+		types: map[columnType]string{
+			columnString: "TEXT",
+			columnInt:    "BIGINT",
+			columnFloat:  "DOUBLE PRECISION",
+			columnBool:   "BOOLEAN",
+			columnTime:   "TIMESTAMPTZ",
+		},
+		nullable:             func(t string) string { return t },
+		placeholder:          func(i int) string { return fmt.Sprintf("$%d", i) },
+		tableOptions:         func(string, string) string { return "" },
+		addColumnIfNotExists: true,
+	},
+	"clickhouse": {
+		driver: "clickhouse",
+		types: map[columnType]string{
+			columnString: "String",
+			columnInt:    "Int64",
+			columnFloat:  "Float64",
+			columnBool:   "UInt8",
+			columnTime:   "DateTime64(9)",
+		},
+		nullable:    func(t string) string { return fmt.Sprintf("Nullable(%s)", t) },
+		placeholder: func(int) string { return "?" },
+		tableOptions: func(ts, name string) string {
+			return fmt.Sprintf(" ENGINE = MergeTree() ORDER BY (%s, %s)", quoteIdent(name), quoteIdent(ts))
+		},
+		addColumnIfNotExists: true,
+		boolAsInt:            true,
+	},
+}
+
+// columnTypeFromDatabase maps a database column type name to a columnType.
+func columnTypeFromDatabase(name string) columnType {
+	name = strings.ToUpper(name)
+	switch {
+	case strings.Contains(name, "BOOL"):
+		return columnBool
+	case strings.Contains(name, "INT"):
+		return columnInt
+	case strings.Contains(name, "FLOAT"),
+		strings.Contains(name, "DOUBLE"),
+		strings.Contains(name, "REAL"),
+		strings.Contains(name, "NUMERIC"),
+		strings.Contains(name, "DECIMAL"):
+		return columnFloat
+	case strings.Contains(name, "TIME"),
+		strings.Contains(name, "DATE"):
+		return columnTime
+	}
+	return columnString
+}
+
+func (d *dialect) createTable(table string, cols []*column) string {
+	sb := new(strings.Builder)
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(table)
+	sb.WriteString(" (")
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(c.name))
+		sb.WriteString(" ")
+		sb.WriteString(d.columnDefinition(c))
+	}
+	sb.WriteString(")")
+	sb.WriteString(d.tableOptions(cols[0].name, cols[1].name))
+	return sb.String()
+}
+
+func (d *dialect) addColumn(table string, c *column) string {
+	if d.addColumnIfNotExists {
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, quoteIdent(c.name), d.columnDefinition(c))
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, quoteIdent(c.name), d.columnDefinition(c))
+}
+
+func (d *dialect) insert(table string, cols []string) string {
+	sb := new(strings.Builder)
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(table)
+	sb.WriteString(" (")
+	for i, c := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(c))
+	}
+	sb.WriteString(") VALUES (")
+	for i := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(d.placeholder(i + 1))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (d *dialect) columnDefinition(c *column) string {
+	if c.required {
+		return d.types[c.typ]
+	}
+	return d.nullable(d.types[c.typ])
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
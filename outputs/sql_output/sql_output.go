@@ -0,0 +1,586 @@
+package sql_output
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	_ "github.com/ClickHouse/clickhouse-go"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/karimra/gnmic/formatters"
+	"github.com/karimra/gnmic/outputs"
+	"github.com/karimra/gnmic/types"
+	"github.com/karimra/gnmic/utils"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	outputType             = "sql"
+	loggingPrefix          = "[sql_output:%s] "
+	defaultTable           = "gnmic"
+	defaultTimestampColumn = "timestamp"
+	defaultNameColumn      = "name"
+	defaultTagsColumn      = "tags"
+	defaultBatchSize       = 1000
+	defaultFlushTimer      = 10 * time.Second
+	defaultBufferSize      = 1000
+	defaultTimeout         = 10 * time.Second
+
+	tagsAsColumns = "columns"
+	tagsAsJSON    = "json"
+)
+
+var (
+	tableNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+	columnNameRegex = regexp.MustCompile("[^a-zA-Z0-9_]+")
+)
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &sqlOutput{
+			Cfg:    &config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type sqlOutput struct {
+	Cfg    *config
+	logger *log.Logger
+
+	db        *sql.DB
+	dialect   *dialect
+	eventChan chan *formatters.EventMsg
+	// table columns, only accessed from the worker goroutine after Init
+	columns map[string]*column
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type column struct {
+	name     string
+	typ      columnType
+	required bool
+}
+
+type config struct {
+	Name               string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Driver             string        `mapstructure:"driver,omitempty" json:"driver,omitempty"`
+	DSN                string        `mapstructure:"dsn,omitempty" json:"-"`
+	Table              string        `mapstructure:"table,omitempty" json:"table,omitempty"`
+	TagsAs             string        `mapstructure:"tags-as,omitempty" json:"tags-as,omitempty"`
+	TagsColumn         string        `mapstructure:"tags-column,omitempty" json:"tags-column,omitempty"`
+	TimestampColumn    string        `mapstructure:"timestamp-column,omitempty" json:"timestamp-column,omitempty"`
+	NameColumn         string        `mapstructure:"name-column,omitempty" json:"name-column,omitempty"`
+	SkipTableCreation  bool          `mapstructure:"skip-table-creation,omitempty" json:"skip-table-creation,omitempty"`
+	BatchSize          int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	FlushTimer         time.Duration `mapstructure:"flush-timer,omitempty" json:"flush-timer,omitempty"`
+	BufferSize         int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	Timeout            time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	AddTarget          string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+func (s *sqlOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, s.Cfg)
+	if err != nil {
+		return err
+	}
+	if s.Cfg.Name == "" {
+		s.Cfg.Name = name
+	}
+	s.logger.SetPrefix(fmt.Sprintf(loggingPrefix, s.Cfg.Name))
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	err = s.setDefaults()
+	if err != nil {
+		return err
+	}
+
+	if s.Cfg.TargetTemplate == "" {
+		s.targetTpl = outputs.DefaultTargetTemplate
+	} else if s.Cfg.AddTarget != "" {
+		s.targetTpl, err = utils.CreateTemplate("target-template", s.Cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	s.db, err = sql.Open(s.dialect.driver, s.Cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open %s database: %v", s.Cfg.Driver, err)
+	}
+	err = s.initTable(ctx)
+	if err != nil {
+		s.db.Close()
+		return err
+	}
+
+	s.eventChan = make(chan *formatters.EventMsg, s.Cfg.BufferSize)
+	ctx, s.cfn = context.WithCancel(ctx)
+	go s.worker(ctx)
+	formatters.StartEmitters(ctx, s.evps, func(evs ...*formatters.EventMsg) {
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- ev:
+			}
+		}
+	})
+	s.logger.Printf("initialized sql output %s: %s", s.Cfg.Name, s.String())
+	return nil
+}
+
+func (s *sqlOutput) setDefaults() error {
+	var ok bool
+	s.Cfg.Driver = strings.ToLower(s.Cfg.Driver)
+	s.dialect, ok = dialects[s.Cfg.Driver]
+	if !ok {
+		return fmt.Errorf("unknown driver %q, expecting one of 'clickhouse', 'postgres' or 'sqlite'", s.Cfg.Driver)
+	}
+	if s.Cfg.DSN == "" {
+		return errors.New("missing dsn field")
+	}
+	if s.Cfg.Table == "" {
+		s.Cfg.Table = defaultTable
+	}
+	if !tableNameRegex.MatchString(s.Cfg.Table) {
+		return fmt.Errorf("invalid table name %q", s.Cfg.Table)
+	}
+	switch s.Cfg.TagsAs {
+	case "":
+		s.Cfg.TagsAs = tagsAsColumns
+	case tagsAsColumns, tagsAsJSON:
+	default:
+		return fmt.Errorf("unknown tags-as value %q, expecting 'columns' or 'json'", s.Cfg.TagsAs)
+	}
+	if s.Cfg.TagsColumn == "" {
+		s.Cfg.TagsColumn = defaultTagsColumn
+	}
+	if s.Cfg.TimestampColumn == "" {
+		s.Cfg.TimestampColumn = defaultTimestampColumn
+	}
+	if s.Cfg.NameColumn == "" {
+		s.Cfg.NameColumn = defaultNameColumn
+	}
+	if s.Cfg.BatchSize <= 0 {
+		s.Cfg.BatchSize = defaultBatchSize
+	}
+	if s.Cfg.FlushTimer <= 0 {
+		s.Cfg.FlushTimer = defaultFlushTimer
+	}
+	if s.Cfg.BufferSize <= 0 {
+		s.Cfg.BufferSize = defaultBufferSize
+	}
+	if s.Cfg.Timeout <= 0 {
+		s.Cfg.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+func (s *sqlOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+	switch rsp := rsp.(type) {
+	case *gnmi.SubscribeResponse:
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		if s.Cfg.AddTarget != "" {
+			var err error
+			rsp, err = outputs.AddSubscriptionTarget(rsp, meta, s.Cfg.AddTarget, s.targetTpl)
+			if err != nil {
+				s.logger.Printf("failed to add target to the response: %v", err)
+			}
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, rsp, meta, s.evps...)
+		if err != nil {
+			s.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- ev:
+			}
+		}
+	}
+}
+
+func (s *sqlOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range s.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (s *sqlOutput) Close() error {
+	if s.cfn == nil {
+		return nil
+	}
+	s.cfn()
+	formatters.CloseProcessors(s.evps)
+	return s.db.Close()
+}
+
+func (s *sqlOutput) RegisterMetrics(_ *prometheus.Registry) {}
+
+func (s *sqlOutput) String() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *sqlOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && s.logger != nil {
+		s.logger.SetOutput(logger.Writer())
+		s.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (s *sqlOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) {
+	for _, epName := range s.Cfg.EventProcessors {
+		if epCfg, ok := ps[epName]; ok {
+			epType := ""
+			for k := range epCfg {
+				epType = k
+				break
+			}
+			if in, ok := formatters.EventProcessors[epType]; ok {
+				ep := in()
+				err := ep.Init(epCfg[epType],
+					formatters.WithLogger(logger),
+					formatters.WithTargets(tcs),
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
+				if err != nil {
+					s.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
+					continue
+				}
+				s.evps = append(s.evps, ep)
+				s.logger.Printf("added event processor '%s' of type=%s to sql output", epName, epType)
+				continue
+			}
+			s.logger.Printf("%q event processor has an unknown type=%q", epName, epType)
+			continue
+		}
+		s.logger.Printf("%q event processor not found!", epName)
+	}
+}
+
+func (s *sqlOutput) SetName(name string) {
+	if s.Cfg.Name == "" {
+		s.Cfg.Name = name
+	}
+}
+
+func (s *sqlOutput) SetClusterName(_ string) {}
+
+func (s *sqlOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+// worker accumulates events and inserts them when the batch size
+// is reached or when the flush timer expires.
+func (s *sqlOutput) worker(ctx context.Context) {
+	ticker := time.NewTicker(s.Cfg.FlushTimer)
+	defer ticker.Stop()
+	evs := make([]*formatters.EventMsg, 0, s.Cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.eventChan:
+			if len(ev.Values) == 0 {
+				continue
+			}
+			evs = append(evs, ev)
+			if len(evs) >= s.Cfg.BatchSize {
+				s.flush(ctx, evs)
+				evs = make([]*formatters.EventMsg, 0, s.Cfg.BatchSize)
+			}
+		case <-ticker.C:
+			if len(evs) == 0 {
+				continue
+			}
+			s.flush(ctx, evs)
+			evs = make([]*formatters.EventMsg, 0, s.Cfg.BatchSize)
+		}
+	}
+}
+
+func (s *sqlOutput) flush(ctx context.Context, evs []*formatters.EventMsg) {
+	if s.Cfg.Debug {
+		s.logger.Printf("inserting %d event(s)", len(evs))
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Cfg.Timeout)
+	defer cancel()
+	err := s.insert(ctx, evs)
+	if err != nil {
+		s.logger.Printf("failed to insert %d event(s): %v", len(evs), err)
+	}
+}
+
+// initTable creates the table if needed and loads its columns.
+func (s *sqlOutput) initTable(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.Cfg.Timeout)
+	defer cancel()
+	if !s.Cfg.SkipTableCreation {
+		cols := []*column{
+			{name: s.Cfg.TimestampColumn, typ: columnTime, required: true},
+			{name: s.Cfg.NameColumn, typ: columnString, required: true},
+		}
+		if s.Cfg.TagsAs == tagsAsJSON {
+			cols = append(cols, &column{name: s.Cfg.TagsColumn, typ: columnString})
+		}
+		_, err := s.db.ExecContext(ctx, s.dialect.createTable(s.Cfg.Table, cols))
+		if err != nil {
+			return fmt.Errorf("failed to create table %q: %v", s.Cfg.Table, err)
+		}
+	}
+	return s.loadColumns(ctx)
+}
+
+func (s *sqlOutput) loadColumns(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", s.Cfg.Table))
+	if err != nil {
+		return fmt.Errorf("failed to read table %q columns: %v", s.Cfg.Table, err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to read table %q columns: %v", s.Cfg.Table, err)
+	}
+	s.columns = make(map[string]*column, len(cts))
+	for _, ct := range cts {
+		s.columns[ct.Name()] = &column{
+			name: ct.Name(),
+			typ:  columnTypeFromDatabase(ct.DatabaseTypeName()),
+		}
+	}
+	return rows.Err()
+}
+
+// ensureColumn returns the table column named name,
+// the column is added to the table if it does not exist.
+// It returns nil if the column does not exist and cannot be added.
+func (s *sqlOutput) ensureColumn(ctx context.Context, name string, typ columnType) *column {
+	if c, ok := s.columns[name]; ok {
+		return c
+	}
+	if s.Cfg.SkipTableCreation {
+		return nil
+	}
+	c := &column{name: name, typ: typ}
+	_, err := s.db.ExecContext(ctx, s.dialect.addColumn(s.Cfg.Table, c))
+	if err != nil {
+		// the column might have been added by another instance
+		if lerr := s.loadColumns(ctx); lerr == nil {
+			if c, ok := s.columns[name]; ok {
+				return c
+			}
+		}
+		s.logger.Printf("failed to add column %q: %v", name, err)
+		return nil
+	}
+	if s.Cfg.Debug {
+		s.logger.Printf("added column %q to table %q", name, s.Cfg.Table)
+	}
+	s.columns[name] = c
+	return c
+}
+
+// row builds the columns and arguments used to insert the event ev,
+// the columns missing from the table are added with the types in valueTypes.
+func (s *sqlOutput) row(ctx context.Context, ev *formatters.EventMsg, valueTypes map[string]columnType) ([]string, []interface{}) {
+	ts := ev.Timestamp
+	if ts == 0 || s.Cfg.OverrideTimestamps {
+		ts = time.Now().UnixNano()
+	}
+	fields := map[string]interface{}{
+		s.Cfg.TimestampColumn: time.Unix(0, ts).UTC(),
+		s.Cfg.NameColumn:      ev.Name,
+	}
+	// tag or value names the event columns are derived from
+	sources := make(map[string]string)
+	switch s.Cfg.TagsAs {
+	case tagsAsJSON:
+		b, err := json.Marshal(ev.Tags)
+		if err == nil {
+			fields[s.Cfg.TagsColumn] = string(b)
+		}
+	case tagsAsColumns:
+		tags := make([]string, 0, len(ev.Tags))
+		for k := range ev.Tags {
+			tags = append(tags, k)
+		}
+		sort.Strings(tags)
+		for _, k := range tags {
+			name := columnName(k)
+			if name == "" || s.isReserved(name) {
+				continue
+			}
+			if src, ok := sources[name]; ok {
+				s.logger.Printf("event %q: dropping tag %q, its column %q is already used by tag %q", ev.Name, k, name, src)
+				continue
+			}
+			c := s.ensureColumn(ctx, name, columnString)
+			if c == nil {
+				continue
+			}
+			v, err := convertValue(ev.Tags[k], c.typ, s.dialect)
+			if err != nil {
+				s.logger.Printf("event %q: dropping tag %q: %v", ev.Name, k, err)
+				continue
+			}
+			sources[name] = k
+			fields[name] = v
+		}
+	}
+	values := make([]string, 0, len(ev.Values))
+	for k := range ev.Values {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	for _, k := range values {
+		name := columnName(k)
+		if name == "" || s.isReserved(name) {
+			continue
+		}
+		if src, ok := sources[name]; ok {
+			s.logger.Printf("event %q: dropping value %q, its column %q is already used by %q", ev.Name, k, name, src)
+			continue
+		}
+		c := s.ensureColumn(ctx, name, valueTypes[name])
+		if c == nil {
+			continue
+		}
+		v, err := convertValue(ev.Values[k], c.typ, s.dialect)
+		if err != nil {
+			s.logger.Printf("event %q: dropping value %q: %v", ev.Name, k, err)
+			continue
+		}
+		sources[name] = k
+		fields[name] = v
+	}
+	cols := make([]string, 0, len(fields))
+	for name := range fields {
+		cols = append(cols, name)
+	}
+	sort.Strings(cols)
+	args := make([]interface{}, 0, len(cols))
+	for _, name := range cols {
+		args = append(args, fields[name])
+	}
+	return cols, args
+}
+
+// insert writes the events evs in a single transaction,
+// events with the same set of columns share a prepared statement.
+func (s *sqlOutput) insert(ctx context.Context, evs []*formatters.EventMsg) error {
+	type row struct {
+		key  string
+		cols []string
+		args []interface{}
+	}
+	// the type of a new value column is wide enough to store all its values in the batch
+	valueTypes := make(map[string]columnType)
+	for _, ev := range evs {
+		for k, v := range ev.Values {
+			name := columnName(k)
+			if typ, ok := valueTypes[name]; ok {
+				valueTypes[name] = widenColumnType(typ, valueColumnType(v))
+				continue
+			}
+			valueTypes[name] = valueColumnType(v)
+		}
+	}
+	rows := make([]*row, 0, len(evs))
+	for _, ev := range evs {
+		cols, args := s.row(ctx, ev, valueTypes)
+		rows = append(rows, &row{key: strings.Join(cols, ","), cols: cols, args: args})
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+	for _, r := range rows {
+		stmt, ok := stmts[r.key]
+		if !ok {
+			stmt, err = tx.PrepareContext(ctx, s.dialect.insert(s.Cfg.Table, r.cols))
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			stmts[r.key] = stmt
+		}
+		_, err = stmt.ExecContext(ctx, r.args...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlOutput) isReserved(name string) bool {
+	switch name {
+	case s.Cfg.TimestampColumn, s.Cfg.NameColumn:
+		return true
+	case s.Cfg.TagsColumn:
+		return s.Cfg.TagsAs == tagsAsJSON
+	}
+	return false
+}
+
+// columnName derives a column name from a tag or value name,
+// non alphanumeric characters are replaced with an underscore.
+func columnName(s string) string {
+	return strings.Trim(columnNameRegex.ReplaceAllString(s, "_"), "_")
+}
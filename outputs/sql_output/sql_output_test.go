@@ -0,0 +1,220 @@
+package sql_output
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/formatters"
+)
+
+func newTestOutput(t *testing.T, cfg map[string]interface{}) *sqlOutput {
+	cfg["driver"] = "sqlite"
+	cfg["dsn"] = filepath.Join(t.TempDir(), "gnmic.db")
+	s := &sqlOutput{
+		Cfg:    &config{},
+		logger: log.New(io.Discard, "", 0),
+	}
+	err := s.Init(context.TODO(), "sql1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+var testEvents = []*formatters.EventMsg{
+	{
+		Name:      "sub1",
+		Timestamp: 1000000000,
+		Tags: map[string]string{
+			"source":         "router1",
+			"interface_name": "ethernet-1/1",
+		},
+		Values: map[string]interface{}{
+			"/interface/statistics/in-octets": uint64(100),
+			"/interface/oper-state":           "up",
+		},
+	},
+	{
+		Name:      "sub1",
+		Timestamp: 2000000000,
+		Tags: map[string]string{
+			"source": "router2",
+		},
+		Values: map[string]interface{}{
+			"/interface/statistics/in-octets": "200",
+			"/interface/statistics/load":      1.5,
+		},
+	},
+}
+
+func TestInsertTagsAsColumns(t *testing.T) {
+	s := newTestOutput(t, map[string]interface{}{
+		"table": "events",
+	})
+	err := s.insert(context.TODO(), testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTypes := map[string]columnType{
+		"timestamp":                      columnTime,
+		"name":                           columnString,
+		"source":                         columnString,
+		"interface_name":                 columnString,
+		"interface_statistics_in_octets": columnInt,
+		"interface_oper_state":           columnString,
+		"interface_statistics_load":      columnFloat,
+	}
+	for name, typ := range expectedTypes {
+		c, ok := s.columns[name]
+		if !ok {
+			t.Errorf("missing column %q", name)
+			continue
+		}
+		if c.typ != typ {
+			t.Errorf("column %q: expected type %d, got %d", name, typ, c.typ)
+		}
+	}
+	rows, err := s.db.Query(`SELECT source, interface_statistics_in_octets, interface_statistics_load FROM events ORDER BY timestamp`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	type result struct {
+		source string
+		octets int64
+		load   *float64
+	}
+	results := make([]result, 0, 2)
+	for rows.Next() {
+		var r result
+		err = rows.Scan(&r.source, &r.octets, &r.load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, r)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(results))
+	}
+	if results[0].source != "router1" || results[0].octets != 100 || results[0].load != nil {
+		t.Errorf("unexpected first row: %+v", results[0])
+	}
+	if results[1].source != "router2" || results[1].octets != 200 || results[1].load == nil || *results[1].load != 1.5 {
+		t.Errorf("unexpected second row: %+v", results[1])
+	}
+
+	// the columns are loaded from the existing table
+	err = s.loadColumns(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.columns["interface_statistics_in_octets"]; c == nil || c.typ != columnInt {
+		t.Errorf("unexpected loaded column: %+v", c)
+	}
+}
+
+func TestInsertTagsAsJSON(t *testing.T) {
+	s := newTestOutput(t, map[string]interface{}{
+		"tags-as": "json",
+	})
+	err := s.insert(context.TODO(), testEvents[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.columns["source"]; ok {
+		t.Errorf("unexpected tag column")
+	}
+	var tags string
+	err = s.db.QueryRow(`SELECT tags FROM gnmic`).Scan(&tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]string)
+	err = json.Unmarshal([]byte(tags), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["source"] != "router1" || m["interface_name"] != "ethernet-1/1" {
+		t.Errorf("unexpected tags: %v", m)
+	}
+}
+
+func TestInsertValueConversions(t *testing.T) {
+	s := newTestOutput(t, map[string]interface{}{})
+	// a new column is wide enough for all the batch values
+	err := s.insert(context.TODO(), []*formatters.EventMsg{
+		{Name: "sub1", Timestamp: 1, Values: map[string]interface{}{"counter": int64(1), "gauge": int64(1)}},
+		{Name: "sub1", Timestamp: 2, Values: map[string]interface{}{"gauge": 2.5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.columns["counter"]; c == nil || c.typ != columnInt {
+		t.Errorf("unexpected column: %+v", c)
+	}
+	if c := s.columns["gauge"]; c == nil || c.typ != columnFloat {
+		t.Errorf("unexpected column: %+v", c)
+	}
+	// a value that does not fit the existing column is dropped, not truncated;
+	// a value whose column name is already used by a tag is dropped
+	err = s.insert(context.TODO(), []*formatters.EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: 3,
+			Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+			Values: map[string]interface{}{
+				"counter":         3.7,
+				"gauge":           int64(3),
+				"/interface/name": "ethernet-1/2",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counter *int64
+	var gauge float64
+	var ifName string
+	err = s.db.QueryRow(`SELECT counter, gauge, interface_name FROM gnmic WHERE timestamp = ?`, time.Unix(0, 3).UTC()).Scan(&counter, &gauge, &ifName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter != nil {
+		t.Errorf("expected the float value to be dropped, got %d", *counter)
+	}
+	if gauge != 3 {
+		t.Errorf("expected gauge 3, got %v", gauge)
+	}
+	if ifName != "ethernet-1/1" {
+		t.Errorf("expected the tag value, got %q", ifName)
+	}
+	if _, err = convertValue("1.5", columnInt, dialects["sqlite"]); err == nil {
+		t.Errorf("expected an error converting a non integer string to an integer column")
+	}
+}
+
+func TestDialectStatements(t *testing.T) {
+	cols := []*column{
+		{name: "timestamp", typ: columnTime, required: true},
+		{name: "name", typ: columnString, required: true},
+		{name: "tags", typ: columnString},
+	}
+	expected := `CREATE TABLE IF NOT EXISTS t ("timestamp" DateTime64(9), "name" String, "tags" Nullable(String)) ENGINE = MergeTree() ORDER BY ("name", "timestamp")`
+	if got := dialects["clickhouse"].createTable("t", cols); got != expected {
+		t.Errorf("unexpected clickhouse create table statement:\n%s\n%s", got, expected)
+	}
+	expected = `INSERT INTO t ("a", "b") VALUES ($1, $2)`
+	if got := dialects["postgres"].insert("t", []string{"a", "b"}); got != expected {
+		t.Errorf("unexpected postgres insert statement: %s", got)
+	}
+	expected = `ALTER TABLE t ADD COLUMN "a" INTEGER`
+	if got := dialects["sqlite"].addColumn("t", &column{name: "a", typ: columnInt}); got != expected {
+		t.Errorf("unexpected sqlite add column statement: %s", got)
+	}
+}
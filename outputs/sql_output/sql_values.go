@@ -0,0 +1,180 @@
+package sql_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// valueColumnType returns the column type used to store the value v,
+// strings holding a number are stored in numeric columns.
+func valueColumnType(v interface{}) columnType {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64:
+		return columnInt
+	case float32, float64, *gnmi.Decimal64:
+		return columnFloat
+	case bool:
+		return columnBool
+	case string:
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return columnInt
+		}
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return columnFloat
+		}
+	}
+	return columnString
+}
+
+// widenColumnType returns a column type able to store
+// the values of both column types a and b.
+func widenColumnType(a, b columnType) columnType {
+	switch {
+	case a == b:
+		return a
+	case a == columnInt && b == columnFloat,
+		a == columnFloat && b == columnInt:
+		return columnFloat
+	}
+	return columnString
+}
+
+// convertValue converts v to a value that can be inserted in a column of type typ,
+// it returns an error if v cannot be converted without losing information.
+func convertValue(v interface{}, typ columnType, d *dialect) (interface{}, error) {
+	switch typ {
+	case columnInt:
+		if i, ok := toInt(v); ok {
+			return i, nil
+		}
+	case columnFloat:
+		if f, ok := toFloat(v); ok {
+			return f, nil
+		}
+	case columnBool:
+		var b bool
+		switch v := v.(type) {
+		case bool:
+			b = v
+		case string:
+			var err error
+			b, err = strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot store value %q in a %s column", v, d.types[typ])
+			}
+		default:
+			f, ok := toFloat(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot store %T value %v in a %s column", v, v, d.types[typ])
+			}
+			b = f != 0
+		}
+		if !d.boolAsInt {
+			return b, nil
+		}
+		if b {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	default:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			return string(b), nil
+		case *gnmi.Decimal64:
+			f, _ := toFloat(v)
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot store %T value %v in a %s column", v, v, d.types[typ])
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return toInt(uint64(v))
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case float32:
+		return floatToInt(float64(v))
+	case float64:
+		return floatToInt(v)
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, false
+			}
+			return floatToInt(f)
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+// floatToInt converts f to an int64 if it has no fractional part
+// and is within the int64 range.
+func floatToInt(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case uint64:
+		return float64(v), true
+	case *gnmi.Decimal64:
+		return float64(v.Digits) / math.Pow10(int(v.Precision)), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	}
+	i, ok := toInt(v)
+	return float64(i), ok
+}
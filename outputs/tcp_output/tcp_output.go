@@ -81,7 +81,7 @@ func (t *TCPOutput) SetEventProcessors(ps map[string]map[string]interface{},
 			}
 			if in, ok := formatters.EventProcessors[epType]; ok {
 				ep := in()
-				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs))
+				err := ep.Init(epCfg[epType], formatters.WithLogger(logger), formatters.WithTargets(tcs), formatters.WithProcessors(ps))
 				if err != nil {
 					t.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -145,6 +145,13 @@ func (t *TCPOutput) Init(ctx context.Context, name string, cfg map[string]interf
 	for i := 0; i < t.Cfg.NumWorkers; i++ {
 		go t.start(ctx, i)
 	}
+	if formatters.HasEmitters(t.evps) {
+		if t.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, t.evps, t.writeEvents)
+		} else {
+			t.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", t.Cfg.Format)
+		}
+	}
 	return nil
 }
 
@@ -169,6 +176,16 @@ func (t *TCPOutput) Write(ctx context.Context, m proto.Message, meta outputs.Met
 	}
 }
 
+// writeEvents writes the events emitted by the event processors.
+func (t *TCPOutput) writeEvents(evs ...*formatters.EventMsg) {
+	b, err := t.mo.MarshalEvents(evs)
+	if err != nil {
+		t.logger.Printf("failed marshaling events: %v", err)
+		return
+	}
+	t.buffer <- b
+}
+
 func (t *TCPOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
 func (t *TCPOutput) Close() error {
@@ -176,6 +193,7 @@ func (t *TCPOutput) Close() error {
 	if t.limiter != nil {
 		t.limiter.Stop()
 	}
+	formatters.CloseProcessors(t.evps)
 	return nil
 }
 func (t *TCPOutput) RegisterMetrics(reg *prometheus.Registry) {}
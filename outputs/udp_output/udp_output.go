@@ -81,7 +81,8 @@ func (u *UDPSock) SetEventProcessors(ps map[string]map[string]interface{},
 				ep := in()
 				err := ep.Init(epCfg[epType], formatters.WithLogger(logger),
 					formatters.WithTargets(tcs),
-					formatters.WithActions(acts))
+					formatters.WithActions(acts),
+					formatters.WithProcessors(ps))
 				if err != nil {
 					u.logger.Printf("failed initializing event processor '%s' of type='%s': %v", epName, epType, err)
 					continue
@@ -137,6 +138,13 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 		}
 		u.targetTpl = u.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
+	if formatters.HasEmitters(u.evps) {
+		if u.Cfg.Format == "event" {
+			formatters.StartEmitters(ctx, u.evps, u.writeEvents)
+		} else {
+			u.logger.Printf("event processors emitting events asynchronously are only supported with format \"event\", they pass the events through with format %q", u.Cfg.Format)
+		}
+	}
 	go u.start(ctx)
 	return nil
 }
@@ -163,6 +171,16 @@ func (u *UDPSock) Write(ctx context.Context, m proto.Message, meta outputs.Meta)
 	}
 }
 
+// writeEvents writes the events emitted by the event processors.
+func (u *UDPSock) writeEvents(evs ...*formatters.EventMsg) {
+	b, err := u.mo.MarshalEvents(evs)
+	if err != nil {
+		u.logger.Printf("failed marshaling events: %v", err)
+		return
+	}
+	u.buffer <- b
+}
+
 func (u *UDPSock) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
 func (u *UDPSock) Close() error {
@@ -170,6 +188,7 @@ func (u *UDPSock) Close() error {
 	if u.limiter != nil {
 		u.limiter.Stop()
 	}
+	formatters.CloseProcessors(u.evps)
 	return nil
 }
 
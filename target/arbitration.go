@@ -0,0 +1,102 @@
+package target
+
+import (
+	"context"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maximum number of times a SetRequest is resent with a higher
+// election ID after the target returned a PermissionDenied error.
+const maxElectionIDRetries = 3
+
+// masterArbitration returns a master arbitration extension built from the target
+// configured election ID and role, nil if no election ID is configured.
+func (t *Target) masterArbitration() *gnmi_ext.Extension {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.electionID == nil {
+		if t.Config.ElectionID == 0 {
+			return nil
+		}
+		t.electionID = &gnmi_ext.Uint128{Low: t.Config.ElectionID}
+	}
+	ma := &gnmi_ext.MasterArbitration{
+		ElectionId: &gnmi_ext.Uint128{
+			High: t.electionID.GetHigh(),
+			Low:  t.electionID.GetLow(),
+		},
+	}
+	if t.Config.Role != "" {
+		ma.Role = &gnmi_ext.Role{Id: t.Config.Role}
+	}
+	return &gnmi_ext.Extension{
+		Ext: &gnmi_ext.Extension_MasterArbitration{MasterArbitration: ma},
+	}
+}
+
+// setElectionID stores id as the target election ID if it is higher than the current one.
+func (t *Target) setElectionID(id *gnmi_ext.Uint128) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.electionID == nil || compareUint128(id, t.electionID) > 0 {
+		t.electionID = &gnmi_ext.Uint128{High: id.GetHigh(), Low: id.GetLow()}
+	}
+}
+
+// set sends the gnmi.SetRequest req, if it includes a master arbitration extension
+// and the target returns a PermissionDenied error,
+// the request is resent with an incremented election ID.
+// req is expected to be a copy of the caller's request.
+func (t *Target) set(ctx context.Context, req *gnmi.SetRequest, storeElectionID bool) (*gnmi.SetResponse, error) {
+	rsp, err := t.Client.Set(ctx, req)
+	for i := 0; i < maxElectionIDRetries; i++ {
+		if status.Code(err) != codes.PermissionDenied {
+			break
+		}
+		ma := getMasterArbitration(req.GetExtension())
+		if ma == nil {
+			break
+		}
+		ma.ElectionId = incrementUint128(ma.GetElectionId())
+		if storeElectionID {
+			t.setElectionID(ma.GetElectionId())
+		}
+		rsp, err = t.Client.Set(ctx, req)
+	}
+	return rsp, err
+}
+
+func getMasterArbitration(exts []*gnmi_ext.Extension) *gnmi_ext.MasterArbitration {
+	for _, ext := range exts {
+		if ma := ext.GetMasterArbitration(); ma != nil {
+			return ma
+		}
+	}
+	return nil
+}
+
+func incrementUint128(u *gnmi_ext.Uint128) *gnmi_ext.Uint128 {
+	n := &gnmi_ext.Uint128{High: u.GetHigh(), Low: u.GetLow() + 1}
+	if n.Low == 0 {
+		n.High++
+	}
+	return n
+}
+
+func compareUint128(a, b *gnmi_ext.Uint128) int {
+	switch {
+	case a.GetHigh() > b.GetHigh():
+		return 1
+	case a.GetHigh() < b.GetHigh():
+		return -1
+	case a.GetLow() > b.GetLow():
+		return 1
+	case a.GetLow() < b.GetLow():
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package target
+
+import (
+	"context"
+	"testing"
+
+	"github.com/karimra/gnmic/types"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// arbitrationClient accepts SetRequests with an election ID
+// higher or equal to the master election ID.
+type arbitrationClient struct {
+	gnmi.GNMIClient
+	master   uint64
+	received []uint64
+}
+
+func (c *arbitrationClient) Set(ctx context.Context, req *gnmi.SetRequest, opts ...grpc.CallOption) (*gnmi.SetResponse, error) {
+	ma := getMasterArbitration(req.GetExtension())
+	if ma == nil {
+		return nil, status.Error(codes.FailedPrecondition, "missing master arbitration")
+	}
+	id := ma.GetElectionId().GetLow()
+	c.received = append(c.received, id)
+	if id < c.master {
+		return nil, status.Error(codes.PermissionDenied, "not master")
+	}
+	c.master = id
+	return &gnmi.SetResponse{}, nil
+}
+
+func TestSetMasterArbitration(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name:       "t1",
+		ElectionID: 10,
+		Role:       "r1",
+	})
+	c := &arbitrationClient{master: 12}
+	tg.Client = c
+	_, err := tg.Set(context.TODO(), &gnmi.SetRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []uint64{10, 11, 12}
+	if len(c.received) != len(expected) {
+		t.Fatalf("expected election IDs %v, got %v", expected, c.received)
+	}
+	for i := range expected {
+		if c.received[i] != expected[i] {
+			t.Fatalf("expected election IDs %v, got %v", expected, c.received)
+		}
+	}
+	// the next request uses the last election ID
+	c.received = nil
+	_, err = tg.Set(context.TODO(), &gnmi.SetRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.received) != 1 || c.received[0] != 12 {
+		t.Errorf("expected election IDs [12], got %v", c.received)
+	}
+	// retries are bounded
+	c.master = 100
+	c.received = nil
+	_, err = tg.Set(context.TODO(), &gnmi.SetRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected a PermissionDenied error, got %v", err)
+	}
+	if len(c.received) != maxElectionIDRetries+1 {
+		t.Errorf("expected %d attempts, got %d", maxElectionIDRetries+1, len(c.received))
+	}
+	// election ID from the request is not stored
+	c.master = 0
+	c.received = nil
+	_, err = tg.Set(context.TODO(), &gnmi.SetRequest{
+		Extension: []*gnmi_ext.Extension{
+			{
+				Ext: &gnmi_ext.Extension_MasterArbitration{
+					MasterArbitration: &gnmi_ext.MasterArbitration{
+						ElectionId: &gnmi_ext.Uint128{Low: 1},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.received) != 1 || c.received[0] != 1 {
+		t.Errorf("expected election IDs [1], got %v", c.received)
+	}
+}
+
+func TestIncrementUint128(t *testing.T) {
+	n := incrementUint128(&gnmi_ext.Uint128{High: 1, Low: ^uint64(0)})
+	if n.GetHigh() != 2 || n.GetLow() != 0 {
+		t.Errorf("unexpected result: %v", n)
+	}
+	if compareUint128(n, &gnmi_ext.Uint128{High: 1, Low: ^uint64(0)}) != 1 {
+		t.Errorf("expected incremented value to be greater")
+	}
+}
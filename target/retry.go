@@ -0,0 +1,158 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryState is a snapshot of the target retry status
+type RetryState struct {
+	ConsecutiveFailures int        `json:"consecutive-failures"`
+	CircuitOpen         bool       `json:"circuit-open"`
+	CircuitOpenUntil    *time.Time `json:"circuit-open-until,omitempty"`
+}
+
+// retryState tracks the consecutive failures of a target,
+// it is used to compute the wait time before the next attempt.
+// Failures are counted per scope: the target connection (the empty scope)
+// and each subscription, so that the failures of a subscription
+// do not shorten or lengthen the retries of the others.
+type retryState struct {
+	m                *sync.Mutex
+	rand             *rand.Rand
+	failures         map[string]int
+	circuitOpenUntil map[string]time.Time
+}
+
+func newRetryState() *retryState {
+	return &retryState{
+		m:                new(sync.Mutex),
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
+		failures:         make(map[string]int),
+		circuitOpenUntil: make(map[string]time.Time),
+	}
+}
+
+// RetryDelay records a connection failure and returns the time to wait before retrying.
+// If the target has no retry policy, the fixed retry timer is returned.
+// If the circuit breaker threshold is reached, the circuit is opened and
+// the circuit breaker timeout is returned.
+func (t *Target) RetryDelay() time.Duration {
+	return t.retryDelay("")
+}
+
+// ResetRetry clears the connection consecutive failures counter and closes its circuit.
+func (t *Target) ResetRetry() {
+	t.resetRetry("")
+}
+
+func (t *Target) retryDelay(scope string) time.Duration {
+	t.retry.m.Lock()
+	defer t.retry.m.Unlock()
+	t.retry.failures[scope]++
+	failures := t.retry.failures[scope]
+	rp := t.Config.RetryPolicy
+	if rp == nil {
+		return t.Config.RetryTimer
+	}
+	if rp.CircuitBreakerThreshold > 0 && failures >= rp.CircuitBreakerThreshold {
+		d := t.retry.jitter(rp.CircuitBreakerTimeout, rp.Jitter)
+		t.retry.circuitOpenUntil[scope] = time.Now().Add(d)
+		return d
+	}
+	d := float64(rp.Initial) * math.Pow(rp.Multiplier, float64(failures-1))
+	if d > float64(rp.Max) {
+		d = float64(rp.Max)
+	}
+	return t.retry.jitter(time.Duration(d), rp.Jitter)
+}
+
+// jitter randomly shortens d by up to jitter*d.
+// The jitter is applied downward only, so that the retries of targets failing together
+// are spread below the computed wait time instead of piling up on its bounds.
+func (r *retryState) jitter(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 - jitter*r.rand.Float64()))
+}
+
+func (t *Target) resetRetry(scope string) {
+	t.retry.m.Lock()
+	defer t.retry.m.Unlock()
+	delete(t.retry.failures, scope)
+	delete(t.retry.circuitOpenUntil, scope)
+}
+
+// CircuitOpen returns true if the target connection circuit
+// or one of its subscriptions circuit is open.
+func (t *Target) CircuitOpen() bool {
+	t.retry.m.Lock()
+	defer t.retry.m.Unlock()
+	for scope := range t.retry.failures {
+		if t.circuitOpen(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Target) circuitOpen(scope string) bool {
+	rp := t.Config.RetryPolicy
+	if rp == nil || rp.CircuitBreakerThreshold <= 0 {
+		return false
+	}
+	return t.retry.failures[scope] >= rp.CircuitBreakerThreshold
+}
+
+// RetryState returns a snapshot of the target retry status,
+// aggregating the connection and the subscriptions failures.
+func (t *Target) RetryState() *RetryState {
+	t.retry.m.Lock()
+	defer t.retry.m.Unlock()
+	rs := new(RetryState)
+	var until time.Time
+	for scope, f := range t.retry.failures {
+		if f > rs.ConsecutiveFailures {
+			rs.ConsecutiveFailures = f
+		}
+		if !t.circuitOpen(scope) {
+			continue
+		}
+		rs.CircuitOpen = true
+		if u := t.retry.circuitOpenUntil[scope]; u.After(until) {
+			until = u
+		}
+	}
+	if rs.CircuitOpen {
+		rs.CircuitOpenUntil = &until
+	}
+	return rs
+}
+
+func (t *Target) retryMsg(scope string, d time.Duration) string {
+	t.retry.m.Lock()
+	open := t.circuitOpen(scope)
+	t.retry.m.Unlock()
+	if open {
+		return fmt.Sprintf("circuit open, retrying in %s", d)
+	}
+	return fmt.Sprintf("retrying in %s", d)
+}
+
+// waitRetry blocks for duration d or until ctx is done.
+// It returns false if ctx is done.
+func waitRetry(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,181 @@
+package target
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/types"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name: "t1",
+		RetryPolicy: &types.RetryPolicy{
+			Initial:                 time.Second,
+			Max:                     5 * time.Second,
+			Multiplier:              2,
+			CircuitBreakerThreshold: 5,
+			CircuitBreakerTimeout:   time.Minute,
+		},
+	})
+	expected := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		time.Minute,
+		time.Minute,
+	}
+	for i, exp := range expected {
+		d := tg.RetryDelay()
+		if d != exp {
+			t.Errorf("failure %d: expected delay %s, got %s", i+1, exp, d)
+		}
+	}
+	rs := tg.RetryState()
+	if !rs.CircuitOpen {
+		t.Errorf("expected circuit to be open")
+	}
+	if rs.ConsecutiveFailures != len(expected) {
+		t.Errorf("expected %d consecutive failures, got %d", len(expected), rs.ConsecutiveFailures)
+	}
+	tg.ResetRetry()
+	if tg.CircuitOpen() {
+		t.Errorf("expected circuit to be closed after reset")
+	}
+	if d := tg.RetryDelay(); d != time.Second {
+		t.Errorf("expected delay %s after reset, got %s", time.Second, d)
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name: "t1",
+		RetryPolicy: &types.RetryPolicy{
+			Initial:    10 * time.Second,
+			Max:        time.Minute,
+			Multiplier: 1,
+			Jitter:     0.5,
+		},
+	})
+	for i := 0; i < 100; i++ {
+		d := tg.RetryDelay()
+		if d < 5*time.Second || d > 10*time.Second {
+			t.Fatalf("delay %s out of the jitter range", d)
+		}
+	}
+	if tg.CircuitOpen() {
+		t.Errorf("circuit breaker should be disabled")
+	}
+}
+
+// checkSpread checks that the delays are spread over [min, max],
+// with no pile up on the bounds.
+func checkSpread(t *testing.T, name string, delays []time.Duration, min, max time.Duration) {
+	t.Helper()
+	var atBounds int
+	buckets := make([]int, 4)
+	for _, d := range delays {
+		if d < min || d > max {
+			t.Fatalf("%s: delay %s out of the jitter range [%s, %s]", name, d, min, max)
+		}
+		if d == min || d == max {
+			atBounds++
+		}
+		i := int(float64(d-min) / float64(max-min) * float64(len(buckets)))
+		if i == len(buckets) {
+			i--
+		}
+		buckets[i]++
+	}
+	if atBounds > len(delays)/20 {
+		t.Errorf("%s: %d/%d delays on the range bounds", name, atBounds, len(delays))
+	}
+	for i, n := range buckets {
+		if n < len(delays)/(2*len(buckets)) {
+			t.Errorf("%s: delays not spread, bucket %d has %d/%d delays: %v", name, i, n, len(delays), buckets)
+		}
+	}
+}
+
+func TestRetryDelayJitterSpread(t *testing.T) {
+	rp := &types.RetryPolicy{
+		Initial:                 time.Second,
+		Max:                     10 * time.Second,
+		Multiplier:              2,
+		Jitter:                  0.5,
+		CircuitBreakerThreshold: 10,
+		CircuitBreakerTimeout:   time.Minute,
+	}
+	// the targets failing together
+	targets := make([]*Target, 1000)
+	for i := range targets {
+		targets[i] = NewTarget(&types.TargetConfig{Name: "t1", RetryPolicy: rp})
+	}
+	delays := func() []time.Duration {
+		ds := make([]time.Duration, 0, len(targets))
+		for _, tg := range targets {
+			ds = append(ds, tg.RetryDelay())
+		}
+		return ds
+	}
+	// first attempt
+	checkSpread(t, "initial", delays(), 500*time.Millisecond, time.Second)
+	// attempts 2 to 5: 2s, 4s, 8s, 10s
+	for i := 0; i < 3; i++ {
+		delays()
+	}
+	// the max is reached
+	checkSpread(t, "max", delays(), 5*time.Second, 10*time.Second)
+	for i := 0; i < 4; i++ {
+		delays()
+	}
+	// circuit open
+	checkSpread(t, "circuit breaker", delays(), 30*time.Second, time.Minute)
+}
+
+func TestRetryDelaySubscriptions(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name: "t1",
+		RetryPolicy: &types.RetryPolicy{
+			Initial:                 time.Second,
+			Max:                     time.Minute,
+			Multiplier:              2,
+			CircuitBreakerThreshold: 3,
+			CircuitBreakerTimeout:   time.Minute,
+		},
+	})
+	tg.retryDelay("sub1")
+	tg.retryDelay("sub1")
+	tg.retryDelay("sub2")
+	// each subscription counts its own failures
+	if d := tg.retryDelay("sub1"); d != time.Minute {
+		t.Errorf("expected sub1 circuit open delay %s, got %s", time.Minute, d)
+	}
+	if d := tg.RetryDelay(); d != time.Second {
+		t.Errorf("expected connection delay %s, got %s", time.Second, d)
+	}
+	rs := tg.RetryState()
+	if !rs.CircuitOpen || rs.ConsecutiveFailures != 3 {
+		t.Errorf("unexpected retry state: %+v", rs)
+	}
+	tg.StopSubscription("sub1")
+	if tg.CircuitOpen() {
+		t.Errorf("expected circuit to be closed after stopping sub1")
+	}
+	if d := tg.retryDelay("sub2"); d != 2*time.Second {
+		t.Errorf("expected sub2 delay %s, got %s", 2*time.Second, d)
+	}
+}
+
+func TestRetryDelayNoPolicy(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name:       "t1",
+		RetryTimer: 10 * time.Second,
+	})
+	for i := 0; i < 3; i++ {
+		if d := tg.RetryDelay(); d != 10*time.Second {
+			t.Errorf("expected fixed delay %s, got %s", 10*time.Second, d)
+		}
+	}
+}
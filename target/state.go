@@ -0,0 +1,160 @@
+package target
+
+import (
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+// target connection states
+const (
+	StateConnecting = "connecting"
+	StateConnected  = "connected"
+	StateRetrying   = "retrying"
+	StateFailed     = "failed"
+)
+
+// subscription states, in addition to connecting, retrying and failed
+const (
+	StateSubscribed = "subscribed"
+	StateSynced     = "synced"
+)
+
+// State is a snapshot of a target connection and subscriptions states
+type State struct {
+	Name              string                        `json:"name,omitempty"`
+	State             string                        `json:"state,omitempty"`
+	ConnectivityState string                        `json:"connectivity-state,omitempty"`
+	LastError         string                        `json:"last-error,omitempty"`
+	LastErrorTime     *time.Time                    `json:"last-error-time,omitempty"`
+	Retry             *RetryState                   `json:"retry,omitempty"`
+	Subscriptions     map[string]*SubscriptionState `json:"subscriptions,omitempty"`
+}
+
+// SubscriptionState is a snapshot of a target subscription state
+type SubscriptionState struct {
+	State            string     `json:"state,omitempty"`
+	LastError        string     `json:"last-error,omitempty"`
+	LastErrorTime    *time.Time `json:"last-error-time,omitempty"`
+	LastSync         *time.Time `json:"last-sync,omitempty"`
+	RetryCount       uint64     `json:"retry-count"`
+	MessagesReceived uint64     `json:"messages-received"`
+	BytesReceived    uint64     `json:"bytes-received"`
+}
+
+type targetState struct {
+	m             *sync.Mutex
+	state         string
+	lastError     string
+	lastErrorTime time.Time
+	subscriptions map[string]*SubscriptionState
+}
+
+func newTargetState() *targetState {
+	return &targetState{
+		m:             new(sync.Mutex),
+		subscriptions: make(map[string]*SubscriptionState),
+	}
+}
+
+// State returns a snapshot of the target connection and subscriptions states
+func (t *Target) State() *State {
+	retry := t.RetryState()
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	s := &State{
+		Name:              t.Config.Name,
+		State:             t.state.state,
+		ConnectivityState: t.ConnState(),
+		LastError:         t.state.lastError,
+		Retry:             retry,
+		Subscriptions:     make(map[string]*SubscriptionState, len(t.state.subscriptions)),
+	}
+	if retry.CircuitOpen {
+		s.State = StateFailed
+	}
+	if !t.state.lastErrorTime.IsZero() {
+		lt := t.state.lastErrorTime
+		s.LastErrorTime = &lt
+	}
+	for name, ss := range t.state.subscriptions {
+		nss := *ss
+		s.Subscriptions[name] = &nss
+	}
+	return s
+}
+
+func (t *Target) setState(state string, err error) {
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	t.state.state = state
+	if err != nil {
+		t.state.lastError = err.Error()
+		t.state.lastErrorTime = time.Now()
+	}
+}
+
+func (t *Target) setSubscriptionState(name, state string) {
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	t.subscriptionState(name).State = state
+}
+
+// subscriptionFailed records a subscription error,
+// the subscription state is set to failed if the subscription circuit is open,
+// retrying otherwise.
+func (t *Target) subscriptionFailed(name string, err error) {
+	state := StateRetrying
+	t.retry.m.Lock()
+	if t.circuitOpen(name) {
+		state = StateFailed
+	}
+	t.retry.m.Unlock()
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	ss := t.subscriptionState(name)
+	ss.State = state
+	ss.RetryCount++
+	if err != nil {
+		now := time.Now()
+		ss.LastError = err.Error()
+		ss.LastErrorTime = &now
+	}
+}
+
+// subscriptionResponse updates the subscription counters
+// and sets its state to synced if the response is a SyncResponse.
+func (t *Target) subscriptionResponse(name string, rsp *gnmi.SubscribeResponse) {
+	size := proto.Size(rsp)
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	ss := t.subscriptionState(name)
+	ss.MessagesReceived++
+	ss.BytesReceived += uint64(size)
+	switch rsp.GetResponse().(type) {
+	case *gnmi.SubscribeResponse_SyncResponse:
+		now := time.Now()
+		ss.State = StateSynced
+		ss.LastSync = &now
+	}
+}
+
+func (t *Target) deleteSubscriptionState(name string) {
+	t.state.m.Lock()
+	defer t.state.m.Unlock()
+	delete(t.state.subscriptions, name)
+}
+
+// subscriptionState returns the state of subscription name,
+// it creates it if it does not exist.
+// it assumes the state lock is acquired.
+func (t *Target) subscriptionState(name string) *SubscriptionState {
+	ss, ok := t.state.subscriptions[name]
+	if !ok {
+		ss = new(SubscriptionState)
+		t.state.subscriptions[name] = ss
+	}
+	return ss
+}
@@ -0,0 +1,75 @@
+package target
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnmic/types"
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestSubscriptionState(t *testing.T) {
+	tg := NewTarget(&types.TargetConfig{
+		Name: "t1",
+		RetryPolicy: &types.RetryPolicy{
+			Initial:                 time.Second,
+			Max:                     time.Minute,
+			Multiplier:              2,
+			CircuitBreakerThreshold: 2,
+			CircuitBreakerTimeout:   time.Minute,
+		},
+	})
+	tg.setSubscriptionState("sub1", StateConnecting)
+	tg.setSubscriptionState("sub1", StateSubscribed)
+	tg.subscriptionResponse("sub1", &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{Timestamp: 42},
+		},
+	})
+	tg.subscriptionResponse("sub1", &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true},
+	})
+	s := tg.State()
+	ss, ok := s.Subscriptions["sub1"]
+	if !ok {
+		t.Fatalf("missing subscription state")
+	}
+	if ss.State != StateSynced {
+		t.Errorf("expected state %q, got %q", StateSynced, ss.State)
+	}
+	if ss.LastSync == nil {
+		t.Errorf("expected last sync time to be set")
+	}
+	if ss.MessagesReceived != 2 {
+		t.Errorf("expected 2 messages received, got %d", ss.MessagesReceived)
+	}
+	if ss.BytesReceived == 0 {
+		t.Errorf("expected received bytes to be counted")
+	}
+
+	tg.retryDelay("sub1")
+	tg.subscriptionFailed("sub1", errors.New("err1"))
+	ss = tg.State().Subscriptions["sub1"]
+	if ss.State != StateRetrying {
+		t.Errorf("expected state %q, got %q", StateRetrying, ss.State)
+	}
+	if ss.LastError != "err1" || ss.RetryCount != 1 {
+		t.Errorf("unexpected subscription state: %+v", ss)
+	}
+
+	tg.retryDelay("sub1")
+	tg.subscriptionFailed("sub1", errors.New("err2"))
+	s = tg.State()
+	if s.State != StateFailed {
+		t.Errorf("expected target state %q, got %q", StateFailed, s.State)
+	}
+	if s.Subscriptions["sub1"].State != StateFailed {
+		t.Errorf("expected state %q, got %q", StateFailed, s.Subscriptions["sub1"].State)
+	}
+
+	tg.deleteSubscriptionState("sub1")
+	if _, ok := tg.State().Subscriptions["sub1"]; ok {
+		t.Errorf("subscription state not deleted")
+	}
+}
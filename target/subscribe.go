@@ -6,11 +6,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"time"
 
 	"github.com/jhump/protoreflect/dynamic"
+	"github.com/karimra/gnmic/types"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 // Subscribe sends a gnmi.SubscribeRequest to the target *t, responses and error are sent to the target channels
@@ -19,12 +20,24 @@ func (t *Target) Subscribe(ctx context.Context, req *gnmi.SubscribeRequest, subs
 	var nctx context.Context
 	var cancel context.CancelFunc
 	var err error
+	// sctx is canceled when the subscription is stopped,
+	// it is the parent of the contexts of all the subscribe RPCs
+	// created for this subscription, including on retries.
+	sctx, scancel := context.WithCancel(ctx)
+	defer scancel()
+	t.m.Lock()
+	if cfn, ok := t.subscribeCancelFn[subscriptionName]; ok {
+		cfn()
+	}
+	t.subscribeCancelFn[subscriptionName] = scancel
+	t.m.Unlock()
 SUBSC:
 	select {
-	case <-ctx.Done():
+	case <-sctx.Done():
 		return
 	default:
-		nctx, cancel = context.WithCancel(ctx)
+		t.setSubscriptionState(subscriptionName, StateConnecting)
+		nctx, cancel = context.WithCancel(sctx)
 		defer cancel()
 		if t.Config.Username != nil {
 			nctx = metadata.AppendToOutgoingContext(nctx, "username", *t.Config.Username)
@@ -34,54 +47,72 @@ SUBSC:
 		}
 		subscribeClient, err = t.Client.Subscribe(nctx)
 		if err != nil {
+			delay := t.retryDelay(subscriptionName)
+			t.subscriptionFailed(subscriptionName, err)
 			t.errors <- &TargetError{
 				SubscriptionName: subscriptionName,
-				Err:              fmt.Errorf("failed to create a subscribe client, target='%s', retry in %d. err=%v", t.Config.Name, t.Config.RetryTimer, err),
+				Err:              fmt.Errorf("failed to create a subscribe client, target='%s', %s. err=%v", t.Config.Name, t.retryMsg(subscriptionName, delay), err),
 			}
 			cancel()
-			time.Sleep(t.Config.RetryTimer)
+			if !waitRetry(sctx, delay) {
+				return
+			}
 			goto SUBSC
 		}
 	}
 	t.m.Lock()
-	if cfn, ok := t.subscribeCancelFn[subscriptionName]; ok {
-		cfn()
-	}
 	t.SubscribeClients[subscriptionName] = subscribeClient
-	t.subscribeCancelFn[subscriptionName] = cancel
 	subConfig := t.Subscriptions[subscriptionName]
 	t.m.Unlock()
-	err = subscribeClient.Send(req)
+	err = subscribeClient.Send(t.withExtensions(req))
 	if err != nil {
+		delay := t.retryDelay(subscriptionName)
+		t.subscriptionFailed(subscriptionName, err)
 		t.errors <- &TargetError{
 			SubscriptionName: subscriptionName,
-			Err:              fmt.Errorf("target '%s' send error, retry in %d. err=%v", t.Config.Name, t.Config.RetryTimer, err),
+			Err:              fmt.Errorf("target '%s' send error, %s. err=%v", t.Config.Name, t.retryMsg(subscriptionName, delay), err),
 		}
 		cancel()
-		time.Sleep(t.Config.RetryTimer)
+		if !waitRetry(sctx, delay) {
+			return
+		}
 		goto SUBSC
 	}
+	t.setSubscriptionState(subscriptionName, StateSubscribed)
 
 	switch req.GetSubscribe().Mode {
 	case gnmi.SubscriptionList_STREAM:
+		retryReset := false
 		for {
 			if nctx.Err() != nil {
 				return
 			}
 			response, err := subscribeClient.Recv()
 			if err != nil {
+				if sctx.Err() != nil {
+					return
+				}
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
 					Err:              err,
 				}
+				delay := t.retryDelay(subscriptionName)
+				t.subscriptionFailed(subscriptionName, err)
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
-					Err:              fmt.Errorf("retrying in %s", t.Config.RetryTimer),
+					Err:              errors.New(t.retryMsg(subscriptionName, delay)),
 				}
 				cancel()
-				time.Sleep(t.Config.RetryTimer)
+				if !waitRetry(sctx, delay) {
+					return
+				}
 				goto SUBSC
 			}
+			if !retryReset {
+				t.resetRetry(subscriptionName)
+				retryReset = true
+			}
+			t.subscriptionResponse(subscriptionName, response)
 			t.subscribeResponses <- &SubscribeResponse{
 				SubscriptionName:   subscriptionName,
 				SubscriptionConfig: subConfig,
@@ -92,6 +123,9 @@ SUBSC:
 		for {
 			response, err := subscribeClient.Recv()
 			if err != nil {
+				if sctx.Err() != nil {
+					return
+				}
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
 					Err:              err,
@@ -99,14 +133,20 @@ SUBSC:
 				if errors.Is(err, io.EOF) {
 					return
 				}
+				delay := t.retryDelay(subscriptionName)
+				t.subscriptionFailed(subscriptionName, err)
 				t.errors <- &TargetError{
 					SubscriptionName: subscriptionName,
-					Err:              fmt.Errorf("retrying in %d", t.Config.RetryTimer),
+					Err:              errors.New(t.retryMsg(subscriptionName, delay)),
 				}
 				cancel()
-				time.Sleep(t.Config.RetryTimer)
+				if !waitRetry(sctx, delay) {
+					return
+				}
 				goto SUBSC
 			}
+			t.resetRetry(subscriptionName)
+			t.subscriptionResponse(subscriptionName, response)
 			t.subscribeResponses <- &SubscribeResponse{
 				SubscriptionName:   subscriptionName,
 				SubscriptionConfig: subConfig,
@@ -141,6 +181,8 @@ SUBSC:
 					}
 					continue
 				}
+				t.resetRetry(subscriptionName)
+				t.subscriptionResponse(subscriptionName, response)
 				t.subscribeResponses <- &SubscribeResponse{
 					SubscriptionName:   subscriptionName,
 					SubscriptionConfig: subConfig,
@@ -166,7 +208,7 @@ func (t *Target) SubscribeOnceChan(ctx context.Context, req *gnmi.SubscribeReque
 			errCh <- err
 			return
 		}
-		err = subscribeClient.Send(req)
+		err = subscribeClient.Send(t.withExtensions(req))
 		if err != nil {
 			errCh <- err
 			return
@@ -213,7 +255,8 @@ func (t *Target) ReadSubscriptions() (chan *SubscribeResponse, chan *TargetError
 
 func (t *Target) NumberOfOnceSubscriptions() int {
 	num := 0
-	for _, sub := range t.Subscriptions {
+	subs, _ := t.GetSubscriptions()
+	for _, sub := range subs {
 		if strings.ToUpper(sub.Mode) == "ONCE" {
 			num++
 		}
@@ -246,19 +289,78 @@ func (t *Target) DecodeProtoBytes(resp *gnmi.SubscribeResponse) error {
 	return nil
 }
 
+// withExtensions returns a copy of the gnmi.SubscribeRequest req
+// with the target extensions added to it.
+func (t *Target) withExtensions(req *gnmi.SubscribeRequest) *gnmi.SubscribeRequest {
+	if len(t.Extensions) == 0 {
+		return req
+	}
+	req = proto.Clone(req).(*gnmi.SubscribeRequest)
+	req.Extension = append(req.Extension, t.Extensions...)
+	return req
+}
+
+// AddSubscription adds subscription sc to the target subscriptions.
+// If the target subscriptions are already started, the subscription is started
+// using the gnmi.SubscribeRequest req, without affecting the other subscriptions.
+func (t *Target) AddSubscription(sc *types.SubscriptionConfig, req *gnmi.SubscribeRequest) {
+	t.m.Lock()
+	t.Subscriptions[sc.Name] = sc
+	t.subscriptionsSet = true
+	ctx := t.subscribeCtx
+	t.m.Unlock()
+	if ctx == nil {
+		return
+	}
+	go t.Subscribe(ctx, req, sc.Name)
+}
+
+// SetSubscribeContext sets ctx as the parent context of the subscriptions added
+// with AddSubscription after the initial ones are started.
+// It returns the target subscriptions configured at the time of the call,
+// the caller is expected to start them.
+func (t *Target) SetSubscribeContext(ctx context.Context) []*types.SubscriptionConfig {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.subscribeCtx = ctx
+	subs := make([]*types.SubscriptionConfig, 0, len(t.Subscriptions))
+	for _, sc := range t.Subscriptions {
+		subs = append(subs, sc)
+	}
+	return subs
+}
+
 func (t *Target) DeleteSubscription(name string) {
+	t.StopSubscription(name)
 	t.m.Lock()
 	defer t.m.Unlock()
-	t.subscribeCancelFn[name]()
-	delete(t.subscribeCancelFn, name)
-	delete(t.SubscribeClients, name)
-	delete(t.Subscriptions, name)
+	if _, ok := t.Subscriptions[name]; ok {
+		delete(t.Subscriptions, name)
+		t.subscriptionsSet = true
+	}
+}
+
+// GetSubscriptions returns a copy of the target subscriptions.
+// The returned bool is false if the target subscriptions were never set,
+// it is true if they were all deleted using DeleteSubscription.
+func (t *Target) GetSubscriptions() (map[string]*types.SubscriptionConfig, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	subs := make(map[string]*types.SubscriptionConfig, len(t.Subscriptions))
+	for n, sc := range t.Subscriptions {
+		subs[n] = sc
+	}
+	return subs, t.subscriptionsSet || len(subs) > 0
 }
 
 func (t *Target) StopSubscription(name string) {
+	t.deleteSubscriptionState(name)
+	t.resetRetry(name)
 	t.m.Lock()
 	defer t.m.Unlock()
-	t.subscribeCancelFn[name]()
+	if cfn, ok := t.subscribeCancelFn[name]; ok {
+		cfn()
+	}
 	delete(t.subscribeCancelFn, name)
 	delete(t.SubscribeClients, name)
 }
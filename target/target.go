@@ -2,6 +2,7 @@ package target
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"golang.org/x/net/proxy"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 type TargetError struct {
@@ -38,13 +40,21 @@ type Target struct {
 	Client             gnmi.GNMIClient                      `json:"-"`
 	SubscribeClients   map[string]gnmi.GNMI_SubscribeClient `json:"-"` // subscription name to subscribeClient
 	subscribeCancelFn  map[string]context.CancelFunc
+	subscribeCtx       context.Context
+	subscriptionsSet   bool        // true once subscriptions were added or deleted at runtime
 	pollChan           chan string // subscription name to be polled
 	subscribeResponses chan *SubscribeResponse
 	errors             chan *TargetError
 	stopped            bool
+	retry              *retryState
+	state              *targetState
 	StopChan           chan struct{}      `json:"-"`
 	Cfn                context.CancelFunc `json:"-"`
 	RootDesc           desc.Descriptor    `json:"-"`
+	// gNMI extensions added to all the RPCs sent to the target
+	Extensions []*gnmi_ext.Extension `json:"-"`
+	// current election ID used in the SetRequests master arbitration extension
+	electionID *gnmi_ext.Uint128
 }
 
 // NewTarget //
@@ -58,15 +68,31 @@ func NewTarget(c *types.TargetConfig) *Target {
 		pollChan:           make(chan string),
 		subscribeResponses: make(chan *SubscribeResponse, c.BufferSize),
 		errors:             make(chan *TargetError, c.BufferSize),
+		retry:              newRetryState(),
+		state:              newTargetState(),
 		StopChan:           make(chan struct{}),
 	}
 	return t
 }
 
+// MarshalJSON adds the target retry state to its JSON representation
+func (t *Target) MarshalJSON() ([]byte, error) {
+	type target Target
+	return json.Marshal(&struct {
+		*target
+		Retry *RetryState `json:"retry,omitempty"`
+	}{
+		target: (*target)(t),
+		Retry:  t.RetryState(),
+	})
+}
+
 // CreateGNMIClient //
 func (t *Target) CreateGNMIClient(ctx context.Context, opts ...grpc.DialOption) error {
+	t.setState(StateConnecting, nil)
 	tOpts, err := t.Config.GrpcDialOptions()
 	if err != nil {
+		t.setState(StateFailed, err)
 		return err
 	}
 	opts = append(opts, tOpts...)
@@ -126,11 +152,14 @@ func (t *Target) CreateGNMIClient(ctx context.Context, opts ...grpc.DialOption)
 			close(done)
 			t.conn = conn
 			t.Client = gnmi.NewGNMIClient(conn)
+			t.setState(StateConnected, nil)
 			return nil
 		case err := <-errC:
 			errs = append(errs, err.Error())
 			if len(errs) == numAddrs {
-				return fmt.Errorf("%s", strings.Join(errs, ", "))
+				err = fmt.Errorf("%s", strings.Join(errs, ", "))
+				t.setState(StateRetrying, err)
+				return err
 			}
 		}
 	}
@@ -144,7 +173,10 @@ func (t *Target) Capabilities(ctx context.Context, ext ...*gnmi_ext.Extension) (
 	if t.Config.Password != nil {
 		ctx = metadata.AppendToOutgoingContext(ctx, "password", *t.Config.Password)
 	}
-	return t.Client.Capabilities(ctx, &gnmi.CapabilityRequest{Extension: ext})
+	exts := make([]*gnmi_ext.Extension, 0, len(ext)+len(t.Extensions))
+	exts = append(exts, ext...)
+	exts = append(exts, t.Extensions...)
+	return t.Client.Capabilities(ctx, &gnmi.CapabilityRequest{Extension: exts})
 }
 
 // Get sends a gnmi.GetRequest to the target *t and returns a gnmi.GetResponse and an error
@@ -155,10 +187,18 @@ func (t *Target) Get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetRespon
 	if t.Config.Password != nil {
 		ctx = metadata.AppendToOutgoingContext(ctx, "password", *t.Config.Password)
 	}
+	if len(t.Extensions) > 0 {
+		req = proto.Clone(req).(*gnmi.GetRequest)
+		req.Extension = append(req.Extension, t.Extensions...)
+	}
 	return t.Client.Get(ctx, req)
 }
 
-// Set sends a gnmi.SetRequest to the target *t and returns a gnmi.SetResponse and an error
+// Set sends a gnmi.SetRequest to the target *t and returns a gnmi.SetResponse and an error.
+// If the target has an election ID configured and req does not include a master arbitration extension,
+// one is added to it.
+// If the request includes a master arbitration extension and the target returns a PermissionDenied error,
+// the request is resent with a higher election ID.
 func (t *Target) Set(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetResponse, error) {
 	if t.Config.Username != nil {
 		ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username)
@@ -166,7 +206,16 @@ func (t *Target) Set(ctx context.Context, req *gnmi.SetRequest) (*gnmi.SetRespon
 	if t.Config.Password != nil {
 		ctx = metadata.AppendToOutgoingContext(ctx, "password", *t.Config.Password)
 	}
-	return t.Client.Set(ctx, req)
+	req = proto.Clone(req).(*gnmi.SetRequest)
+	req.Extension = append(req.Extension, t.Extensions...)
+	var fromConfig bool
+	if getMasterArbitration(req.GetExtension()) == nil {
+		if ma := t.masterArbitration(); ma != nil {
+			req.Extension = append(req.Extension, ma)
+			fromConfig = true
+		}
+	}
+	return t.set(ctx, req, fromConfig)
 }
 
 func (t *Target) StopSubscriptions() {
@@ -7,6 +7,7 @@ import (
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/openconfig/gnmi/proto/gnmi_ext"
 	tpb "github.com/openconfig/grpctunnel/proto/tunnel"
+	"google.golang.org/protobuf/proto"
 )
 
 func CapabilitiesResponsesEqual(rsp1, rsp2 *gnmi.CapabilityResponse) bool {
@@ -83,9 +84,15 @@ func SetRequestsEqual(req1, req2 *gnmi.SetRequest) bool {
 	}
 	if len(req1.GetDelete()) != len(req2.GetDelete()) ||
 		len(req1.GetReplace()) != len(req2.GetReplace()) ||
-		len(req1.GetUpdate()) != len(req2.GetUpdate()) {
+		len(req1.GetUpdate()) != len(req2.GetUpdate()) ||
+		len(req1.GetExtension()) != len(req2.GetExtension()) {
 		return false
 	}
+	for i := range req1.GetExtension() {
+		if !proto.Equal(req1.GetExtension()[i], req2.GetExtension()[i]) {
+			return false
+		}
+	}
 	if !GnmiPathsEqual(req1.GetPrefix(), req2.GetPrefix()) {
 		return false
 	}
@@ -0,0 +1,18 @@
+package types
+
+// ExtensionConfig defines a gNMI registered extension.
+// The proto message is used to encode the extension value added to the gNMI RPCs,
+// as well as to decode the extensions with the same ID received from the targets.
+type ExtensionConfig struct {
+	// registered extension ID
+	ID int32 `mapstructure:"id,omitempty" json:"id,omitempty" yaml:"id,omitempty"`
+	// fully qualified name of the proto message describing the extension
+	MessageName string `mapstructure:"message-name,omitempty" json:"message-name,omitempty" yaml:"message-name,omitempty"`
+	// proto files defining the message
+	ProtoFiles []string `mapstructure:"proto-files,omitempty" json:"proto-files,omitempty" yaml:"proto-files,omitempty"`
+	// directories to look for the proto files in
+	ProtoDirs []string `mapstructure:"proto-dirs,omitempty" json:"proto-dirs,omitempty" yaml:"proto-dirs,omitempty"`
+	// extension value in JSON format, if not set the extension is not sent,
+	// only used to decode the received extensions.
+	Value interface{} `mapstructure:"value,omitempty" json:"value,omitempty" yaml:"value,omitempty"`
+}
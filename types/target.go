@@ -19,35 +19,58 @@ import (
 
 // TargetConfig //
 type TargetConfig struct {
-	Name          string            `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
-	Address       string            `mapstructure:"address,omitempty" json:"address,omitempty" yaml:"address,omitempty"`
-	Username      *string           `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
-	Password      *string           `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
-	Timeout       time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"`
-	Insecure      *bool             `mapstructure:"insecure,omitempty" json:"insecure,omitempty" yaml:"insecure,omitempty"`
-	TLSCA         *string           `mapstructure:"tls-ca,omitempty" json:"tls-ca,omitempty" yaml:"tlsca,omitempty"`
-	TLSCert       *string           `mapstructure:"tls-cert,omitempty" json:"tls-cert,omitempty" yaml:"tls-cert,omitempty"`
-	TLSKey        *string           `mapstructure:"tls-key,omitempty" json:"tls-key,omitempty" yaml:"tls-key,omitempty"`
-	SkipVerify    *bool             `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty" yaml:"skip-verify,omitempty"`
-	Subscriptions []string          `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
-	Outputs       []string          `mapstructure:"outputs,omitempty" json:"outputs,omitempty" yaml:"outputs,omitempty"`
-	BufferSize    uint              `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty" yaml:"buffer-size,omitempty"`
-	RetryTimer    time.Duration     `mapstructure:"retry,omitempty" json:"retry-timer,omitempty" yaml:"retry-timer,omitempty"`
-	TLSMinVersion string            `mapstructure:"tls-min-version,omitempty" json:"tls-min-version,omitempty" yaml:"tls-min-version,omitempty"`
-	TLSMaxVersion string            `mapstructure:"tls-max-version,omitempty" json:"tls-max-version,omitempty" yaml:"tls-max-version,omitempty"`
-	TLSVersion    string            `mapstructure:"tls-version,omitempty" json:"tls-version,omitempty" yaml:"tls-version,omitempty"`
-	LogTLSSecret  *bool             `mapstructure:"log-tls-secret,omitempty" json:"log-tls-secret,omitempty" yaml:"log-tls-secret,omitempty"`
-	ProtoFiles    []string          `mapstructure:"proto-files,omitempty" json:"proto-files,omitempty" yaml:"proto-files,omitempty"`
-	ProtoDirs     []string          `mapstructure:"proto-dirs,omitempty" json:"proto-dirs,omitempty" yaml:"proto-dirs,omitempty"`
-	Tags          []string          `mapstructure:"tags,omitempty" json:"tags,omitempty" yaml:"tags,omitempty"`
-	EventTags     map[string]string `mapstructure:"event-tags,omitempty" json:"event-tags,omitempty" yaml:"event-tags,omitempty"`
-	Gzip          *bool             `mapstructure:"gzip,omitempty" json:"gzip,omitempty" yaml:"gzip,omitempty"`
-	Token         *string           `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
-	Proxy         string            `mapstructure:"proxy,omitempty" json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Name          string             `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Address       string             `mapstructure:"address,omitempty" json:"address,omitempty" yaml:"address,omitempty"`
+	Username      *string            `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
+	Password      *string            `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	Timeout       time.Duration      `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Insecure      *bool              `mapstructure:"insecure,omitempty" json:"insecure,omitempty" yaml:"insecure,omitempty"`
+	TLSCA         *string            `mapstructure:"tls-ca,omitempty" json:"tls-ca,omitempty" yaml:"tlsca,omitempty"`
+	TLSCert       *string            `mapstructure:"tls-cert,omitempty" json:"tls-cert,omitempty" yaml:"tls-cert,omitempty"`
+	TLSKey        *string            `mapstructure:"tls-key,omitempty" json:"tls-key,omitempty" yaml:"tls-key,omitempty"`
+	SkipVerify    *bool              `mapstructure:"skip-verify,omitempty" json:"skip-verify,omitempty" yaml:"skip-verify,omitempty"`
+	Subscriptions []string           `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	Outputs       []string           `mapstructure:"outputs,omitempty" json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	BufferSize    uint               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty" yaml:"buffer-size,omitempty"`
+	RetryTimer    time.Duration      `mapstructure:"retry,omitempty" json:"retry-timer,omitempty" yaml:"retry-timer,omitempty"`
+	TLSMinVersion string             `mapstructure:"tls-min-version,omitempty" json:"tls-min-version,omitempty" yaml:"tls-min-version,omitempty"`
+	TLSMaxVersion string             `mapstructure:"tls-max-version,omitempty" json:"tls-max-version,omitempty" yaml:"tls-max-version,omitempty"`
+	TLSVersion    string             `mapstructure:"tls-version,omitempty" json:"tls-version,omitempty" yaml:"tls-version,omitempty"`
+	LogTLSSecret  *bool              `mapstructure:"log-tls-secret,omitempty" json:"log-tls-secret,omitempty" yaml:"log-tls-secret,omitempty"`
+	ProtoFiles    []string           `mapstructure:"proto-files,omitempty" json:"proto-files,omitempty" yaml:"proto-files,omitempty"`
+	ProtoDirs     []string           `mapstructure:"proto-dirs,omitempty" json:"proto-dirs,omitempty" yaml:"proto-dirs,omitempty"`
+	Tags          []string           `mapstructure:"tags,omitempty" json:"tags,omitempty" yaml:"tags,omitempty"`
+	EventTags     map[string]string  `mapstructure:"event-tags,omitempty" json:"event-tags,omitempty" yaml:"event-tags,omitempty"`
+	Gzip          *bool              `mapstructure:"gzip,omitempty" json:"gzip,omitempty" yaml:"gzip,omitempty"`
+	Token         *string            `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
+	Proxy         string             `mapstructure:"proxy,omitempty" json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	RetryPolicy   *RetryPolicy       `mapstructure:"retry-policy,omitempty" json:"retry-policy,omitempty" yaml:"retry-policy,omitempty"`
+	Extensions    []*ExtensionConfig `mapstructure:"extensions,omitempty" json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	ElectionID    uint64             `mapstructure:"election-id,omitempty" json:"election-id,omitempty" yaml:"election-id,omitempty"`
+	Role          string             `mapstructure:"role,omitempty" json:"role,omitempty" yaml:"role,omitempty"`
 	//
 	TunnelTargetType string `mapstructure:"-" json:"tunnel-target-type,omitempty" yaml:"tunnel-target-type,omitempty"`
 }
 
+// RetryPolicy defines the exponential backoff applied between
+// a target's gRPC dial and subscription retries,
+// as well as the circuit breaker thresholds.
+type RetryPolicy struct {
+	// initial wait time after the first failure
+	Initial time.Duration `mapstructure:"initial,omitempty" json:"initial,omitempty" yaml:"initial,omitempty"`
+	// upper bound of the wait time between retries
+	Max time.Duration `mapstructure:"max,omitempty" json:"max,omitempty" yaml:"max,omitempty"`
+	// factor applied to the wait time after each consecutive failure
+	Multiplier float64 `mapstructure:"multiplier,omitempty" json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	// randomization factor in [0, 1], each wait time is randomly shortened by up to this fraction
+	Jitter float64 `mapstructure:"jitter,omitempty" json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	// number of consecutive failures after which the circuit is opened,
+	// 0 disables the circuit breaker
+	CircuitBreakerThreshold int `mapstructure:"circuit-breaker-threshold,omitempty" json:"circuit-breaker-threshold,omitempty" yaml:"circuit-breaker-threshold,omitempty"`
+	// time the circuit stays open before a new attempt is made
+	CircuitBreakerTimeout time.Duration `mapstructure:"circuit-breaker-timeout,omitempty" json:"circuit-breaker-timeout,omitempty" yaml:"circuit-breaker-timeout,omitempty"`
+}
+
 func (tc TargetConfig) String() string {
 	if tc.Password != nil {
 		pwd := "****"
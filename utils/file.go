@@ -283,3 +283,53 @@ func getHostKey(host string) (ssh.PublicKey, error) {
 	}
 	return hostKey, nil
 }
+
+// FindYangFiles returns the YANG files in files,
+// the directories in files are walked recursively.
+func FindYangFiles(files []string) ([]string, error) {
+	yfiles := make([]string, 0, len(files))
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			fls, err := walkDir(file, ".yang")
+			if err != nil {
+				return nil, err
+			}
+			yfiles = append(yfiles, fls...)
+		case mode.IsRegular():
+			if filepath.Ext(file) == ".yang" {
+				yfiles = append(yfiles, file)
+			}
+		}
+	}
+	return yfiles, nil
+}
+
+func walkDir(path, ext string) ([]string, error) {
+	fs := make([]string, 0)
+	err := filepath.Walk(path,
+		func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			switch mode := fi.Mode(); {
+			case mode.IsRegular():
+				if filepath.Ext(path) == ext {
+					fs = append(fs, path)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}